@@ -15,9 +15,11 @@
 package api
 
 import (
+	"context"
 	"sync"
 
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/stat"
 )
 
 var entryOptsPool = sync.Pool{
@@ -39,20 +41,35 @@ type EntryOptions struct {
 	resourceType base.ResourceType
 	entryType    base.TrafficType
 	batchCount   uint32
+	weight       uint32
 	flag         int32
 	slotChain    *base.SlotChain
 	args         []interface{}
 	attachments  map[interface{}]interface{}
+	tags         []string
+	context      context.Context
+	// needRateLimitInfo indicates whether to collect the quota state of rules
+	needRateLimitInfo bool
+	// reserve indicates whether to reserve the tokens rather than waiting, only used by Reserve
+	reserve bool
+	// statConfig is the statistic window declared by the resource
+	statConfig *stat.ResourceStatConfig
 }
 
 func (o *EntryOptions) Reset() {
 	o.resourceType = base.ResTypeCommon
 	o.entryType = base.Outbound
 	o.batchCount = 1
+	o.weight = 0
 	o.flag = 0
 	o.slotChain = nil
 	o.args = o.args[:0]
 	o.attachments = nil
+	o.tags = nil
+	o.context = nil
+	o.needRateLimitInfo = false
+	o.reserve = false
+	o.statConfig = nil
 }
 
 type EntryOption func(*EntryOptions)
@@ -86,6 +103,14 @@ func WithBatchCount(batchCount uint32) EntryOption {
 	}
 }
 
+// WithWeight sets the resource entry with the given weight (e.g. bytes, estimated CPU cost).
+// The weight is used by the weighted concurrency isolation rules, BatchCount is used if the weight is not set.
+func WithWeight(weight uint32) EntryOption {
+	return func(opts *EntryOptions) {
+		opts.weight = weight
+	}
+}
+
 // WithFlag sets the resource entry with the given additional flag.
 func WithFlag(flag int32) EntryOption {
 	return func(opts *EntryOptions) {
@@ -129,6 +154,38 @@ func WithAttachments(data map[interface{}]interface{}) EntryOption {
 	}
 }
 
+// WithTags sets the resource entry with the given tags, which could be selected by the rules with ResourceSelector.
+func WithTags(tags ...string) EntryOption {
+	return func(opts *EntryOptions) {
+		opts.tags = append(opts.tags, tags...)
+	}
+}
+
+// WithContext sets the resource entry with the given context of caller.
+// The slots which may wait (e.g. isolation queueing) return immediately once the context is done.
+func WithContext(ctx context.Context) EntryOption {
+	return func(opts *EntryOptions) {
+		opts.context = ctx
+	}
+}
+
+// WithRateLimitInfo makes the flow and hotspot rules report their quota state, which could be read by
+// SentinelEntry.RateLimitInfo on pass, or BlockError.RateLimitInfo on block to write the rate limit headers.
+func WithRateLimitInfo() EntryOption {
+	return func(opts *EntryOptions) {
+		opts.needRateLimitInfo = true
+	}
+}
+
+// WithStatConfig declares the statistic window of the resource, the Resource field of conf is ignored.
+// Only the first declaration of the resource takes effect, and the config loaded by stat.LoadResourceStatConfigs
+// takes precedence over the declaration.
+func WithStatConfig(conf stat.ResourceStatConfig) EntryOption {
+	return func(opts *EntryOptions) {
+		opts.statConfig = &conf
+	}
+}
+
 // Entry is the basic API of Sentinel.
 func Entry(resource string, opts ...EntryOption) (*base.SentinelEntry, *base.BlockError) {
 	options := entryOptsPool.Get().(*EntryOptions)
@@ -149,6 +206,10 @@ func Entry(resource string, opts ...EntryOption) (*base.SentinelEntry, *base.Blo
 func entry(resource string, options *EntryOptions) (*base.SentinelEntry, *base.BlockError) {
 	rw := base.NewResourceWrapper(resource, options.resourceType, options.entryType)
 	sc := options.slotChain
+	if options.statConfig != nil {
+		// must be declared before the resource node is created
+		stat.DeclareResourceStatConfig(resource, *options.statConfig)
+	}
 
 	if sc == nil {
 		return base.NewSentinelEntry(nil, rw, nil), nil
@@ -157,6 +218,7 @@ func entry(resource string, options *EntryOptions) (*base.SentinelEntry, *base.B
 	ctx := sc.GetPooledContext()
 	ctx.Resource = rw
 	ctx.Input.BatchCount = options.batchCount
+	ctx.Input.Weight = options.weight
 	ctx.Input.Flag = options.flag
 	if len(options.args) != 0 {
 		ctx.Input.Args = options.args
@@ -164,6 +226,10 @@ func entry(resource string, options *EntryOptions) (*base.SentinelEntry, *base.B
 	if len(options.attachments) != 0 {
 		ctx.Input.Attachments = options.attachments
 	}
+	ctx.Input.Tags = options.tags
+	ctx.Input.Context = options.context
+	ctx.Input.NeedRateLimitInfo = options.needRateLimitInfo
+	ctx.Input.Reserve = options.reserve
 	e := base.NewSentinelEntry(ctx, rw, sc)
 	ctx.SetEntry(e)
 	r := sc.Entry(ctx)
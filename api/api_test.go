@@ -18,6 +18,8 @@ import (
 	"testing"
 
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/flow"
+	"github.com/alibaba/sentinel-golang/core/stat"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -157,3 +159,41 @@ func Test_entryWithArgsAndChainBlock(t *testing.T) {
 	ssm.AssertNumberOfCalls(t, "OnEntryBlocked", 1)
 	ssm.AssertNumberOfCalls(t, "OnCompleted", 0)
 }
+
+func TestEntryWithShadowRule(t *testing.T) {
+	_, err := flow.LoadRules([]*flow.Rule{
+		{
+			Resource:               "shadow-test",
+			TokenCalculateStrategy: flow.Direct,
+			ControlBehavior:        flow.Reject,
+			Threshold:              1,
+			StatIntervalInMs:       1000,
+			Mode:                   base.Shadow,
+		},
+	})
+	assert.Nil(t, err)
+	defer flow.ClearRules()
+
+	for i := 0; i < 3; i++ {
+		e, b := Entry("shadow-test")
+		assert.Nil(t, b)
+		e.Exit()
+	}
+	node := stat.GetResourceNode("shadow-test")
+	assert.Equal(t, int64(3), node.GetSum(base.MetricEventPass))
+	assert.Equal(t, int64(2), node.GetSum(base.MetricEventShadowBlock))
+	assert.Equal(t, int64(0), node.GetSum(base.MetricEventBlock))
+}
+
+func TestEntryWithStatConfig(t *testing.T) {
+	conf := stat.ResourceStatConfig{SampleCountTotal: 60, IntervalMsTotal: 60000}
+	for i := 0; i < 2; i++ {
+		e, b := Entry("stat-config-test", WithStatConfig(conf))
+		assert.Nil(t, b)
+		e.Exit()
+	}
+	node := stat.GetResourceNode("stat-config-test")
+	assert.Equal(t, uint32(60), node.SampleCountTotal())
+	assert.Equal(t, uint32(60000), node.IntervalMsTotal())
+	assert.Equal(t, int64(2), node.GetSum(base.MetricEventPass))
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/alibaba/sentinel-golang/core/config"
 	"github.com/alibaba/sentinel-golang/core/log/metric"
+	"github.com/alibaba/sentinel-golang/core/stat"
 	"github.com/alibaba/sentinel-golang/core/system_metric"
 	metric_exporter "github.com/alibaba/sentinel-golang/exporter/metric"
 	"github.com/alibaba/sentinel-golang/util"
@@ -92,6 +93,7 @@ func initCoreComponents() error {
 	loadStatInterval := systemStatInterval
 	cpuStatInterval := systemStatInterval
 	memStatInterval := systemStatInterval
+	runtimeStatInterval := systemStatInterval
 
 	if config.LoadStatCollectIntervalMs() > 0 {
 		loadStatInterval = config.LoadStatCollectIntervalMs()
@@ -102,6 +104,9 @@ func initCoreComponents() error {
 	if config.MemoryStatCollectIntervalMs() > 0 {
 		memStatInterval = config.MemoryStatCollectIntervalMs()
 	}
+	if config.RuntimeStatCollectIntervalMs() > 0 {
+		runtimeStatInterval = config.RuntimeStatCollectIntervalMs()
+	}
 
 	if loadStatInterval > 0 {
 		system_metric.InitLoadCollector(loadStatInterval)
@@ -112,11 +117,18 @@ func initCoreComponents() error {
 	if memStatInterval > 0 {
 		system_metric.InitMemoryCollector(memStatInterval)
 	}
+	if runtimeStatInterval > 0 {
+		system_metric.InitRuntimeCollector(runtimeStatInterval)
+	}
 
 	if config.UseCacheTime() {
 		util.StartTimeTicker()
 	}
 
+	if config.ResourceIdleTimeoutMs() > 0 {
+		stat.InitResourceNodeEvictor(config.ResourceIdleTimeoutMs())
+	}
+
 	if config.MetricExportHTTPAddr() != "" {
 		httpAddr := config.MetricExportHTTPAddr()
 		httpPath := config.MetricExportHTTPPath()
@@ -0,0 +1,146 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/pkg/errors"
+)
+
+// Reservation holds the tokens reserved from the rules of resource. The caller should wait for Delay before
+// performing the action, or Cancel the reservation if the action would not be performed.
+type Reservation struct {
+	ok       bool
+	blockErr *base.BlockError
+	// timeToAct is the time (in nanoseconds) when the reserved tokens are available.
+	timeToAct uint64
+	cancels   []func()
+	cancelled int32
+}
+
+// OK returns whether the tokens are reserved, BlockError tells the reason if not.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// BlockError returns the block error if the tokens could not be reserved.
+func (r *Reservation) BlockError() *base.BlockError {
+	return r.blockErr
+}
+
+// Delay returns the duration to wait from now until the reserved tokens are available.
+// Zero means the action could be performed immediately.
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return 0
+	}
+	now := util.CurrentTimeNano()
+	if r.timeToAct <= now {
+		return 0
+	}
+	return time.Duration(r.timeToAct - now)
+}
+
+// Cancel takes back the reserved tokens, so that they could be used by the following requests.
+// It should be called before the action is performed, and only takes effect once.
+func (r *Reservation) Cancel() {
+	if !r.ok || !atomic.CompareAndSwapInt32(&r.cancelled, 0, 1) {
+		return
+	}
+	for _, cancel := range r.cancels {
+		cancel()
+	}
+}
+
+// Reserve reserves n tokens of the resource without waiting, which is similar to rate.Limiter.ReserveN.
+// The rules that pace requests (e.g. flow rules with Throttling, TokenBucket and GCRA control behavior) reserve
+// the tokens available in the future within MaxQueueingTimeMs, and the caller waits for Reservation.Delay.
+//
+// The reservation only acquires the tokens, so the entry is exited at once and holds no concurrency. The exit is
+// not recorded as a completion (e.g. the rt or the probe of circuit breakers), since the work has not run yet.
+func Reserve(resource string, n uint32, opts ...EntryOption) *Reservation {
+	options := entryOptsPool.Get().(*EntryOptions)
+	defer func() {
+		options.Reset()
+		entryOptsPool.Put(options)
+	}()
+
+	for _, opt := range opts {
+		opt(options)
+	}
+	options.batchCount = n
+	options.reserve = true
+	if options.slotChain == nil {
+		options.slotChain = GlobalSlotChain()
+	}
+
+	e, blockErr := entry(resource, options)
+	if blockErr != nil {
+		// The tokens reserved before blocking have been taken back by the slots.
+		return &Reservation{blockErr: blockErr}
+	}
+	r := &Reservation{
+		ok:        true,
+		timeToAct: util.CurrentTimeNano(),
+	}
+	if ctx := e.Context(); ctx != nil {
+		r.timeToAct += uint64(ctx.ReservedWait())
+		r.cancels = ctx.ReservationCancels()
+	}
+	e.Exit()
+	return r
+}
+
+// Wait reserves n tokens of the resource and blocks until the tokens are available, which is similar to
+// rate.Limiter.WaitN. It returns the block error if the tokens could not be reserved, or the error of ctx
+// if ctx is done before the tokens are available. The reservation is cancelled if the tokens are not used.
+func Wait(ctx context.Context, resource string, n uint32, opts ...EntryOption) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	waitOpts := make([]EntryOption, 0, len(opts)+1)
+	waitOpts = append(waitOpts, opts...)
+	waitOpts = append(waitOpts, WithContext(ctx))
+	r := Reserve(resource, n, waitOpts...)
+	if !r.OK() {
+		return r.BlockError()
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && util.Now().Add(delay).After(deadline) {
+		r.Cancel()
+		return errors.Errorf("sentinel: Wait(n=%d) would exceed context deadline", n)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
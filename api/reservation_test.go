@@ -0,0 +1,196 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/adaptive"
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/circuitbreaker"
+	"github.com/alibaba/sentinel-golang/core/flow"
+	"github.com/alibaba/sentinel-golang/core/stat"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserve(t *testing.T) {
+	_, err := flow.LoadRules([]*flow.Rule{
+		{
+			Resource:               "reserve-test",
+			TokenCalculateStrategy: flow.Direct,
+			ControlBehavior:        flow.Throttling,
+			Threshold:              10,
+			MaxQueueingTimeMs:      250,
+		},
+	})
+	assert.Nil(t, err)
+	defer flow.ClearRules()
+
+	r1 := Reserve("reserve-test", 1)
+	assert.True(t, r1.OK())
+	assert.Equal(t, time.Duration(0), r1.Delay())
+
+	r2 := Reserve("reserve-test", 1)
+	assert.True(t, r2.OK())
+	assert.InDelta(t, float64(100*time.Millisecond), float64(r2.Delay()), float64(20*time.Millisecond))
+
+	r3 := Reserve("reserve-test", 1)
+	assert.True(t, r3.OK())
+	assert.InDelta(t, float64(200*time.Millisecond), float64(r3.Delay()), float64(20*time.Millisecond))
+
+	// The reservations hold no concurrency and aren't recorded as completions.
+	node := stat.GetResourceNode("reserve-test")
+	assert.Equal(t, int32(0), node.CurrentConcurrency())
+	assert.Equal(t, int64(3), node.GetSum(base.MetricEventPass))
+	assert.Equal(t, int64(0), node.GetSum(base.MetricEventComplete))
+
+	r4 := Reserve("reserve-test", 1)
+	assert.False(t, r4.OK())
+	if assert.NotNil(t, r4.BlockError()) {
+		assert.Equal(t, base.BlockTypeFlow, r4.BlockError().BlockType())
+	}
+
+	// The cancelled reservation gives back its pass time, so the next one is reserved again.
+	r3.Cancel()
+	r3.Cancel()
+	r5 := Reserve("reserve-test", 1)
+	assert.True(t, r5.OK())
+	assert.InDelta(t, float64(200*time.Millisecond), float64(r5.Delay()), float64(20*time.Millisecond))
+}
+
+type stateRecorder struct {
+	states []circuitbreaker.State
+}
+
+func (r *stateRecorder) OnTransformToClosed(_ circuitbreaker.State, _ circuitbreaker.Rule) {
+	r.states = append(r.states, circuitbreaker.Closed)
+}
+
+func (r *stateRecorder) OnTransformToOpen(_ circuitbreaker.State, _ circuitbreaker.Rule, _ interface{}) {
+	r.states = append(r.states, circuitbreaker.Open)
+}
+
+func (r *stateRecorder) OnTransformToHalfOpen(_ circuitbreaker.State, _ circuitbreaker.Rule) {
+	r.states = append(r.states, circuitbreaker.HalfOpen)
+}
+
+func TestReserve_CircuitBreakerHalfOpen(t *testing.T) {
+	recorder := &stateRecorder{}
+	circuitbreaker.RegisterStateChangeListeners(recorder)
+	defer circuitbreaker.ClearStateChangeListeners()
+	_, err := circuitbreaker.LoadRules([]*circuitbreaker.Rule{
+		{
+			Resource:         "reserve-cb-test",
+			Strategy:         circuitbreaker.ErrorCount,
+			RetryTimeoutMs:   50,
+			MinRequestAmount: 1,
+			StatIntervalMs:   1000,
+			Threshold:        1,
+		},
+	})
+	assert.Nil(t, err)
+	defer circuitbreaker.ClearRules()
+
+	e, b := Entry("reserve-cb-test")
+	assert.Nil(t, b)
+	TraceError(e, errors.New("biz error"))
+	e.Exit()
+	assert.Equal(t, []circuitbreaker.State{circuitbreaker.Open}, recorder.states)
+
+	// The reservation doesn't complete the probe, so the breaker is rolled back to Open rather than closed.
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, Reserve("reserve-cb-test", 1).OK())
+	assert.Equal(t, []circuitbreaker.State{circuitbreaker.Open, circuitbreaker.HalfOpen, circuitbreaker.Open}, recorder.states)
+
+	e, b = Entry("reserve-cb-test")
+	assert.Nil(t, b)
+	e.Exit()
+	assert.Equal(t, circuitbreaker.Closed, recorder.states[len(recorder.states)-1])
+}
+
+func TestReserve_AdaptiveLimiter(t *testing.T) {
+	_, err := adaptive.LoadRules([]*adaptive.Rule{
+		{
+			Resource:     "reserve-adaptive-test",
+			Algorithm:    adaptive.AIMD,
+			InitialLimit: 1,
+			MinLimit:     1,
+			MaxLimit:     10,
+			BackoffRatio: 0.5,
+			TimeoutMs:    100,
+		},
+	})
+	assert.Nil(t, err)
+	defer adaptive.ClearRules()
+
+	// The reservations give back the permits without feeding the zero rt samples to the limit.
+	for i := 0; i < 3; i++ {
+		assert.True(t, Reserve("reserve-adaptive-test", 1).OK())
+	}
+	assert.Equal(t, []uint32{1}, adaptive.CurrentLimit("reserve-adaptive-test"))
+
+	e, b := Entry("reserve-adaptive-test")
+	assert.Nil(t, b)
+	e.Exit()
+	assert.Equal(t, []uint32{2}, adaptive.CurrentLimit("reserve-adaptive-test"))
+}
+
+func TestWait(t *testing.T) {
+	_, err := flow.LoadRules([]*flow.Rule{
+		{
+			Resource:               "wait-test",
+			TokenCalculateStrategy: flow.Direct,
+			ControlBehavior:        flow.TokenBucket,
+			Threshold:              20,
+			BurstSize:              1,
+			MaxQueueingTimeMs:      1000,
+		},
+	})
+	assert.Nil(t, err)
+	defer flow.ClearRules()
+
+	ctx := context.Background()
+	assert.Nil(t, Wait(ctx, "wait-test", 1))
+	begin := time.Now()
+	assert.Nil(t, Wait(ctx, "wait-test", 1))
+	assert.True(t, time.Since(begin) >= 40*time.Millisecond)
+
+	// The tokens could not be available before the deadline.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.NotNil(t, Wait(timeoutCtx, "wait-test", 1))
+
+	// The wait is interrupted by cancellation, and the reserved tokens are taken back.
+	cancelCtx, cancelFn := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancelFn()
+	}()
+	assert.Equal(t, context.Canceled, Wait(cancelCtx, "wait-test", 1))
+	r := Reserve("wait-test", 1)
+	assert.True(t, r.OK())
+	assert.True(t, r.Delay() <= 50*time.Millisecond)
+
+	// The batch count exceeding the burst size is never available.
+	err = Wait(ctx, "wait-test", 2)
+	if assert.NotNil(t, err) {
+		blockErr, ok := err.(*base.BlockError)
+		assert.True(t, ok)
+		assert.Equal(t, base.BlockTypeFlow, blockErr.BlockType())
+	}
+}
@@ -15,12 +15,14 @@
 package api
 
 import (
+	"github.com/alibaba/sentinel-golang/core/adaptive"
 	"github.com/alibaba/sentinel-golang/core/base"
 	"github.com/alibaba/sentinel-golang/core/circuitbreaker"
 	"github.com/alibaba/sentinel-golang/core/flow"
 	"github.com/alibaba/sentinel-golang/core/hotspot"
 	"github.com/alibaba/sentinel-golang/core/isolation"
 	"github.com/alibaba/sentinel-golang/core/log"
+	"github.com/alibaba/sentinel-golang/core/quota"
 	"github.com/alibaba/sentinel-golang/core/stat"
 	"github.com/alibaba/sentinel-golang/core/system"
 )
@@ -38,13 +40,19 @@ func BuildDefaultSlotChain() *base.SlotChain {
 	sc.AddRuleCheckSlot(system.DefaultAdaptiveSlot)
 	sc.AddRuleCheckSlot(flow.DefaultSlot)
 	sc.AddRuleCheckSlot(isolation.DefaultSlot)
+	sc.AddRuleCheckSlot(adaptive.DefaultSlot)
 	sc.AddRuleCheckSlot(hotspot.DefaultSlot)
+	sc.AddRuleCheckSlot(quota.DefaultSlot)
 	sc.AddRuleCheckSlot(circuitbreaker.DefaultSlot)
 
 	sc.AddStatSlot(stat.DefaultSlot)
 	sc.AddStatSlot(log.DefaultSlot)
 	sc.AddStatSlot(flow.DefaultStandaloneStatSlot)
+	sc.AddStatSlot(isolation.DefaultStatSlot)
+	sc.AddStatSlot(adaptive.DefaultStatSlot)
 	sc.AddStatSlot(hotspot.DefaultConcurrencyStatSlot)
+	sc.AddStatSlot(quota.DefaultStatSlot)
 	sc.AddStatSlot(circuitbreaker.DefaultMetricStatSlot)
+	sc.AddStatSlot(system.DefaultStatSlot)
 	return sc
 }
@@ -0,0 +1,26 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package adaptive provides the adaptive concurrency limiting.
+//
+// Different from the static threshold of isolation rules, the concurrency limit of resource is adjusted
+// continuously according to the observed RTT and drops of the completed requests, with pluggable algorithms
+// in the style of Netflix concurrency-limits:
+//
+//   - Vegas: estimates the queue size by comparing the RTT with the no-load RTT, and adjusts the limit to keep the queue small.
+//   - Gradient2: adjusts the limit by the gradient between the long-term and short-term RTT.
+//   - AIMD: additive increase and multiplicative decrease on drop.
+//
+// A request is considered as dropped if its RT exceeds Rule.TimeoutMs, or it fails with context.DeadlineExceeded.
+package adaptive
@@ -0,0 +1,117 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Sample is the observation of a completed request.
+type Sample struct {
+	// StartTime is the start time of request in milliseconds.
+	StartTime uint64
+	// RtMs is the response time of request in milliseconds.
+	RtMs uint64
+	// InFlight is the count of in-flight requests when the request started, including itself.
+	InFlight uint32
+	// Dropped indicates whether the request is dropped (e.g. timeout).
+	Dropped bool
+}
+
+// Limit adjusts the concurrency limit according to the samples of completed requests.
+// The calls to a Limit are serialized, so the implementations need not be thread safe.
+type Limit interface {
+	// EstimatedLimit returns the current concurrency limit.
+	EstimatedLimit() float64
+	// OnSample updates the concurrency limit with the given sample.
+	OnSample(sample Sample)
+}
+
+// LimitGenFunc represents the Limit generator function of a specific algorithm.
+type LimitGenFunc func(rule *Rule) (Limit, error)
+
+var (
+	limitGenFuncMap = make(map[LimitAlgorithm]LimitGenFunc, 4)
+	limitGenMux     = new(sync.RWMutex)
+)
+
+func init() {
+	limitGenFuncMap[Vegas] = func(rule *Rule) (Limit, error) {
+		return newVegasLimit(rule), nil
+	}
+	limitGenFuncMap[Gradient2] = func(rule *Rule) (Limit, error) {
+		return newGradient2Limit(rule), nil
+	}
+	limitGenFuncMap[AIMD] = func(rule *Rule) (Limit, error) {
+		return newAimdLimit(rule), nil
+	}
+}
+
+// SetLimitGenerator sets the Limit generator for the given algorithm.
+// Note that modifying the generator of the built-in algorithms is not allowed.
+func SetLimitGenerator(algorithm LimitAlgorithm, generator LimitGenFunc) error {
+	if generator == nil {
+		return errors.New("nil generator")
+	}
+	if algorithm >= Vegas && algorithm <= AIMD {
+		return errors.New("not allowed to replace the generator for built-in algorithms")
+	}
+	limitGenMux.Lock()
+	defer limitGenMux.Unlock()
+
+	limitGenFuncMap[algorithm] = generator
+	return nil
+}
+
+// RemoveLimitGenerator removes the Limit generator of the given algorithm.
+func RemoveLimitGenerator(algorithm LimitAlgorithm) error {
+	if algorithm >= Vegas && algorithm <= AIMD {
+		return errors.New("not allowed to remove the generator for built-in algorithms")
+	}
+	limitGenMux.Lock()
+	defer limitGenMux.Unlock()
+
+	delete(limitGenFuncMap, algorithm)
+	return nil
+}
+
+func newLimit(rule *Rule) (Limit, error) {
+	limitGenMux.RLock()
+	generator, ok := limitGenFuncMap[rule.Algorithm]
+	limitGenMux.RUnlock()
+	if !ok || generator == nil {
+		return nil, errors.Errorf("unsupported limit algorithm: %s", rule.Algorithm)
+	}
+	l, err := generator(rule)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return nil, errors.New("nil limit generated")
+	}
+	return l, nil
+}
+
+func clampLimit(v float64, min, max uint32) float64 {
+	return math.Max(float64(min), math.Min(float64(max), v))
+}
+
+// log10Root returns the integral part of log10(limit), at least 1.
+func log10Root(limit float64) float64 {
+	return math.Max(1, math.Floor(math.Log10(limit)))
+}
@@ -0,0 +1,60 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"math"
+)
+
+const (
+	defaultAimdBackoffRatio = 0.9
+)
+
+// aimdLimit increases the limit by 1 if the limit is reached without drop,
+// and multiplies the limit by the backoff ratio on drop.
+type aimdLimit struct {
+	estimatedLimit float64
+	minLimit       uint32
+	maxLimit       uint32
+	backoffRatio   float64
+}
+
+func newAimdLimit(rule *Rule) *aimdLimit {
+	initial, min, max := rule.limits()
+	ratio := rule.BackoffRatio
+	if ratio <= 0 {
+		ratio = defaultAimdBackoffRatio
+	}
+	return &aimdLimit{
+		estimatedLimit: float64(initial),
+		minLimit:       min,
+		maxLimit:       max,
+		backoffRatio:   ratio,
+	}
+}
+
+func (l *aimdLimit) EstimatedLimit() float64 {
+	return l.estimatedLimit
+}
+
+func (l *aimdLimit) OnSample(s Sample) {
+	if s.Dropped {
+		l.estimatedLimit = clampLimit(math.Floor(l.estimatedLimit*l.backoffRatio), l.minLimit, l.maxLimit)
+		return
+	}
+	if float64(s.InFlight)*2 >= l.estimatedLimit {
+		l.estimatedLimit = clampLimit(l.estimatedLimit+1, l.minLimit, l.maxLimit)
+	}
+}
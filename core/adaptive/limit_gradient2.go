@@ -0,0 +1,115 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"math"
+)
+
+const (
+	defaultGradient2Smoothing    = 0.2
+	defaultGradient2RttTolerance = 1.5
+	defaultGradient2LongWindow   = 600
+	gradient2WarmUpWindow        = 10
+	gradient2QueueSize           = 4
+)
+
+// gradient2Limit adjusts the limit by the gradient between the exponential average of long-term RTT
+// and the short-term RTT, i.e. limit = limit*gradient + queueSize.
+type gradient2Limit struct {
+	estimatedLimit float64
+	minLimit       uint32
+	maxLimit       uint32
+	smoothing      float64
+	tolerance      float64
+	longRtt        *expAvg
+}
+
+func newGradient2Limit(rule *Rule) *gradient2Limit {
+	initial, min, max := rule.limits()
+	smoothing := rule.Smoothing
+	if smoothing <= 0 {
+		smoothing = defaultGradient2Smoothing
+	}
+	tolerance := rule.RttTolerance
+	if tolerance <= 0 {
+		tolerance = defaultGradient2RttTolerance
+	}
+	window := rule.LongWindow
+	if window == 0 {
+		window = defaultGradient2LongWindow
+	}
+	return &gradient2Limit{
+		estimatedLimit: float64(initial),
+		minLimit:       min,
+		maxLimit:       max,
+		smoothing:      smoothing,
+		tolerance:      tolerance,
+		longRtt:        newExpAvg(window, gradient2WarmUpWindow),
+	}
+}
+
+func (l *gradient2Limit) EstimatedLimit() float64 {
+	return l.estimatedLimit
+}
+
+func (l *gradient2Limit) OnSample(s Sample) {
+	shortRtt := float64(s.RtMs)
+	if shortRtt <= 0 {
+		shortRtt = 1
+	}
+	longRtt := l.longRtt.add(shortRtt)
+	// If the long-term RTT is substantially larger than the short-term RTT, the load has gone down,
+	// so decay the long-term RTT quickly to catch up.
+	if longRtt/shortRtt > 2 {
+		l.longRtt.value *= 0.95
+	}
+	// Don't grow the limit if the limit is not reached by the traffic.
+	if float64(s.InFlight) < l.estimatedLimit/2 {
+		return
+	}
+
+	gradient := math.Max(0.5, math.Min(1.0, l.tolerance*longRtt/shortRtt))
+	newLimit := l.estimatedLimit*gradient + gradient2QueueSize
+	newLimit = l.estimatedLimit*(1-l.smoothing) + newLimit*l.smoothing
+	l.estimatedLimit = clampLimit(newLimit, l.minLimit, l.maxLimit)
+}
+
+// expAvg is the exponential average of samples, which is the simple average during warm-up.
+type expAvg struct {
+	factor       float64
+	warmUpWindow uint32
+	count        uint32
+	value        float64
+	sum          float64
+}
+
+func newExpAvg(window, warmUpWindow uint32) *expAvg {
+	return &expAvg{
+		factor:       2.0 / float64(window+1),
+		warmUpWindow: warmUpWindow,
+	}
+}
+
+func (a *expAvg) add(sample float64) float64 {
+	if a.count < a.warmUpWindow {
+		a.count++
+		a.sum += sample
+		a.value = a.sum / float64(a.count)
+	} else {
+		a.value = a.value*(1-a.factor) + sample*a.factor
+	}
+	return a.value
+}
@@ -0,0 +1,154 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// sampleAfter sleeps rtMs with the mock clock and returns the sample of the request started before sleeping.
+func sampleAfter(rtMs uint64, inFlight uint32, dropped bool) Sample {
+	start := util.CurrentTimeMillis()
+	util.Sleep(time.Duration(rtMs) * time.Millisecond)
+	return Sample{
+		StartTime: start,
+		RtMs:      util.CurrentTimeMillis() - start,
+		InFlight:  inFlight,
+		Dropped:   dropped,
+	}
+}
+
+func TestVegasLimit(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	t.Run("IncreaseWithoutQueueing", func(t *testing.T) {
+		l := newVegasLimit(&Rule{InitialLimit: 10, MaxLimit: 100})
+		// The first sample sets the no-load RTT.
+		l.OnSample(sampleAfter(10, 10, false))
+		assert.Equal(t, 10.0, l.EstimatedLimit())
+		// limit 10: log10 = 1, beta = 6
+		l.OnSample(sampleAfter(10, 10, false))
+		assert.Equal(t, 16.0, l.EstimatedLimit())
+	})
+
+	t.Run("DecreaseWithQueueing", func(t *testing.T) {
+		l := newVegasLimit(&Rule{InitialLimit: 20, MaxLimit: 100})
+		l.OnSample(sampleAfter(10, 20, false))
+		// queueSize = ceil(20 * (1 - 10/50)) = 16 > beta(6)
+		l.OnSample(sampleAfter(50, 20, false))
+		assert.Equal(t, 19.0, l.EstimatedLimit())
+	})
+
+	t.Run("AppLimited", func(t *testing.T) {
+		l := newVegasLimit(&Rule{InitialLimit: 20, MaxLimit: 100})
+		l.OnSample(sampleAfter(10, 20, false))
+		l.OnSample(sampleAfter(10, 5, false))
+		assert.Equal(t, 20.0, l.EstimatedLimit())
+	})
+
+	t.Run("DecreaseOnDrop", func(t *testing.T) {
+		l := newVegasLimit(&Rule{InitialLimit: 2, MinLimit: 1})
+		l.OnSample(sampleAfter(10, 2, false))
+		l.OnSample(sampleAfter(10, 2, true))
+		assert.Equal(t, 1.0, l.EstimatedLimit())
+		l.OnSample(sampleAfter(10, 2, true))
+		assert.Equal(t, 1.0, l.EstimatedLimit())
+	})
+}
+
+func TestGradient2Limit(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	t.Run("IncreaseWithSteadyRtt", func(t *testing.T) {
+		l := newGradient2Limit(&Rule{InitialLimit: 20, MaxLimit: 100, Smoothing: 1})
+		// gradient = min(1, 1.5 * 10 / 10) = 1
+		l.OnSample(sampleAfter(10, 20, false))
+		assert.Equal(t, 24.0, l.EstimatedLimit())
+	})
+
+	t.Run("DecreaseWithRisingRtt", func(t *testing.T) {
+		l := newGradient2Limit(&Rule{InitialLimit: 100, MaxLimit: 200, Smoothing: 1})
+		for i := 0; i < gradient2WarmUpWindow; i++ {
+			l.OnSample(sampleAfter(10, 10, false))
+		}
+		assert.Equal(t, 100.0, l.EstimatedLimit())
+		// long RTT = (10*10+100)/11 ~= 18.18, gradient = max(0.5, 1.5*18.18/100) = 0.5
+		l.OnSample(sampleAfter(100, 100, false))
+		assert.Equal(t, 54.0, l.EstimatedLimit())
+	})
+
+	t.Run("Smoothing", func(t *testing.T) {
+		l := newGradient2Limit(&Rule{InitialLimit: 20, MaxLimit: 100})
+		l.OnSample(sampleAfter(10, 20, false))
+		assert.InDelta(t, 20.8, l.EstimatedLimit(), 1e-9)
+	})
+}
+
+func TestAimdLimit(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	l := newAimdLimit(&Rule{InitialLimit: 10, MinLimit: 5, MaxLimit: 11})
+	l.OnSample(sampleAfter(10, 4, false))
+	assert.Equal(t, 10.0, l.EstimatedLimit())
+	l.OnSample(sampleAfter(10, 5, false))
+	assert.Equal(t, 11.0, l.EstimatedLimit())
+	l.OnSample(sampleAfter(10, 11, false))
+	assert.Equal(t, 11.0, l.EstimatedLimit())
+	l.OnSample(sampleAfter(10, 11, true))
+	assert.Equal(t, 9.0, l.EstimatedLimit())
+	for i := 0; i < 5; i++ {
+		l.OnSample(sampleAfter(10, 11, true))
+	}
+	assert.Equal(t, 5.0, l.EstimatedLimit())
+}
+
+type fixedLimit struct {
+	limit float64
+}
+
+func (l *fixedLimit) EstimatedLimit() float64 {
+	return l.limit
+}
+
+func (l *fixedLimit) OnSample(_ Sample) {
+}
+
+func TestSetLimitGenerator(t *testing.T) {
+	assert.Error(t, SetLimitGenerator(Gradient2, func(rule *Rule) (Limit, error) {
+		return &fixedLimit{limit: 1}, nil
+	}))
+	assert.Error(t, RemoveLimitGenerator(Vegas))
+
+	custom := LimitAlgorithm(100)
+	_, err := newLimit(&Rule{Resource: "abc", Algorithm: custom})
+	assert.Error(t, err)
+
+	assert.NoError(t, SetLimitGenerator(custom, func(rule *Rule) (Limit, error) {
+		return &fixedLimit{limit: float64(rule.InitialLimit)}, nil
+	}))
+	defer func() {
+		assert.NoError(t, RemoveLimitGenerator(custom))
+	}()
+	l, err := newLimit(&Rule{Resource: "abc", Algorithm: custom, InitialLimit: 7})
+	assert.NoError(t, err)
+	assert.Equal(t, 7.0, l.EstimatedLimit())
+}
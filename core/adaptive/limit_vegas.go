@@ -0,0 +1,96 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"math"
+)
+
+const (
+	defaultVegasSmoothing = 1.0
+	// vegasProbeMultiplier decides how often the no-load RTT is probed, i.e. every probeMultiplier*limit samples,
+	// so that the no-load RTT won't be stuck at a stale low value.
+	vegasProbeMultiplier = 30
+)
+
+// vegasLimit estimates the queue size by limit*(1 - rttNoLoad/rtt). The limit is increased
+// if the queue size is less than alpha, and decreased if the queue size is greater than beta.
+type vegasLimit struct {
+	estimatedLimit float64
+	minLimit       uint32
+	maxLimit       uint32
+	smoothing      float64
+	rttNoLoad      uint64
+	probeCount     uint64
+}
+
+func newVegasLimit(rule *Rule) *vegasLimit {
+	initial, min, max := rule.limits()
+	smoothing := rule.Smoothing
+	if smoothing <= 0 {
+		smoothing = defaultVegasSmoothing
+	}
+	return &vegasLimit{
+		estimatedLimit: float64(initial),
+		minLimit:       min,
+		maxLimit:       max,
+		smoothing:      smoothing,
+	}
+}
+
+func (l *vegasLimit) EstimatedLimit() float64 {
+	return l.estimatedLimit
+}
+
+func (l *vegasLimit) OnSample(s Sample) {
+	rtt := s.RtMs
+	if rtt == 0 {
+		rtt = 1
+	}
+	l.probeCount++
+	if float64(l.probeCount) >= vegasProbeMultiplier*l.estimatedLimit {
+		l.probeCount = 0
+		l.rttNoLoad = rtt
+		return
+	}
+	if l.rttNoLoad == 0 || rtt < l.rttNoLoad {
+		l.rttNoLoad = rtt
+		return
+	}
+
+	limit := l.estimatedLimit
+	queueSize := math.Ceil(limit * (1 - float64(l.rttNoLoad)/float64(rtt)))
+	logLimit := log10Root(limit)
+	alpha, beta, threshold := 3*logLimit, 6*logLimit, logLimit
+
+	var newLimit float64
+	switch {
+	case s.Dropped:
+		newLimit = limit - logLimit
+	case float64(s.InFlight)*2 < limit:
+		// The limit is not reached by the traffic, keep it to avoid growing unboundedly.
+		return
+	case queueSize <= threshold:
+		newLimit = limit + beta
+	case queueSize < alpha:
+		newLimit = limit + logLimit
+	case queueSize > beta:
+		newLimit = limit - logLimit
+	default:
+		return
+	}
+	newLimit = clampLimit(newLimit, l.minLimit, l.maxLimit)
+	l.estimatedLimit = clampLimit((1-l.smoothing)*limit+l.smoothing*newLimit, l.minLimit, l.maxLimit)
+}
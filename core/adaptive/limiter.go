@@ -0,0 +1,127 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	metric_exporter "github.com/alibaba/sentinel-golang/exporter/metric"
+)
+
+const (
+	permitsKey = "adaptive-concurrency-permits"
+)
+
+var (
+	limitGauge = metric_exporter.NewGauge(
+		"adaptive_concurrency_limit",
+		"Adaptive concurrency limit of resource",
+		[]string{"resource"})
+)
+
+func init() {
+	metric_exporter.Register(limitGauge)
+}
+
+// limiter holds the in-flight requests and the adjusted concurrency limit of a rule.
+type limiter struct {
+	rule *Rule
+	// inFlight is the count of requests acquired the permits and not completed yet.
+	inFlight int64
+	// currentLimit is the integral part of the estimated limit, read by the requests without lock.
+	currentLimit int64
+
+	// mux serializes the samples fed to limit.
+	mux   sync.Mutex
+	limit Limit
+}
+
+type permit struct {
+	l     *limiter
+	count uint32
+	// inFlight is the count of in-flight requests when the permit is acquired, including itself.
+	inFlight uint32
+}
+
+func newLimiter(rule *Rule) (*limiter, error) {
+	limit, err := newLimit(rule)
+	if err != nil {
+		return nil, err
+	}
+	l := &limiter{
+		rule:  rule,
+		limit: limit,
+	}
+	l.updateLimit(limit.EstimatedLimit())
+	return l, nil
+}
+
+func (l *limiter) updateLimit(estimated float64) {
+	atomic.StoreInt64(&l.currentLimit, int64(math.Max(1, math.Floor(estimated))))
+	limitGauge.Set(estimated, l.rule.Resource)
+}
+
+// tryAcquire acquires count permits if the in-flight requests won't exceed the limit.
+// It returns the in-flight count after acquiring, or the current in-flight count if failed.
+func (l *limiter) tryAcquire(count uint32) (bool, int64) {
+	for {
+		cur := atomic.LoadInt64(&l.inFlight)
+		if cur+int64(count) > atomic.LoadInt64(&l.currentLimit) {
+			return false, cur
+		}
+		if atomic.CompareAndSwapInt64(&l.inFlight, cur, cur+int64(count)) {
+			return true, cur + int64(count)
+		}
+	}
+}
+
+// acquire acquires count permits regardless of the limit and returns the in-flight count after acquiring.
+func (l *limiter) acquire(count uint32) int64 {
+	return atomic.AddInt64(&l.inFlight, int64(count))
+}
+
+func (l *limiter) release(count uint32) {
+	atomic.AddInt64(&l.inFlight, -int64(count))
+}
+
+func (l *limiter) onSample(s Sample) {
+	l.mux.Lock()
+	l.limit.OnSample(s)
+	estimated := l.limit.EstimatedLimit()
+	l.mux.Unlock()
+	l.updateLimit(estimated)
+}
+
+func (l *limiter) estimatedLimit() int64 {
+	return atomic.LoadInt64(&l.currentLimit)
+}
+
+func markPermits(ctx *base.EntryContext, permits []permit) {
+	if ctx.Data == nil {
+		ctx.Data = make(map[interface{}]interface{})
+	}
+	ctx.SetPair(permitsKey, permits)
+}
+
+func permitsOf(ctx *base.EntryContext) []permit {
+	if ctx.Data == nil {
+		return nil
+	}
+	permits, _ := ctx.GetPair(permitsKey).([]permit)
+	return permits
+}
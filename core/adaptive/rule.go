@@ -0,0 +1,168 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
+	"github.com/alibaba/sentinel-golang/util"
+)
+
+const (
+	DefaultInitialLimit uint32 = 20
+	DefaultMinLimit     uint32 = 1
+	DefaultMaxLimit     uint32 = 1000
+)
+
+// LimitAlgorithm represents the algorithm adjusting the concurrency limit.
+type LimitAlgorithm int32
+
+const (
+	Vegas LimitAlgorithm = iota
+	Gradient2
+	AIMD
+)
+
+func (a LimitAlgorithm) String() string {
+	switch a {
+	case Vegas:
+		return "Vegas"
+	case Gradient2:
+		return "Gradient2"
+	case AIMD:
+		return "AIMD"
+	default:
+		return "Undefined"
+	}
+}
+
+// Rule describes the adaptive concurrency limiting policy of a resource.
+type Rule struct {
+	// ID represents the unique ID of the rule (optional).
+	ID string `json:"id,omitempty"`
+	// Resource represents the target resource definition.
+	Resource  string         `json:"resource"`
+	Algorithm LimitAlgorithm `json:"algorithm"`
+	// InitialLimit is the concurrency limit before any adjustment, 20 by default.
+	InitialLimit uint32 `json:"initialLimit"`
+	// MinLimit and MaxLimit bound the adjusted concurrency limit, 1 and 1000 by default.
+	MinLimit uint32 `json:"minLimit"`
+	MaxLimit uint32 `json:"maxLimit"`
+	// Smoothing is the factor in (0.0, 1.0] to smooth the changes of limit,
+	// 1.0 for Vegas and 0.2 for Gradient2 by default. Not used by AIMD.
+	Smoothing float64 `json:"smoothing"`
+	// RttTolerance is the tolerated ratio of the short-term RTT to the long-term RTT before Gradient2
+	// reduces the limit, 1.5 by default.
+	RttTolerance float64 `json:"rttTolerance"`
+	// LongWindow is the count of samples Gradient2 averages the long-term RTT over, 600 by default.
+	LongWindow uint32 `json:"longWindow"`
+	// BackoffRatio in [0.5, 1.0) is the ratio AIMD multiplies the limit by on drop, 0.9 by default.
+	BackoffRatio float64 `json:"backoffRatio"`
+	// TimeoutMs is the RT above which the request is considered as dropped, 0 means no timeout.
+	TimeoutMs uint32 `json:"timeoutMs"`
+	// Mode indicates whether the rule blocks the requests (Enforce) or only records the would-be blocked ones (Shadow).
+	Mode base.RuleMode `json:"mode,omitempty"`
+	// EnforcePercent is the percentage of traffic the rule is enforced on, the valid range is [0.0, 100.0] and 0 means all the traffic.
+	// The rest of the traffic is checked in the Shadow mode.
+	EnforcePercent float64 `json:"enforcePercent,omitempty"`
+	// EnforceKey is the attachment key whose value selects the enforced traffic by hash, the traffic is selected randomly if empty.
+	EnforceKey string `json:"enforceKey,omitempty"`
+	// Canary selects the instances the rule is enforced on, nil means all the instances.
+	Canary *base.CanarySelector `json:"canary,omitempty"`
+	// Condition is the optional expression the rule only takes effect when it holds, e.g. `attachments.caller != "internal"`,
+	// see package condition for the syntax.
+	Condition string `json:"condition,omitempty"`
+	// conditionProgram is the compiled Condition, which is compiled when the rule is loaded.
+	conditionProgram *condition.Program
+	// ExpireAt is the Unix timestamp in milliseconds when the rule expires and is dropped automatically, 0 means never.
+	ExpireAt uint64 `json:"expireAt,omitempty"`
+	// TTLSec is the lifetime in seconds of the rule since it's loaded, 0 means forever.
+	// If both ExpireAt and TTLSec are set, the earlier one takes effect.
+	// A rule expired by TTLSec comes back with a new lifetime if it's loaded again, so prefer ExpireAt for rules from data sources.
+	TTLSec uint32 `json:"ttlSec,omitempty"`
+}
+
+// limits returns the initial, min and max limit of rule with the defaults applied.
+func (r *Rule) limits() (initial, min, max uint32) {
+	initial, min, max = r.InitialLimit, r.MinLimit, r.MaxLimit
+	if min == 0 {
+		min = DefaultMinLimit
+	}
+	if max == 0 {
+		max = DefaultMaxLimit
+	}
+	if initial == 0 {
+		initial = DefaultInitialLimit
+		if initial < min {
+			initial = min
+		}
+		if initial > max {
+			initial = max
+		}
+	}
+	return initial, min, max
+}
+
+func (r *Rule) isEqualsTo(newRule *Rule) bool {
+	if newRule == nil {
+		return false
+	}
+	return r.Resource == newRule.Resource && r.Algorithm == newRule.Algorithm && r.InitialLimit == newRule.InitialLimit &&
+		r.MinLimit == newRule.MinLimit && r.MaxLimit == newRule.MaxLimit && util.Float64Equals(r.Smoothing, newRule.Smoothing) &&
+		util.Float64Equals(r.RttTolerance, newRule.RttTolerance) && r.LongWindow == newRule.LongWindow &&
+		util.Float64Equals(r.BackoffRatio, newRule.BackoffRatio) && r.TimeoutMs == newRule.TimeoutMs && r.Mode == newRule.Mode &&
+		util.Float64Equals(r.EnforcePercent, newRule.EnforcePercent) && r.EnforceKey == newRule.EnforceKey &&
+		r.Canary.Equals(newRule.Canary) && r.Condition == newRule.Condition
+}
+
+func (r *Rule) String() string {
+	b, err := json.Marshal(r)
+	if err != nil {
+		// Return the fallback string
+		return fmt.Sprintf("Rule{Resource=%s, Algorithm=%s, InitialLimit=%d, MinLimit=%d, MaxLimit=%d, TimeoutMs=%d}",
+			r.Resource, r.Algorithm, r.InitialLimit, r.MinLimit, r.MaxLimit, r.TimeoutMs)
+	}
+	return string(b)
+}
+
+func (r *Rule) ResourceName() string {
+	return r.Resource
+}
+
+// Lifetime returns the ExpireAt and TTLSec of the rule.
+func (r *Rule) Lifetime() (uint64, uint32) {
+	return r.ExpireAt, r.TTLSec
+}
+
+// isEnforcedOn reports whether the rule is enforced on the entry, otherwise the rule is checked in the Shadow mode.
+func (r *Rule) isEnforcedOn(ctx *base.EntryContext) bool {
+	return base.IsEnforcedOn(ctx, r.Mode, r.EnforcePercent, r.EnforceKey, r.Canary)
+}
+
+// conditionHolds reports whether the Condition of the rule holds on the entry.
+func (r *Rule) conditionHolds(ctx *base.EntryContext) bool {
+	return r.conditionProgram.Holds(ctx)
+}
+
+func (r *Rule) setConditionProgram(program *condition.Program) {
+	r.conditionProgram = program
+}
+
+func (r *Rule) setCanary(canary *base.CanarySelector) {
+	r.Canary = canary
+}
@@ -0,0 +1,293 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/pkg/errors"
+)
+
+var (
+	limiterMap     = make(map[string][]*limiter)
+	rwMux          = &sync.RWMutex{}
+	currentRules   = make(map[string][]*Rule, 0)
+	updateRuleMux  = new(sync.Mutex)
+	ruleExpiration *base.RuleExpirationManager[Rule, *Rule]
+)
+
+func init() {
+	ruleExpiration = base.NewRuleExpirationManager("[AdaptiveConcurrency]", updateRuleMux,
+		func() map[string][]*Rule {
+			return currentRules
+		},
+		func(resRulesMap map[string][]*Rule) error {
+			onRuleUpdate(resRulesMap)
+			return nil
+		},
+		func(rule *Rule, expireAt uint64, ttlSec uint32) {
+			rule.ExpireAt, rule.TTLSec = expireAt, ttlSec
+		})
+}
+
+// LoadRules loads the given adaptive concurrency rules to the rule manager, while all previous rules will be replaced.
+// the first returned value indicates whether do real load operation, if the rules is the same with previous rules, return false
+func LoadRules(rules []*Rule) (bool, error) {
+	resRulesMap := make(map[string][]*Rule, 16)
+	for _, rule := range rules {
+		resRules, exist := resRulesMap[rule.Resource]
+		if !exist {
+			resRules = make([]*Rule, 0, 1)
+		}
+		resRulesMap[rule.Resource] = append(resRules, rule)
+	}
+
+	updateRuleMux.Lock()
+	defer updateRuleMux.Unlock()
+	isEqual := reflect.DeepEqual(currentRules, resRulesMap)
+	if isEqual {
+		logging.Info("[AdaptiveConcurrency] Load rules is the same with current rules, so ignore load operation.")
+		return false, nil
+	}
+
+	onRuleUpdate(resRulesMap)
+	ruleExpiration.Track()
+	return true, nil
+}
+
+// buildResourceLimiters builds the limiters of the valid rules, the limiter of the equal rule in oldLimiters is reused
+// so that the adjusted limit and in-flight requests are kept.
+func buildResourceLimiters(res string, rules []*Rule, oldLimiters []*limiter) []*limiter {
+	newLimiters := make([]*limiter, 0, len(rules))
+	for _, rule := range rules {
+		if err := IsValidRule(rule); err != nil {
+			logging.Warn("[AdaptiveConcurrency buildResourceLimiters] Ignoring invalid adaptive concurrency rule", "rule", rule, "reason", err.Error())
+			continue
+		}
+		rule = condition.Prepare(rule, rule.Condition, (*Rule).setConditionProgram)
+		rule = base.ResolveCanary(rule, rule.Canary, (*Rule).setCanary)
+		reuseIdx := -1
+		for i, old := range oldLimiters {
+			if old != nil && old.rule.isEqualsTo(rule) {
+				reuseIdx = i
+				break
+			}
+		}
+		if reuseIdx >= 0 {
+			newLimiters = append(newLimiters, oldLimiters[reuseIdx])
+			// Remove the reused limiter, so the equal rules won't share the same limiter.
+			oldLimiters = append(oldLimiters[:reuseIdx:reuseIdx], oldLimiters[reuseIdx+1:]...)
+			continue
+		}
+		l, err := newLimiter(rule)
+		if err != nil {
+			logging.Warn("[AdaptiveConcurrency buildResourceLimiters] Ignoring the rule due to failure of creating limiter", "rule", rule, "reason", err.Error())
+			continue
+		}
+		newLimiters = append(newLimiters, l)
+	}
+	if len(newLimiters) == 0 {
+		logging.Info("[AdaptiveConcurrency] no valid rules of resource", "resource", res)
+	}
+	return newLimiters
+}
+
+func onRuleUpdate(rawResRulesMap map[string][]*Rule) {
+	start := util.CurrentTimeNano()
+	rwMux.RLock()
+	oldLimiterMap := limiterMap
+	rwMux.RUnlock()
+
+	newLimiterMap := make(map[string][]*limiter, len(rawResRulesMap))
+	for res, rules := range rawResRulesMap {
+		if limiters := buildResourceLimiters(res, rules, oldLimiterMap[res]); len(limiters) > 0 {
+			newLimiterMap[res] = limiters
+		}
+	}
+
+	rwMux.Lock()
+	limiterMap = newLimiterMap
+	rwMux.Unlock()
+	currentRules = rawResRulesMap
+
+	logging.Debug("[AdaptiveConcurrency onRuleUpdate] Time statistic(ns) for updating adaptive concurrency rule", "timeCost", util.CurrentTimeNano()-start)
+	logRuleUpdate(newLimiterMap)
+}
+
+// LoadRulesOfResource loads the given resource's adaptive concurrency rules to the rule manager, while all previous resource's rules will be replaced.
+// the first returned value indicates whether do real load operation, if the rules is the same with previous resource's rules, return false
+func LoadRulesOfResource(res string, rules []*Rule) (bool, error) {
+	if len(res) == 0 {
+		return false, errors.New("empty resource")
+	}
+	updateRuleMux.Lock()
+	defer updateRuleMux.Unlock()
+	// clear resource rules
+	if len(rules) == 0 {
+		delete(currentRules, res)
+		rwMux.Lock()
+		delete(limiterMap, res)
+		rwMux.Unlock()
+		ruleExpiration.Track()
+		logging.Info("[AdaptiveConcurrency] clear resource level rules", "resource", res)
+		return true, nil
+	}
+	// load resource level rules
+	isEqual := reflect.DeepEqual(currentRules[res], rules)
+	if isEqual {
+		logging.Info("[AdaptiveConcurrency] Load resource level rules is the same with current resource level rules, so ignore load operation.")
+		return false, nil
+	}
+
+	rwMux.RLock()
+	oldLimiters := limiterMap[res]
+	rwMux.RUnlock()
+	limiters := buildResourceLimiters(res, rules, oldLimiters)
+
+	rwMux.Lock()
+	if len(limiters) == 0 {
+		delete(limiterMap, res)
+	} else {
+		limiterMap[res] = limiters
+	}
+	rwMux.Unlock()
+	currentRules[res] = rules
+	ruleExpiration.Track()
+	logging.Info("[AdaptiveConcurrency] load resource level rules", "resource", res, "rules", rules)
+	return true, nil
+}
+
+// ClearRules clears all the rules in adaptive concurrency module.
+func ClearRules() error {
+	_, err := LoadRules(nil)
+	return err
+}
+
+// ClearRulesOfResource clears resource level rules in adaptive concurrency module.
+func ClearRulesOfResource(res string) error {
+	_, err := LoadRulesOfResource(res, nil)
+	return err
+}
+
+// GetRules returns all the rules based on copy.
+// It doesn't take effect for adaptive concurrency module if user changes the rule.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
+func GetRules() []Rule {
+	rwMux.RLock()
+	defer rwMux.RUnlock()
+
+	now := util.CurrentTimeMillis()
+	ret := make([]Rule, 0, len(limiterMap))
+	for _, limiters := range limiterMap {
+		for _, l := range limiters {
+			ret = append(ret, ruleExpiration.CopyWithLifetime(l.rule, now))
+		}
+	}
+	return ret
+}
+
+// GetRulesOfResource returns specific resource's rules based on copy.
+// It doesn't take effect for adaptive concurrency module if user changes the rule.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
+func GetRulesOfResource(res string) []Rule {
+	limiters := getLimitersOfResource(res)
+	now := util.CurrentTimeMillis()
+	ret := make([]Rule, 0, len(limiters))
+	for _, l := range limiters {
+		ret = append(ret, ruleExpiration.CopyWithLifetime(l.rule, now))
+	}
+	return ret
+}
+
+// CurrentLimit returns the current concurrency limits of the rules on the given resource, in the order of rules.
+func CurrentLimit(res string) []uint32 {
+	limiters := getLimitersOfResource(res)
+	ret := make([]uint32, 0, len(limiters))
+	for _, l := range limiters {
+		ret = append(ret, uint32(l.estimatedLimit()))
+	}
+	return ret
+}
+
+func getLimitersOfResource(res string) []*limiter {
+	rwMux.RLock()
+	defer rwMux.RUnlock()
+
+	return limiterMap[res]
+}
+
+func logRuleUpdate(m map[string][]*limiter) {
+	rules := make([]*Rule, 0, len(m))
+	for _, limiters := range m {
+		for _, l := range limiters {
+			rules = append(rules, l.rule)
+		}
+	}
+	if len(rules) == 0 {
+		logging.Info("[AdaptiveConcurrencyRuleManager] Adaptive concurrency rules were cleared")
+	} else {
+		logging.Info("[AdaptiveConcurrencyRuleManager] Adaptive concurrency rules were loaded", "rules", rules)
+	}
+}
+
+// IsValidRule checks whether the given Rule is valid.
+func IsValidRule(r *Rule) error {
+	if r == nil {
+		return errors.New("nil adaptive concurrency rule")
+	}
+	if len(r.Resource) == 0 {
+		return errors.New("empty resource of adaptive concurrency rule")
+	}
+	if r.ExpireAt > 0 && r.ExpireAt <= util.CurrentTimeMillis() {
+		return errors.New("rule already expired")
+	}
+	if r.Algorithm < 0 {
+		return errors.Errorf("invalid algorithm: %d", r.Algorithm)
+	}
+	if r.Mode != base.Enforce && r.Mode != base.Shadow {
+		return errors.Errorf("invalid mode: %d", r.Mode)
+	}
+	if err := base.CheckEnforcePercent(r.EnforcePercent); err != nil {
+		return err
+	}
+	if err := r.Canary.IsValid(); err != nil {
+		return err
+	}
+	if err := condition.IsValid(r.Condition); err != nil {
+		return errors.Wrap(err, "invalid Condition")
+	}
+	initial, min, max := r.limits()
+	if min > max {
+		return errors.New("MinLimit is greater than MaxLimit")
+	}
+	if initial < min || initial > max {
+		return errors.New("InitialLimit should be in [MinLimit, MaxLimit]")
+	}
+	if r.Smoothing < 0 || r.Smoothing > 1 {
+		return errors.New("invalid Smoothing, valid range is (0.0, 1.0]")
+	}
+	if r.RttTolerance != 0 && r.RttTolerance < 1 {
+		return errors.New("invalid RttTolerance, should be at least 1.0")
+	}
+	if r.BackoffRatio != 0 && (r.BackoffRatio < 0.5 || r.BackoffRatio >= 1) {
+		return errors.New("invalid BackoffRatio, valid range is [0.5, 1.0)")
+	}
+	return nil
+}
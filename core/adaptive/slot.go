@@ -0,0 +1,82 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"github.com/alibaba/sentinel-golang/core/base"
+)
+
+const (
+	RuleCheckSlotOrder = 3500
+)
+
+var (
+	DefaultSlot = &Slot{}
+)
+
+type Slot struct {
+}
+
+func (s *Slot) Order() uint32 {
+	return RuleCheckSlotOrder
+}
+
+func (s *Slot) Check(ctx *base.EntryContext) *base.TokenResult {
+	resource := ctx.Resource.Name()
+	result := ctx.RuleCheckResult
+	if len(resource) == 0 {
+		return result
+	}
+	if passed, rule, snapshot := checkPass(ctx); !passed {
+		msg := "adaptive concurrency exceeds limit"
+		if result == nil {
+			result = base.NewTokenResultBlockedWithCause(base.BlockTypeAdaptiveConcurrency, msg, rule, snapshot)
+		} else {
+			result.ResetToBlockedWithCause(base.BlockTypeAdaptiveConcurrency, msg, rule, snapshot)
+		}
+	}
+	return result
+}
+
+// checkPass acquires the permits from all the limiters of resource. If any of them fails,
+// the acquired permits are given back and the in-flight count of the failed limiter is returned.
+// The limiters of shadow rules always acquire the permits, so that their limits keep learning from the real traffic.
+func checkPass(ctx *base.EntryContext) (bool, *Rule, int64) {
+	limiters := getLimitersOfResource(ctx.Resource.Name())
+	if len(limiters) == 0 {
+		return true, nil, 0
+	}
+	count := ctx.Input.BatchCount
+	permits := make([]permit, 0, len(limiters))
+	for _, l := range limiters {
+		if !l.rule.conditionHolds(ctx) {
+			continue
+		}
+		ok, inFlight := l.tryAcquire(count)
+		if !ok && !l.rule.isEnforcedOn(ctx) {
+			ctx.AddShadowBlock(base.NewBlockErrorWithCause(base.BlockTypeAdaptiveConcurrency, "adaptive concurrency exceeds limit", l.rule, inFlight))
+			ok, inFlight = true, l.acquire(count)
+		}
+		if !ok {
+			for _, p := range permits {
+				p.l.release(p.count)
+			}
+			return false, l.rule, inFlight
+		}
+		permits = append(permits, permit{l: l, count: count, inFlight: uint32(inFlight)})
+	}
+	markPermits(ctx, permits)
+	return true, nil, 0
+}
@@ -0,0 +1,128 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEntryContext(res string) *base.EntryContext {
+	return &base.EntryContext{
+		Resource: base.NewResourceWrapper(res, base.ResTypeCommon, base.Inbound),
+		Input: &base.SentinelInput{
+			BatchCount: 1,
+		},
+	}
+}
+
+func TestSlotCheckAndComplete(t *testing.T) {
+	defer ClearRules()
+
+	slot, statSlot := DefaultSlot, DefaultStatSlot
+	_, err := LoadRules([]*Rule{{
+		Resource:     "abc",
+		Algorithm:    AIMD,
+		InitialLimit: 2,
+		MinLimit:     1,
+		MaxLimit:     10,
+		BackoffRatio: 0.5,
+		TimeoutMs:    100,
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{2}, CurrentLimit("abc"))
+
+	ctx1, ctx2, ctx3 := newEntryContext("abc"), newEntryContext("abc"), newEntryContext("abc")
+	assert.Nil(t, slot.Check(ctx1))
+	assert.Nil(t, slot.Check(ctx2))
+	r := slot.Check(ctx3)
+	if assert.NotNil(t, r) {
+		assert.True(t, r.IsBlocked())
+		assert.Equal(t, base.BlockTypeAdaptiveConcurrency, r.BlockError().BlockType())
+		assert.Equal(t, int64(2), r.BlockError().TriggeredValue())
+	}
+
+	// The limit grows since it's reached without drop.
+	ctx1.PutRt(10)
+	statSlot.OnCompleted(ctx1)
+	assert.Equal(t, []uint32{3}, CurrentLimit("abc"))
+
+	// The limit backs off on timeout.
+	ctx2.PutRt(200)
+	statSlot.OnCompleted(ctx2)
+	assert.Equal(t, []uint32{1}, CurrentLimit("abc"))
+
+	// The limit backs off on deadline exceeded.
+	ctx4 := newEntryContext("abc")
+	assert.Nil(t, slot.Check(ctx4))
+	ctx4.PutRt(10)
+	ctx4.SetError(errors.Wrap(context.DeadlineExceeded, "call failed"))
+	statSlot.OnCompleted(ctx4)
+	assert.Equal(t, []uint32{1}, CurrentLimit("abc"))
+
+	// The permits are given back without sampling if the entry is blocked by other slots.
+	ctx5 := newEntryContext("abc")
+	assert.Nil(t, slot.Check(ctx5))
+	assert.NotNil(t, slot.Check(newEntryContext("abc")))
+	statSlot.OnEntryBlocked(ctx5, nil)
+	assert.Equal(t, []uint32{1}, CurrentLimit("abc"))
+	assert.Nil(t, slot.Check(newEntryContext("abc")))
+}
+
+func TestLoadRulesReuseLimiter(t *testing.T) {
+	defer ClearRules()
+
+	rule := &Rule{Resource: "abc", Algorithm: AIMD, InitialLimit: 5}
+	_, err := LoadRules([]*Rule{rule})
+	assert.NoError(t, err)
+	ctxs := []*base.EntryContext{newEntryContext("abc"), newEntryContext("abc"), newEntryContext("abc")}
+	for _, ctx := range ctxs {
+		assert.Nil(t, DefaultSlot.Check(ctx))
+	}
+	ctxs[2].PutRt(1)
+	DefaultStatSlot.OnCompleted(ctxs[2])
+	assert.Equal(t, []uint32{6}, CurrentLimit("abc"))
+
+	// The adjusted limit is kept for the equal rule.
+	ok, err := LoadRules([]*Rule{{Resource: "abc", Algorithm: AIMD, InitialLimit: 5}, {Resource: "def"}})
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{6}, CurrentLimit("abc"))
+
+	ok, err = LoadRulesOfResource("abc", []*Rule{{Resource: "abc", Algorithm: AIMD, InitialLimit: 8}})
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{8}, CurrentLimit("abc"))
+	assert.Len(t, GetRulesOfResource("abc"), 1)
+
+	assert.NoError(t, ClearRulesOfResource("abc"))
+	assert.Len(t, GetRules(), 1)
+}
+
+func TestIsValidRule(t *testing.T) {
+	assert.NoError(t, IsValidRule(&Rule{Resource: "abc"}))
+	assert.Error(t, IsValidRule(nil))
+	assert.Error(t, IsValidRule(&Rule{}))
+	assert.Error(t, IsValidRule(&Rule{Resource: "abc", MinLimit: 10, MaxLimit: 5}))
+	assert.Error(t, IsValidRule(&Rule{Resource: "abc", InitialLimit: 50, MaxLimit: 10}))
+	assert.Error(t, IsValidRule(&Rule{Resource: "abc", Smoothing: 1.5}))
+	assert.Error(t, IsValidRule(&Rule{Resource: "abc", RttTolerance: 0.5}))
+	assert.Error(t, IsValidRule(&Rule{Resource: "abc", Algorithm: AIMD, BackoffRatio: 1}))
+	assert.Error(t, IsValidRule(&Rule{Resource: "abc", Algorithm: AIMD, BackoffRatio: 0.3}))
+}
@@ -0,0 +1,75 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"context"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/pkg/errors"
+)
+
+const (
+	StatSlotOrder = 3500
+)
+
+var (
+	DefaultStatSlot = &StatSlot{}
+)
+
+// StatSlot gives back the permits acquired in Slot and feeds the samples to the limits.
+// It must be placed after stat.Slot, which records the RT of entry.
+type StatSlot struct {
+}
+
+func (s *StatSlot) Order() uint32 {
+	return StatSlotOrder
+}
+
+func (s *StatSlot) OnEntryPassed(_ *base.EntryContext) {
+}
+
+func (s *StatSlot) OnEntryBlocked(ctx *base.EntryContext, _ *base.BlockError) {
+	// The entry is blocked by other rules, so give back the permits without sampling.
+	for _, p := range permitsOf(ctx) {
+		p.l.release(p.count)
+	}
+}
+
+func (s *StatSlot) OnCompleted(ctx *base.EntryContext) {
+	permits := permitsOf(ctx)
+	if len(permits) == 0 {
+		return
+	}
+	if ctx.IsReservation() {
+		// The reserved work has not run yet, so give back the permits without sampling.
+		for _, p := range permits {
+			p.l.release(p.count)
+		}
+		return
+	}
+	rt := ctx.Rt()
+	deadlineExceeded := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	for _, p := range permits {
+		p.l.release(p.count)
+		timeout := p.l.rule.TimeoutMs
+		p.l.onSample(Sample{
+			StartTime: ctx.StartTime(),
+			RtMs:      rt,
+			InFlight:  p.inFlight,
+			Dropped:   deadlineExceeded || (timeout > 0 && rt > uint64(timeout)),
+		})
+	}
+}
@@ -14,7 +14,10 @@
 
 package base
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // BlockError indicates the request was blocked by Sentinel.
 type BlockError struct {
@@ -25,6 +28,10 @@ type BlockError struct {
 	rule SentinelRule
 	// snapshotValue represents the triggered "snapshot" value
 	snapshotValue interface{}
+	// retryAfter is the estimated duration after which the request may pass, 0 means unknown.
+	retryAfter time.Duration
+	// rateLimitInfo is the quota state of the rule which blocks the request, only collected on demand.
+	rateLimitInfo *RateLimitInfo
 }
 
 type BlockErrorOption func(*BlockError)
@@ -53,6 +60,21 @@ func WithSnapshotValue(snapshotValue interface{}) BlockErrorOption {
 	}
 }
 
+// WithRetryAfter sets the estimated duration after which the blocked request may pass,
+// e.g. the time until the next token is available.
+func WithRetryAfter(retryAfter time.Duration) BlockErrorOption {
+	return func(b *BlockError) {
+		b.retryAfter = retryAfter
+	}
+}
+
+// WithRateLimitInfo sets the quota state of the rule which blocks the request.
+func WithRateLimitInfo(info *RateLimitInfo) BlockErrorOption {
+	return func(b *BlockError) {
+		b.rateLimitInfo = info
+	}
+}
+
 func NewBlockError(opts ...BlockErrorOption) *BlockError {
 	b := &BlockError{
 		blockType: BlockTypeUnknown,
@@ -87,12 +109,26 @@ func (e *BlockError) TriggeredValue() interface{} {
 	return e.snapshotValue
 }
 
+// RetryAfter returns the estimated duration after which the request may pass, 0 means unknown.
+// The caller could use it to set the Retry-After header.
+func (e *BlockError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// RateLimitInfo returns the quota state of the rule which blocks the request,
+// nil if it's not required by the entry or not reported by the rule.
+func (e *BlockError) RateLimitInfo() *RateLimitInfo {
+	return e.rateLimitInfo
+}
+
 func NewBlockErrorFromDeepCopy(from *BlockError) *BlockError {
 	return &BlockError{
 		blockType:     from.blockType,
 		blockMsg:      from.blockMsg,
 		rule:          from.rule,
 		snapshotValue: from.snapshotValue,
+		retryAfter:    from.retryAfter,
+		rateLimitInfo: from.rateLimitInfo,
 	}
 }
 
@@ -17,6 +17,9 @@ package base
 // global variable
 const (
 	TotalInBoundResourceName = "__total_inbound_traffic__"
+	// OverflowResourceName is the name of the resource node recording the statistic of the resources
+	// beyond the max resource amount.
+	OverflowResourceName = "__overflow_resources__"
 
 	DefaultMaxResourceAmount uint32 = 10000
 
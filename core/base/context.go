@@ -14,7 +14,12 @@
 
 package base
 
-import "github.com/alibaba/sentinel-golang/util"
+import (
+	"context"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/util"
+)
 
 type EntryContext struct {
 	entry *SentinelEntry
@@ -25,6 +30,14 @@ type EntryContext struct {
 	startTime uint64
 	// the rt of this transaction
 	rt uint64
+	// rateLimitInfo is the quota state of the most restrictive rule, only collected if Input.NeedRateLimitInfo is true
+	rateLimitInfo *RateLimitInfo
+	// reservedWait is the duration to wait until all reserved tokens are available, only used if Input.Reserve is true
+	reservedWait time.Duration
+	// reservationCancels return the reserved tokens to the rules
+	reservationCancels []func()
+	// shadowBlocks are the block errors of the shadow rules which would have blocked the entry
+	shadowBlocks []*BlockError
 
 	Resource *ResourceWrapper
 	StatNode StatNode
@@ -75,6 +88,72 @@ func (ctx *EntryContext) Rt() uint64 {
 	return ctx.rt
 }
 
+// RateLimitInfo returns the quota state of the most restrictive rule checked for the entry,
+// nil if Input.NeedRateLimitInfo is false or no rule reports the quota state.
+func (ctx *EntryContext) RateLimitInfo() *RateLimitInfo {
+	return ctx.rateLimitInfo
+}
+
+// MergeRateLimitInfo records the quota state of a rule, only the most restrictive one is kept.
+func (ctx *EntryContext) MergeRateLimitInfo(info RateLimitInfo) {
+	if ctx.rateLimitInfo == nil {
+		ctx.rateLimitInfo = &info
+		return
+	}
+	if info.moreRestrictiveThan(ctx.rateLimitInfo) {
+		*ctx.rateLimitInfo = info
+	}
+}
+
+// AddReservation records the tokens reserved from a rule, wait is the duration until the tokens are available
+// and cancel returns the tokens to the rule, which could be nil if the tokens are not returnable.
+func (ctx *EntryContext) AddReservation(wait time.Duration, cancel func()) {
+	if wait > ctx.reservedWait {
+		ctx.reservedWait = wait
+	}
+	if cancel != nil {
+		ctx.reservationCancels = append(ctx.reservationCancels, cancel)
+	}
+}
+
+// IsReservation reports whether the entry only reserves the tokens (see api.Reserve), which is exited right after
+// the reservation. Its completion is not a sample of the work, so the rt and the completion must not be recorded.
+func (ctx *EntryContext) IsReservation() bool {
+	return ctx.Input != nil && ctx.Input.Reserve
+}
+
+// ReservedWait returns the duration to wait until all tokens reserved for the entry are available.
+func (ctx *EntryContext) ReservedWait() time.Duration {
+	return ctx.reservedWait
+}
+
+// ReservationCancels returns the functions which return the reserved tokens to the rules.
+func (ctx *EntryContext) ReservationCancels() []func() {
+	return ctx.reservationCancels
+}
+
+// CancelReservations returns all tokens reserved for the entry to the rules.
+func (ctx *EntryContext) CancelReservations() {
+	for _, cancel := range ctx.reservationCancels {
+		cancel()
+	}
+	ctx.reservationCancels = nil
+	ctx.reservedWait = 0
+}
+
+// AddShadowBlock records the block error of a shadow rule which would have blocked the entry.
+func (ctx *EntryContext) AddShadowBlock(err *BlockError) {
+	if err == nil {
+		return
+	}
+	ctx.shadowBlocks = append(ctx.shadowBlocks, err)
+}
+
+// ShadowBlocks returns the block errors of the shadow rules which would have blocked the entry.
+func (ctx *EntryContext) ShadowBlocks() []*BlockError {
+	return ctx.shadowBlocks
+}
+
 func (ctx *EntryContext) FilterNodes() []string {
 	return ctx.RuleCheckResult.FilterNodes()
 }
@@ -98,19 +177,43 @@ func NewEmptyEntryContext() *EntryContext {
 // The input data of sentinel
 type SentinelInput struct {
 	BatchCount uint32
-	Flag       int32
-	Args       []interface{}
+	// Weight is the cost of the entry (e.g. bytes, estimated CPU cost), BatchCount is used if Weight is zero.
+	Weight uint32
+	Flag   int32
+	Args   []interface{}
 	// store some values in this context when calling context in slot.
 	Attachments map[interface{}]interface{}
+	// Tags are the tags of entry, which are selected by ResourceSelector of rules.
+	Tags []string
+	// Context is the context of caller, the slots which may wait (e.g. queueing) should respect its cancellation.
+	Context context.Context
+	// NeedRateLimitInfo indicates whether the rule checking slots should report the quota state of rules.
+	NeedRateLimitInfo bool
+	// Reserve indicates whether the rule checking slots should reserve the tokens rather than waiting for them,
+	// the duration to wait is recorded in the context and left to the caller.
+	Reserve bool
+}
+
+// EntryWeight returns the weight of the entry, which falls back to BatchCount if Weight is not set.
+func (i *SentinelInput) EntryWeight() uint32 {
+	if i.Weight > 0 {
+		return i.Weight
+	}
+	return i.BatchCount
 }
 
 func (i *SentinelInput) reset() {
 	i.BatchCount = 1
+	i.Weight = 0
 	i.Flag = 0
 	i.Args = i.Args[:0]
 	if len(i.Attachments) != 0 {
 		i.Attachments = make(map[interface{}]interface{})
 	}
+	i.Tags = nil
+	i.Context = nil
+	i.NeedRateLimitInfo = false
+	i.Reserve = false
 }
 
 // Reset init EntryContext,
@@ -120,6 +223,10 @@ func (ctx *EntryContext) Reset() {
 	ctx.err = nil
 	ctx.startTime = 0
 	ctx.rt = 0
+	ctx.rateLimitInfo = nil
+	ctx.reservedWait = 0
+	ctx.reservationCancels = nil
+	ctx.shadowBlocks = nil
 	ctx.Resource = nil
 	ctx.StatNode = nil
 	ctx.Input.reset()
@@ -66,6 +66,16 @@ func (e *SentinelEntry) Context() *EntryContext {
 	return e.ctx
 }
 
+// RateLimitInfo returns the quota state of the most restrictive rule checked for the entry,
+// which is only collected if the entry is created with api.WithRateLimitInfo.
+// It should be read before the entry exits.
+func (e *SentinelEntry) RateLimitInfo() *RateLimitInfo {
+	if e.ctx == nil {
+		return nil
+	}
+	return e.ctx.RateLimitInfo()
+}
+
 func (e *SentinelEntry) Resource() *ResourceWrapper {
 	return e.res
 }
@@ -43,3 +43,16 @@ func TestMetricItemFromFatStringIllegal(t *testing.T) {
 	_, err = MetricItemFromFatString(line2)
 	assert.Error(t, err, "Error should occur when parsing malformed line")
 }
+
+func TestMetricItemShadowBlockQps(t *testing.T) {
+	item := &MetricItem{Resource: "foo", Timestamp: 1564382218000, PassQps: 4, ShadowBlockQps: 3}
+	fat, err := item.ToFatString()
+	assert.NoError(t, err)
+	parsed, err := MetricItemFromFatString(fat)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), parsed.ShadowBlockQps)
+
+	legacy, err := MetricItemFromFatString("1564382218000|2019-07-29 14:36:58|/foo/*|4|9|3|0|25|0|2|1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), legacy.ShadowBlockQps)
+}
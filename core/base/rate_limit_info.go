@@ -0,0 +1,91 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// The header fields defined by IETF draft RateLimit header fields for HTTP, and Retry-After defined by RFC 9110.
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RateLimitPolicyHeader    = "RateLimit-Policy"
+	RetryAfterHeader         = "Retry-After"
+)
+
+// RateLimitInfo describes the quota state of a rule for an entry, which could be used to
+// write the rate limit headers (e.g. RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset).
+type RateLimitInfo struct {
+	// Limit is the threshold applied by the rule within Window.
+	Limit float64
+	// Remaining is the quota remaining within Window after the entry.
+	Remaining float64
+	// Window is the length of the statistic window of the rule.
+	Window time.Duration
+	// Reset is the duration until the quota is fully restored.
+	Reset time.Duration
+}
+
+// moreRestrictiveThan returns true if the quota described by i would be exhausted before o.
+func (i *RateLimitInfo) moreRestrictiveThan(o *RateLimitInfo) bool {
+	if i.Remaining != o.Remaining {
+		return i.Remaining < o.Remaining
+	}
+	return i.Reset > o.Reset
+}
+
+// deltaSeconds formats the duration as the non-negative integer seconds, rounded up.
+func deltaSeconds(d time.Duration) string {
+	if d <= 0 {
+		return "0"
+	}
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// WriteRateLimitHeaders writes the RateLimit header fields of the quota state by set, nothing is written if info is nil.
+func WriteRateLimitHeaders(info *RateLimitInfo, set func(key, value string)) {
+	if info == nil || set == nil {
+		return
+	}
+	limit := strconv.FormatInt(int64(math.Floor(info.Limit)), 10)
+	set(RateLimitLimitHeader, limit)
+	set(RateLimitRemainingHeader, strconv.FormatInt(int64(math.Max(0, math.Floor(info.Remaining))), 10))
+	set(RateLimitResetHeader, deltaSeconds(info.Reset))
+	if info.Window > 0 {
+		set(RateLimitPolicyHeader, limit+";w="+deltaSeconds(info.Window))
+	}
+}
+
+// WriteBlockHeaders writes the RateLimit header fields of the rule which blocks the request and
+// the Retry-After header field by set. Retry-After is written if the time to retry is known.
+func WriteBlockHeaders(err *BlockError, set func(key, value string)) {
+	if err == nil || set == nil {
+		return
+	}
+	info := err.RateLimitInfo()
+	WriteRateLimitHeaders(info, set)
+
+	retryAfter := err.RetryAfter()
+	if retryAfter <= 0 && info != nil {
+		retryAfter = info.Reset
+	}
+	if retryAfter > 0 {
+		set(RetryAfterHeader, deltaSeconds(retryAfter))
+	}
+}
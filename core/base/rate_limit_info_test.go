@@ -0,0 +1,84 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryContext_MergeRateLimitInfo(t *testing.T) {
+	ctx := NewEmptyEntryContext()
+	assert.Nil(t, ctx.RateLimitInfo())
+
+	ctx.MergeRateLimitInfo(RateLimitInfo{Limit: 10, Remaining: 5, Reset: time.Second})
+	ctx.MergeRateLimitInfo(RateLimitInfo{Limit: 100, Remaining: 50, Reset: time.Second})
+	assert.Equal(t, &RateLimitInfo{Limit: 10, Remaining: 5, Reset: time.Second}, ctx.RateLimitInfo())
+
+	ctx.MergeRateLimitInfo(RateLimitInfo{Limit: 20, Remaining: 5, Reset: 2 * time.Second})
+	assert.Equal(t, &RateLimitInfo{Limit: 20, Remaining: 5, Reset: 2 * time.Second}, ctx.RateLimitInfo())
+
+	ctx.Input = &SentinelInput{}
+	ctx.Reset()
+	assert.Nil(t, ctx.RateLimitInfo())
+}
+
+func TestWriteRateLimitHeaders(t *testing.T) {
+	headers := make(map[string]string)
+	set := func(key, value string) {
+		headers[key] = value
+	}
+
+	WriteRateLimitHeaders(nil, set)
+	assert.Len(t, headers, 0)
+
+	WriteRateLimitHeaders(&RateLimitInfo{Limit: 10.5, Remaining: 3.7, Window: time.Second, Reset: 1500 * time.Millisecond}, set)
+	assert.Equal(t, map[string]string{
+		RateLimitLimitHeader:     "10",
+		RateLimitRemainingHeader: "3",
+		RateLimitResetHeader:     "2",
+		RateLimitPolicyHeader:    "10;w=1",
+	}, headers)
+}
+
+func TestWriteBlockHeaders(t *testing.T) {
+	t.Run("RetryAfter", func(t *testing.T) {
+		headers := make(map[string]string)
+		WriteBlockHeaders(NewBlockError(WithRetryAfter(100*time.Millisecond)), func(key, value string) {
+			headers[key] = value
+		})
+		assert.Equal(t, map[string]string{RetryAfterHeader: "1"}, headers)
+	})
+
+	t.Run("RateLimitInfo", func(t *testing.T) {
+		headers := make(map[string]string)
+		info := &RateLimitInfo{Limit: 5, Remaining: 0, Reset: 3 * time.Second}
+		WriteBlockHeaders(NewBlockError(WithRateLimitInfo(info)), func(key, value string) {
+			headers[key] = value
+		})
+		assert.Equal(t, map[string]string{
+			RateLimitLimitHeader:     "5",
+			RateLimitRemainingHeader: "0",
+			RateLimitResetHeader:     "3",
+			RetryAfterHeader:         "3",
+		}, headers)
+
+		copied := NewBlockErrorFromDeepCopy(NewBlockError(WithRateLimitInfo(info), WithRetryAfter(time.Second)))
+		assert.Equal(t, info, copied.RateLimitInfo())
+		assert.Equal(t, time.Second, copied.RetryAfter())
+	})
+}
@@ -29,6 +29,27 @@ const (
 	ResTypeMQ
 )
 
+func (t ResourceType) String() string {
+	switch t {
+	case ResTypeCommon:
+		return "Common"
+	case ResTypeWeb:
+		return "Web"
+	case ResTypeRPC:
+		return "RPC"
+	case ResTypeAPIGateway:
+		return "APIGateway"
+	case ResTypeDBSQL:
+		return "DBSQL"
+	case ResTypeCache:
+		return "Cache"
+	case ResTypeMQ:
+		return "MQ"
+	default:
+		return fmt.Sprintf("%d", t)
+	}
+}
+
 // TrafficType describes the traffic type: Inbound or Outbound
 type TrafficType int32
 
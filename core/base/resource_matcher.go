@@ -0,0 +1,248 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultResourceMatchCacheCapacity is the max number of resources whose matched patterns are cached by ResourceMatcher.
+const DefaultResourceMatchCacheCapacity = 10000
+
+// ResourceMatchStrategy indicates how the Resource of rule matches the resource names.
+type ResourceMatchStrategy int32
+
+const (
+	// ExactMatch matches the resource whose name is exactly the Resource of rule.
+	ExactMatch ResourceMatchStrategy = iota
+	// RegexMatch matches the resources whose names contain a match of the regular expression.
+	RegexMatch
+	// GlobMatch matches the resources whose whole names match the glob pattern,
+	// in which '*' matches any sequence of characters and '?' matches any single character.
+	GlobMatch
+	// PrefixMatch matches the resources whose names start with the prefix.
+	PrefixMatch
+)
+
+func (s ResourceMatchStrategy) String() string {
+	switch s {
+	case ExactMatch:
+		return "Exact"
+	case RegexMatch:
+		return "Regex"
+	case GlobMatch:
+		return "Glob"
+	case PrefixMatch:
+		return "Prefix"
+	default:
+		return "Undefined"
+	}
+}
+
+// ResourcePattern is the pattern matching the resource names by the strategy.
+type ResourcePattern struct {
+	Strategy ResourceMatchStrategy
+	Pattern  string
+}
+
+// IsValid checks whether the pattern is valid.
+func (p ResourcePattern) IsValid() error {
+	if len(p.Pattern) == 0 {
+		return errors.New("empty resource pattern")
+	}
+	switch p.Strategy {
+	case ExactMatch, PrefixMatch:
+		return nil
+	case RegexMatch, GlobMatch:
+		if _, err := regexp.Compile(p.expr()); err != nil {
+			return errors.Wrapf(err, "invalid %s pattern", p.Strategy)
+		}
+		return nil
+	default:
+		return errors.Errorf("invalid resource match strategy: %d", p.Strategy)
+	}
+}
+
+// Matches reports whether the resource name matches the pattern, it compiles the pattern on each call.
+func (p ResourcePattern) Matches(resource string) bool {
+	switch p.Strategy {
+	case ExactMatch:
+		return p.Pattern == resource
+	case PrefixMatch:
+		return strings.HasPrefix(resource, p.Pattern)
+	case RegexMatch, GlobMatch:
+		re, err := regexp.Compile(p.expr())
+		return err == nil && re.MatchString(resource)
+	default:
+		return false
+	}
+}
+
+// expr returns the regular expression of the regex and glob pattern.
+func (p ResourcePattern) expr() string {
+	if p.Strategy != GlobMatch {
+		return p.Pattern
+	}
+	var sb strings.Builder
+	sb.WriteString("(?s)^")
+	for _, c := range p.Pattern {
+		switch c {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+type prefixNode struct {
+	children map[byte]*prefixNode
+	// patterns are the prefix patterns ending at the node
+	patterns []ResourcePattern
+}
+
+type compiledPattern struct {
+	pattern ResourcePattern
+	re      *regexp.Regexp
+}
+
+// ResourceMatcher is the compiled index of the resource patterns shared by the rule managers.
+// The prefix patterns are indexed by a trie, and the regex and glob patterns are compiled into a regex set
+// guarded by the union of them, so that the resource matching none of them is rejected by a single scan.
+// The matched patterns of each resource are cached. ResourceMatcher is immutable,
+// the rule managers build a new one on rule update, which invalidates the cache.
+type ResourceMatcher struct {
+	prefixes  *prefixNode
+	regexes   []compiledPattern
+	union     *regexp.Regexp
+	cache     sync.Map
+	cacheSize int64
+}
+
+// NewResourceMatcher builds the matcher of the given patterns, the exact patterns are ignored.
+func NewResourceMatcher(patterns []ResourcePattern) (*ResourceMatcher, error) {
+	sorted := make([]ResourcePattern, 0, len(patterns))
+	seen := make(map[ResourcePattern]struct{}, len(patterns))
+	for _, p := range patterns {
+		if _, ok := seen[p]; ok || p.Strategy == ExactMatch {
+			continue
+		}
+		if err := p.IsValid(); err != nil {
+			return nil, err
+		}
+		seen[p] = struct{}{}
+		sorted = append(sorted, p)
+	}
+	// keep the order of the matched patterns stable
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Pattern != sorted[j].Pattern {
+			return sorted[i].Pattern < sorted[j].Pattern
+		}
+		return sorted[i].Strategy < sorted[j].Strategy
+	})
+
+	m := &ResourceMatcher{}
+	exprs := make([]string, 0, len(sorted))
+	for _, p := range sorted {
+		if p.Strategy == PrefixMatch {
+			m.addPrefix(p)
+			continue
+		}
+		expr := p.expr()
+		m.regexes = append(m.regexes, compiledPattern{
+			pattern: p,
+			re:      regexp.MustCompile(expr),
+		})
+		exprs = append(exprs, "(?:"+expr+")")
+	}
+	if len(exprs) > 1 {
+		// the union is only an optimization, the regex set is checked one by one without it
+		m.union, _ = regexp.Compile(strings.Join(exprs, "|"))
+	}
+	return m, nil
+}
+
+func (m *ResourceMatcher) addPrefix(p ResourcePattern) {
+	if m.prefixes == nil {
+		m.prefixes = &prefixNode{}
+	}
+	node := m.prefixes
+	for i := 0; i < len(p.Pattern); i++ {
+		if node.children == nil {
+			node.children = make(map[byte]*prefixNode)
+		}
+		child, ok := node.children[p.Pattern[i]]
+		if !ok {
+			child = &prefixNode{}
+			node.children[p.Pattern[i]] = child
+		}
+		node = child
+	}
+	node.patterns = append(node.patterns, p)
+}
+
+// IsEmpty reports whether the matcher has no patterns.
+func (m *ResourceMatcher) IsEmpty() bool {
+	return m == nil || (m.prefixes == nil && len(m.regexes) == 0)
+}
+
+// Match returns the patterns matching the resource name, the prefix patterns come first from the shortest.
+// The returned slice is shared and must not be modified.
+func (m *ResourceMatcher) Match(resource string) []ResourcePattern {
+	if m.IsEmpty() {
+		return nil
+	}
+	if matched, ok := m.cache.Load(resource); ok {
+		return matched.([]ResourcePattern)
+	}
+	matched := m.match(resource)
+	if atomic.LoadInt64(&m.cacheSize) < DefaultResourceMatchCacheCapacity {
+		if _, loaded := m.cache.LoadOrStore(resource, matched); !loaded {
+			atomic.AddInt64(&m.cacheSize, 1)
+		}
+	}
+	return matched
+}
+
+func (m *ResourceMatcher) match(resource string) []ResourcePattern {
+	var matched []ResourcePattern
+	node := m.prefixes
+	for i := 0; node != nil; i++ {
+		matched = append(matched, node.patterns...)
+		if i == len(resource) {
+			break
+		}
+		node = node.children[resource[i]]
+	}
+	if len(m.regexes) == 0 || (m.union != nil && !m.union.MatchString(resource)) {
+		return matched
+	}
+	for _, p := range m.regexes {
+		if p.re.MatchString(resource) {
+			matched = append(matched, p.pattern)
+		}
+	}
+	return matched
+}
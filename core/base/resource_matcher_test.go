@@ -0,0 +1,101 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourcePattern(t *testing.T) {
+	assert.True(t, ResourcePattern{Strategy: GlobMatch, Pattern: "/api/*/orders?"}.Matches("/api/v1/orders2"))
+	assert.False(t, ResourcePattern{Strategy: GlobMatch, Pattern: "/api/*/orders?"}.Matches("/api/v1/orders"))
+	assert.True(t, ResourcePattern{Strategy: GlobMatch, Pattern: "a.b*"}.Matches("a.bc"))
+	assert.False(t, ResourcePattern{Strategy: GlobMatch, Pattern: "a.b*"}.Matches("axbc"))
+	assert.True(t, ResourcePattern{Strategy: RegexMatch, Pattern: "user/[0-9]+"}.Matches("/user/123/info"))
+	assert.True(t, ResourcePattern{Strategy: PrefixMatch, Pattern: "/api"}.Matches("/api/v1"))
+	assert.False(t, ResourcePattern{Strategy: ExactMatch, Pattern: "/api"}.Matches("/api/v1"))
+
+	assert.Nil(t, ResourcePattern{Strategy: GlobMatch, Pattern: "(*"}.IsValid())
+	assert.Error(t, ResourcePattern{Strategy: RegexMatch, Pattern: "(*"}.IsValid())
+	assert.Error(t, ResourcePattern{Strategy: PrefixMatch}.IsValid())
+	assert.Error(t, ResourcePattern{Strategy: ResourceMatchStrategy(100), Pattern: "a"}.IsValid())
+}
+
+func TestResourceMatcher(t *testing.T) {
+	m, err := NewResourceMatcher([]ResourcePattern{
+		{Strategy: PrefixMatch, Pattern: "/api/v1"},
+		{Strategy: PrefixMatch, Pattern: "/api"},
+		{Strategy: PrefixMatch, Pattern: "/api"},
+		{Strategy: RegexMatch, Pattern: "^/api/v[0-9]+/users"},
+		{Strategy: GlobMatch, Pattern: "/api/*/users/*"},
+		{Strategy: ExactMatch, Pattern: "/api/v1/users"},
+	})
+	assert.Nil(t, err)
+	assert.False(t, m.IsEmpty())
+
+	assert.Equal(t, []ResourcePattern{
+		{Strategy: PrefixMatch, Pattern: "/api"},
+		{Strategy: PrefixMatch, Pattern: "/api/v1"},
+		{Strategy: GlobMatch, Pattern: "/api/*/users/*"},
+		{Strategy: RegexMatch, Pattern: "^/api/v[0-9]+/users"},
+	}, m.Match("/api/v1/users/1"))
+	assert.Equal(t, []ResourcePattern{
+		{Strategy: PrefixMatch, Pattern: "/api"},
+		{Strategy: RegexMatch, Pattern: "^/api/v[0-9]+/users"},
+	}, m.Match("/api/v2/users"))
+	assert.Equal(t, []ResourcePattern{{Strategy: PrefixMatch, Pattern: "/api"}}, m.Match("/api"))
+	assert.Empty(t, m.Match("/ap"))
+	assert.Empty(t, m.Match("/health"))
+	// cached
+	assert.Equal(t, []ResourcePattern{{Strategy: PrefixMatch, Pattern: "/api"}}, m.Match("/api"))
+
+	_, err = NewResourceMatcher([]ResourcePattern{{Strategy: RegexMatch, Pattern: "(*"}})
+	assert.Error(t, err)
+
+	var nilMatcher *ResourceMatcher
+	assert.True(t, nilMatcher.IsEmpty())
+	assert.Nil(t, nilMatcher.Match("/api"))
+}
+
+func TestResourceMatcherCacheCapacity(t *testing.T) {
+	m, err := NewResourceMatcher([]ResourcePattern{{Strategy: PrefixMatch, Pattern: "/user/"}})
+	assert.Nil(t, err)
+	for i := 0; i < DefaultResourceMatchCacheCapacity+10; i++ {
+		assert.Len(t, m.Match(fmt.Sprintf("/user/%d", i)), 1)
+	}
+	assert.Equal(t, int64(DefaultResourceMatchCacheCapacity), m.cacheSize)
+}
+
+func BenchmarkResourceMatcher_Match(b *testing.B) {
+	patterns := make([]ResourcePattern, 0, 300)
+	for i := 0; i < 100; i++ {
+		patterns = append(patterns,
+			ResourcePattern{Strategy: PrefixMatch, Pattern: fmt.Sprintf("/prefix%d/", i)},
+			ResourcePattern{Strategy: RegexMatch, Pattern: fmt.Sprintf("^/regex%d/[0-9]+$", i)},
+			ResourcePattern{Strategy: GlobMatch, Pattern: fmt.Sprintf("/glob%d/*", i)},
+		)
+	}
+	m, err := NewResourceMatcher(patterns)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match("/regex99/123")
+	}
+}
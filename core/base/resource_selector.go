@@ -0,0 +1,135 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ResourceSelector selects the resources by the classification, the traffic type and the tags of entry
+// rather than the exact resource name, e.g. all the Outbound DBSQL resources. The empty fields select all.
+//
+// The rules targeting the resource by name take precedence over the rules selecting the resource by selector,
+// and among the latter, only the rules with the most specific selectors take effect.
+type ResourceSelector struct {
+	// ResourceTypes are the classifications of the resources selected.
+	ResourceTypes []ResourceType `json:"resourceTypes,omitempty"`
+	// TrafficTypes are the traffic types of the resources selected.
+	TrafficTypes []TrafficType `json:"trafficTypes,omitempty"`
+	// Tags are the tags which the entry must all carry (see api.WithTags).
+	Tags []string `json:"tags,omitempty"`
+}
+
+// MatchesResource reports whether the resource matches the ResourceTypes and TrafficTypes of selector.
+func (s *ResourceSelector) MatchesResource(res *ResourceWrapper) bool {
+	if s == nil || res == nil {
+		return false
+	}
+	if len(s.ResourceTypes) > 0 {
+		matched := false
+		for _, t := range s.ResourceTypes {
+			if t == res.Classification() {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(s.TrafficTypes) > 0 {
+		matched := false
+		for _, t := range s.TrafficTypes {
+			if t == res.FlowType() {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesTags reports whether the tags of entry contain all the Tags of selector.
+func (s *ResourceSelector) MatchesTags(tags []string) bool {
+	if s == nil {
+		return false
+	}
+	for _, required := range s.Tags {
+		found := false
+		for _, t := range tags {
+			if t == required {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether the entry is selected.
+func (s *ResourceSelector) Matches(ctx *EntryContext) bool {
+	if ctx == nil || !s.MatchesResource(ctx.Resource) {
+		return false
+	}
+	var tags []string
+	if ctx.Input != nil {
+		tags = ctx.Input.Tags
+	}
+	return s.MatchesTags(tags)
+}
+
+// Specificity is the count of the restrictions of selector, the rules with higher specificity take precedence.
+func (s *ResourceSelector) Specificity() int {
+	if s == nil {
+		return 0
+	}
+	specificity := len(s.Tags)
+	if len(s.ResourceTypes) > 0 {
+		specificity++
+	}
+	if len(s.TrafficTypes) > 0 {
+		specificity++
+	}
+	return specificity
+}
+
+// IsValid checks whether the selector is valid, a nil selector is valid.
+func (s *ResourceSelector) IsValid() error {
+	if s == nil {
+		return nil
+	}
+	for _, t := range s.ResourceTypes {
+		if t < ResTypeCommon || t > ResTypeMQ {
+			return errors.Errorf("invalid resource type of selector: %d", t)
+		}
+	}
+	for _, t := range s.TrafficTypes {
+		if t != Inbound && t != Outbound {
+			return errors.Errorf("invalid traffic type of selector: %d", t)
+		}
+	}
+	for _, tag := range s.Tags {
+		if len(tag) == 0 {
+			return errors.New("empty tag of selector")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceSelector(t *testing.T) {
+	s := &ResourceSelector{
+		ResourceTypes: []ResourceType{ResTypeDBSQL, ResTypeCache},
+		TrafficTypes:  []TrafficType{Outbound},
+		Tags:          []string{"batch"},
+	}
+	assert.Equal(t, 3, s.Specificity())
+	assert.Nil(t, s.IsValid())
+
+	newCtx := func(resType ResourceType, trafficType TrafficType, tags ...string) *EntryContext {
+		ctx := NewEmptyEntryContext()
+		ctx.Resource = NewResourceWrapper("abc", resType, trafficType)
+		ctx.Input = &SentinelInput{Tags: tags}
+		return ctx
+	}
+	assert.True(t, s.Matches(newCtx(ResTypeCache, Outbound, "batch", "report")))
+	assert.False(t, s.Matches(newCtx(ResTypeCache, Outbound)))
+	assert.False(t, s.Matches(newCtx(ResTypeCache, Inbound, "batch")))
+	assert.False(t, s.Matches(newCtx(ResTypeRPC, Outbound, "batch")))
+
+	all := &ResourceSelector{}
+	assert.Equal(t, 0, all.Specificity())
+	assert.True(t, all.Matches(newCtx(ResTypeRPC, Inbound)))
+
+	var nilSelector *ResourceSelector
+	assert.False(t, nilSelector.Matches(newCtx(ResTypeRPC, Inbound)))
+	assert.Nil(t, nilSelector.IsValid())
+
+	assert.Error(t, (&ResourceSelector{ResourceTypes: []ResourceType{ResourceType(100)}}).IsValid())
+	assert.Error(t, (&ResourceSelector{TrafficTypes: []TrafficType{TrafficType(100)}}).IsValid())
+	assert.Error(t, (&ResourceSelector{Tags: []string{""}}).IsValid())
+}
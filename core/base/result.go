@@ -28,16 +28,20 @@ const (
 	BlockTypeCircuitBreaking
 	BlockTypeSystemFlow
 	BlockTypeHotSpotParamFlow
+	BlockTypeAdaptiveConcurrency
+	BlockTypeQuota
 )
 
 var (
 	blockTypeMap = map[BlockType]string{
-		BlockTypeUnknown:          "BlockTypeUnknown",
-		BlockTypeFlow:             "BlockTypeFlowControl",
-		BlockTypeIsolation:        "BlockTypeIsolation",
-		BlockTypeCircuitBreaking:  "BlockTypeCircuitBreaking",
-		BlockTypeSystemFlow:       "BlockTypeSystem",
-		BlockTypeHotSpotParamFlow: "BlockTypeHotSpotParamFlow",
+		BlockTypeUnknown:             "BlockTypeUnknown",
+		BlockTypeFlow:                "BlockTypeFlowControl",
+		BlockTypeIsolation:           "BlockTypeIsolation",
+		BlockTypeCircuitBreaking:     "BlockTypeCircuitBreaking",
+		BlockTypeSystemFlow:          "BlockTypeSystem",
+		BlockTypeHotSpotParamFlow:    "BlockTypeHotSpotParamFlow",
+		BlockTypeAdaptiveConcurrency: "BlockTypeAdaptiveConcurrency",
+		BlockTypeQuota:               "BlockTypeQuota",
 	}
 	blockTypeExisted = fmt.Errorf("block type existed")
 )
@@ -99,6 +103,8 @@ func (r *TokenResult) DeepCopyFrom(newResult *TokenResult) {
 			blockMsg:      newResult.blockErr.blockMsg,
 			rule:          newResult.blockErr.rule,
 			snapshotValue: newResult.blockErr.snapshotValue,
+			retryAfter:    newResult.blockErr.retryAfter,
+			rateLimitInfo: newResult.blockErr.rateLimitInfo,
 		}
 	} else {
 		// TODO: review the reusing logic
@@ -106,6 +112,8 @@ func (r *TokenResult) DeepCopyFrom(newResult *TokenResult) {
 		r.blockErr.blockMsg = newResult.blockErr.blockMsg
 		r.blockErr.rule = newResult.blockErr.rule
 		r.blockErr.snapshotValue = newResult.blockErr.snapshotValue
+		r.blockErr.retryAfter = newResult.blockErr.retryAfter
+		r.blockErr.rateLimitInfo = newResult.blockErr.rateLimitInfo
 	}
 }
 func (r *TokenResult) ResetToPass() {
@@ -156,6 +164,14 @@ func (r *TokenResult) NanosToWait() time.Duration {
 	return r.nanosToWait
 }
 
+// RetryAfter returns the estimated duration after which the blocked request may pass, 0 means unknown.
+func (r *TokenResult) RetryAfter() time.Duration {
+	if r.blockErr == nil {
+		return 0
+	}
+	return r.blockErr.retryAfter
+}
+
 func (r *TokenResult) FilterNodes() []string {
 	return r.filterNodes
 }
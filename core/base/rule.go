@@ -14,10 +14,112 @@
 
 package base
 
-import "fmt"
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
 
 type SentinelRule interface {
 	fmt.Stringer
 
 	ResourceName() string
 }
+
+// RuleMode indicates whether the rule takes effect on the traffic.
+type RuleMode int32
+
+const (
+	// Enforce means the requests are blocked when the rule is triggered, which is the default mode.
+	Enforce RuleMode = iota
+	// Shadow means the rule is evaluated fully but never blocks the requests (i.e. dry-run),
+	// the requests which would have been blocked are recorded as MetricEventShadowBlock instead.
+	// It's useful to tune the threshold of a new rule against the production traffic.
+	Shadow
+)
+
+func (m RuleMode) String() string {
+	switch m {
+	case Enforce:
+		return "Enforce"
+	case Shadow:
+		return "Shadow"
+	default:
+		return fmt.Sprintf("%d", m)
+	}
+}
+
+// sentinelPkgPrefix is the import path prefix of the types of sentinel.
+const sentinelPkgPrefix = "github.com/alibaba/sentinel-golang/"
+
+// RuleDefinitionEquals reports whether the rules x and y are deeply equal in their definitions like reflect.DeepEqual,
+// except that the unexported fields of the structs of sentinel are ignored. These fields hold the state prepared
+// when the rules are loaded (e.g. the compiled condition and the resolved canary selection) rather than the definition,
+// so that the rule held by a rule manager equals to the rule it's prepared from.
+func RuleDefinitionEquals(x, y interface{}) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	vx, vy := reflect.ValueOf(x), reflect.ValueOf(y)
+	if vx.Type() != vy.Type() {
+		return false
+	}
+	return definitionEquals(vx, vy)
+}
+
+func definitionEquals(x, y reflect.Value) bool {
+	switch x.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if x.IsNil() || y.IsNil() {
+			return x.IsNil() == y.IsNil()
+		}
+		if x.Kind() == reflect.Ptr && x.Pointer() == y.Pointer() {
+			return true
+		}
+		if x.Kind() == reflect.Interface && x.Elem().Type() != y.Elem().Type() {
+			return false
+		}
+		return definitionEquals(x.Elem(), y.Elem())
+	case reflect.Struct:
+		if !strings.HasPrefix(x.Type().PkgPath(), sentinelPkgPrefix) {
+			return reflect.DeepEqual(x.Interface(), y.Interface())
+		}
+		for i := 0; i < x.NumField(); i++ {
+			if !x.Type().Field(i).IsExported() {
+				continue
+			}
+			if !definitionEquals(x.Field(i), y.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if x.IsNil() != y.IsNil() {
+			return false
+		}
+		fallthrough
+	case reflect.Array:
+		if x.Len() != y.Len() {
+			return false
+		}
+		for i := 0; i < x.Len(); i++ {
+			if !definitionEquals(x.Index(i), y.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if x.IsNil() != y.IsNil() || x.Len() != y.Len() {
+			return false
+		}
+		for _, k := range x.MapKeys() {
+			vy := y.MapIndex(k)
+			if !vy.IsValid() || !definitionEquals(x.MapIndex(k), vy) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(x.Interface(), y.Interface())
+	}
+}
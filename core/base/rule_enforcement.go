@@ -0,0 +1,217 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// enforceBuckets is the resolution of the enforce percentages, i.e. 0.01%.
+const enforceBuckets = 10000
+
+// CanarySource is the identity of the instance which CanarySelector hashes.
+type CanarySource int32
+
+const (
+	// CanaryHost hashes the host name of the instance.
+	CanaryHost CanarySource = iota
+	// CanaryPod hashes the pod name from the POD_NAME environment variable,
+	// or from the HOSTNAME environment variable which Kubernetes sets to the pod name.
+	CanaryPod
+	// CanaryEnv hashes the value of the environment variable CanarySelector.EnvKey.
+	CanaryEnv
+)
+
+func (s CanarySource) String() string {
+	switch s {
+	case CanaryHost:
+		return "Host"
+	case CanaryPod:
+		return "Pod"
+	case CanaryEnv:
+		return "Env"
+	default:
+		return fmt.Sprintf("%d", s)
+	}
+}
+
+// CanarySelector selects a stable fraction of the instances by the hash of the instance identity,
+// so that a rule can be rolled out to part of the replicas first.
+type CanarySelector struct {
+	// Percent is the percentage of the instances selected, the valid range is [0.0, 100.0].
+	// Unlike EnforcePercent of rules which enforces the rule on all the traffic if 0, Percent 0 selects none of
+	// the instances so that a rule could be rolled out from no instance. Use a nil selector to select all the instances.
+	Percent float64 `json:"percent"`
+	// Source is the identity of the instance which is hashed.
+	Source CanarySource `json:"source"`
+	// EnvKey is the environment variable holding the identity if Source is CanaryEnv.
+	EnvKey string `json:"envKey,omitempty"`
+	// Salt is hashed together with the identity, the selectors with the same Salt select the same instances.
+	Salt string `json:"salt,omitempty"`
+
+	// selection caches whether the current instance is selected, which is set on the copy resolved by Resolved
+	// and excluded from Equals.
+	selection int32
+}
+
+const (
+	canaryUnresolved int32 = iota
+	canarySelected
+	canaryUnselected
+)
+
+var (
+	hostNameOnce sync.Once
+	hostName     string
+)
+
+func (s *CanarySelector) identity() string {
+	switch s.Source {
+	case CanaryHost:
+		hostNameOnce.Do(func() {
+			hostName, _ = os.Hostname()
+		})
+		return hostName
+	case CanaryPod:
+		if pod := os.Getenv("POD_NAME"); len(pod) > 0 {
+			return pod
+		}
+		return os.Getenv("HOSTNAME")
+	case CanaryEnv:
+		return os.Getenv(s.EnvKey)
+	default:
+		return ""
+	}
+}
+
+// Selected reports whether the current instance is selected. A nil selector selects all the instances,
+// while the instances without the identity are never selected by a non-nil selector.
+// The selection of the selector returned by Resolved is returned if present.
+func (s *CanarySelector) Selected() bool {
+	if s == nil {
+		return true
+	}
+	switch s.selection {
+	case canarySelected:
+		return true
+	case canaryUnselected:
+		return false
+	default:
+		return s.selected()
+	}
+}
+
+// Resolved returns the copy of the selector, on which whether the current instance is selected is resolved once,
+// so that the entries never look up the identity. The selector itself is not modified, nil is returned if it's nil.
+func (s *CanarySelector) Resolved() *CanarySelector {
+	if s == nil {
+		return nil
+	}
+	ret := *s
+	ret.selection = canaryUnselected
+	if s.selected() {
+		ret.selection = canarySelected
+	}
+	return &ret
+}
+
+// Equals reports whether the selectors select the same instances, the resolved selection is not compared.
+func (s *CanarySelector) Equals(other *CanarySelector) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+	return s.Percent == other.Percent && s.Source == other.Source && s.EnvKey == other.EnvKey && s.Salt == other.Salt
+}
+
+// ResolveCanary returns the rule to be held by the rule manager, to which the resolved copy of its canary selector
+// is bound by setCanary. Like condition.Prepare, the rule passed to the rule manager is never modified,
+// since it may be loaded already and read by the slots. The rule without canary selector is returned as-is.
+func ResolveCanary[R any](rule *R, canary *CanarySelector, setCanary func(rule *R, canary *CanarySelector)) *R {
+	if canary == nil {
+		return rule
+	}
+	ret := *rule
+	setCanary(&ret, canary.Resolved())
+	return &ret
+}
+
+func (s *CanarySelector) selected() bool {
+	id := s.identity()
+	if len(id) == 0 {
+		return false
+	}
+	return hashBucket(s.Salt+"|"+id) < percentBuckets(s.Percent)
+}
+
+// IsValid checks whether the selector is valid, a nil selector is valid.
+func (s *CanarySelector) IsValid() error {
+	if s == nil {
+		return nil
+	}
+	if s.Percent < 0 || s.Percent > 100 {
+		return errors.New("invalid canary percent, valid range is [0.0, 100.0]")
+	}
+	if s.Source < CanaryHost || s.Source > CanaryEnv {
+		return errors.Errorf("invalid canary source: %d", s.Source)
+	}
+	if s.Source == CanaryEnv && len(s.EnvKey) == 0 {
+		return errors.New("empty EnvKey of canary Env source")
+	}
+	return nil
+}
+
+// IsEnforcedOn reports whether a rule with the mode, EnforcePercent, EnforceKey and Canary is enforced on the entry.
+// The rules not enforced on the entry are checked in the Shadow mode instead.
+//
+// enforcePercent is the percentage of traffic the rule is enforced on, 0 means all the traffic.
+// The traffic is selected by the hash of the attachment enforceKey if present, or randomly otherwise.
+func IsEnforcedOn(ctx *EntryContext, mode RuleMode, enforcePercent float64, enforceKey string, canary *CanarySelector) bool {
+	if mode == Shadow || !canary.Selected() {
+		return false
+	}
+	if enforcePercent <= 0 || enforcePercent >= 100 {
+		return true
+	}
+	if len(enforceKey) > 0 && ctx != nil && ctx.Input != nil {
+		if v, ok := ctx.Input.Attachments[enforceKey]; ok {
+			return hashBucket(fmt.Sprint(v)) < percentBuckets(enforcePercent)
+		}
+	}
+	return rand.Intn(enforceBuckets) < percentBuckets(enforcePercent)
+}
+
+// CheckEnforcePercent checks the EnforcePercent of rule.
+func CheckEnforcePercent(enforcePercent float64) error {
+	if enforcePercent < 0 || enforcePercent > 100 {
+		return errors.New("invalid EnforcePercent, valid range is [0.0, 100.0]")
+	}
+	return nil
+}
+
+func percentBuckets(percent float64) int {
+	return int(percent * enforceBuckets / 100)
+}
+
+func hashBucket(s string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	return int(h.Sum32() % enforceBuckets)
+}
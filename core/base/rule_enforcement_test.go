@@ -0,0 +1,97 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsEnforcedOn(t *testing.T) {
+	newCtx := func(v interface{}) *EntryContext {
+		return &EntryContext{Input: &SentinelInput{Attachments: map[interface{}]interface{}{"uid": v}}}
+	}
+
+	assert.True(t, IsEnforcedOn(newCtx(1), Enforce, 0, "", nil))
+	assert.True(t, IsEnforcedOn(newCtx(1), Enforce, 100, "uid", nil))
+	assert.False(t, IsEnforcedOn(newCtx(1), Shadow, 0, "", nil))
+
+	enforced := 0
+	for i := 0; i < 1000; i++ {
+		ctx := newCtx(i)
+		e := IsEnforcedOn(ctx, Enforce, 10, "uid", nil)
+		// The traffic with the same key is always selected consistently.
+		assert.Equal(t, e, IsEnforcedOn(ctx, Enforce, 10, "uid", nil))
+		if e {
+			enforced++
+		}
+	}
+	assert.InDelta(t, 100, enforced, 50)
+
+	enforced = 0
+	for i := 0; i < 1000; i++ {
+		if IsEnforcedOn(nil, Enforce, 10, "", nil) {
+			enforced++
+		}
+	}
+	assert.InDelta(t, 100, enforced, 50)
+	assert.Error(t, CheckEnforcePercent(101))
+	assert.Error(t, CheckEnforcePercent(-1))
+}
+
+func TestCanarySelector(t *testing.T) {
+	var nilSelector *CanarySelector
+	assert.True(t, nilSelector.Selected())
+	assert.Nil(t, nilSelector.IsValid())
+
+	const envKey = "SENTINEL_CANARY_TEST_INSTANCE"
+	defer os.Unsetenv(envKey)
+	s := &CanarySelector{Percent: 10, Source: CanaryEnv, EnvKey: envKey}
+	assert.Nil(t, s.IsValid())
+	assert.False(t, s.Selected(), "instances without identity should never be selected")
+
+	selected := 0
+	for i := 0; i < 1000; i++ {
+		_ = os.Setenv(envKey, "pod-"+strconv.Itoa(i))
+		if s.Selected() {
+			selected++
+		}
+	}
+	assert.InDelta(t, 100, selected, 50)
+
+	_ = os.Setenv(envKey, "pod-0")
+	assert.True(t, (&CanarySelector{Percent: 100, Source: CanaryEnv, EnvKey: envKey}).Selected())
+	assert.False(t, (&CanarySelector{Percent: 0, Source: CanaryEnv, EnvKey: envKey}).Selected())
+	assert.False(t, IsEnforcedOn(nil, Enforce, 0, "", &CanarySelector{Percent: 0, Source: CanaryEnv, EnvKey: envKey}))
+
+	// the resolved selection is kept even if the identity changes
+	raw := &CanarySelector{Percent: 100, Source: CanaryEnv, EnvKey: envKey}
+	resolved := raw.Resolved()
+	_ = os.Unsetenv(envKey)
+	assert.True(t, resolved.Selected())
+	assert.False(t, raw.Selected(), "the selector itself shouldn't be resolved")
+	assert.True(t, raw.Equals(resolved), "the resolved selection shouldn't be compared")
+	assert.False(t, raw.Equals(&CanarySelector{Percent: 100, Source: CanaryEnv, EnvKey: envKey, Salt: "v2"}))
+	assert.False(t, raw.Equals(nil))
+	assert.True(t, nilSelector.Equals(nil))
+	assert.Nil(t, nilSelector.Resolved())
+
+	assert.Error(t, (&CanarySelector{Percent: 120}).IsValid())
+	assert.Error(t, (&CanarySelector{Percent: 10, Source: CanaryEnv}).IsValid())
+	assert.Error(t, (&CanarySelector{Percent: 10, Source: CanarySource(5)}).IsValid())
+}
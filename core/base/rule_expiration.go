@@ -0,0 +1,265 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/alibaba/sentinel-golang/util"
+)
+
+// RuleExpirationCheckInterval is the interval to check whether there are expired rules.
+const RuleExpirationCheckInterval = time.Second
+
+// ExpirableRule is the rule which could expire on its own.
+type ExpirableRule interface {
+	SentinelRule
+
+	// Lifetime returns the ExpireAt (Unix timestamp in milliseconds) and TTLSec of the rule, 0 means unset.
+	Lifetime() (expireAt uint64, ttlSec uint32)
+}
+
+// RuleDeadline resolves the deadline (Unix timestamp in milliseconds) of the rule loaded at loadedAtMs,
+// the earlier one of expireAt and loadedAtMs+ttlSec takes effect. 0 means the rule never expires.
+func RuleDeadline(expireAt uint64, ttlSec uint32, loadedAtMs uint64) uint64 {
+	if ttlSec == 0 {
+		return expireAt
+	}
+	deadline := loadedAtMs + uint64(ttlSec)*1000
+	if expireAt > 0 && expireAt < deadline {
+		return expireAt
+	}
+	return deadline
+}
+
+// RuleExpiration tracks the deadlines of the current rules of a rule manager,
+// and periodically invokes the expire function of the rule manager once some rules expire.
+// The periodical check only runs while there are tracked rules.
+type RuleExpiration struct {
+	expire    func()
+	mux       sync.RWMutex
+	deadlines map[ExpirableRule]uint64
+	// running indicates whether the periodical check is running, guarded by mux.
+	running bool
+}
+
+// NewRuleExpiration creates the RuleExpiration, expire is invoked when some tracked rules expire,
+// and the rule manager should drop the expired rules in it.
+func NewRuleExpiration(expire func()) *RuleExpiration {
+	return &RuleExpiration{
+		expire:    expire,
+		deadlines: make(map[ExpirableRule]uint64),
+	}
+}
+
+// Track replaces the tracked rules with the given current rules of the rule manager.
+// TTLSec counts from the time the rule is tracked at the first time,
+// the rule equal to a tracked one (e.g. the same rules pushed by data source again) inherits its deadline.
+func (e *RuleExpiration) Track(rules []ExpirableRule) {
+	now := util.CurrentTimeMillis()
+	e.mux.Lock()
+	deadlines := make(map[ExpirableRule]uint64)
+	for _, r := range rules {
+		expireAt, ttlSec := r.Lifetime()
+		if expireAt == 0 && ttlSec == 0 {
+			continue
+		}
+		deadline, ok := e.lookupLocked(r)
+		if !ok {
+			deadline = RuleDeadline(expireAt, ttlSec, now)
+		}
+		deadlines[r] = deadline
+	}
+	e.deadlines = deadlines
+	if len(deadlines) > 0 && !e.running {
+		e.running = true
+		e.start()
+	}
+	e.mux.Unlock()
+}
+
+func (e *RuleExpiration) start() {
+	ticker := util.NewTicker(RuleExpirationCheckInterval)
+	go util.RunWithRecover(func() {
+		defer ticker.Stop()
+		for range ticker.C() {
+			if !e.keepRunning() {
+				return
+			}
+			if e.hasExpired(util.CurrentTimeMillis()) {
+				e.expire()
+			}
+		}
+	})
+}
+
+// keepRunning reports whether the periodical check should go on, it stops once no rule is tracked
+// and is started again by Track.
+func (e *RuleExpiration) keepRunning() bool {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if len(e.deadlines) == 0 {
+		e.running = false
+	}
+	return e.running
+}
+
+func (e *RuleExpiration) hasExpired(nowMs uint64) bool {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	for _, deadline := range e.deadlines {
+		if deadline <= nowMs {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupLocked finds the deadline of the rule itself or the tracked rule equal to it, must be guarded by e.mux.
+// The rules prepared by the rule managers on load are equal to the tracked rules they are prepared from.
+func (e *RuleExpiration) lookupLocked(r ExpirableRule) (uint64, bool) {
+	if deadline, ok := e.deadlines[r]; ok {
+		return deadline, true
+	}
+	for tracked, deadline := range e.deadlines {
+		if RuleDefinitionEquals(tracked, r) {
+			return deadline, true
+		}
+	}
+	return 0, false
+}
+
+// DeadlineOf returns the resolved deadline of the rule, 0 if the rule never expires or is not tracked.
+func (e *RuleExpiration) DeadlineOf(r ExpirableRule) uint64 {
+	if expireAt, ttlSec := r.Lifetime(); expireAt == 0 && ttlSec == 0 {
+		return 0
+	}
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	deadline, _ := e.lookupLocked(r)
+	return deadline
+}
+
+// IsExpired checks whether the rule has expired at nowMs.
+func (e *RuleExpiration) IsExpired(r ExpirableRule, nowMs uint64) bool {
+	deadline := e.DeadlineOf(r)
+	return deadline > 0 && deadline <= nowMs
+}
+
+// RemainingLifetime returns the resolved ExpireAt and the remaining TTLSec (rounded up) of the rule at nowMs,
+// which are reported by GetRules of rule managers. The Lifetime of the rule is returned as-is if it's not tracked.
+func (e *RuleExpiration) RemainingLifetime(r ExpirableRule, nowMs uint64) (uint64, uint32) {
+	deadline := e.DeadlineOf(r)
+	if deadline == 0 {
+		return r.Lifetime()
+	}
+	if deadline <= nowMs {
+		return deadline, 0
+	}
+	return deadline, uint32((deadline - nowMs + 999) / 1000)
+}
+
+// ExpirableRulePointer is the constraint of the pointer to the rule type T of a rule manager.
+type ExpirableRulePointer[T any] interface {
+	*T
+	ExpirableRule
+}
+
+// RuleExpirationManager drops the expired rules of a rule manager, whose current rules are grouped by resource.
+// T is the rule type of the rule manager.
+type RuleExpirationManager[T any, R ExpirableRulePointer[T]] struct {
+	expiration   *RuleExpiration
+	logPrefix    string
+	mux          sync.Locker
+	currentRules func() map[string][]R
+	update       func(map[string][]R) error
+	setLifetime  func(rule *T, expireAt uint64, ttlSec uint32)
+}
+
+// NewRuleExpirationManager creates the RuleExpirationManager of a rule manager.
+// mux guards the current rules, currentRules returns the current rules grouped by resource,
+// update replaces the current rules with the unexpired ones, and setLifetime sets the ExpireAt and TTLSec of the rule.
+func NewRuleExpirationManager[T any, R ExpirableRulePointer[T]](logPrefix string, mux sync.Locker,
+	currentRules func() map[string][]R, update func(map[string][]R) error,
+	setLifetime func(rule *T, expireAt uint64, ttlSec uint32)) *RuleExpirationManager[T, R] {
+	m := &RuleExpirationManager[T, R]{
+		logPrefix:    logPrefix,
+		mux:          mux,
+		currentRules: currentRules,
+		update:       update,
+		setLifetime:  setLifetime,
+	}
+	m.expiration = NewRuleExpiration(m.Expire)
+	return m
+}
+
+// Track tracks the deadlines of the current rules, must be guarded by the mux of the rule manager.
+func (m *RuleExpirationManager[T, R]) Track() {
+	m.track(m.currentRules())
+}
+
+func (m *RuleExpirationManager[T, R]) track(resRulesMap map[string][]R) {
+	rules := make([]ExpirableRule, 0)
+	for _, resRules := range resRulesMap {
+		for _, rule := range resRules {
+			rules = append(rules, rule)
+		}
+	}
+	m.expiration.Track(rules)
+}
+
+// Expire drops the expired rules from the current rules. The expired rules are no longer tracked even if
+// they fail to be dropped, so that the failure is logged once rather than on every check.
+func (m *RuleExpirationManager[T, R]) Expire() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	now := util.CurrentTimeMillis()
+	current := m.currentRules()
+	resRulesMap := make(map[string][]R, len(current))
+	expired := false
+	for res, rules := range current {
+		for _, rule := range rules {
+			if m.expiration.IsExpired(rule, now) {
+				logging.Info(m.logPrefix+" Rule expired", "rule", rule)
+				expired = true
+				continue
+			}
+			resRulesMap[res] = append(resRulesMap[res], rule)
+		}
+	}
+	if !expired {
+		return
+	}
+	if err := m.update(resRulesMap); err != nil {
+		logging.Error(err, m.logPrefix+" Failed to drop expired rules, the expired rules are kept without lifetime")
+		m.track(resRulesMap)
+		return
+	}
+	m.Track()
+}
+
+// CopyWithLifetime returns the copy of rule with the resolved ExpireAt and the remaining TTLSec.
+func (m *RuleExpirationManager[T, R]) CopyWithLifetime(rule R, nowMs uint64) T {
+	ret := *rule
+	expireAt, ttlSec := m.expiration.RemainingLifetime(rule, nowMs)
+	m.setLifetime(&ret, expireAt, ttlSec)
+	return ret
+}
@@ -0,0 +1,181 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type expirableRuleMock struct {
+	Resource string
+	ExpireAt uint64
+	TTLSec   uint32
+}
+
+func (r *expirableRuleMock) String() string {
+	return r.Resource
+}
+
+func (r *expirableRuleMock) ResourceName() string {
+	return r.Resource
+}
+
+func (r *expirableRuleMock) Lifetime() (uint64, uint32) {
+	return r.ExpireAt, r.TTLSec
+}
+
+func TestRuleDeadline(t *testing.T) {
+	assert.Equal(t, uint64(0), RuleDeadline(0, 0, 1000))
+	assert.Equal(t, uint64(5000), RuleDeadline(5000, 0, 1000))
+	assert.Equal(t, uint64(11000), RuleDeadline(0, 10, 1000))
+	assert.Equal(t, uint64(5000), RuleDeadline(5000, 10, 1000))
+	assert.Equal(t, uint64(11000), RuleDeadline(20000, 10, 1000))
+}
+
+func TestRuleExpiration(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	e := NewRuleExpiration(func() {})
+	now := util.CurrentTimeMillis()
+	forever := &expirableRuleMock{Resource: "forever"}
+	ttl := &expirableRuleMock{Resource: "ttl", TTLSec: 10}
+	expireAt := &expirableRuleMock{Resource: "expireAt", ExpireAt: now + 5000}
+	e.Track([]ExpirableRule{forever, ttl, expireAt})
+
+	assert.Equal(t, uint64(0), e.DeadlineOf(forever))
+	assert.Equal(t, now+10000, e.DeadlineOf(ttl))
+	assert.Equal(t, now+5000, e.DeadlineOf(expireAt))
+	expireAtMs, ttlSec := e.RemainingLifetime(forever, now)
+	assert.Equal(t, uint64(0), expireAtMs)
+	assert.Equal(t, uint32(0), ttlSec)
+	expireAtMs, ttlSec = e.RemainingLifetime(ttl, now+1500)
+	assert.Equal(t, now+10000, expireAtMs)
+	assert.Equal(t, uint32(9), ttlSec)
+
+	util.Sleep(6 * time.Second)
+	assert.True(t, e.hasExpired(util.CurrentTimeMillis()))
+	assert.True(t, e.IsExpired(expireAt, util.CurrentTimeMillis()))
+	assert.False(t, e.IsExpired(ttl, util.CurrentTimeMillis()))
+
+	// the equal rule loaded again inherits the deadline rather than starting a new lifetime
+	e.Track([]ExpirableRule{&expirableRuleMock{Resource: "ttl", TTLSec: 10}})
+	assert.Equal(t, now+10000, e.DeadlineOf(&expirableRuleMock{Resource: "ttl", TTLSec: 10}))
+	assert.Equal(t, uint64(0), e.DeadlineOf(expireAt))
+	assert.False(t, e.hasExpired(util.CurrentTimeMillis()))
+
+	// the changed rule starts a new lifetime
+	e.Track([]ExpirableRule{&expirableRuleMock{Resource: "ttl", TTLSec: 20}})
+	assert.Equal(t, util.CurrentTimeMillis()+20000, e.DeadlineOf(&expirableRuleMock{Resource: "ttl", TTLSec: 20}))
+}
+
+func TestRuleExpirationManager(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	now := util.CurrentTimeMillis()
+	forever := &expirableRuleMock{Resource: "abc"}
+	ttl := &expirableRuleMock{Resource: "abc", TTLSec: 5}
+	mux := new(sync.Mutex)
+	currentRules := map[string][]*expirableRuleMock{"abc": {forever, ttl}}
+	m := NewRuleExpirationManager("[Test]", mux,
+		func() map[string][]*expirableRuleMock {
+			return currentRules
+		},
+		func(resRulesMap map[string][]*expirableRuleMock) error {
+			currentRules = resRulesMap
+			return nil
+		},
+		func(rule *expirableRuleMock, expireAt uint64, ttlSec uint32) {
+			rule.ExpireAt, rule.TTLSec = expireAt, ttlSec
+		})
+	mux.Lock()
+	m.Track()
+	mux.Unlock()
+
+	util.Sleep(2 * time.Second)
+	r := m.CopyWithLifetime(ttl, util.CurrentTimeMillis())
+	assert.Equal(t, now+5000, r.ExpireAt)
+	assert.Equal(t, uint32(3), r.TTLSec)
+	assert.Equal(t, uint32(5), ttl.TTLSec)
+	m.Expire()
+	assert.Equal(t, 2, len(currentRules["abc"]))
+
+	util.Sleep(3 * time.Second)
+	m.Expire()
+	assert.Equal(t, []*expirableRuleMock{forever}, currentRules["abc"])
+	assert.Equal(t, uint64(0), m.expiration.DeadlineOf(ttl))
+}
+
+func TestRuleExpirationManager_UpdateFailure(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	util.SetTickerCreator(util.NewMockTickerCreator())
+	defer util.SetClock(util.NewRealClock())
+	defer util.SetTickerCreator(util.NewRealTickerCreator())
+
+	ttl := &expirableRuleMock{Resource: "abc", TTLSec: 1}
+	mux := new(sync.Mutex)
+	currentRules := map[string][]*expirableRuleMock{"abc": {ttl}}
+	var updates int32
+	m := NewRuleExpirationManager("[Test]", mux,
+		func() map[string][]*expirableRuleMock {
+			return currentRules
+		},
+		func(resRulesMap map[string][]*expirableRuleMock) error {
+			atomic.AddInt32(&updates, 1)
+			return errors.New("mock update failure")
+		},
+		func(rule *expirableRuleMock, expireAt uint64, ttlSec uint32) {
+			rule.ExpireAt, rule.TTLSec = expireAt, ttlSec
+		})
+	running := func() bool {
+		m.expiration.mux.RLock()
+		defer m.expiration.mux.RUnlock()
+		return m.expiration.running
+	}
+	mux.Lock()
+	m.Track()
+	mux.Unlock()
+	assert.True(t, running())
+
+	// the rule failed to be dropped is no longer tracked, so that it's not retried on every check
+	util.Sleep(2 * time.Second)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&updates) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []*expirableRuleMock{ttl}, currentRules["abc"])
+	assert.Equal(t, uint64(0), m.expiration.DeadlineOf(ttl))
+
+	// the periodical check stops without tracked rules
+	util.Sleep(2 * time.Second)
+	assert.Eventually(t, func() bool {
+		return !running()
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&updates))
+
+	// and starts again once there are rules to track
+	currentRules = map[string][]*expirableRuleMock{"abc": {{Resource: "abc", TTLSec: 10}}}
+	mux.Lock()
+	m.Track()
+	mux.Unlock()
+	assert.True(t, running())
+}
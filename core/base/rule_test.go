@@ -0,0 +1,65 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDefinedRule struct {
+	Resource string
+	Canary   *CanarySelector
+	Tags     []string
+	Begin    time.Time
+	prepared *int
+}
+
+func TestRuleDefinitionEquals(t *testing.T) {
+	begin := time.Unix(1600000000, 0)
+	newRule := func() *testDefinedRule {
+		return &testDefinedRule{
+			Resource: "abc",
+			Canary:   &CanarySelector{Percent: 10, Source: CanaryHost},
+			Tags:     []string{"a"},
+			Begin:    begin,
+		}
+	}
+	r := newRule()
+	prepared := *newRule()
+	prepared.Canary = prepared.Canary.Resolved()
+	prepared.prepared = new(int)
+	assert.True(t, RuleDefinitionEquals(r, &prepared), "the prepared state should be ignored")
+	assert.True(t, RuleDefinitionEquals(r, r))
+	assert.False(t, RuleDefinitionEquals(r, nil))
+	assert.True(t, RuleDefinitionEquals(nil, nil))
+
+	other := newRule()
+	other.Tags = nil
+	assert.False(t, RuleDefinitionEquals(r, other))
+	other = newRule()
+	other.Canary.Salt = "v2"
+	assert.False(t, RuleDefinitionEquals(r, other))
+	other = newRule()
+	other.Canary = nil
+	assert.False(t, RuleDefinitionEquals(r, other))
+	// the unexported fields of the types out of sentinel are compared
+	other = newRule()
+	other.Begin = begin.Add(time.Second)
+	assert.False(t, RuleDefinitionEquals(r, other))
+	assert.False(t, RuleDefinitionEquals(r, &CanarySelector{}))
+}
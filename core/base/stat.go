@@ -22,7 +22,7 @@ type TimePredicate func(uint64) bool
 
 type MetricEvent int8
 
-// There are five events to record
+// There are six events to record
 // pass + block == Total
 const (
 	// sentinel rules check pass
@@ -35,6 +35,8 @@ const (
 	MetricEventError
 	// request execute rt, unit is millisecond
 	MetricEventRt
+	// the requests which would have been blocked by shadow rules
+	MetricEventShadowBlock
 	// hack for the number of event
 	MetricEventTotal
 )
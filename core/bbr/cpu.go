@@ -0,0 +1,90 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbr
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alibaba/sentinel-golang/core/system_metric"
+	"github.com/alibaba/sentinel-golang/util"
+)
+
+const (
+	cpuEwmaDecay            = 0.95
+	cpuEwmaSampleIntervalMs = 250
+)
+
+var globalCpuEwma = &ewma{
+	decay:            cpuEwmaDecay,
+	sampleIntervalMs: cpuEwmaSampleIntervalMs,
+}
+
+// ewma is the exponentially weighted moving average of the samples taken at a fixed interval.
+// It's updated lazily on reading, and the missed samples are considered to be the latest one.
+type ewma struct {
+	decay            float64
+	sampleIntervalMs uint64
+
+	mux        sync.Mutex
+	value      uint64
+	lastUpdate uint64
+	inited     int32
+}
+
+func (e *ewma) load() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&e.value))
+}
+
+// update updates the average with the given sample at the given time, and returns the average.
+func (e *ewma) update(now uint64, sample float64) float64 {
+	if atomic.LoadInt32(&e.inited) == 1 && now < atomic.LoadUint64(&e.lastUpdate)+e.sampleIntervalMs {
+		return e.load()
+	}
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if atomic.LoadInt32(&e.inited) == 0 {
+		atomic.StoreUint64(&e.value, math.Float64bits(sample))
+		atomic.StoreUint64(&e.lastUpdate, now)
+		atomic.StoreInt32(&e.inited, 1)
+		return sample
+	}
+	last := atomic.LoadUint64(&e.lastUpdate)
+	if now < last+e.sampleIntervalMs {
+		return e.load()
+	}
+	n := (now - last) / e.sampleIntervalMs
+	d := math.Pow(e.decay, float64(n))
+	v := e.load()*d + sample*(1-d)
+	atomic.StoreUint64(&e.value, math.Float64bits(v))
+	atomic.StoreUint64(&e.lastUpdate, last+n*e.sampleIntervalMs)
+	return v
+}
+
+// CurrentCpuUsage returns the exponentially weighted moving average of the CPU usage ratio in [0.0, 1.0]
+// (see system_metric.CurrentCpuUsage), which smooths the spikes of CPU usage to avoid the jitter of limiting.
+// It returns system_metric.NotRetrievedCpuUsageValue if the CPU usage is not retrieved yet.
+func CurrentCpuUsage() float64 {
+	cpu := system_metric.CurrentCpuUsage()
+	if cpu < 0 {
+		if atomic.LoadInt32(&globalCpuEwma.inited) == 0 {
+			return system_metric.NotRetrievedCpuUsageValue
+		}
+		return globalCpuEwma.load()
+	}
+	return globalCpuEwma.update(util.CurrentTimeMillis(), cpu)
+}
@@ -0,0 +1,51 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/system_metric"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrentCpuUsage(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+	origin := globalCpuEwma
+	globalCpuEwma = &ewma{decay: cpuEwmaDecay, sampleIntervalMs: cpuEwmaSampleIntervalMs}
+	defer func() {
+		globalCpuEwma = origin
+		system_metric.SetSystemCpuUsage(system_metric.NotRetrievedCpuUsageValue)
+	}()
+
+	system_metric.SetSystemCpuUsage(system_metric.NotRetrievedCpuUsageValue)
+	assert.Equal(t, system_metric.NotRetrievedCpuUsageValue, CurrentCpuUsage())
+
+	// a container using 0.9 of its 2 CPU limit is collected as the ratio 0.45
+	system_metric.SetSystemCpuUsage(0.45)
+	assert.True(t, util.Float64Equals(0.45, CurrentCpuUsage()))
+
+	// the average approaches the sustained usage of 1.9 of 2 CPUs
+	system_metric.SetSystemCpuUsage(0.95)
+	for i := 0; i < 40; i++ {
+		util.Sleep(cpuEwmaSampleIntervalMs * time.Millisecond)
+		CurrentCpuUsage()
+	}
+	usage := CurrentCpuUsage()
+	assert.True(t, usage > 0.8 && usage <= 1.0, "usage: %f", usage)
+}
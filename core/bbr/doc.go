@@ -0,0 +1,23 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bbr provides the adaptive limiter based on ideas of TCP BBR.
+//
+// The limiter estimates the capacity of a service by the max count of completed requests per second
+// and the min response time within a rolling window, i.e. maxInFlight = maxPass * minRt.
+// Once the service is overloaded (e.g. the CPU usage exceeds the threshold), the requests are dropped
+// as long as the in-flight requests exceed the estimated capacity. The limiter keeps dropping in the
+// cool-down period after the latest drop even if the service is no longer overloaded, so that the
+// traffic won't rush in immediately and overload the service again.
+package bbr
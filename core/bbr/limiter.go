@@ -0,0 +1,176 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbr
+
+import (
+	"math"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	sbase "github.com/alibaba/sentinel-golang/core/stat/base"
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultWindowMs    uint32 = 10000
+	DefaultBucketCount uint32 = 100
+	DefaultCoolDownMs  uint32 = 1000
+)
+
+// Limiter is the BBR adaptive limiter. It's safe for concurrent use.
+type Limiter struct {
+	arr             *sbase.BucketLeapArray
+	bucketLengthMs  uint64
+	bucketPerSecond float64
+	coolDownMs      uint64
+	// prevDropTime is the time in milliseconds of the latest drop, 0 means not in cool-down.
+	prevDropTime uint64
+	// capacity caches the estimation of the completed buckets, it's refreshed when the current bucket moves on.
+	capacity atomic.Value
+}
+
+type capacity struct {
+	bucketStart uint64
+	maxPass     int64
+	minRt       int64
+}
+
+// NewLimiter creates a Limiter tracking the max-pass and min-RT over the rolling window of windowMs,
+// which is divided into bucketCount buckets. The zero values mean the defaults.
+func NewLimiter(windowMs, bucketCount, coolDownMs uint32) (*Limiter, error) {
+	if windowMs == 0 {
+		windowMs = DefaultWindowMs
+	}
+	if bucketCount == 0 {
+		bucketCount = DefaultBucketCount
+	}
+	if coolDownMs == 0 {
+		coolDownMs = DefaultCoolDownMs
+	}
+	if err := base.CheckValidityForStatistic(bucketCount, windowMs); err != nil {
+		return nil, errors.Wrapf(err, "invalid window of bbr limiter, windowMs: %d, bucketCount: %d", windowMs, bucketCount)
+	}
+	bucketLengthMs := windowMs / bucketCount
+	return &Limiter{
+		arr:             sbase.NewBucketLeapArray(bucketCount, windowMs),
+		bucketLengthMs:  uint64(bucketLengthMs),
+		bucketPerSecond: 1000.0 / float64(bucketLengthMs),
+		coolDownMs:      uint64(coolDownMs),
+	}, nil
+}
+
+// OnCompleted records the completed requests and their response time in milliseconds.
+func (l *Limiter) OnCompleted(rtMs uint64, count uint32) {
+	if count == 0 {
+		return
+	}
+	l.arr.AddCount(base.MetricEventRt, int64(rtMs)*int64(count))
+	l.arr.AddCount(base.MetricEventComplete, int64(count))
+}
+
+func (l *Limiter) loadCapacity(now uint64) *capacity {
+	curStart := now - now%l.bucketLengthMs
+	if c, ok := l.capacity.Load().(*capacity); ok && c.bucketStart == curStart {
+		return c
+	}
+
+	c := &capacity{
+		bucketStart: curStart,
+		maxPass:     0,
+		minRt:       math.MaxInt64,
+	}
+	for _, w := range l.arr.Values(now) {
+		// The current bucket is still being filled, so only the completed buckets are counted.
+		if w.BucketStart == curStart {
+			continue
+		}
+		v := w.Value.Load()
+		mb, ok := v.(*sbase.MetricBucket)
+		if !ok {
+			logging.Error(errors.New("type assert failed"), "Fail to do type assert in bbr.Limiter.loadCapacity()", "expectType", "*MetricBucket", "actualType", reflect.TypeOf(v))
+			continue
+		}
+		complete := mb.Get(base.MetricEventComplete)
+		if complete <= 0 {
+			continue
+		}
+		if complete > c.maxPass {
+			c.maxPass = complete
+		}
+		if rt := int64(math.Ceil(float64(mb.Get(base.MetricEventRt)) / float64(complete))); rt < c.minRt {
+			c.minRt = rt
+		}
+	}
+	if c.maxPass <= 0 {
+		c.maxPass = 1
+	}
+	if c.minRt == math.MaxInt64 || c.minRt <= 0 {
+		c.minRt = 1
+	}
+	l.capacity.Store(c)
+	return c
+}
+
+// MaxPass returns the max count of completed requests of single bucket within the rolling window.
+func (l *Limiter) MaxPass() int64 {
+	return l.loadCapacity(util.CurrentTimeMillis()).maxPass
+}
+
+// MinRt returns the min average response time in milliseconds of single bucket within the rolling window.
+func (l *Limiter) MinRt() int64 {
+	return l.loadCapacity(util.CurrentTimeMillis()).minRt
+}
+
+// MaxInFlight returns the estimated capacity of in-flight requests.
+func (l *Limiter) MaxInFlight() int64 {
+	return l.maxInFlightWithTime(util.CurrentTimeMillis())
+}
+
+func (l *Limiter) maxInFlightWithTime(now uint64) int64 {
+	c := l.loadCapacity(now)
+	return int64(math.Floor(float64(c.maxPass)*l.bucketPerSecond*float64(c.minRt)/1000.0 + 0.5))
+}
+
+// InCoolDown indicates whether the limiter is in the cool-down period after the latest drop.
+func (l *Limiter) InCoolDown() bool {
+	prevDrop := atomic.LoadUint64(&l.prevDropTime)
+	return prevDrop != 0 && util.CurrentTimeMillis()-prevDrop <= l.coolDownMs
+}
+
+// ShouldDrop checks whether the request should be dropped, inFlight is the count of in-flight requests
+// excluding the current one, and overloaded indicates whether the service is overloaded currently.
+func (l *Limiter) ShouldDrop(inFlight int64, overloaded bool) bool {
+	now := util.CurrentTimeMillis()
+	if !overloaded {
+		prevDrop := atomic.LoadUint64(&l.prevDropTime)
+		if prevDrop == 0 {
+			return false
+		}
+		if now-prevDrop <= l.coolDownMs {
+			return inFlight > 1 && inFlight > l.maxInFlightWithTime(now)
+		}
+		// The cool-down period is over.
+		atomic.CompareAndSwapUint64(&l.prevDropTime, prevDrop, 0)
+		return false
+	}
+	drop := inFlight > 1 && inFlight > l.maxInFlightWithTime(now)
+	if drop && atomic.LoadUint64(&l.prevDropTime) == 0 {
+		atomic.CompareAndSwapUint64(&l.prevDropTime, 0, now)
+	}
+	return drop
+}
@@ -0,0 +1,90 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLimiter(t *testing.T) {
+	l, err := NewLimiter(0, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(DefaultWindowMs/DefaultBucketCount), l.bucketLengthMs)
+	assert.Equal(t, uint64(DefaultCoolDownMs), l.coolDownMs)
+
+	_, err = NewLimiter(1000, 3, 0)
+	assert.NotNil(t, err)
+}
+
+func TestLimiter_ShouldDrop(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	l, err := NewLimiter(1000, 10, 1000)
+	assert.Nil(t, err)
+
+	t.Run("NoHistory", func(t *testing.T) {
+		assert.Equal(t, int64(1), l.MaxPass())
+		assert.Equal(t, int64(1), l.MinRt())
+		assert.False(t, l.ShouldDrop(100, false))
+		assert.False(t, l.InCoolDown())
+	})
+
+	// 50 requests completed with 20 ms RT in a bucket of 100 ms, so the capacity is 500 QPS * 20 ms = 10.
+	for i := 0; i < 10; i++ {
+		l.OnCompleted(20, 5)
+	}
+	l.OnCompleted(100, 0)
+	util.Sleep(100 * time.Millisecond)
+
+	t.Run("Capacity", func(t *testing.T) {
+		assert.Equal(t, int64(50), l.MaxPass())
+		assert.Equal(t, int64(20), l.MinRt())
+		assert.Equal(t, int64(10), l.MaxInFlight())
+	})
+
+	t.Run("Overloaded", func(t *testing.T) {
+		assert.False(t, l.ShouldDrop(10, true))
+		assert.False(t, l.InCoolDown())
+		assert.True(t, l.ShouldDrop(11, true))
+		assert.True(t, l.InCoolDown())
+	})
+
+	t.Run("CoolDown", func(t *testing.T) {
+		util.Sleep(500 * time.Millisecond)
+		assert.True(t, l.ShouldDrop(11, false))
+		assert.False(t, l.ShouldDrop(10, false))
+
+		util.Sleep(600 * time.Millisecond)
+		assert.False(t, l.InCoolDown())
+		assert.False(t, l.ShouldDrop(11, false))
+		assert.Equal(t, uint64(0), l.prevDropTime)
+	})
+}
+
+func TestEwma(t *testing.T) {
+	e := &ewma{decay: 0.5, sampleIntervalMs: 100}
+	assert.True(t, util.Float64Equals(0.8, e.update(1000, 0.8)))
+	// Not reached the sample interval.
+	assert.True(t, util.Float64Equals(0.8, e.update(1050, 0.0)))
+	assert.True(t, util.Float64Equals(0.4, e.update(1100, 0.0)))
+	// Two samples are missed.
+	assert.True(t, util.Float64Equals(0.1, e.update(1320, 0.0)))
+	assert.Equal(t, uint64(1300), e.lastUpdate)
+}
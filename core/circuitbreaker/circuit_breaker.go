@@ -19,6 +19,7 @@ import (
 	"sync/atomic"
 
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/schedule"
 	sbase "github.com/alibaba/sentinel-golang/core/stat/base"
 	metric_exporter "github.com/alibaba/sentinel-golang/exporter/metric"
 	"github.com/alibaba/sentinel-golang/logging"
@@ -141,6 +142,16 @@ type circuitBreakerBase struct {
 	curProbeNumber uint64
 	// state is the state machine of circuit breaker
 	state *State
+	// schedule overrides the threshold in the scheduled time windows, nil if the rule has no schedule.
+	schedule *schedule.Evaluator
+}
+
+func newScheduleEvaluator(r *Rule) *schedule.Evaluator {
+	evaluator, err := schedule.NewEvaluator(r.Schedule)
+	if err != nil {
+		logging.Warn("[CircuitBreaker newScheduleEvaluator] Ignoring the invalid schedule of rule", "rule", r, "reason", err.Error())
+	}
+	return evaluator
 }
 
 func (b *circuitBreakerBase) BoundRule() *Rule {
@@ -195,10 +206,11 @@ func (b *circuitBreakerBase) fromOpenToHalfOpen(ctx *base.EntryContext) bool {
 			logging.Error(errors.New("nil entry"), "Nil entry in circuitBreakerBase.fromOpenToHalfOpen()", "rule", b.rule)
 		} else {
 			// add hook for entry exit
-			// if the current circuit breaker performs the probe through this entry, but the entry was blocked,
+			// if the current circuit breaker performs the probe through this entry, but the entry was blocked
+			// or only reserved the tokens without completing the probe,
 			// this hook will guarantee current circuit breaker state machine will rollback to Open from Half-Open
 			entry.WhenExit(func(entry *base.SentinelEntry, ctx *base.EntryContext) error {
-				if ctx.IsBlocked() && b.state.cas(HalfOpen, Open) {
+				if (ctx.IsBlocked() || ctx.IsReservation()) && b.state.cas(HalfOpen, Open) {
 					for _, listener := range stateChangeListeners {
 						listener.OnTransformToOpen(HalfOpen, *b.rule, 1.0)
 					}
@@ -261,6 +273,7 @@ func newSlowRtCircuitBreakerWithStat(r *Rule, stat *slowRequestLeapArray) *slowR
 			nextRetryTimestampMs: 0,
 			state:                newState(),
 			probeNumber:          r.ProbeNum,
+			schedule:             newScheduleEvaluator(r),
 		},
 		stat:                stat,
 		maxAllowedRt:        r.MaxAllowedRtMs,
@@ -349,7 +362,8 @@ func (b *slowRtCircuitBreaker) OnRequestComplete(rt uint64, _ error) {
 		return
 	}
 
-	if slowRatio > b.maxSlowRequestRatio || util.Float64Equals(slowRatio, b.maxSlowRequestRatio) {
+	maxSlowRequestRatio := b.schedule.Threshold(b.maxSlowRequestRatio)
+	if slowRatio > maxSlowRequestRatio || util.Float64Equals(slowRatio, maxSlowRequestRatio) {
 		curStatus = b.CurrentState()
 		switch curStatus {
 		case Closed:
@@ -453,6 +467,7 @@ func newErrorRatioCircuitBreakerWithStat(r *Rule, stat *errorCounterLeapArray) *
 			nextRetryTimestampMs: 0,
 			state:                newState(),
 			probeNumber:          r.ProbeNum,
+			schedule:             newScheduleEvaluator(r),
 		},
 		minRequestAmount:    r.MinRequestAmount,
 		errorRatioThreshold: r.Threshold,
@@ -535,7 +550,8 @@ func (b *errorRatioCircuitBreaker) OnRequestComplete(_ uint64, err error) {
 	if totalCount < b.minRequestAmount {
 		return
 	}
-	if errorRatio > b.errorRatioThreshold || util.Float64Equals(errorRatio, b.errorRatioThreshold) {
+	errorRatioThreshold := b.schedule.Threshold(b.errorRatioThreshold)
+	if errorRatio > errorRatioThreshold || util.Float64Equals(errorRatio, errorRatioThreshold) {
 		curStatus = b.CurrentState()
 		switch curStatus {
 		case Closed:
@@ -638,6 +654,7 @@ func newErrorCountCircuitBreakerWithStat(r *Rule, stat *errorCounterLeapArray) *
 			nextRetryTimestampMs: 0,
 			state:                newState(),
 			probeNumber:          r.ProbeNum,
+			schedule:             newScheduleEvaluator(r),
 		},
 		minRequestAmount:    r.MinRequestAmount,
 		errorCountThreshold: uint64(r.Threshold),
@@ -717,7 +734,8 @@ func (b *errorCountCircuitBreaker) OnRequestComplete(_ uint64, err error) {
 	if totalCount < b.minRequestAmount {
 		return
 	}
-	if errorCount >= b.errorCountThreshold {
+	errorCountThreshold := uint64(b.schedule.Threshold(float64(b.errorCountThreshold)))
+	if errorCount >= errorCountThreshold {
 		curStatus = b.CurrentState()
 		switch curStatus {
 		case Closed:
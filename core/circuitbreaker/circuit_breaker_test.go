@@ -18,8 +18,10 @@ import (
 	"errors"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/schedule"
 	sbase "github.com/alibaba/sentinel-golang/core/stat/base"
 	"github.com/alibaba/sentinel-golang/logging"
 	"github.com/alibaba/sentinel-golang/util"
@@ -464,6 +466,42 @@ func TestErrorCount_OnRequestComplete(t *testing.T) {
 	})
 }
 
+func TestErrorCount_Schedule(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	begin := util.Now().Add(2 * time.Second)
+	r := &Rule{
+		Resource:         "abc",
+		Strategy:         ErrorCount,
+		RetryTimeoutMs:   3000,
+		MinRequestAmount: 1,
+		StatIntervalMs:   1000,
+		Threshold:        1.0,
+		Schedule: &schedule.Schedule{
+			Windows: []schedule.Window{
+				{Begin: begin.Format(time.RFC3339), End: begin.Add(time.Minute).Format(time.RFC3339), Threshold: 3},
+			},
+		},
+	}
+	b, err := newErrorCountCircuitBreaker(r)
+	assert.Nil(t, err)
+
+	util.Sleep(3 * time.Second)
+	for i := 0; i < 2; i++ {
+		b.OnRequestComplete(0, errors.New("errorCount"))
+		assert.True(t, b.CurrentState() == Closed)
+	}
+	b.OnRequestComplete(0, errors.New("errorCount"))
+	assert.True(t, b.CurrentState() == Open)
+
+	util.Sleep(time.Minute)
+	b.state.set(Closed)
+	b.resetMetric()
+	b.OnRequestComplete(0, errors.New("errorCount"))
+	assert.True(t, b.CurrentState() == Open)
+}
+
 func TestFromClosedToOpen(t *testing.T) {
 	ClearStateChangeListeners()
 	stateChangeListenerMock := &StateChangeListenerMock{}
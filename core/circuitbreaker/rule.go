@@ -16,7 +16,11 @@ package circuitbreaker
 
 import (
 	"fmt"
+	"reflect"
 
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
+	"github.com/alibaba/sentinel-golang/core/schedule"
 	"github.com/alibaba/sentinel-golang/util"
 )
 
@@ -51,8 +55,11 @@ type Rule struct {
 	// unique id
 	Id string `json:"id,omitempty"`
 	// resource name
-	Resource string   `json:"resource"`
-	Strategy Strategy `json:"strategy"`
+	Resource string `json:"resource"`
+	// MatchStrategy indicates how Resource matches the resource names. For the strategies other than ExactMatch,
+	// Resource is the pattern of the names and each matched resource has its own circuit breaker.
+	MatchStrategy base.ResourceMatchStrategy `json:"matchStrategy,omitempty"`
+	Strategy      Strategy                   `json:"strategy"`
 	// RetryTimeoutMs represents recovery timeout (in milliseconds) before the circuit breaker opens.
 	// During the open period, no requests are permitted until the timeout has elapsed.
 	// After that, the circuit breaker will transform to half-open state for trying a few "trial" requests.
@@ -83,6 +90,28 @@ type Rule struct {
 	// if err occurs during the probe, the circuit breaker is opened immediately.
 	// otherwise,the circuit breaker is closed only after the number of probes is reached
 	ProbeNum uint64 `json:"probeNum"`
+	// Schedule overrides Threshold in the scheduled time windows.
+	Schedule *schedule.Schedule `json:"schedule,omitempty"`
+	// Mode indicates whether the rule blocks the requests (Enforce) or only records the would-be blocked ones (Shadow).
+	Mode base.RuleMode `json:"mode,omitempty"`
+	// EnforcePercent is the percentage of traffic the rule is enforced on, the valid range is [0.0, 100.0] and 0 means all the traffic.
+	// The rest of the traffic is checked in the Shadow mode.
+	EnforcePercent float64 `json:"enforcePercent,omitempty"`
+	// EnforceKey is the attachment key whose value selects the enforced traffic by hash, the traffic is selected randomly if empty.
+	EnforceKey string `json:"enforceKey,omitempty"`
+	// Canary selects the instances the rule is enforced on, nil means all the instances.
+	Canary *base.CanarySelector `json:"canary,omitempty"`
+	// Condition is the optional expression the rule only takes effect when it holds, e.g. `attachments.caller != "internal"`,
+	// see package condition for the syntax.
+	Condition string `json:"condition,omitempty"`
+	// conditionProgram is the compiled Condition, which is compiled when the rule is loaded.
+	conditionProgram *condition.Program
+	// ExpireAt is the Unix timestamp in milliseconds when the rule expires and is dropped automatically, 0 means never.
+	ExpireAt uint64 `json:"expireAt,omitempty"`
+	// TTLSec is the lifetime in seconds of the rule since it's loaded, 0 means forever.
+	// If both ExpireAt and TTLSec are set, the earlier one takes effect.
+	// A rule expired by TTLSec comes back with a new lifetime if it's loaded again, so prefer ExpireAt for rules from data sources.
+	TTLSec uint32 `json:"ttlSec,omitempty"`
 }
 
 func (r *Rule) String() string {
@@ -95,7 +124,7 @@ func (r *Rule) isStatReusable(newRule *Rule) bool {
 	if newRule == nil {
 		return false
 	}
-	return r.Resource == newRule.Resource && r.Strategy == newRule.Strategy && r.StatIntervalMs == newRule.StatIntervalMs &&
+	return r.Resource == newRule.Resource && r.MatchStrategy == newRule.MatchStrategy && r.Strategy == newRule.Strategy && r.StatIntervalMs == newRule.StatIntervalMs &&
 		r.StatSlidingWindowBucketCount == newRule.StatSlidingWindowBucketCount
 }
 
@@ -103,14 +132,39 @@ func (r *Rule) ResourceName() string {
 	return r.Resource
 }
 
+// Lifetime returns the ExpireAt and TTLSec of the rule.
+func (r *Rule) Lifetime() (uint64, uint32) {
+	return r.ExpireAt, r.TTLSec
+}
+
+// isEnforcedOn reports whether the rule is enforced on the entry, otherwise the rule is checked in the Shadow mode.
+func (r *Rule) isEnforcedOn(ctx *base.EntryContext) bool {
+	return base.IsEnforcedOn(ctx, r.Mode, r.EnforcePercent, r.EnforceKey, r.Canary)
+}
+
+// conditionHolds reports whether the Condition of the rule holds on the entry.
+func (r *Rule) conditionHolds(ctx *base.EntryContext) bool {
+	return r.conditionProgram.Holds(ctx)
+}
+
+func (r *Rule) setConditionProgram(program *condition.Program) {
+	r.conditionProgram = program
+}
+
+func (r *Rule) setCanary(canary *base.CanarySelector) {
+	r.Canary = canary
+}
+
 // Check whether the fields shared by all rule strategy types are consistent
 func (r *Rule) isEqualsToBase(newRule *Rule) bool {
 	if newRule == nil {
 		return false
 	}
-	return r.Resource == newRule.Resource && r.Strategy == newRule.Strategy && r.RetryTimeoutMs == newRule.RetryTimeoutMs &&
+	return r.Resource == newRule.Resource && r.MatchStrategy == newRule.MatchStrategy && r.Strategy == newRule.Strategy && r.RetryTimeoutMs == newRule.RetryTimeoutMs &&
 		r.MinRequestAmount == newRule.MinRequestAmount && r.StatIntervalMs == newRule.StatIntervalMs && r.StatSlidingWindowBucketCount == newRule.StatSlidingWindowBucketCount &&
-		r.ProbeNum == newRule.ProbeNum
+		r.ProbeNum == newRule.ProbeNum && reflect.DeepEqual(r.Schedule, newRule.Schedule) && r.Mode == newRule.Mode &&
+		util.Float64Equals(r.EnforcePercent, newRule.EnforcePercent) && r.EnforceKey == newRule.EnforceKey &&
+		r.Canary.Equals(newRule.Canary) && r.Condition == newRule.Condition
 }
 
 func (r *Rule) isEqualsTo(newRule *Rule) bool {
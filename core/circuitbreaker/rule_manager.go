@@ -21,6 +21,8 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
 	"github.com/alibaba/sentinel-golang/logging"
 	"github.com/alibaba/sentinel-golang/util"
 )
@@ -30,16 +32,31 @@ type CircuitBreakerGenFunc func(r *Rule, reuseStat interface{}) (CircuitBreaker,
 var (
 	cbGenFuncMap = make(map[Strategy]CircuitBreakerGenFunc, 4)
 
-	breakerRules  = make(map[string][]*Rule)
-	breakers      = make(map[string][]CircuitBreaker)
-	updateMux     = new(sync.RWMutex)
-	currentRules  = make(map[string][]*Rule, 0)
-	updateRuleMux = new(sync.Mutex)
+	breakerRules = make(map[string][]*Rule)
+	breakers     = make(map[string][]CircuitBreaker)
+	// patternBreakers holds the circuit breakers of the rules matching resources by pattern, which are keyed by the pattern
+	// and only used to generate the circuit breakers of each matched resource cached in patternBreakerCache.
+	patternBreakers     = make(map[string][]CircuitBreaker)
+	patternBreakerCache = make(map[string][]CircuitBreaker)
+	breakerMatcher      *base.ResourceMatcher
+	updateMux           = new(sync.RWMutex)
+	currentRules        = make(map[string][]*Rule, 0)
+	updateRuleMux       = new(sync.Mutex)
+	ruleExpiration      *base.RuleExpirationManager[Rule, *Rule]
 
 	stateChangeListeners = make([]StateChangeListener, 0)
 )
 
 func init() {
+	ruleExpiration = base.NewRuleExpirationManager("[CircuitBreaker]", updateRuleMux,
+		func() map[string][]*Rule {
+			return currentRules
+		},
+		onRuleUpdate,
+		func(rule *Rule, expireAt uint64, ttlSec uint32) {
+			rule.ExpireAt, rule.TTLSec = expireAt, ttlSec
+		})
+
 	cbGenFuncMap[SlowRequestRatio] = func(r *Rule, reuseStat interface{}) (CircuitBreaker, error) {
 		if r == nil {
 			return nil, errors.New("nil rule")
@@ -88,6 +105,7 @@ func init() {
 
 // GetRulesOfResource returns specific resource's rules based on copy.
 // It doesn't take effect for circuit breaker module if user changes the rule.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
 // GetRulesOfResource need to compete circuit breaker module's global lock and the high performance losses of copy,
 //
 //	reduce or do not call GetRulesOfResource frequently if possible
@@ -98,15 +116,17 @@ func GetRulesOfResource(resource string) []Rule {
 	if !ok {
 		return nil
 	}
+	now := util.CurrentTimeMillis()
 	ret := make([]Rule, 0, len(resRules))
 	for _, rule := range resRules {
-		ret = append(ret, *rule)
+		ret = append(ret, ruleExpiration.CopyWithLifetime(rule, now))
 	}
 	return ret
 }
 
 // GetRules returns all the rules based on copy.
 // It doesn't take effect for circuit breaker module if user changes the rule.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
 // GetRules need to compete circuit breaker module's global lock and the high performance losses of copy,
 //
 //	reduce or do not call GetRules if possible
@@ -114,9 +134,10 @@ func GetRules() []Rule {
 	updateMux.RLock()
 	rules := rulesFrom(breakerRules)
 	updateMux.RUnlock()
+	now := util.CurrentTimeMillis()
 	ret := make([]Rule, 0, len(rules))
 	for _, rule := range rules {
-		ret = append(ret, *rule)
+		ret = append(ret, ruleExpiration.CopyWithLifetime(rule, now))
 	}
 	return ret
 }
@@ -152,6 +173,7 @@ func LoadRules(rules []*Rule) (bool, error) {
 	}
 
 	err := onRuleUpdate(resRulesMap)
+	ruleExpiration.Track()
 	return true, err
 }
 
@@ -171,7 +193,12 @@ func LoadRulesOfResource(res string, rules []*Rule) (bool, error) {
 		updateMux.Lock()
 		delete(breakers, res)
 		delete(breakerRules, res)
+		if _, ok := patternBreakers[res]; ok {
+			delete(patternBreakers, res)
+			resetPatternBreakers()
+		}
 		updateMux.Unlock()
+		ruleExpiration.Track()
 		logging.Info("[CircuitBreaker] clear resource level rules", "resource", res)
 		return true, nil
 	}
@@ -182,21 +209,88 @@ func LoadRulesOfResource(res string, rules []*Rule) (bool, error) {
 		return false, nil
 	}
 	err := onResourceRuleUpdate(res, rules)
+	ruleExpiration.Track()
 	return true, err
 }
 
 func getBreakersOfResource(resource string) []CircuitBreaker {
 	updateMux.RLock()
 	resCBs := breakers[resource]
+	patternCBs, cached := patternBreakerCache[resource]
+	noPattern := breakerMatcher.IsEmpty()
 	updateMux.RUnlock()
-	ret := make([]CircuitBreaker, 0, len(resCBs))
-	if len(resCBs) == 0 {
+	if !cached && !noPattern {
+		updateMux.Lock()
+		// double check
+		if patternCBs, cached = patternBreakerCache[resource]; !cached {
+			patternCBs = patternBreakersOf(resource)
+			patternBreakerCache[resource] = patternCBs
+		}
+		updateMux.Unlock()
+	}
+	ret := make([]CircuitBreaker, 0, len(resCBs)+len(patternCBs))
+	if len(resCBs)+len(patternCBs) == 0 {
 		return ret
 	}
 	ret = append(ret, resCBs...)
+	ret = append(ret, patternCBs...)
+	return ret
+}
+
+// patternBreakersOf generates the circuit breakers of the rules whose patterns match the resource,
+// it must be called with updateMux locked.
+func patternBreakersOf(resource string) []CircuitBreaker {
+	ret := make([]CircuitBreaker, 0)
+	for _, pattern := range breakerMatcher.Match(resource) {
+		for _, cb := range patternBreakers[pattern.Pattern] {
+			r := cb.BoundRule()
+			if r.MatchStrategy != pattern.Strategy {
+				continue
+			}
+			generator := cbGenFuncMap[r.Strategy]
+			if generator == nil {
+				continue
+			}
+			newCb, e := generator(r, nil)
+			if newCb == nil || e != nil {
+				logging.Warn("[CircuitBreaker patternBreakersOf] Ignoring the rule due to bad generated circuit breaker", "rule", r, "resource", resource, "err", e)
+				continue
+			}
+			ret = append(ret, newCb)
+		}
+	}
 	return ret
 }
 
+// splitPatternBreakers splits the circuit breakers of the rules matching resources by pattern from the others.
+func splitPatternBreakers(cbs []CircuitBreaker) (exactCbs, patternCbs []CircuitBreaker) {
+	for _, cb := range cbs {
+		if cb.BoundRule().MatchStrategy == base.ExactMatch {
+			exactCbs = append(exactCbs, cb)
+		} else {
+			patternCbs = append(patternCbs, cb)
+		}
+	}
+	return exactCbs, patternCbs
+}
+
+// resetPatternBreakers rebuilds the matcher of patternBreakers and drops the cached circuit breakers,
+// it must be called with updateMux locked.
+func resetPatternBreakers() {
+	patterns := make([]base.ResourcePattern, 0, len(patternBreakers))
+	for res, cbs := range patternBreakers {
+		for _, cb := range cbs {
+			patterns = append(patterns, base.ResourcePattern{Strategy: cb.BoundRule().MatchStrategy, Pattern: res})
+		}
+	}
+	matcher, err := base.NewResourceMatcher(patterns)
+	if err != nil {
+		logging.Error(err, "Fail to build the matcher of pattern rules in circuitbreaker.resetPatternBreakers()")
+	}
+	breakerMatcher = matcher
+	patternBreakerCache = make(map[string][]CircuitBreaker)
+}
+
 func calculateReuseIndexFor(r *Rule, oldResCbs []CircuitBreaker) (equalIdx, reuseStatIdx int) {
 	// the index of equivalent rule in old circuit breaker slice
 	equalIdx = -1
@@ -243,6 +337,8 @@ func onRuleUpdate(rawResRulesMap map[string][]*Rule) (err error) {
 				logging.Warn("[CircuitBreaker onRuleUpdate] Ignoring invalid circuit breaking rule when loading new rules", "rule", rule, "err", err.Error())
 				continue
 			}
+			rule = condition.Prepare(rule, rule.Condition, (*Rule).setConditionProgram)
+			rule = base.ResolveCanary(rule, rule.Canary, (*Rule).setCanary)
 			validResRules = append(validResRules, rule)
 		}
 		if len(validResRules) > 0 {
@@ -259,19 +355,28 @@ func onRuleUpdate(rawResRulesMap map[string][]*Rule) (err error) {
 		resTcClone = append(resTcClone, tcs...)
 		breakersClone[res] = resTcClone
 	}
+	for res, tcs := range patternBreakers {
+		breakersClone[res] = append(breakersClone[res], tcs...)
+	}
 	updateMux.RUnlock()
 
 	newBreakers := make(map[string][]CircuitBreaker, len(validResRulesMap))
+	newPatternBreakers := make(map[string][]CircuitBreaker)
 	for res, resRules := range validResRulesMap {
-		newCbsOfRes := BuildResourceCircuitBreaker(res, resRules, breakersClone[res])
+		newCbsOfRes, newPatternCbsOfRes := splitPatternBreakers(BuildResourceCircuitBreaker(res, resRules, breakersClone[res]))
 		if len(newCbsOfRes) > 0 {
 			newBreakers[res] = newCbsOfRes
 		}
+		if len(newPatternCbsOfRes) > 0 {
+			newPatternBreakers[res] = newPatternCbsOfRes
+		}
 	}
 
 	updateMux.Lock()
 	breakerRules = validResRulesMap
 	breakers = newBreakers
+	patternBreakers = newPatternBreakers
+	resetPatternBreakers()
 	updateMux.Unlock()
 	currentRules = rawResRulesMap
 
@@ -297,6 +402,8 @@ func onResourceRuleUpdate(res string, rawResRules []*Rule) (err error) {
 			logging.Warn("[CircuitBreaker onResourceRuleUpdate] Ignoring invalid circuitBreaker rule", "rule", rule, "reason", err.Error())
 			continue
 		}
+		rule = condition.Prepare(rule, rule.Condition, (*Rule).setConditionProgram)
+		rule = base.ResolveCanary(rule, rule.Canary, (*Rule).setCanary)
 		validResRules = append(validResRules, rule)
 	}
 
@@ -304,17 +411,31 @@ func onResourceRuleUpdate(res string, rawResRules []*Rule) (err error) {
 	oldResCbs := make([]CircuitBreaker, 0)
 	updateMux.RLock()
 	oldResCbs = append(oldResCbs, breakers[res]...)
+	oldResCbs = append(oldResCbs, patternBreakers[res]...)
 	updateMux.RUnlock()
 
 	newCbsOfRes := BuildResourceCircuitBreaker(res, rawResRules, oldResCbs)
+	newExactCbsOfRes, newPatternCbsOfRes := splitPatternBreakers(newCbsOfRes)
 
 	updateMux.Lock()
 	if len(newCbsOfRes) == 0 {
 		delete(breakerRules, res)
-		delete(breakers, res)
 	} else {
 		breakerRules[res] = validResRules
-		breakers[res] = newCbsOfRes
+	}
+	if len(newExactCbsOfRes) == 0 {
+		delete(breakers, res)
+	} else {
+		breakers[res] = newExactCbsOfRes
+	}
+	_, hadPattern := patternBreakers[res]
+	if len(newPatternCbsOfRes) == 0 {
+		delete(patternBreakers, res)
+	} else {
+		patternBreakers[res] = newPatternCbsOfRes
+	}
+	if hadPattern || len(newPatternCbsOfRes) > 0 {
+		resetPatternBreakers()
 	}
 	updateMux.Unlock()
 	currentRules[res] = rawResRules
@@ -453,6 +574,26 @@ func IsValidRule(r *Rule) error {
 	if len(r.Resource) == 0 {
 		return errors.New("empty resource name")
 	}
+	if r.MatchStrategy != base.ExactMatch {
+		if err := (base.ResourcePattern{Strategy: r.MatchStrategy, Pattern: r.Resource}).IsValid(); err != nil {
+			return err
+		}
+	}
+	if r.Mode != base.Enforce && r.Mode != base.Shadow {
+		return errors.New("invalid mode")
+	}
+	if err := base.CheckEnforcePercent(r.EnforcePercent); err != nil {
+		return err
+	}
+	if err := r.Canary.IsValid(); err != nil {
+		return err
+	}
+	if err := condition.IsValid(r.Condition); err != nil {
+		return errors.Wrap(err, "invalid Condition")
+	}
+	if r.ExpireAt > 0 && r.ExpireAt <= util.CurrentTimeMillis() {
+		return errors.New("rule already expired")
+	}
 	if r.StatIntervalMs <= 0 {
 		return errors.New("invalid StatIntervalMs")
 	}
@@ -462,10 +603,13 @@ func IsValidRule(r *Rule) error {
 	if r.Threshold < 0.0 {
 		return errors.New("invalid Threshold")
 	}
-	if r.Strategy == SlowRequestRatio && r.Threshold > 1.0 {
+	if err := r.Schedule.IsValid(); err != nil {
+		return errors.Wrap(err, "invalid schedule")
+	}
+	if r.Strategy == SlowRequestRatio && r.Schedule.MaxThreshold(r.Threshold) > 1.0 {
 		return errors.New("invalid slow request ratio threshold (valid range: [0.0, 1.0])")
 	}
-	if r.Strategy == ErrorRatio && r.Threshold > 1.0 {
+	if r.Strategy == ErrorRatio && r.Schedule.MaxThreshold(r.Threshold) > 1.0 {
 		return errors.New("invalid error ratio threshold (valid range: [0.0, 1.0])")
 	}
 	if r.StatSlidingWindowBucketCount != 0 && r.StatIntervalMs%r.StatSlidingWindowBucketCount != 0 {
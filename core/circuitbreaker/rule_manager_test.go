@@ -18,12 +18,15 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/alibaba/sentinel-golang/core/base"
 	"github.com/stretchr/testify/assert"
 )
 
 func clearData() {
 	breakerRules = make(map[string][]*Rule)
 	breakers = make(map[string][]CircuitBreaker)
+	patternBreakers = make(map[string][]CircuitBreaker)
+	resetPatternBreakers()
 	currentRules = make(map[string][]*Rule, 0)
 }
 
@@ -535,3 +538,64 @@ func TestClearRulesOfResource(t *testing.T) {
 		clearData()
 	})
 }
+
+func TestPatternRules(t *testing.T) {
+	r1 := &Rule{
+		Resource:         "/api/users/",
+		MatchStrategy:    base.PrefixMatch,
+		Strategy:         ErrorCount,
+		RetryTimeoutMs:   1000,
+		MinRequestAmount: 1,
+		StatIntervalMs:   1000,
+		Threshold:        1.0,
+	}
+	r2 := &Rule{
+		Resource:         "/api/*/orders",
+		MatchStrategy:    base.GlobMatch,
+		Strategy:         ErrorCount,
+		RetryTimeoutMs:   1000,
+		MinRequestAmount: 1,
+		StatIntervalMs:   1000,
+		Threshold:        1.0,
+	}
+	r3 := &Rule{
+		Resource:         "/api/users/1",
+		Strategy:         SlowRequestRatio,
+		RetryTimeoutMs:   1000,
+		MinRequestAmount: 1,
+		StatIntervalMs:   1000,
+		MaxAllowedRtMs:   20,
+		Threshold:        0.1,
+	}
+	_, err := LoadRules([]*Rule{r1, r2, r3})
+	assert.Nil(t, err)
+	defer clearData()
+
+	cbs1 := getBreakersOfResource("/api/users/1")
+	assert.Equal(t, 2, len(cbs1))
+	assert.True(t, cbs1[0].BoundRule() == r3 && cbs1[1].BoundRule() == r1)
+	// each matched resource has its own circuit breaker
+	cbs2 := getBreakersOfResource("/api/users/2")
+	assert.True(t, len(cbs2) == 1 && cbs2[0].BoundRule() == r1 && cbs2[0] != cbs1[1])
+	assert.True(t, getBreakersOfResource("/api/users/2")[0] == cbs2[0])
+	cbs3 := getBreakersOfResource("/api/v1/orders")
+	assert.True(t, len(cbs3) == 1 && cbs3[0].BoundRule() == r2)
+	assert.Empty(t, getBreakersOfResource("/api/v1/orders/1"))
+	// the pattern of rule is not looked up as the exact resource name
+	assert.Empty(t, getBreakersOfResource("/api/*/orders/"))
+	assert.Equal(t, 3, len(GetRules()))
+
+	// the cached circuit breakers are dropped on rule update
+	_, err = LoadRulesOfResource("/api/users/", nil)
+	assert.Nil(t, err)
+	assert.True(t, len(getBreakersOfResource("/api/users/2")) == 0)
+	assert.True(t, len(getBreakersOfResource("/api/users/1")) == 1)
+
+	assert.Error(t, IsValidRule(&Rule{
+		Resource:       "/api/(",
+		MatchStrategy:  base.RegexMatch,
+		Strategy:       ErrorCount,
+		RetryTimeoutMs: 1000,
+		StatIntervalMs: 1000,
+	}))
+}
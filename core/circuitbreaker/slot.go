@@ -53,10 +53,19 @@ func (b *Slot) Check(ctx *base.EntryContext) *base.TokenResult {
 func checkPass(ctx *base.EntryContext) (bool, *Rule) {
 	breakers := getBreakersOfResource(ctx.Resource.Name())
 	for _, breaker := range breakers {
+		if !breaker.BoundRule().conditionHolds(ctx) {
+			continue
+		}
 		passed := breaker.TryPass(ctx)
-		if !passed {
-			return false, breaker.BoundRule()
+		if passed {
+			continue
+		}
+		if rule := breaker.BoundRule(); !rule.isEnforcedOn(ctx) {
+			// Shadow rules only record the would-be blocked requests.
+			ctx.AddShadowBlock(base.NewBlockErrorWithCause(base.BlockTypeCircuitBreaking, "circuit breaker check blocked", rule, nil))
+			continue
 		}
+		return false, breaker.BoundRule()
 	}
 	return true, nil
 }
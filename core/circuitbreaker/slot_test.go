@@ -95,6 +95,7 @@ func Test_Check(t *testing.T) {
 		e := SetCircuitBreakerGenerator(100, func(r *Rule, reuseStat interface{}) (CircuitBreaker, error) {
 			circuitBreakerMock := &CircuitBreakerMock{}
 			circuitBreakerMock.On("TryPass", mock.Anything).Return(true)
+			circuitBreakerMock.On("BoundRule", mock.Anything).Return(r)
 			return circuitBreakerMock, nil
 		})
 		assert.True(t, e == nil)
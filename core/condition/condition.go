@@ -0,0 +1,104 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package condition
+
+import (
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/pkg/errors"
+)
+
+// Program is the compiled condition expression, which is safe for concurrent use.
+type Program struct {
+	expr string
+	root node
+}
+
+func (p *Program) String() string {
+	if p == nil {
+		return ""
+	}
+	return p.expr
+}
+
+// Eval evaluates the condition on the entry, it returns error if the expression doesn't yield a boolean
+// or fails to evaluate (e.g. comparing a string with a number).
+func (p *Program) Eval(ctx *base.EntryContext) (bool, error) {
+	if ctx == nil {
+		ctx = &base.EntryContext{}
+	}
+	v, err := p.root.eval(&env{ctx: ctx})
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, errors.Errorf("condition yields %s rather than bool", typeName(v))
+	}
+	return b, nil
+}
+
+// Compile compiles the expression, nil is returned for the empty expression which always holds.
+// Rule managers compile the conditions of rules when the rules are loaded, and evaluate the compiled programs on entries.
+func Compile(expr string) (*Program, error) {
+	if len(expr) == 0 {
+		return nil, nil
+	}
+	root, err := parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{expr: expr, root: root}, nil
+}
+
+// Holds reports whether the condition holds on the entry, the nil program always holds.
+// The condition doesn't hold if it fails to evaluate, so that the rule doesn't take effect.
+func (p *Program) Holds(ctx *base.EntryContext) bool {
+	if p == nil {
+		return true
+	}
+	ok, err := p.Eval(ctx)
+	if err != nil {
+		logging.FrequentErrorOnce.Do(func() {
+			logging.Error(err, "Fail to evaluate the condition in Program.Holds()", "condition", p.expr)
+		})
+		return false
+	}
+	return ok
+}
+
+// Prepare returns the rule to be held by the rule manager, to which the program compiled from its condition expr
+// is bound by setProgram. The program is bound to a copy of the rule rather than the rule itself, since the rule
+// passed to the rule manager may be loaded already and read by the slots. The rule without condition is returned as-is.
+// The rule must have been validated (see IsValid), it's returned as-is if the condition fails to compile.
+func Prepare[R any](rule *R, expr string, setProgram func(rule *R, program *Program)) *R {
+	if len(expr) == 0 {
+		return rule
+	}
+	program, err := Compile(expr)
+	if err != nil {
+		logging.Error(err, "Fail to compile the condition of the validated rule in condition.Prepare()", "condition", expr)
+		return rule
+	}
+	ret := *rule
+	setProgram(&ret, program)
+	return &ret
+}
+
+// IsValid checks whether the expression compiles, the empty expression is valid.
+func IsValid(expr string) error {
+	_, err := Compile(expr)
+	return err
+}
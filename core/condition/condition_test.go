@@ -0,0 +1,144 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package condition
+
+import (
+	"testing"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/system_metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func newContext(resType base.ResourceType, trafficType base.TrafficType, attachments map[interface{}]interface{}, args ...interface{}) *base.EntryContext {
+	return &base.EntryContext{
+		Resource: base.NewResourceWrapper("/orders", resType, trafficType),
+		Input: &base.SentinelInput{
+			BatchCount:  2,
+			Args:        args,
+			Attachments: attachments,
+		},
+	}
+}
+
+func TestProgram_Eval(t *testing.T) {
+	ctx := newContext(base.ResTypeDBSQL, base.Outbound, map[interface{}]interface{}{"caller": "web", "plan": "free", "weight": 3}, "uid-1", 42)
+	system_metric.SetSystemMemoryUsage(3 << 30)
+	defer system_metric.SetSystemMemoryUsage(0)
+
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{`attachments.caller != "internal"`, true},
+		{`attachments["caller"] == 'web'`, true},
+		{`attachments.absent == null && !has(attachments.absent)`, true},
+		{`resource.trafficType == "Outbound" && resource.type == "DBSQL"`, true},
+		{`resource.name == "/orders" || resource.name == "/users"`, true},
+		{`system.memoryUsage > 2GB`, true},
+		{`system.memoryUsage > 4GB`, false},
+		{`attachments.weight * batchCount >= 6`, true},
+		{`attachments.plan in ["free", "trial"]`, true},
+		{`args[0] == "uid-1" && args[1] == 42 && args[2] == null`, true},
+		{`startsWith(resource.name, "/ord") && endsWith(args[0], "-1") && contains(["a", "b"], "b")`, true},
+		{`matches(attachments.caller, "^w.b$") && size(attachments.caller) == 3`, true},
+		{`startsWith(attachments.absent, "x")`, false},
+		{`!(1 + 2 * 3 == 7) || 7 % 4 == 3`, true},
+		{`-1 < 0 && "a" < "b"`, true},
+	}
+	for _, c := range cases {
+		p, err := Compile(c.expr)
+		if !assert.NoError(t, err, c.expr) {
+			continue
+		}
+		got, err := p.Eval(ctx)
+		assert.NoError(t, err, c.expr)
+		assert.Equal(t, c.want, got, c.expr)
+	}
+}
+
+func TestProgram_EvalError(t *testing.T) {
+	ctx := newContext(base.ResTypeWeb, base.Inbound, map[interface{}]interface{}{"caller": "web"})
+	for _, expr := range []string{`attachments.caller > 1`, `1 + 1`, `attachments.caller && true`, `1 / 0 > 0`} {
+		p, err := Compile(expr)
+		assert.NoError(t, err, expr)
+		_, err = p.Eval(ctx)
+		assert.Error(t, err, expr)
+		assert.False(t, p.Holds(ctx), expr)
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		`attachments.caller ==`,
+		`resource.nmae == "a"`,
+		`system.memory > 1`,
+		`unknown == 1`,
+		`has(1, 2)`,
+		`matches(attachments.caller, attachments.pattern)`,
+		`matches(attachments.caller, "(")`,
+		`"unterminated`,
+		`1 == 1 )`,
+		`attachments.caller # 1`,
+		`12abc > 1`,
+	} {
+		_, err := Compile(expr)
+		assert.Error(t, err, expr)
+		assert.Error(t, IsValid(expr), expr)
+	}
+
+	deep := ""
+	for i := 0; i < 100; i++ {
+		deep += "("
+	}
+	_, err := Compile(deep + "true" + deep)
+	assert.Error(t, err)
+}
+
+func TestProgram_Holds(t *testing.T) {
+	ctx := newContext(base.ResTypeWeb, base.Inbound, map[interface{}]interface{}{"caller": "internal"})
+	empty, err := Compile("")
+	assert.NoError(t, err)
+	assert.Nil(t, empty)
+	assert.True(t, empty.Holds(ctx))
+	assert.Equal(t, "", empty.String())
+
+	p, err := Compile(`attachments.caller != "internal"`)
+	assert.NoError(t, err)
+	assert.False(t, p.Holds(ctx))
+	p, err = Compile(`attachments.caller == null`)
+	assert.NoError(t, err)
+	assert.True(t, p.Holds(nil))
+}
+
+func TestPrepare(t *testing.T) {
+	type rule struct {
+		Condition string
+		program   *Program
+	}
+	setProgram := func(r *rule, p *Program) { r.program = p }
+
+	plain := &rule{}
+	assert.True(t, plain == Prepare(plain, plain.Condition, setProgram))
+
+	r := &rule{Condition: `attachments.caller != "internal"`}
+	prepared := Prepare(r, r.Condition, setProgram)
+	assert.True(t, r != prepared)
+	assert.Nil(t, r.program, "the given rule shouldn't be modified")
+	assert.Equal(t, r.Condition, prepared.program.String())
+
+	invalid := &rule{Condition: `attachments.caller !=`}
+	assert.True(t, invalid == Prepare(invalid, invalid.Condition, setProgram))
+}
@@ -0,0 +1,47 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package condition provides the small expression language of the rule conditions,
+// which decide whether a rule takes effect on the entry.
+//
+// The expressions are side-effect free and evaluated in a sandbox: there are no loops, no assignments
+// and no access to anything other than the variables below. A condition must yield a boolean.
+//
+// Variables:
+//
+//   - resource.name: the name of resource.
+//   - resource.type: the classification of resource, e.g. "Web", "RPC", "DBSQL", "Cache", "MQ".
+//   - resource.trafficType: "Inbound" or "Outbound".
+//   - attachments.key or attachments["key"]: the entry attachment of key, null if absent.
+//   - args[i]: the i-th entry argument, null if absent.
+//   - batchCount: the batch count of entry.
+//   - system.load, system.cpuUsage (ratio in [0.0, 1.0]), system.memoryUsage (bytes), system.goroutines, system.heapLiveBytes,
+//     system.gcPauseP99 and system.schedLatencyP99 (ms): the system metrics.
+//
+// Literals are numbers (with optional KB, MB, GB or TB suffix, e.g. 2GB), strings in single or double quotes,
+// true, false, null and lists like ["a", "b"].
+//
+// Operators, in the order of precedence from low to high:
+//
+//	||  &&  == != < <= > >= in  + -  * / %  ! -(unary)
+//
+// Functions: has(x) (x is not null), size(x), startsWith(s, prefix), endsWith(s, suffix),
+// contains(s, substr) and matches(s, regex).
+//
+// Examples:
+//
+//	attachments.caller != "internal"
+//	resource.trafficType == "Outbound" && resource.type == "DBSQL"
+//	system.memoryUsage > 2GB
+package condition
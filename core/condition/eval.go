@@ -0,0 +1,530 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package condition
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/system_metric"
+	"github.com/pkg/errors"
+)
+
+// The values of expressions are one of nil, bool, float64, string, []interface{} and object.
+
+// object is the value with members, i.e. the variables like resource and system.
+type object interface {
+	member(env *env, name string) (interface{}, error)
+}
+
+// env is the environment which the expressions are evaluated in.
+type env struct {
+	ctx *base.EntryContext
+}
+
+var (
+	// rootObjects are the objects accessible by name.
+	rootObjects = map[string]object{
+		"resource":    resourceObject{},
+		"attachments": attachmentsObject{},
+		"args":        argsObject{},
+		"system":      systemObject{},
+	}
+	// knownMembers are the members of the objects which are checked on compiling.
+	knownMembers = map[string]map[string]struct{}{
+		"resource": {"name": {}, "type": {}, "trafficType": {}},
+		"system": {"load": {}, "cpuUsage": {}, "memoryUsage": {}, "goroutines": {}, "heapLiveBytes": {},
+			"gcPauseP99": {}, "schedLatencyP99": {}},
+	}
+)
+
+type resourceObject struct{}
+
+func (resourceObject) member(env *env, name string) (interface{}, error) {
+	res := env.ctx.Resource
+	if res == nil {
+		return nil, nil
+	}
+	switch name {
+	case "name":
+		return res.Name(), nil
+	case "type":
+		return res.Classification().String(), nil
+	case "trafficType":
+		return res.FlowType().String(), nil
+	default:
+		return nil, errors.Errorf("unknown member of resource: %s", name)
+	}
+}
+
+type attachmentsObject struct{}
+
+func (attachmentsObject) member(env *env, name string) (interface{}, error) {
+	if env.ctx.Input == nil {
+		return nil, nil
+	}
+	return normalize(env.ctx.Input.Attachments[name]), nil
+}
+
+type argsObject struct{}
+
+func (argsObject) member(_ *env, name string) (interface{}, error) {
+	return nil, errors.Errorf("args has no member: %s", name)
+}
+
+func (argsObject) index(env *env, i int) interface{} {
+	if env.ctx.Input == nil || i < 0 || i >= len(env.ctx.Input.Args) {
+		return nil
+	}
+	return normalize(env.ctx.Input.Args[i])
+}
+
+type systemObject struct{}
+
+func (systemObject) member(_ *env, name string) (interface{}, error) {
+	switch name {
+	case "load":
+		return system_metric.CurrentLoad(), nil
+	case "cpuUsage":
+		return system_metric.CurrentCpuUsage(), nil
+	case "memoryUsage":
+		return float64(system_metric.CurrentMemoryUsage()), nil
+	case "goroutines":
+		return system_metric.CurrentGoroutines(), nil
+	case "heapLiveBytes":
+		return system_metric.CurrentHeapLiveBytes(), nil
+	case "gcPauseP99":
+		return system_metric.CurrentGcPauseP99(), nil
+	case "schedLatencyP99":
+		return system_metric.CurrentSchedLatencyP99(), nil
+	default:
+		return nil, errors.Errorf("unknown member of system: %s", name)
+	}
+}
+
+// normalize converts the value from the entry to the value of expressions.
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, bool, string, float64:
+		return x
+	case int:
+		return float64(x)
+	case int8:
+		return float64(x)
+	case int16:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case uint:
+		return float64(x)
+	case uint8:
+		return float64(x)
+	case uint16:
+		return float64(x)
+	case uint32:
+		return float64(x)
+	case uint64:
+		return float64(x)
+	case float32:
+		return float64(x)
+	case fmt.Stringer:
+		return x.String()
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	default:
+		return "object"
+	}
+}
+
+func equals(a, b interface{}) bool {
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case float64:
+		y, ok := b.(float64)
+		return ok && x == y
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equals(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+type node interface {
+	eval(env *env) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(*env) (interface{}, error) {
+	return n.value, nil
+}
+
+type listNode struct {
+	elems []node
+}
+
+func (n *listNode) eval(env *env) (interface{}, error) {
+	list := make([]interface{}, 0, len(n.elems))
+	for _, e := range n.elems {
+		v, err := e.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+type rootNode struct {
+	name string
+}
+
+func (n *rootNode) eval(env *env) (interface{}, error) {
+	if n.name == "batchCount" {
+		if env.ctx.Input == nil {
+			return float64(0), nil
+		}
+		return float64(env.ctx.Input.BatchCount), nil
+	}
+	return rootObjects[n.name], nil
+}
+
+type memberNode struct {
+	x    node
+	name string
+}
+
+func (n *memberNode) eval(env *env) (interface{}, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(object)
+	if !ok {
+		return nil, errors.Errorf("%s has no member: %s", typeName(v), n.name)
+	}
+	return obj.member(env, n.name)
+}
+
+type indexNode struct {
+	x     node
+	index node
+}
+
+func (n *indexNode) eval(env *env) (interface{}, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch x := v.(type) {
+	case argsObject:
+		i, ok := idx.(float64)
+		if !ok {
+			return nil, errors.Errorf("invalid index of args: %s", typeName(idx))
+		}
+		return x.index(env, int(i)), nil
+	case object:
+		key, ok := idx.(string)
+		if !ok {
+			return nil, errors.Errorf("invalid key of object: %s", typeName(idx))
+		}
+		return x.member(env, key)
+	case []interface{}:
+		i, ok := idx.(float64)
+		if !ok {
+			return nil, errors.Errorf("invalid index of list: %s", typeName(idx))
+		}
+		if i < 0 || int(i) >= len(x) {
+			return nil, nil
+		}
+		return x[int(i)], nil
+	default:
+		return nil, errors.Errorf("%s is not indexable", typeName(v))
+	}
+}
+
+type unaryNode struct {
+	op string
+	x  node
+}
+
+func (n *unaryNode) eval(env *env) (interface{}, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, errors.Errorf("operator ! on %s", typeName(v))
+		}
+		return !b, nil
+	default:
+		f, ok := v.(float64)
+		if !ok {
+			return nil, errors.Errorf("operator - on %s", typeName(v))
+		}
+		return -f, nil
+	}
+}
+
+type logicalNode struct {
+	op   string
+	l, r node
+}
+
+func (n *logicalNode) eval(env *env) (interface{}, error) {
+	l, err := evalBool(env, n.l, n.op)
+	if err != nil {
+		return nil, err
+	}
+	// Short circuit.
+	if (n.op == "||") == l {
+		return l, nil
+	}
+	return evalBool(env, n.r, n.op)
+}
+
+func evalBool(env *env, n node, op string) (bool, error) {
+	v, err := n.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, errors.Errorf("operator %s on %s", op, typeName(v))
+	}
+	return b, nil
+}
+
+type binaryNode struct {
+	op   string
+	l, r node
+}
+
+func (n *binaryNode) eval(env *env) (interface{}, error) {
+	l, err := n.l.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.r.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equals(l, r), nil
+	case "!=":
+		return !equals(l, r), nil
+	case "in":
+		list, ok := r.([]interface{})
+		if !ok {
+			return nil, errors.Errorf("operator in on %s", typeName(r))
+		}
+		for _, e := range list {
+			if equals(l, e) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "<", "<=", ">", ">=":
+		return compare(n.op, l, r)
+	default:
+		return arithmetic(n.op, l, r)
+	}
+}
+
+func compare(op string, l, r interface{}) (interface{}, error) {
+	var c int
+	switch x := l.(type) {
+	case float64:
+		y, ok := r.(float64)
+		if !ok {
+			return nil, errors.Errorf("operator %s on number and %s", op, typeName(r))
+		}
+		if x < y {
+			c = -1
+		} else if x > y {
+			c = 1
+		}
+	case string:
+		y, ok := r.(string)
+		if !ok {
+			return nil, errors.Errorf("operator %s on string and %s", op, typeName(r))
+		}
+		c = strings.Compare(x, y)
+	default:
+		return nil, errors.Errorf("operator %s on %s", op, typeName(l))
+	}
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+func arithmetic(op string, l, r interface{}) (interface{}, error) {
+	if ls, ok := l.(string); ok && op == "+" {
+		if rs, ok := r.(string); ok {
+			return ls + rs, nil
+		}
+	}
+	x, ok1 := l.(float64)
+	y, ok2 := r.(float64)
+	if !ok1 || !ok2 {
+		return nil, errors.Errorf("operator %s on %s and %s", op, typeName(l), typeName(r))
+	}
+	switch op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return x / y, nil
+	default:
+		if y == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return math.Mod(x, y), nil
+	}
+}
+
+type callNode struct {
+	fn   string
+	args []node
+	// re is the compiled regex of matches.
+	re *regexp.Regexp
+}
+
+// functionArity is the count of arguments of the functions.
+var functionArity = map[string]int{
+	"has":        1,
+	"size":       1,
+	"startsWith": 2,
+	"endsWith":   2,
+	"contains":   2,
+	"matches":    2,
+}
+
+func (n *callNode) eval(env *env) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	switch n.fn {
+	case "has":
+		return args[0] != nil, nil
+	case "size":
+		switch x := args[0].(type) {
+		case nil:
+			return float64(0), nil
+		case string:
+			return float64(len(x)), nil
+		case []interface{}:
+			return float64(len(x)), nil
+		default:
+			return nil, errors.Errorf("size of %s", typeName(x))
+		}
+	case "contains":
+		if list, ok := args[0].([]interface{}); ok {
+			for _, e := range list {
+				if equals(e, args[1]) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+	}
+	if args[0] == nil {
+		// The absent attachments or args never match.
+		return false, nil
+	}
+	s, ok1 := args[0].(string)
+	if n.fn == "matches" {
+		if !ok1 {
+			return nil, errors.Errorf("%s on %s", n.fn, typeName(args[0]))
+		}
+		return n.re.MatchString(s), nil
+	}
+	sub, ok2 := args[1].(string)
+	if !ok1 || !ok2 {
+		return nil, errors.Errorf("%s on %s and %s", n.fn, typeName(args[0]), typeName(args[1]))
+	}
+	switch n.fn {
+	case "startsWith":
+		return strings.HasPrefix(s, sub), nil
+	case "endsWith":
+		return strings.HasSuffix(s, sub), nil
+	default:
+		return strings.Contains(s, sub), nil
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package condition
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxExpressionLength is the max length of expressions.
+	MaxExpressionLength = 4096
+	// maxDepth is the max nesting depth of expressions.
+	maxDepth = 64
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+func parse(expr string) (node, error) {
+	if len(expr) > MaxExpressionLength {
+		return nil, errors.Errorf("expression exceeds %d characters", MaxExpressionLength)
+	}
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errors.Errorf("unexpected token at %d", t.pos)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOperator(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator && !(t.kind == tokenIdent && t.text == "in") {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.isOperator(op); !ok {
+		return errors.Errorf("expect %s at %d", op, p.peek().pos)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return errors.Errorf("expression nests deeper than %d", maxDepth)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.isOperator("||"); !ok {
+			return l, nil
+		}
+		p.next()
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &logicalNode{op: "||", l: l, r: r}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.isOperator("&&"); !ok {
+			return l, nil
+		}
+		p.next()
+		r, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		l = &logicalNode{op: "&&", l: l, r: r}
+	}
+}
+
+func (p *parser) parseComparison() (node, error) {
+	l, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.isOperator("==", "!=", "<=", ">=", "<", ">", "in")
+	if !ok {
+		return l, nil
+	}
+	p.next()
+	r, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryNode{op: op, l: l, r: r}, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	l, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.isOperator("+", "-")
+		if !ok {
+			return l, nil
+		}
+		p.next()
+		r, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.isOperator("*", "/", "%")
+		if !ok {
+			return l, nil
+		}
+		p.next()
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	op, ok := p.isOperator("!", "-")
+	if !ok {
+		return p.parsePostfix()
+	}
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	p.next()
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &unaryNode{op: op, x: x}, nil
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.isOperator(".", "[")
+		if !ok {
+			return x, nil
+		}
+		p.next()
+		if op == "." {
+			t := p.next()
+			if t.kind != tokenIdent {
+				return nil, errors.Errorf("expect member name at %d", t.pos)
+			}
+			if root, ok := x.(*rootNode); ok {
+				if members, ok := knownMembers[root.name]; ok {
+					if _, ok := members[t.text]; !ok {
+						return nil, errors.Errorf("unknown member of %s: %s", root.name, t.text)
+					}
+				}
+				if root.name == "args" {
+					return nil, errors.Errorf("args has no member: %s", t.text)
+				}
+			}
+			x = &memberNode{x: x, name: t.text}
+			continue
+		}
+		index, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		x = &indexNode{x: x, index: index}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		return &literalNode{value: t.number}, nil
+	case tokenString:
+		return &literalNode{value: t.text}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		case "batchCount":
+			return &rootNode{name: t.text}, nil
+		}
+		if _, ok := rootObjects[t.text]; ok {
+			return &rootNode{name: t.text}, nil
+		}
+		if _, ok := functionArity[t.text]; ok {
+			return p.parseCall(t)
+		}
+		return nil, errors.Errorf("unknown identifier at %d: %s", t.pos, t.text)
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		case "[":
+			return p.parseList()
+		}
+	}
+	if t.kind == tokenEOF {
+		return nil, errors.New("unexpected end of expression")
+	}
+	return nil, errors.Errorf("unexpected token at %d", t.pos)
+}
+
+func (p *parser) parseList() (node, error) {
+	list := &listNode{}
+	if _, ok := p.isOperator("]"); ok {
+		p.next()
+		return list, nil
+	}
+	for {
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		list.elems = append(list.elems, e)
+		if _, ok := p.isOperator(","); ok {
+			p.next()
+			continue
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+}
+
+func (p *parser) parseCall(fn token) (node, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	call := &callNode{fn: fn.text}
+	if _, ok := p.isOperator(")"); !ok {
+		for {
+			a, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, a)
+			if _, ok := p.isOperator(","); !ok {
+				break
+			}
+			p.next()
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if len(call.args) != functionArity[fn.text] {
+		return nil, errors.Errorf("%s expects %d arguments but got %d", fn.text, functionArity[fn.text], len(call.args))
+	}
+	if fn.text == "matches" {
+		var pattern string
+		if lit, ok := call.args[1].(*literalNode); ok {
+			pattern, ok = lit.value.(string)
+			if !ok {
+				return nil, errors.New("the regex of matches must be a string literal")
+			}
+		} else {
+			return nil, errors.New("the regex of matches must be a string literal")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid regex of matches")
+		}
+		call.re = re
+	}
+	return call, nil
+}
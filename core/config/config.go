@@ -240,6 +240,10 @@ func MemoryStatCollectIntervalMs() uint32 {
 	return globalCfg.MemoryStatCollectIntervalMs()
 }
 
+func RuntimeStatCollectIntervalMs() uint32 {
+	return globalCfg.RuntimeStatCollectIntervalMs()
+}
+
 func UseCacheTime() bool {
 	return globalCfg.UseCacheTime()
 }
@@ -262,3 +266,15 @@ func MetricStatisticIntervalMs() uint32 {
 func MetricStatisticSampleCount() uint32 {
 	return globalCfg.MetricStatisticSampleCount()
 }
+
+func MaxResourceAmount() uint32 {
+	return globalCfg.MaxResourceAmount()
+}
+
+func ResourceIdleTimeoutMs() uint32 {
+	return globalCfg.ResourceIdleTimeoutMs()
+}
+
+func ShardedCounter() bool {
+	return globalCfg.ShardedCounter()
+}
@@ -28,12 +28,13 @@ const (
 	DefaultConfigFilename       = "sentinel.yml"
 	DefaultAppType        int32 = 0
 
-	DefaultMetricLogFlushIntervalSec   uint32 = 1
-	DefaultMetricLogSingleFileMaxSize  uint64 = 1024 * 1024 * 50
-	DefaultMetricLogMaxFileAmount      uint32 = 8
-	DefaultSystemStatCollectIntervalMs uint32 = 1000
-	DefaultLoadStatCollectIntervalMs   uint32 = 1000
-	DefaultCpuStatCollectIntervalMs    uint32 = 1000
-	DefaultMemoryStatCollectIntervalMs uint32 = 150
-	DefaultWarmUpColdFactor            uint32 = 3
+	DefaultMetricLogFlushIntervalSec    uint32 = 1
+	DefaultMetricLogSingleFileMaxSize   uint64 = 1024 * 1024 * 50
+	DefaultMetricLogMaxFileAmount       uint32 = 8
+	DefaultSystemStatCollectIntervalMs  uint32 = 1000
+	DefaultLoadStatCollectIntervalMs    uint32 = 1000
+	DefaultCpuStatCollectIntervalMs     uint32 = 1000
+	DefaultMemoryStatCollectIntervalMs  uint32 = 150
+	DefaultRuntimeStatCollectIntervalMs uint32 = 1000
+	DefaultWarmUpColdFactor             uint32 = 3
 )
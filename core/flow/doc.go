@@ -19,7 +19,13 @@
 // The TrafficShapingController consists of two part: TrafficShapingCalculator and TrafficShapingChecker
 //
 //  1. TrafficShapingCalculator calculates the actual traffic shaping token threshold. Currently, Sentinel supports two token calculate strategy: Direct and WarmUp.
-//  2. TrafficShapingChecker performs checking logic according to current metrics and the traffic shaping strategy, then yield the token result. Currently, Sentinel supports two control behavior: Reject and Throttling.
+//  2. TrafficShapingChecker performs checking logic according to current metrics and the traffic shaping strategy, then yield the token result. Currently, Sentinel supports five control behavior: Reject, Throttling, TokenBucket, GCRA and SlidingLog.
+//
+// The token calculate strategy and control behavior could be combined. For example, WarmUp with Throttling paces the requests
+// uniformly while the pacing interval follows the warm-up token curve, from ColdFactor/Threshold to 1/Threshold.
+//
+// Throttling, TokenBucket and GCRA implement TokenReserver, so the tokens could be reserved by api.Reserve and api.Wait
+// without waiting in the slot, and the tokens of cancelled reservations are taken back.
 //
 // Besides, Sentinel supports customized TrafficShapingCalculator and TrafficShapingChecker. User could call function SetTrafficShapingGenerator to register customized TrafficShapingController and call function RemoveTrafficShapingGenerator to unregister TrafficShapingController.
 // There are a few notes users need to be aware of:
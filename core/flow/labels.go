@@ -0,0 +1,177 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/config"
+	"github.com/alibaba/sentinel-golang/core/hotspot/cache"
+	sbase "github.com/alibaba/sentinel-golang/core/stat/base"
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultLabelsMaxCapacity int64 = 1000
+)
+
+// LabelMatcher matches the entry attachment of Key, whose value is compared in the string form.
+type LabelMatcher struct {
+	// Key is the key of the entry attachment.
+	Key string `json:"key"`
+	// Values are the accepted values of the attachment. Any value is accepted if Values is empty,
+	// and each value is counted as a distinct label set.
+	Values []string `json:"values,omitempty"`
+}
+
+// valueOf returns the matched value of the entry attachment, false if the attachment is absent or unaccepted.
+func (m *LabelMatcher) valueOf(attachments map[interface{}]interface{}) (string, bool) {
+	v, ok := attachments[m.Key]
+	if !ok || v == nil {
+		return "", false
+	}
+	s, ok := v.(string)
+	if !ok {
+		s = fmt.Sprint(v)
+	}
+	if len(m.Values) == 0 {
+		return s, true
+	}
+	for _, accepted := range m.Values {
+		if s == accepted {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+func (r *Rule) labelsMaxCapacity() int64 {
+	if r.LabelsMaxCapacity <= 0 {
+		return DefaultLabelsMaxCapacity
+	}
+	return r.LabelsMaxCapacity
+}
+
+// labelControllers holds the TrafficShapingController of each label set matched by the rule.
+type labelControllers struct {
+	rule      *Rule
+	generator TrafficControllerGenFunc
+
+	mux sync.Mutex
+	// controllers is the LRU cache of the label set to its TrafficShapingController.
+	controllers *cache.LRU
+}
+
+func newLabelControllers(rule *Rule, generator TrafficControllerGenFunc) (*labelControllers, error) {
+	controllers, err := cache.NewLRU(int(rule.labelsMaxCapacity()), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &labelControllers{
+		rule:        rule,
+		generator:   generator,
+		controllers: controllers,
+	}, nil
+}
+
+// labelSetOf returns the label set of the entry, false if the entry doesn't match the rule.
+// The values are quoted in the label set, so that the values containing the separators never collide,
+// e.g. {a: "x,b=y"} is encoded as `a="x,b=y"` rather than the label set of {a: "x", b: "y"}.
+func (l *labelControllers) labelSetOf(ctx *base.EntryContext) (string, bool) {
+	if ctx.Input == nil || len(ctx.Input.Attachments) == 0 {
+		return "", false
+	}
+	var b strings.Builder
+	for i := range l.rule.LabelMatchers {
+		m := &l.rule.LabelMatchers[i]
+		v, ok := m.valueOf(ctx.Input.Attachments)
+		if !ok {
+			return "", false
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(m.Key)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(v))
+	}
+	return b.String(), true
+}
+
+// controllerOf returns the TrafficShapingController of the label set, which is generated if absent.
+func (l *labelControllers) controllerOf(labelSet string) *TrafficShapingController {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if tc, ok := l.controllers.Get(labelSet); ok {
+		return tc.(*TrafficShapingController)
+	}
+	boundStat, err := generateLabelStatFor(l.rule)
+	if err != nil {
+		logging.Error(err, "Fail to generate the statistic of label set in flow.labelControllers.controllerOf()", "rule", l.rule, "labelSet", labelSet)
+		return nil
+	}
+	tc, err := l.generator(l.rule, boundStat)
+	if err != nil || tc == nil {
+		logging.Error(errors.New("bad generated traffic controller"), "Ignoring the label set due to bad generated traffic controller in flow.labelControllers.controllerOf()", "rule", l.rule, "labelSet", labelSet)
+		return nil
+	}
+	l.controllers.Add(labelSet, tc)
+	return tc
+}
+
+// generateLabelStatFor generates the independent statistic of a label set, which never reuses the resource's statistic.
+func generateLabelStatFor(rule *Rule) (*standaloneStatistic, error) {
+	if !rule.needStatistic() {
+		return nopStat, nil
+	}
+	intervalInMs := rule.StatIntervalInMs
+	if intervalInMs == 0 {
+		intervalInMs = config.MetricStatisticIntervalMs()
+	}
+	sampleCount := sampleCountOf(intervalInMs)
+	leapArray := sbase.NewBucketLeapArray(sampleCount, intervalInMs)
+	metricStat, err := sbase.NewSlidingWindowMetric(sampleCount, intervalInMs, leapArray)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to generate statistic for label set of rule: %+v", rule)
+	}
+	return &standaloneStatistic{
+		reuseResourceStat: false,
+		readOnlyMetric:    metricStat,
+		writeOnlyMetric:   leapArray,
+	}, nil
+}
+
+// newTrafficShapingController generates the TrafficShapingController of rule by the generator.
+// The controller of the rule with LabelMatchers checks the entries by the controllers of label sets instead.
+func newTrafficShapingController(generator TrafficControllerGenFunc, rule *Rule, boundStat *standaloneStatistic) (*TrafficShapingController, error) {
+	if len(rule.LabelMatchers) == 0 {
+		return generator(rule, boundStat)
+	}
+	tc, err := generator(rule, nopStat)
+	if err != nil || tc == nil {
+		return tc, err
+	}
+	tc.labels, err = newLabelControllers(rule, generator)
+	if err != nil {
+		return nil, err
+	}
+	return tc, nil
+}
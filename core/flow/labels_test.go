@@ -0,0 +1,139 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import (
+	"testing"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/stat"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLabelEntryContext(res string, attachments map[interface{}]interface{}) *base.EntryContext {
+	return &base.EntryContext{
+		Resource: base.NewResourceWrapper(res, base.ResTypeCommon, base.Inbound),
+		StatNode: stat.GetOrCreateResourceNode(res, base.ResTypeCommon),
+		Input: &base.SentinelInput{
+			BatchCount:  1,
+			Attachments: attachments,
+		},
+	}
+}
+
+func checkAndRecord(ctx *base.EntryContext) bool {
+	r := DefaultSlot.Check(ctx)
+	if r != nil && r.IsBlocked() {
+		return false
+	}
+	DefaultStandaloneStatSlot.OnEntryPassed(ctx)
+	return true
+}
+
+func TestLabelMatchers(t *testing.T) {
+	_, err := LoadRules([]*Rule{
+		{
+			Resource:               "/orders",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			Threshold:              2,
+			StatIntervalInMs:       1000,
+			LabelMatchers: []LabelMatcher{
+				{Key: "region", Values: []string{"eu"}},
+				{Key: "plan", Values: []string{"free"}},
+			},
+		},
+		{
+			Resource:               "/orders",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			Threshold:              3,
+			StatIntervalInMs:       1000,
+			LabelMatchers:          []LabelMatcher{{Key: "tenant"}},
+			LabelsMaxCapacity:      2,
+		},
+	})
+	assert.Nil(t, err)
+	defer ClearRules()
+
+	euFree := map[interface{}]interface{}{"region": "eu", "plan": "free"}
+	for i := 0; i < 2; i++ {
+		assert.True(t, checkAndRecord(newLabelEntryContext("/orders", euFree)))
+	}
+	assert.False(t, checkAndRecord(newLabelEntryContext("/orders", euFree)))
+	// The unmatched entries are not limited by the rule.
+	for i := 0; i < 5; i++ {
+		assert.True(t, checkAndRecord(newLabelEntryContext("/orders", map[interface{}]interface{}{"region": "us", "plan": "free"})))
+		assert.True(t, checkAndRecord(newLabelEntryContext("/orders", nil)))
+	}
+
+	// Each value of tenant has its own statistic.
+	for _, tenant := range []interface{}{"a", 1} {
+		for i := 0; i < 3; i++ {
+			assert.True(t, checkAndRecord(newLabelEntryContext("/orders", map[interface{}]interface{}{"tenant": tenant})))
+		}
+		assert.False(t, checkAndRecord(newLabelEntryContext("/orders", map[interface{}]interface{}{"tenant": tenant})))
+	}
+	assert.True(t, checkAndRecord(newLabelEntryContext("/orders", map[interface{}]interface{}{"tenant": "c"})))
+
+	tcs := getTrafficControllerListFor("/orders")
+	assert.Equal(t, 2, len(tcs))
+	assert.Equal(t, 1, tcs[0].labels.controllers.Len())
+	assert.Equal(t, 2, tcs[1].labels.controllers.Len())
+	assert.False(t, tcs[1].labels.controllers.Contains(`tenant="a"`))
+	assert.True(t, tcs[1].labels.controllers.Contains(`tenant="c"`))
+}
+
+func TestLabelSetOf(t *testing.T) {
+	l := &labelControllers{rule: &Rule{LabelMatchers: []LabelMatcher{{Key: "a"}, {Key: "b"}}}}
+	labelSetOf := func(attachments map[interface{}]interface{}) string {
+		labelSet, ok := l.labelSetOf(newLabelEntryContext("/orders", attachments))
+		assert.True(t, ok)
+		return labelSet
+	}
+	assert.Equal(t, `a="x",b="y"`, labelSetOf(map[interface{}]interface{}{"a": "x", "b": "y"}))
+	// the values containing the separators never collide with other label sets
+	assert.NotEqual(t,
+		labelSetOf(map[interface{}]interface{}{"a": "x,b=y", "b": "z"}),
+		labelSetOf(map[interface{}]interface{}{"a": "x", "b": "y,b=z"}))
+	assert.NotEqual(t,
+		labelSetOf(map[interface{}]interface{}{"a": `x"`, "b": `,b="y`}),
+		labelSetOf(map[interface{}]interface{}{"a": `x",b="`, "b": `y`}))
+	_, ok := l.labelSetOf(newLabelEntryContext("/orders", map[interface{}]interface{}{"a": "x"}))
+	assert.False(t, ok)
+}
+
+func TestIsValidRule_LabelMatchers(t *testing.T) {
+	r := &Rule{
+		Resource:               "/orders",
+		TokenCalculateStrategy: Direct,
+		ControlBehavior:        Reject,
+		Threshold:              10,
+		LabelMatchers:          []LabelMatcher{{Key: "region"}},
+	}
+	assert.Nil(t, IsValidRule(r))
+
+	r.LabelMatchers = []LabelMatcher{{Key: "region"}, {Key: "region", Values: []string{"eu"}}}
+	assert.Error(t, IsValidRule(r))
+	r.LabelMatchers = []LabelMatcher{{Values: []string{"eu"}}}
+	assert.Error(t, IsValidRule(r))
+	r.LabelMatchers = []LabelMatcher{{Key: "region"}}
+	r.LabelsMaxCapacity = -1
+	assert.Error(t, IsValidRule(r))
+	r.LabelsMaxCapacity = 0
+	r.RelationStrategy = AssociatedResource
+	r.RefResource = "/users"
+	assert.Error(t, IsValidRule(r))
+}
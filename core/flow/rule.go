@@ -17,7 +17,11 @@ package flow
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
+	"github.com/alibaba/sentinel-golang/core/schedule"
 	"github.com/alibaba/sentinel-golang/util"
 )
 
@@ -48,6 +52,13 @@ const (
 	Direct TokenCalculateStrategy = iota
 	WarmUp
 	MemoryAdaptive
+	// BBR indicates the BBR adaptive limiter, which estimates the capacity of resource by the max-pass and min-RT
+	// over the rolling window, and limits the concurrency of resource to the capacity once the CPU usage exceeds
+	// BbrCpuThreshold. The Threshold of rule is not used.
+	BBR
+	// CpuAdaptive indicates the threshold is interpolated between LowCpuUsageThreshold and HighCpuUsageThreshold
+	// according to the current CPU usage. The Threshold of rule is not used.
+	CpuAdaptive
 )
 
 func (s TokenCalculateStrategy) String() string {
@@ -58,6 +69,10 @@ func (s TokenCalculateStrategy) String() string {
 		return "WarmUp"
 	case MemoryAdaptive:
 		return "MemoryAdaptive"
+	case BBR:
+		return "BBR"
+	case CpuAdaptive:
+		return "CpuAdaptive"
 	default:
 		return "Undefined"
 	}
@@ -70,6 +85,16 @@ const (
 	Reject ControlBehavior = iota
 	// Throttling indicates that pending requests will be throttled, wait in queue (until free capacity is available)
 	Throttling
+	// TokenBucket indicates that requests consume the tokens generated at the rate of threshold, and at most
+	// BurstSize tokens can be accumulated, which allows a controlled burst.
+	TokenBucket
+	// GCRA indicates the generic cell rate algorithm, which spaces requests evenly at the rate of threshold
+	// while tolerating at most BurstSize requests ahead of schedule.
+	GCRA
+	// SlidingLog indicates that the pass time of requests in the last StatIntervalInMs are logged, so that the count of
+	// requests in any window of StatIntervalInMs never exceeds threshold. The memory is proportional to threshold,
+	// so it's intended for low thresholds (at most SlidingLogMaxThreshold) where the precision matters.
+	SlidingLog
 )
 
 func (s ControlBehavior) String() string {
@@ -78,6 +103,12 @@ func (s ControlBehavior) String() string {
 		return "Reject"
 	case Throttling:
 		return "Throttling"
+	case TokenBucket:
+		return "TokenBucket"
+	case GCRA:
+		return "GCRA"
+	case SlidingLog:
+		return "SlidingLog"
 	default:
 		return "Undefined"
 	}
@@ -96,12 +127,17 @@ type Rule struct {
 	Threshold        float64          `json:"threshold"`
 	RelationStrategy RelationStrategy `json:"relationStrategy"`
 	RefResource      string           `json:"refResource"`
-	// MaxQueueingTimeMs only takes effect when ControlBehavior is Throttling.
+	// MaxQueueingTimeMs only takes effect when ControlBehavior is Throttling, or the tokens are reserved
+	// (e.g. api.Reserve and api.Wait) when ControlBehavior is TokenBucket or GCRA.
 	// When MaxQueueingTimeMs is 0, it means Throttling only controls interval of requests,
 	// and requests exceeding the threshold will be rejected directly.
 	MaxQueueingTimeMs uint32 `json:"maxQueueingTimeMs"`
 	WarmUpPeriodSec   uint32 `json:"warmUpPeriodSec"`
 	WarmUpColdFactor  uint32 `json:"warmUpColdFactor"`
+	// BurstSize only takes effect when ControlBehavior is TokenBucket or GCRA.
+	// For TokenBucket, it's the capacity of bucket, i.e. the max count of requests passed at once.
+	// For GCRA, it's the count of requests allowed ahead of the evenly spaced schedule.
+	BurstSize uint32 `json:"burstSize"`
 	// StatIntervalInMs indicates the statistic interval and it's the optional setting for flow Rule.
 	// If user doesn't set StatIntervalInMs, that means using default metric statistic of resource.
 	// If the StatIntervalInMs user specifies can not reuse the global statistic of resource,
@@ -117,8 +153,62 @@ type Rule struct {
 	HighMemUsageThreshold int64 `json:"highMemUsageThreshold"`
 	MemLowWaterMarkBytes  int64 `json:"memLowWaterMarkBytes"`
 	MemHighWaterMarkBytes int64 `json:"memHighWaterMarkBytes"`
+
+	// CPU adaptive flow control algorithm related parameters, the water marks are the CPU usage ratio in [0.0, 1.0]
+	// relative to the CPU limit of container or all the CPUs, see system_metric.CurrentCpuUsage
+	// limitation: LowCpuUsageThreshold > HighCpuUsageThreshold && CpuHighWaterMark > CpuLowWaterMark
+	// if the current CPU usage is less than or equals to CpuLowWaterMark, threshold == LowCpuUsageThreshold
+	// if the current CPU usage is more than or equals to CpuHighWaterMark, threshold == HighCpuUsageThreshold
+	// if the current CPU usage is in (CpuLowWaterMark, CpuHighWaterMark), threshold is in (HighCpuUsageThreshold, LowCpuUsageThreshold)
+	LowCpuUsageThreshold  int64   `json:"lowCpuUsageThreshold"`
+	HighCpuUsageThreshold int64   `json:"highCpuUsageThreshold"`
+	CpuLowWaterMark       float64 `json:"cpuLowWaterMark"`
+	CpuHighWaterMark      float64 `json:"cpuHighWaterMark"`
+
+	// BBR adaptive flow control algorithm related parameters
+	// BbrCpuThreshold is the CPU usage in [0.0, 1.0] above which the resource is considered to be overloaded.
+	BbrCpuThreshold float64 `json:"bbrCpuThreshold"`
+	// BbrWindowMs and BbrBucketCount describe the rolling window tracking the max-pass and min-RT,
+	// 10000 ms and 100 buckets by default.
+	BbrWindowMs    uint32 `json:"bbrWindowMs"`
+	BbrBucketCount uint32 `json:"bbrBucketCount"`
+	// BbrCoolDownMs is the period to keep limiting after the latest drop, 1000 ms by default.
+	BbrCoolDownMs uint32 `json:"bbrCoolDownMs"`
+	// Schedule overrides Threshold in the scheduled time windows (e.g. business hours or promotions),
+	// it only takes effect when TokenCalculateStrategy is Direct.
+	Schedule *schedule.Schedule `json:"schedule,omitempty"`
+	// Mode indicates whether the rule blocks the requests (Enforce) or only records the would-be blocked ones (Shadow).
+	Mode base.RuleMode `json:"mode,omitempty"`
+	// EnforcePercent is the percentage of traffic the rule is enforced on, the valid range is [0.0, 100.0] and 0 means all the traffic.
+	// The rest of the traffic is checked in the Shadow mode.
+	EnforcePercent float64 `json:"enforcePercent,omitempty"`
+	// EnforceKey is the attachment key whose value selects the enforced traffic by hash, the traffic is selected randomly if empty.
+	EnforceKey string `json:"enforceKey,omitempty"`
+	// Canary selects the instances the rule is enforced on, nil means all the instances.
+	Canary *base.CanarySelector `json:"canary,omitempty"`
+	// Condition is the optional expression the rule only takes effect when it holds, e.g. `attachments.caller != "internal"`,
+	// see package condition for the syntax.
+	Condition string `json:"condition,omitempty"`
+	// conditionProgram is the compiled Condition, which is compiled when the rule is loaded.
+	conditionProgram *condition.Program
+	// ExpireAt is the Unix timestamp in milliseconds when the rule expires and is dropped automatically, 0 means never.
+	ExpireAt uint64 `json:"expireAt,omitempty"`
+	// TTLSec is the lifetime in seconds of the rule since it's loaded, 0 means forever.
+	// If both ExpireAt and TTLSec are set, the earlier one takes effect.
+	// A rule expired by TTLSec comes back with a new lifetime if it's loaded again, so prefer ExpireAt for rules from data sources.
+	TTLSec uint32 `json:"ttlSec,omitempty"`
+	// LabelMatchers restrict the rule to the entries whose attachments (see api.WithAttachments) match all the matchers,
+	// and each distinct set of the matched label values has its own statistic and threshold.
+	LabelMatchers []LabelMatcher `json:"labelMatchers,omitempty"`
+	// LabelsMaxCapacity is the max count of label sets counted, 1000 by default.
+	// The statistics of the least recently used label sets are evicted beyond the capacity.
+	LabelsMaxCapacity int64 `json:"labelsMaxCapacity,omitempty"`
 	// Regex indicates whether the rule is a regex rule
 	Regex bool `json:"regex"`
+	// Selector selects the resources by classification, traffic type and tags instead of Resource,
+	// in which case Resource is only the name of the rule group. Each selected resource has its own statistic,
+	// which is standalone for the resources beyond the max resource amount (see config.MaxResourceAmount).
+	Selector *base.ResourceSelector `json:"selector,omitempty"`
 }
 
 func (r *Rule) isEqualsTo(newRule *Rule) bool {
@@ -130,9 +220,17 @@ func (r *Rule) isEqualsTo(newRule *Rule) bool {
 		r.TokenCalculateStrategy == newRule.TokenCalculateStrategy && r.ControlBehavior == newRule.ControlBehavior &&
 		util.Float64Equals(r.Threshold, newRule.Threshold) &&
 		r.MaxQueueingTimeMs == newRule.MaxQueueingTimeMs && r.WarmUpPeriodSec == newRule.WarmUpPeriodSec &&
-		r.WarmUpColdFactor == newRule.WarmUpColdFactor &&
+		r.WarmUpColdFactor == newRule.WarmUpColdFactor && r.BurstSize == newRule.BurstSize &&
 		r.LowMemUsageThreshold == newRule.LowMemUsageThreshold && r.HighMemUsageThreshold == newRule.HighMemUsageThreshold &&
-		r.MemLowWaterMarkBytes == newRule.MemLowWaterMarkBytes && r.MemHighWaterMarkBytes == newRule.MemHighWaterMarkBytes) {
+		r.MemLowWaterMarkBytes == newRule.MemLowWaterMarkBytes && r.MemHighWaterMarkBytes == newRule.MemHighWaterMarkBytes &&
+		r.LowCpuUsageThreshold == newRule.LowCpuUsageThreshold && r.HighCpuUsageThreshold == newRule.HighCpuUsageThreshold &&
+		util.Float64Equals(r.CpuLowWaterMark, newRule.CpuLowWaterMark) && util.Float64Equals(r.CpuHighWaterMark, newRule.CpuHighWaterMark) &&
+		util.Float64Equals(r.BbrCpuThreshold, newRule.BbrCpuThreshold) && r.BbrWindowMs == newRule.BbrWindowMs &&
+		r.BbrBucketCount == newRule.BbrBucketCount && r.BbrCoolDownMs == newRule.BbrCoolDownMs &&
+		reflect.DeepEqual(r.Schedule, newRule.Schedule) && r.Mode == newRule.Mode &&
+		util.Float64Equals(r.EnforcePercent, newRule.EnforcePercent) && r.EnforceKey == newRule.EnforceKey &&
+		r.Canary.Equals(newRule.Canary) && r.Condition == newRule.Condition && reflect.DeepEqual(r.LabelMatchers, newRule.LabelMatchers) &&
+		r.LabelsMaxCapacity == newRule.LabelsMaxCapacity && reflect.DeepEqual(r.Selector, newRule.Selector)) {
 
 		return false
 	}
@@ -145,10 +243,15 @@ func (r *Rule) isStatReusable(newRule *Rule) bool {
 	}
 	return r.Resource == newRule.Resource && r.RelationStrategy == newRule.RelationStrategy &&
 		r.RefResource == newRule.RefResource && r.StatIntervalInMs == newRule.StatIntervalInMs &&
-		r.needStatistic() && newRule.needStatistic()
+		r.needStatistic() && newRule.needStatistic() && len(r.LabelMatchers) == 0 && len(newRule.LabelMatchers) == 0 &&
+		r.Condition == newRule.Condition
 }
 
 func (r *Rule) needStatistic() bool {
+	if r.TokenCalculateStrategy == BBR {
+		// BBR limiter tracks the statistic by itself.
+		return false
+	}
 	return r.TokenCalculateStrategy == WarmUp || r.ControlBehavior == Reject
 }
 
@@ -158,10 +261,12 @@ func (r *Rule) String() string {
 		// Return the fallback string
 		return fmt.Sprintf("Rule{Resource=%s, TokenCalculateStrategy=%s, ControlBehavior=%s, "+
 			"Threshold=%.2f, RelationStrategy=%s, RefResource=%s, MaxQueueingTimeMs=%d, WarmUpPeriodSec=%d, WarmUpColdFactor=%d, StatIntervalInMs=%d, "+
-			"LowMemUsageThreshold=%v, HighMemUsageThreshold=%v, MemLowWaterMarkBytes=%v, MemHighWaterMarkBytes=%v}",
+			"LowMemUsageThreshold=%v, HighMemUsageThreshold=%v, MemLowWaterMarkBytes=%v, MemHighWaterMarkBytes=%v, "+
+			"LowCpuUsageThreshold=%v, HighCpuUsageThreshold=%v, CpuLowWaterMark=%v, CpuHighWaterMark=%v}",
 			r.Resource, r.TokenCalculateStrategy, r.ControlBehavior, r.Threshold, r.RelationStrategy, r.RefResource,
 			r.MaxQueueingTimeMs, r.WarmUpPeriodSec, r.WarmUpColdFactor, r.StatIntervalInMs,
-			r.LowMemUsageThreshold, r.HighMemUsageThreshold, r.MemLowWaterMarkBytes, r.MemHighWaterMarkBytes)
+			r.LowMemUsageThreshold, r.HighMemUsageThreshold, r.MemLowWaterMarkBytes, r.MemHighWaterMarkBytes,
+			r.LowCpuUsageThreshold, r.HighCpuUsageThreshold, r.CpuLowWaterMark, r.CpuHighWaterMark)
 	}
 	return string(b)
 }
@@ -169,3 +274,26 @@ func (r *Rule) String() string {
 func (r *Rule) ResourceName() string {
 	return r.Resource
 }
+
+// Lifetime returns the ExpireAt and TTLSec of the rule.
+func (r *Rule) Lifetime() (uint64, uint32) {
+	return r.ExpireAt, r.TTLSec
+}
+
+// isEnforcedOn reports whether the rule is enforced on the entry, otherwise the rule is checked in the Shadow mode.
+func (r *Rule) isEnforcedOn(ctx *base.EntryContext) bool {
+	return base.IsEnforcedOn(ctx, r.Mode, r.EnforcePercent, r.EnforceKey, r.Canary)
+}
+
+// conditionHolds reports whether the Condition of the rule holds on the entry.
+func (r *Rule) conditionHolds(ctx *base.EntryContext) bool {
+	return r.conditionProgram.Holds(ctx)
+}
+
+func (r *Rule) setConditionProgram(program *condition.Program) {
+	r.conditionProgram = program
+}
+
+func (r *Rule) setCanary(canary *base.CanarySelector) {
+	r.Canary = canary
+}
@@ -17,11 +17,13 @@ package flow
 import (
 	"fmt"
 	"reflect"
-	"regexp"
 	"sync"
 
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/bbr"
+	"github.com/alibaba/sentinel-golang/core/condition"
 	"github.com/alibaba/sentinel-golang/core/config"
+	"github.com/alibaba/sentinel-golang/core/hotspot/cache"
 	"github.com/alibaba/sentinel-golang/core/stat"
 	sbase "github.com/alibaba/sentinel-golang/core/stat/base"
 	"github.com/alibaba/sentinel-golang/core/system_metric"
@@ -46,17 +48,33 @@ var (
 	tcMap           = make(TrafficControllerMap)
 	tcRegexMap      = make(TrafficControllerMap)
 	tcRegexCacheMap = make(TrafficControllerMap)
-	tcMux           = new(sync.RWMutex)
-	nopStat         = &standaloneStatistic{
+	// tcRegexMatcher indexes the patterns of the regex rules in tcRegexMap.
+	tcRegexMatcher *base.ResourceMatcher
+	// tcSelectorCache is the LRU cache of the controllers generated for each resource selected by the rules with Selector,
+	// which is guarded by selectorCacheMux.
+	tcSelectorCache  = newSelectorCache()
+	selectorCacheMux = new(sync.Mutex)
+	tcMux            = new(sync.RWMutex)
+	nopStat          = &standaloneStatistic{
 		reuseResourceStat: false,
 		readOnlyMetric:    base.NopReadStat(),
 		writeOnlyMetric:   base.NopWriteStat(),
 	}
-	currentRules  = make(map[string][]*Rule, 0)
-	updateRuleMux = new(sync.Mutex)
+	currentRules   = make(map[string][]*Rule, 0)
+	updateRuleMux  = new(sync.Mutex)
+	ruleExpiration *base.RuleExpirationManager[Rule, *Rule]
 )
 
 func init() {
+	ruleExpiration = base.NewRuleExpirationManager("[Flow]", updateRuleMux,
+		func() map[string][]*Rule {
+			return currentRules
+		},
+		onRuleUpdate,
+		func(rule *Rule, expireAt uint64, ttlSec uint32) {
+			rule.ExpireAt, rule.TTLSec = expireAt, ttlSec
+		})
+
 	// Initialize the traffic shaping controller generator map for existing control behaviors.
 	tcGenFuncMap[trafficControllerGenKey{
 		tokenCalculateStrategy: Direct,
@@ -160,6 +178,85 @@ func init() {
 		tsc.flowChecker = NewThrottlingChecker(tsc, rule.MaxQueueingTimeMs, rule.StatIntervalInMs)
 		return tsc, nil
 	}
+	tcGenFuncMap[trafficControllerGenKey{
+		tokenCalculateStrategy: CpuAdaptive,
+		controlBehavior:        Reject,
+	}] = func(rule *Rule, boundStat *standaloneStatistic) (*TrafficShapingController, error) {
+		if boundStat == nil {
+			var err error
+			boundStat, err = generateStatFor(rule)
+			if err != nil {
+				return nil, err
+			}
+		}
+		tsc, err := NewTrafficShapingController(rule, boundStat)
+		if err != nil || tsc == nil {
+			return nil, err
+		}
+		tsc.flowCalculator = NewCpuAdaptiveTrafficShapingCalculator(tsc, rule)
+		tsc.flowChecker = NewRejectTrafficShapingChecker(tsc, rule)
+		return tsc, nil
+	}
+	tcGenFuncMap[trafficControllerGenKey{
+		tokenCalculateStrategy: CpuAdaptive,
+		controlBehavior:        Throttling,
+	}] = func(rule *Rule, _ *standaloneStatistic) (*TrafficShapingController, error) {
+		// CpuAdaptive token calculate strategy and throttling control behavior don't use stat, so we just give a nop stat.
+		tsc, err := NewTrafficShapingController(rule, nopStat)
+		if err != nil || tsc == nil {
+			return nil, err
+		}
+		tsc.flowCalculator = NewCpuAdaptiveTrafficShapingCalculator(tsc, rule)
+		tsc.flowChecker = NewThrottlingChecker(tsc, rule.MaxQueueingTimeMs, rule.StatIntervalInMs)
+		return tsc, nil
+	}
+	tcGenFuncMap[trafficControllerGenKey{
+		tokenCalculateStrategy: BBR,
+		controlBehavior:        Reject,
+	}] = func(rule *Rule, _ *standaloneStatistic) (*TrafficShapingController, error) {
+		// BBR limiter tracks the statistic by itself, so we just give a nop stat.
+		tsc, err := NewTrafficShapingController(rule, nopStat)
+		if err != nil || tsc == nil {
+			return nil, err
+		}
+		limiter, err := bbr.NewLimiter(rule.BbrWindowMs, rule.BbrBucketCount, rule.BbrCoolDownMs)
+		if err != nil {
+			return nil, err
+		}
+		tsc.flowCalculator = NewBBRTrafficShapingCalculator(tsc, limiter)
+		tsc.flowChecker = NewBBRTrafficShapingChecker(tsc, rule, limiter)
+		return tsc, nil
+	}
+
+	// TokenBucket and GCRA only rely on the calculated threshold, so they work with the calculators which don't need statistic.
+	for _, strategy := range []TokenCalculateStrategy{Direct, MemoryAdaptive, CpuAdaptive} {
+		for _, behavior := range []ControlBehavior{TokenBucket, GCRA} {
+			tcGenFuncMap[trafficControllerGenKey{
+				tokenCalculateStrategy: strategy,
+				controlBehavior:        behavior,
+			}] = burstTrafficShapingGenerator
+		}
+	}
+	tcGenFuncMap[trafficControllerGenKey{
+		tokenCalculateStrategy: Direct,
+		controlBehavior:        SlidingLog,
+	}] = slidingLogTrafficShapingGenerator
+
+	stat.RegisterNodeRecreatedHandler(onResourceNodesRecreated)
+}
+
+// onResourceNodesRecreated regenerates the traffic shaping controllers whose reused statistic is stale
+// since the resource nodes were recreated due to the change of the statistic config.
+func onResourceNodesRecreated(resources []string) {
+	updateRuleMux.Lock()
+	defer updateRuleMux.Unlock()
+
+	if len(currentRules) == 0 {
+		return
+	}
+	if err := onRuleUpdate(currentRules); err != nil {
+		logging.Error(err, "Fail to regenerate flow controllers of recreated resource nodes in flow.onResourceNodesRecreated()", "resources", resources)
+	}
 }
 
 func logRuleUpdate(m map[string][]*Rule) {
@@ -197,6 +294,8 @@ func onRuleUpdate(rawResRulesMap map[string][]*Rule) (err error) {
 				logging.Warn("[Flow onRuleUpdate] Ignoring invalid flow rule", "rule", rule, "reason", err.Error())
 				continue
 			}
+			rule = condition.Prepare(rule, rule.Condition, (*Rule).setConditionProgram)
+			rule = base.ResolveCanary(rule, rule.Canary, (*Rule).setCanary)
 			validResRules = append(validResRules, rule)
 		}
 		if len(validResRules) > 0 {
@@ -204,6 +303,12 @@ func onRuleUpdate(rawResRulesMap map[string][]*Rule) (err error) {
 		}
 	}
 
+	validRules := make([]*Rule, 0, len(validResRulesMap))
+	for _, rules := range validResRulesMap {
+		validRules = append(validRules, rules...)
+	}
+	retainStatNodesOf(validRules)
+
 	start := util.CurrentTimeNano()
 
 	tcMux.RLock()
@@ -236,6 +341,8 @@ func onRuleUpdate(rawResRulesMap map[string][]*Rule) (err error) {
 	tcMap = m
 	tcRegexMap = regexM
 	tcRegexCacheMap = make(TrafficControllerMap)
+	tcRegexMatcher = buildRegexMatcher(tcRegexMap)
+	resetSelectorCache()
 	tcMux.Unlock()
 	currentRules = rawResRulesMap
 
@@ -264,6 +371,7 @@ func LoadRules(rules []*Rule) (bool, error) {
 		return false, nil
 	}
 	err := onRuleUpdate(resRulesMap)
+	ruleExpiration.Track()
 	return true, err
 }
 
@@ -284,15 +392,27 @@ func onResourceRuleUpdate(res string, rawResRules []*Rule) (err error) {
 			logging.Warn("[Flow onResourceRuleUpdate] Ignoring invalid flow rule", "rule", rule, "reason", err.Error())
 			continue
 		}
+		rule = condition.Prepare(rule, rule.Condition, (*Rule).setConditionProgram)
+		rule = base.ResolveCanary(rule, rule.Canary, (*Rule).setCanary)
 		validResRules = append(validResRules, rule)
 	}
 
 	start := util.CurrentTimeNano()
 	oldResTcs := make([]*TrafficShapingController, 0)
+	retainedRules := make([]*Rule, 0, len(validResRules))
 	tcMux.RLock()
 	oldResTcs = append(oldResTcs, tcMap[res]...)
 	oldResTcs = append(oldResTcs, tcRegexMap[res]...)
+	for r, tcs := range tcMap {
+		if r == res {
+			continue
+		}
+		for _, tc := range tcs {
+			retainedRules = append(retainedRules, tc.BoundRule())
+		}
+	}
 	tcMux.RUnlock()
+	retainStatNodesOf(append(retainedRules, validResRules...))
 	newResTcs, newRegexResTcs := buildResourceTrafficShapingController(res, validResRules, oldResTcs)
 
 	tcMux.Lock()
@@ -307,6 +427,8 @@ func onResourceRuleUpdate(res string, rawResRules []*Rule) (err error) {
 		tcRegexMap[res] = newRegexResTcs
 	}
 	tcRegexCacheMap = make(TrafficControllerMap)
+	tcRegexMatcher = buildRegexMatcher(tcRegexMap)
+	resetSelectorCache()
 	tcMux.Unlock()
 	currentRules[res] = rawResRules
 	logging.Debug("[Flow onResourceRuleUpdate] Time statistic(ns) for updating flow rule", "timeCost", util.CurrentTimeNano()-start)
@@ -331,7 +453,11 @@ func LoadRulesOfResource(res string, rules []*Rule) (bool, error) {
 		delete(tcMap, res)
 		delete(tcRegexMap, res)
 		tcRegexCacheMap = make(TrafficControllerMap)
+		tcRegexMatcher = buildRegexMatcher(tcRegexMap)
+		resetSelectorCache()
 		tcMux.Unlock()
+		retainStatNodesOf(getRules())
+		ruleExpiration.Track()
 		logging.Info("[Flow] clear resource level rules", "resource", res)
 		return true, nil
 	}
@@ -343,6 +469,7 @@ func LoadRulesOfResource(res string, rules []*Rule) (bool, error) {
 	}
 
 	err := onResourceRuleUpdate(res, rules)
+	ruleExpiration.Track()
 	return true, err
 }
 
@@ -386,22 +513,26 @@ func getRulesOfResource(res string) []*Rule {
 
 // GetRules returns all the rules based on copy.
 // It doesn't take effect for flow module if user changes the rule.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
 func GetRules() []Rule {
 	rules := getRules()
+	now := util.CurrentTimeMillis()
 	ret := make([]Rule, 0, len(rules))
 	for _, rule := range rules {
-		ret = append(ret, *rule)
+		ret = append(ret, ruleExpiration.CopyWithLifetime(rule, now))
 	}
 	return ret
 }
 
 // GetRulesOfResource returns specific resource's rules based on copy.
 // It doesn't take effect for flow module if user changes the rule.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
 func GetRulesOfResource(res string) []Rule {
 	rules := getRulesOfResource(res)
+	now := util.CurrentTimeMillis()
 	ret := make([]Rule, 0, len(rules))
 	for _, rule := range rules {
-		ret = append(ret, *rule)
+		ret = append(ret, ruleExpiration.CopyWithLifetime(rule, now))
 	}
 	return ret
 }
@@ -436,6 +567,23 @@ func rulesFrom(m TrafficControllerMap) []*Rule {
 	return rules
 }
 
+// retainStatNodesOf retains the nodes of the resources whose statistic the rules targeting the resource by name read,
+// so the nodes are never evicted or overflowed. The nodes retained for the rules no longer loaded are released.
+func retainStatNodesOf(rules []*Rule) {
+	resources := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Regex || rule.Selector != nil || !rule.needStatistic() {
+			continue
+		}
+		if rule.RelationStrategy == AssociatedResource {
+			resources = append(resources, rule.RefResource)
+		} else {
+			resources = append(resources, rule.Resource)
+		}
+	}
+	stat.RetainResourceNodes(statNodeOwner, resources)
+}
+
 func generateStatFor(rule *Rule) (*standaloneStatistic, error) {
 	if !rule.needStatistic() {
 		return nopStat, nil
@@ -452,31 +600,31 @@ func generateStatFor(rule *Rule) (*standaloneStatistic, error) {
 	} else {
 		resNode = stat.GetOrCreateResourceNode(rule.Resource, base.ResTypeCommon)
 	}
-	if intervalInMs == 0 || intervalInMs == config.MetricStatisticIntervalMs() {
+	if len(rule.Condition) > 0 || resNode == stat.OverflowNode() {
+		// the statistic of the resource counts the entries the condition excludes, and the overflow node
+		// of the resources beyond the max resource amount (e.g. selected by regex or Selector) is shared by them,
+		// so the rule counts on its own
+		if intervalInMs == 0 {
+			intervalInMs = resNode.IntervalMs()
+		}
+		sampleCount := sampleCountWithin(intervalInMs, resNode.SampleCountTotal(), resNode.IntervalMsTotal())
+		return newStandaloneStatFor(rule, resNode, sampleCount, intervalInMs)
+	}
+	if intervalInMs == 0 || intervalInMs == resNode.IntervalMs() {
 		// default case, use the resource's default statistic
 		readStat := resNode.DefaultMetric()
 		retStat.reuseResourceStat = true
 		retStat.readOnlyMetric = readStat
 		retStat.writeOnlyMetric = nil
+		retStat.resNode = resNode
 		return &retStat, nil
 	}
 
-	sampleCount := uint32(0)
-	//calculate the sample count
-	if intervalInMs > config.GlobalStatisticIntervalMsTotal() {
-		sampleCount = 1
-	} else if intervalInMs < config.GlobalStatisticBucketLengthInMs() {
-		sampleCount = 1
-	} else {
-		if intervalInMs%config.GlobalStatisticBucketLengthInMs() == 0 {
-			sampleCount = intervalInMs / config.GlobalStatisticBucketLengthInMs()
-		} else {
-			sampleCount = 1
-		}
-	}
-	err := base.CheckValidityForReuseStatistic(sampleCount, intervalInMs, config.GlobalStatisticSampleCountTotal(), config.GlobalStatisticIntervalMsTotal())
+	// the statistic window of the resource node may be declared by the resource itself, see stat.ResourceStatConfig
+	sampleCount := sampleCountWithin(intervalInMs, resNode.SampleCountTotal(), resNode.IntervalMsTotal())
+	err := base.CheckValidityForReuseStatistic(sampleCount, intervalInMs, resNode.SampleCountTotal(), resNode.IntervalMsTotal())
 	if err == nil {
-		// global statistic reusable
+		// statistic of resource node reusable
 		readStat, e := resNode.GenerateReadStat(sampleCount, intervalInMs)
 		if e != nil {
 			return nil, e
@@ -484,33 +632,58 @@ func generateStatFor(rule *Rule) (*standaloneStatistic, error) {
 		retStat.reuseResourceStat = true
 		retStat.readOnlyMetric = readStat
 		retStat.writeOnlyMetric = nil
+		retStat.resNode = resNode
 		return &retStat, nil
 	} else if err == base.GlobalStatisticNonReusableError {
 		logging.Info("[FlowRuleManager] Flow rule couldn't reuse global statistic and will generate independent statistic", "rule", rule)
-		retStat.reuseResourceStat = false
-		realLeapArray := sbase.NewBucketLeapArray(sampleCount, intervalInMs)
-		metricStat, e := sbase.NewSlidingWindowMetric(sampleCount, intervalInMs, realLeapArray)
-		if e != nil {
-			return nil, errors.Errorf("fail to generate statistic for warm up rule: %+v, err: %+v", rule, e)
-		}
-		retStat.readOnlyMetric = metricStat
-		retStat.writeOnlyMetric = realLeapArray
-		return &retStat, nil
+		return newStandaloneStatFor(rule, resNode, sampleCount, intervalInMs)
 	}
 	return nil, errors.Wrapf(err, "fail to new standalone statistic because of invalid StatIntervalInMs in flow.Rule, StatIntervalInMs: %d", intervalInMs)
 }
 
+// newStandaloneStatFor generates the independent statistic of the rule, which is written by StandaloneStatSlot.
+func newStandaloneStatFor(rule *Rule, resNode *stat.ResourceNode, sampleCount, intervalInMs uint32) (*standaloneStatistic, error) {
+	realLeapArray := sbase.NewBucketLeapArray(sampleCount, intervalInMs)
+	metricStat, e := sbase.NewSlidingWindowMetric(sampleCount, intervalInMs, realLeapArray)
+	if e != nil {
+		return nil, errors.Errorf("fail to generate statistic for warm up rule: %+v, err: %+v", rule, e)
+	}
+	return &standaloneStatistic{
+		reuseResourceStat: false,
+		readOnlyMetric:    metricStat,
+		writeOnlyMetric:   realLeapArray,
+		resNode:           resNode,
+	}, nil
+}
+
+// sampleCountOf calculates the sample count of the standalone statistic with the given interval.
+func sampleCountOf(intervalInMs uint32) uint32 {
+	return sampleCountWithin(intervalInMs, config.GlobalStatisticSampleCountTotal(), config.GlobalStatisticIntervalMsTotal())
+}
+
+// sampleCountWithin calculates the sample count of the statistic with the given interval based on the given sliding window.
+func sampleCountWithin(intervalInMs, sampleCountTotal, intervalMsTotal uint32) uint32 {
+	if sampleCountTotal == 0 {
+		return 1
+	}
+	bucketLengthInMs := intervalMsTotal / sampleCountTotal
+	if bucketLengthInMs == 0 || intervalInMs > intervalMsTotal || intervalInMs < bucketLengthInMs {
+		return 1
+	}
+	if intervalInMs%bucketLengthInMs == 0 {
+		return intervalInMs / bucketLengthInMs
+	}
+	return 1
+}
+
 // SetTrafficShapingGenerator sets the traffic controller generator for the given TokenCalculateStrategy and ControlBehavior.
-// Note that modifying the generator of default control strategy is not allowed.
+// Note that modifying the generator of default control behavior (Reject and Throttling) is not allowed.
 func SetTrafficShapingGenerator(tokenCalculateStrategy TokenCalculateStrategy, controlBehavior ControlBehavior, generator TrafficControllerGenFunc) error {
 	if generator == nil {
 		return errors.New("nil generator")
 	}
 
-	if tokenCalculateStrategy >= Direct && tokenCalculateStrategy <= WarmUp {
-		return errors.New("not allowed to replace the generator for default control strategy")
-	}
-	if controlBehavior >= Reject && controlBehavior <= Throttling {
+	if controlBehavior >= Reject && controlBehavior <= SlidingLog {
 		return errors.New("not allowed to replace the generator for default control strategy")
 	}
 	tcMux.Lock()
@@ -524,10 +697,7 @@ func SetTrafficShapingGenerator(tokenCalculateStrategy TokenCalculateStrategy, c
 }
 
 func RemoveTrafficShapingGenerator(tokenCalculateStrategy TokenCalculateStrategy, controlBehavior ControlBehavior) error {
-	if tokenCalculateStrategy >= Direct && tokenCalculateStrategy <= WarmUp {
-		return errors.New("not allowed to replace the generator for default control strategy")
-	}
-	if controlBehavior >= Reject && controlBehavior <= Throttling {
+	if controlBehavior >= Reject && controlBehavior <= SlidingLog {
 		return errors.New("not allowed to replace the generator for default control strategy")
 	}
 	tcMux.Lock()
@@ -566,6 +736,142 @@ func getTrafficControllerListFor(name string) []*TrafficShapingController {
 	return rules
 }
 
+// statNodeOwner is the owner of the resource nodes retained by the flow rules, see stat.RetainResourceNodes.
+const statNodeOwner = "flow"
+
+// selectorCacheCapacity is the max count of the resources whose controllers generated by the rules with Selector are cached.
+const selectorCacheCapacity = 10000
+
+type selectorCacheKey struct {
+	name         string
+	resourceType base.ResourceType
+	trafficType  base.TrafficType
+}
+
+func newSelectorCache() *cache.LRU {
+	c, err := cache.NewLRU(selectorCacheCapacity, nil)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// resetSelectorCache drops the cached controllers of the rules with Selector, must be guarded by tcMux.
+func resetSelectorCache() {
+	selectorCacheMux.Lock()
+	tcSelectorCache.Purge()
+	selectorCacheMux.Unlock()
+}
+
+// selectorTcsOf returns the controllers generated by the rules with Selector matching the resource.
+func selectorTcsOf(res *base.ResourceWrapper) []*TrafficShapingController {
+	key := selectorCacheKey{
+		name:         res.Name(),
+		resourceType: res.Classification(),
+		trafficType:  res.FlowType(),
+	}
+	selectorCacheMux.Lock()
+	cached, ok := tcSelectorCache.Get(key)
+	selectorCacheMux.Unlock()
+	if ok && !hasStaleStat(cached.([]*TrafficShapingController)) {
+		return cached.([]*TrafficShapingController)
+	}
+
+	tcMux.RLock()
+	defer tcMux.RUnlock()
+	selectorCacheMux.Lock()
+	defer selectorCacheMux.Unlock()
+	// double check
+	if cached, ok = tcSelectorCache.Get(key); ok && !hasStaleStat(cached.([]*TrafficShapingController)) {
+		return cached.([]*TrafficShapingController)
+	}
+	// the nodes of the selected resources aren't retained, regenerate the controllers once the node is evicted
+	selectorTcs := selectorTcMatches(res)
+	tcSelectorCache.Add(key, selectorTcs)
+	return selectorTcs
+}
+
+func hasStaleStat(tcs []*TrafficShapingController) bool {
+	for _, tc := range tcs {
+		if tc.boundStat.isStale() {
+			return true
+		}
+	}
+	return false
+}
+
+// getTrafficControllersOf returns the controllers checking the entry. The rules targeting the resource by name take
+// precedence, the rules with Selector only take effect on the resource without them and only the most specific ones apply.
+func getTrafficControllersOf(ctx *base.EntryContext) []*TrafficShapingController {
+	res := ctx.Resource
+	if tcs := getTrafficControllerListFor(res.Name()); len(tcs) > 0 {
+		return tcs
+	}
+
+	selectorTcs := selectorTcsOf(res)
+	if len(selectorTcs) == 0 {
+		return nil
+	}
+
+	var tags []string
+	if ctx.Input != nil {
+		tags = ctx.Input.Tags
+	}
+	result := make([]*TrafficShapingController, 0, len(selectorTcs))
+	maxSpecificity := -1
+	for _, tc := range selectorTcs {
+		selector := tc.rule.Selector
+		if !selector.MatchesTags(tags) {
+			continue
+		}
+		specificity := selector.Specificity()
+		if specificity > maxSpecificity {
+			maxSpecificity = specificity
+			result = result[:0]
+		}
+		if specificity == maxSpecificity {
+			result = append(result, tc)
+		}
+	}
+	return result
+}
+
+// selectorTcMatches generates the controllers of the rules with Selector matching the resource.
+// Each generated controller binds to the statistic of the resource rather than the rule group.
+func selectorTcMatches(res *base.ResourceWrapper) []*TrafficShapingController {
+	result := make([]*TrafficShapingController, 0)
+	for _, controllers := range tcRegexMap {
+		for _, controller := range controllers {
+			if controller.rule.Selector == nil || !controller.rule.Selector.MatchesResource(res) {
+				continue
+			}
+			generator, supported := tcGenFuncMap[trafficControllerGenKey{
+				tokenCalculateStrategy: controller.rule.TokenCalculateStrategy,
+				controlBehavior:        controller.rule.ControlBehavior,
+			}]
+			if !supported || generator == nil {
+				logging.Error(errors.New("get trafficShapingController copy failed, unsupported flow control strategy"),
+					"Ignoring the rule due to unsupported control behavior in flow.selectorTcMatches()", "rule", controller.rule)
+				continue
+			}
+			ruleOfRes := *controller.rule
+			ruleOfRes.Resource = res.Name()
+			boundStat, e := generateStatFor(&ruleOfRes)
+			if e != nil {
+				logging.Error(e, "Ignoring the rule due to failing to generate statistic in flow.selectorTcMatches()", "rule", controller.rule)
+				continue
+			}
+			if tc, e := newTrafficShapingController(generator, controller.rule, boundStat); e == nil && tc != nil {
+				result = append(result, tc)
+			} else {
+				logging.Error(errors.New("get trafficShapingController copy failed, bad generated traffic controller"),
+					"Ignoring the rule due to bad generated traffic controller in flow.selectorTcMatches()", "rule", controller.rule)
+			}
+		}
+	}
+	return result
+}
+
 func calculateReuseIndexFor(r *Rule, oldResTcs []*TrafficShapingController) (equalIdx, reuseStatIdx int) {
 	// the index of equivalent rule in old traffic shaping controller slice
 	equalIdx = -1
@@ -573,6 +879,10 @@ func calculateReuseIndexFor(r *Rule, oldResTcs []*TrafficShapingController) (equ
 	reuseStatIdx = -1
 
 	for idx, oldTc := range oldResTcs {
+		if oldTc.boundStat.isStale() {
+			// the resource node has been recreated, regenerate the statistic
+			continue
+		}
 		oldRule := oldTc.BoundRule()
 		if oldRule.isEqualsTo(r) {
 			// break if there is equivalent rule
@@ -625,9 +935,9 @@ func buildResourceTrafficShapingController(res string, rulesOfRes []*Rule,
 		var tc *TrafficShapingController
 		var e error
 		if reuseStatIdx >= 0 {
-			tc, e = generator(rule, &(oldResTcs[reuseStatIdx].boundStat))
+			tc, e = newTrafficShapingController(generator, rule, &(oldResTcs[reuseStatIdx].boundStat))
 		} else {
-			tc, e = generator(rule, nil)
+			tc, e = newTrafficShapingController(generator, rule, nil)
 		}
 
 		if tc == nil || e != nil {
@@ -639,7 +949,7 @@ func buildResourceTrafficShapingController(res string, rulesOfRes []*Rule,
 			oldResTcs = append(oldResTcs[:reuseStatIdx], oldResTcs[reuseStatIdx+1:]...)
 		}
 
-		if tc.rule != nil && tc.rule.Regex {
+		if tc.rule != nil && (tc.rule.Regex || tc.rule.Selector != nil) {
 			newRegexTcsOfRes = append(newRegexTcsOfRes, tc)
 		} else {
 			newTcsOfRes = append(newTcsOfRes, tc)
@@ -657,9 +967,22 @@ func IsValidRule(rule *Rule) error {
 	if rule.Resource == "" {
 		return errors.New("empty Resource")
 	}
+	if rule.Regex {
+		if err := (base.ResourcePattern{Strategy: base.RegexMatch, Pattern: rule.Resource}).IsValid(); err != nil {
+			return err
+		}
+	}
 	if rule.Threshold < 0 {
 		return errors.New("negative Threshold")
 	}
+	if rule.Selector != nil {
+		if rule.Regex {
+			return errors.New("Regex and Selector are mutually exclusive")
+		}
+		if err := rule.Selector.IsValid(); err != nil {
+			return errors.Wrap(err, "invalid Selector")
+		}
+	}
 	if int32(rule.TokenCalculateStrategy) < 0 {
 		return errors.New("negative TokenCalculateStrategy")
 	}
@@ -708,16 +1031,101 @@ func IsValidRule(rule *Rule) error {
 			return errors.New("rule.MemLowWaterMarkBytes >= rule.MemHighWaterMarkBytes")
 		}
 	}
+	if rule.ControlBehavior == TokenBucket && rule.BurstSize == 0 {
+		return errors.New("BurstSize must be great than 0 for TokenBucket control behavior")
+	}
+	if rule.ControlBehavior == SlidingLog && rule.Schedule.MaxThreshold(rule.Threshold) > SlidingLogMaxThreshold {
+		return errors.Errorf("Threshold must not be great than %d for SlidingLog control behavior", SlidingLogMaxThreshold)
+	}
+	if rule.Mode != base.Enforce && rule.Mode != base.Shadow {
+		return errors.New("invalid Mode")
+	}
+	if err := base.CheckEnforcePercent(rule.EnforcePercent); err != nil {
+		return err
+	}
+	if err := rule.Canary.IsValid(); err != nil {
+		return err
+	}
+	if err := condition.IsValid(rule.Condition); err != nil {
+		return errors.Wrap(err, "invalid Condition")
+	}
+	if rule.ExpireAt > 0 && rule.ExpireAt <= util.CurrentTimeMillis() {
+		return errors.New("rule already expired")
+	}
+	if rule.Schedule != nil {
+		if rule.TokenCalculateStrategy != Direct {
+			return errors.New("Schedule only takes effect when TokenCalculateStrategy is Direct")
+		}
+		if err := rule.Schedule.IsValid(); err != nil {
+			return errors.Wrap(err, "invalid Schedule")
+		}
+	}
+	if rule.LabelsMaxCapacity < 0 {
+		return errors.New("negative LabelsMaxCapacity")
+	}
+	if len(rule.LabelMatchers) > 0 && rule.RelationStrategy == AssociatedResource {
+		return errors.New("LabelMatchers doesn't take effect when RelationStrategy is AssociatedResource")
+	}
+	labelKeys := make(map[string]struct{}, len(rule.LabelMatchers))
+	for _, m := range rule.LabelMatchers {
+		if len(m.Key) == 0 {
+			return errors.New("empty Key of LabelMatcher")
+		}
+		if _, ok := labelKeys[m.Key]; ok {
+			return errors.Errorf("duplicate Key of LabelMatcher: %s", m.Key)
+		}
+		labelKeys[m.Key] = struct{}{}
+	}
+	if rule.TokenCalculateStrategy == CpuAdaptive {
+		if rule.LowCpuUsageThreshold <= 0 {
+			return errors.New("rule.LowCpuUsageThreshold <= 0")
+		}
+		if rule.HighCpuUsageThreshold <= 0 {
+			return errors.New("rule.HighCpuUsageThreshold <= 0")
+		}
+		if rule.HighCpuUsageThreshold >= rule.LowCpuUsageThreshold {
+			return errors.New("rule.HighCpuUsageThreshold >= rule.LowCpuUsageThreshold")
+		}
+
+		if rule.CpuLowWaterMark <= 0 {
+			return errors.New("rule.CpuLowWaterMark <= 0")
+		}
+		if rule.CpuHighWaterMark > 1 {
+			return errors.New("rule.CpuHighWaterMark > 1")
+		}
+		if rule.CpuLowWaterMark >= rule.CpuHighWaterMark {
+			// can not be equal to defeat from zero overflow
+			return errors.New("rule.CpuLowWaterMark >= rule.CpuHighWaterMark")
+		}
+	}
+	if rule.TokenCalculateStrategy == BBR {
+		if rule.ControlBehavior != Reject {
+			return errors.New("BBR strategy only supports Reject control behavior")
+		}
+		if rule.RelationStrategy != CurrentResource {
+			return errors.New("BBR strategy only supports CurrentResource relation strategy")
+		}
+		if rule.BbrCpuThreshold <= 0 || rule.BbrCpuThreshold > 1 {
+			return errors.New("invalid BbrCpuThreshold, valid range is (0.0, 1.0]")
+		}
+		if rule.BbrWindowMs != 0 || rule.BbrBucketCount != 0 {
+			if err := base.CheckValidityForStatistic(rule.BbrBucketCount, rule.BbrWindowMs); err != nil {
+				return errors.New("invalid BbrWindowMs or BbrBucketCount, BbrWindowMs must be divisible by BbrBucketCount")
+			}
+		}
+	}
 
 	return nil
 }
 
 func regexTcMatches(resource string) []*TrafficShapingController {
 	result := make([]*TrafficShapingController, 0)
-	for pattern, controllers := range tcRegexMap {
-		re := regexp.MustCompile(pattern)
-		if !re.MatchString(resource) {
-			continue
+	for _, pattern := range tcRegexMatcher.Match(resource) {
+		controllers := make([]*TrafficShapingController, 0, len(tcRegexMap[pattern.Pattern]))
+		for _, controller := range tcRegexMap[pattern.Pattern] {
+			if controller.rule.Regex {
+				controllers = append(controllers, controller)
+			}
 		}
 		controllersCopy := make([]*TrafficShapingController, len(controllers))
 		for resourceName, controller := range controllers {
@@ -731,7 +1139,7 @@ func regexTcMatches(resource string) []*TrafficShapingController {
 						"buildResourceTrafficShapingController()", "rule", controller.rule)
 				continue
 			}
-			if tc, e := generator(controller.rule, nil); e == nil && tc != nil {
+			if tc, e := newTrafficShapingController(generator, controller.rule, nil); e == nil && tc != nil {
 				controllersCopy[resourceName] = tc
 			} else {
 				logging.Error(errors.New("get trafficShapingController copy failed, bad generated traffic controller"),
@@ -743,3 +1151,20 @@ func regexTcMatches(resource string) []*TrafficShapingController {
 	}
 	return result
 }
+
+func buildRegexMatcher(regexTcs TrafficControllerMap) *base.ResourceMatcher {
+	patterns := make([]base.ResourcePattern, 0, len(regexTcs))
+	for pattern, tcs := range regexTcs {
+		for _, tc := range tcs {
+			if tc.rule.Regex {
+				patterns = append(patterns, base.ResourcePattern{Strategy: base.RegexMatch, Pattern: pattern})
+				break
+			}
+		}
+	}
+	matcher, err := base.NewResourceMatcher(patterns)
+	if err != nil {
+		logging.Error(err, "Fail to build the matcher of regex rules in flow.buildRegexMatcher()")
+	}
+	return matcher
+}
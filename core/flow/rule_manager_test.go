@@ -17,9 +17,12 @@ package flow
 import (
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/alibaba/sentinel-golang/core/base"
 	"github.com/alibaba/sentinel-golang/core/stat"
 	sbase "github.com/alibaba/sentinel-golang/core/stat/base"
+	"github.com/alibaba/sentinel-golang/util"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,6 +40,21 @@ func TestSetAndRemoveTrafficShapingGenerator(t *testing.T) {
 	err = RemoveTrafficShapingGenerator(Direct, Reject)
 	assert.Error(t, err, "default control behaviors are not allowed to be removed")
 
+	for _, key := range []trafficControllerGenKey{
+		{tokenCalculateStrategy: Direct, controlBehavior: TokenBucket},
+		{tokenCalculateStrategy: MemoryAdaptive, controlBehavior: GCRA},
+		{tokenCalculateStrategy: Direct, controlBehavior: SlidingLog},
+	} {
+		assert.Contains(t, tcGenFuncMap, key)
+		err = SetTrafficShapingGenerator(key.tokenCalculateStrategy, key.controlBehavior, func(_ *Rule, _ *standaloneStatistic) (*TrafficShapingController, error) {
+			return tsc, nil
+		})
+		assert.Error(t, err)
+		err = RemoveTrafficShapingGenerator(key.tokenCalculateStrategy, key.controlBehavior)
+		assert.Error(t, err)
+		assert.Contains(t, tcGenFuncMap, key)
+	}
+
 	err = SetTrafficShapingGenerator(TokenCalculateStrategy(111), ControlBehavior(112), func(_ *Rule, _ *standaloneStatistic) (*TrafficShapingController, error) {
 		return tsc, nil
 	})
@@ -243,6 +261,62 @@ func Test_generateStatFor(t *testing.T) {
 		}
 		assert.True(t, boundStat.reuseResourceStat == false && boundStat.writeOnlyMetric != nil)
 	})
+
+	t.Run("generateStatFor_reuse_resource_stat_config", func(t *testing.T) {
+		_, err := stat.LoadResourceStatConfigs([]*stat.ResourceStatConfig{{Resource: "abc-stat-config", SampleCountTotal: 60, IntervalMsTotal: 60000}})
+		assert.NoError(t, err)
+		defer stat.ClearResourceStatConfigs()
+
+		// resource: 60000ms, 60 sample, bucketLen: 1000ms
+		r1 := &Rule{
+			Resource:               "abc-stat-config",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			StatIntervalInMs:       30000,
+			Threshold:              100,
+			RelationStrategy:       CurrentResource,
+		}
+		boundStat, err := generateStatFor(r1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, boundStat.reuseResourceStat && boundStat.writeOnlyMetric == nil)
+		assert.True(t, boundStat.resNode == stat.GetResourceNode("abc-stat-config"))
+	})
+}
+
+func TestLoadRules_ResourceStatConfig(t *testing.T) {
+	clearData()
+	defer func() {
+		_ = ClearRules()
+		_ = stat.ClearResourceStatConfigs()
+	}()
+
+	r1 := &Rule{
+		Resource:               "abc-stat-reload",
+		TokenCalculateStrategy: Direct,
+		ControlBehavior:        Reject,
+		StatIntervalInMs:       30000,
+		Threshold:              100,
+		RelationStrategy:       CurrentResource,
+	}
+	_, err := LoadRules([]*Rule{r1})
+	assert.NoError(t, err)
+	tc := tcMap["abc-stat-reload"][0]
+	// the global statistic is not reusable for 30000ms
+	assert.False(t, tc.boundStat.reuseResourceStat)
+
+	_, err = stat.LoadResourceStatConfigs([]*stat.ResourceStatConfig{{Resource: "abc-stat-reload", SampleCountTotal: 60, IntervalMsTotal: 60000}})
+	assert.NoError(t, err)
+	// the controllers are regenerated with the recreated resource node
+	newTc := tcMap["abc-stat-reload"][0]
+	assert.True(t, newTc != tc)
+	assert.True(t, newTc.boundStat.reuseResourceStat)
+	assert.True(t, newTc.boundStat.resNode == stat.GetResourceNode("abc-stat-reload"))
+
+	assert.NoError(t, stat.ClearResourceStatConfigs())
+	assert.False(t, tcMap["abc-stat-reload"][0].boundStat.reuseResourceStat)
+	assert.False(t, tcMap["abc-stat-reload"][0].boundStat.isStale())
 }
 
 func Test_buildResourceTrafficShapingController(t *testing.T) {
@@ -498,6 +572,35 @@ func TestIsValidRule(t *testing.T) {
 	assert.NotNil(t, IsValidRule(rule1))
 	rule1.MemHighWaterMarkBytes = 300 * 1024 * 1024
 	assert.Nil(t, IsValidRule(rule1))
+
+	rule2 := &Rule{
+		Resource:               "hello0",
+		TokenCalculateStrategy: CpuAdaptive,
+		ControlBehavior:        Reject,
+		StatIntervalInMs:       10,
+		LowCpuUsageThreshold:   100,
+		HighCpuUsageThreshold:  10,
+		CpuLowWaterMark:        0.5,
+		CpuHighWaterMark:       0.8,
+	}
+	assert.Nil(t, IsValidRule(rule2))
+
+	rule2.HighCpuUsageThreshold = 100
+	assert.NotNil(t, IsValidRule(rule2))
+	rule2.HighCpuUsageThreshold = 0
+	assert.NotNil(t, IsValidRule(rule2))
+	rule2.HighCpuUsageThreshold = 10
+	assert.Nil(t, IsValidRule(rule2))
+
+	rule2.CpuLowWaterMark = 0
+	assert.NotNil(t, IsValidRule(rule2))
+	rule2.CpuLowWaterMark = 0.9
+	assert.NotNil(t, IsValidRule(rule2))
+	rule2.CpuLowWaterMark = 0.5
+	rule2.CpuHighWaterMark = 1.5
+	assert.NotNil(t, IsValidRule(rule2))
+	rule2.CpuHighWaterMark = 1
+	assert.Nil(t, IsValidRule(rule2))
 }
 
 func TestLoadRulesOfResource(t *testing.T) {
@@ -634,3 +737,144 @@ func TestClearRulesOfResource(t *testing.T) {
 		clearData()
 	})
 }
+
+func TestExpireRules(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+	defer ClearRules()
+
+	now := util.CurrentTimeMillis()
+	r1 := &Rule{
+		Resource:               "abc-expire",
+		TokenCalculateStrategy: Direct,
+		ControlBehavior:        Reject,
+		Threshold:              1,
+		StatIntervalInMs:       1000,
+		TTLSec:                 10,
+	}
+	r2 := &Rule{
+		Resource:               "abc-expire",
+		TokenCalculateStrategy: Direct,
+		ControlBehavior:        Reject,
+		Threshold:              10,
+		StatIntervalInMs:       1000,
+	}
+	expired := *r1
+	expired.TTLSec = 0
+	expired.ExpireAt = now
+	assert.Error(t, IsValidRule(&expired))
+
+	_, err := LoadRules([]*Rule{r1, r2})
+	assert.Nil(t, err)
+	util.Sleep(4 * time.Second)
+	rules := GetRulesOfResource("abc-expire")
+	assert.Equal(t, 2, len(rules))
+	assert.Equal(t, now+10000, rules[0].ExpireAt)
+	assert.Equal(t, uint32(6), rules[0].TTLSec)
+	assert.Equal(t, uint64(0), rules[1].ExpireAt)
+	assert.Equal(t, uint32(0), rules[1].TTLSec)
+
+	ruleExpiration.Expire()
+	assert.Equal(t, 2, len(getRulesOfResource("abc-expire")))
+	util.Sleep(6 * time.Second)
+	ruleExpiration.Expire()
+	rules = GetRulesOfResource("abc-expire")
+	assert.Equal(t, 1, len(rules))
+	assert.Equal(t, 10.0, rules[0].Threshold)
+	assert.Equal(t, 1, len(currentRules["abc-expire"]))
+}
+
+func TestLoadRules_PreparedCopies(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+	defer ClearRules()
+
+	newRule := func() *Rule {
+		return &Rule{
+			Resource:               "abc-prepared",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			Threshold:              10,
+			StatIntervalInMs:       1000,
+			Canary:                 &base.CanarySelector{Percent: 100, Source: base.CanaryHost},
+			Condition:              `attachments.caller != "internal"`,
+			TTLSec:                 10,
+		}
+	}
+	now := util.CurrentTimeMillis()
+	r := newRule()
+	_, err := LoadRules([]*Rule{r})
+	assert.Nil(t, err)
+	assert.True(t, reflect.DeepEqual(newRule(), r), "the loaded rule shouldn't be modified")
+	tc := getTrafficControllerListFor("abc-prepared")[0]
+	assert.True(t, tc.rule != r && tc.rule.Canary != r.Canary)
+	assert.True(t, tc.rule.conditionProgram != nil)
+
+	// the equal rules with resolved canary selector are reused rather than rebuilt
+	_, err = LoadRules([]*Rule{newRule()})
+	assert.Nil(t, err)
+	assert.True(t, tc == getTrafficControllerListFor("abc-prepared")[0])
+	rules := GetRulesOfResource("abc-prepared")
+	assert.Equal(t, 1, len(rules))
+	assert.Equal(t, now+10000, rules[0].ExpireAt, "the lifetime of the prepared rule should be resolved")
+}
+
+func TestRetainStatNodes(t *testing.T) {
+	_, err := LoadRules([]*Rule{
+		{
+			Resource:               "abc-retain",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			Threshold:              10,
+			StatIntervalInMs:       1000,
+		},
+		{
+			Resource:               "def-retain",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			Threshold:              10,
+			StatIntervalInMs:       1000,
+			RelationStrategy:       AssociatedResource,
+			RefResource:            "ref-retain",
+		},
+	})
+	assert.Nil(t, err)
+	defer ClearRules()
+	assert.True(t, stat.GetResourceNode("abc-retain").IsRetained())
+	assert.True(t, stat.GetResourceNode("ref-retain").IsRetained())
+	assert.Nil(t, stat.GetResourceNode("def-retain"))
+
+	// the nodes are released once the rules reading them are removed
+	assert.Nil(t, ClearRulesOfResource("abc-retain"))
+	assert.False(t, stat.GetResourceNode("abc-retain").IsRetained())
+	assert.True(t, stat.GetResourceNode("ref-retain").IsRetained())
+	_, err = LoadRulesOfResource("def-retain", []*Rule{
+		{
+			Resource:               "def-retain",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			Threshold:              10,
+			StatIntervalInMs:       1000,
+		},
+	})
+	assert.Nil(t, err)
+	assert.False(t, stat.GetResourceNode("ref-retain").IsRetained())
+	assert.True(t, stat.GetResourceNode("def-retain").IsRetained())
+	assert.Nil(t, ClearRules())
+	assert.False(t, stat.GetResourceNode("def-retain").IsRetained())
+}
+
+func TestIsValidRule_Regex(t *testing.T) {
+	r := &Rule{
+		Resource:               "abc/(",
+		TokenCalculateStrategy: Direct,
+		ControlBehavior:        Reject,
+		Threshold:              10,
+		StatIntervalInMs:       1000,
+	}
+	assert.Nil(t, IsValidRule(r))
+	r.Regex = true
+	assert.NotNil(t, IsValidRule(r))
+	r.Resource = "abc/[0-9]+"
+	assert.Nil(t, IsValidRule(r))
+}
@@ -15,6 +15,8 @@
 package flow
 
 import (
+	"time"
+
 	"github.com/alibaba/sentinel-golang/core/base"
 	"github.com/alibaba/sentinel-golang/core/stat"
 	metric_exporter "github.com/alibaba/sentinel-golang/exporter/metric"
@@ -39,6 +41,9 @@ func init() {
 	metric_exporter.Register(flowWaitCount)
 }
 
+// trafficControllersKey is the key of the controllers selected for the entry in EntryContext.Data.
+const trafficControllersKey = "flow-traffic-controllers"
+
 type Slot struct {
 }
 
@@ -48,7 +53,9 @@ func (s *Slot) Order() uint32 {
 
 func (s *Slot) Check(ctx *base.EntryContext) *base.TokenResult {
 	res := ctx.Resource.Name()
-	tcs := getTrafficControllerListFor(res)
+	// the controllers whose condition doesn't hold neither check nor record the entry
+	tcs := holdingTrafficControllers(ctx, getTrafficControllersOf(ctx))
+	setCheckedTrafficControllers(ctx, tcs)
 	result := ctx.RuleCheckResult
 
 	// Check rules in order
@@ -57,15 +64,41 @@ func (s *Slot) Check(ctx *base.EntryContext) *base.TokenResult {
 			logging.Warn("[FlowSlot Check]Nil traffic controller found", "resourceName", res)
 			continue
 		}
-		r := canPassCheck(tc, ctx.StatNode, ctx.Input.BatchCount)
+		if tc = tc.controllerFor(ctx); tc == nil {
+			continue
+		}
+		shadow := !tc.rule.isEnforcedOn(ctx)
+		var r *base.TokenResult
+		switch {
+		case shadow:
+			// Shadow rules never reserve tokens or report the quota state.
+			r = canPassCheck(tc, ctx.StatNode, ctx.Input.BatchCount)
+		case ctx.Input.Reserve:
+			r = reserveWithContext(ctx, tc)
+		case ctx.Input.NeedRateLimitInfo:
+			r = checkWithRateLimitInfo(ctx, tc)
+		default:
+			r = canPassCheck(tc, ctx.StatNode, ctx.Input.BatchCount)
+		}
 		if r == nil {
 			// nil means pass
 			continue
 		}
+		if shadow {
+			// Shadow rules only record the would-be blocked requests, and never make the requests wait.
+			if r.IsBlocked() {
+				ctx.AddShadowBlock(r.BlockError())
+			}
+			continue
+		}
 		if r.Status() == base.ResultStatusBlocked {
 			return r
 		}
 		if r.Status() == base.ResultStatusShouldWait {
+			if ctx.Input.Reserve {
+				// The waiting is left to the caller of reservation.
+				continue
+			}
 			if nanosToWait := r.NanosToWait(); nanosToWait > 0 {
 				flowWaitCount.Add(float64(ctx.Input.BatchCount), ctx.Resource.Name())
 				// Handle waiting action.
@@ -85,6 +118,43 @@ func canPassCheckWithFlag(tc *TrafficShapingController, node base.StatNode, batc
 	return checkInLocal(tc, node, batchCount, flag)
 }
 
+// checkWithRateLimitInfo checks the rule and records the quota state of rule to the entry context,
+// or to the block error if blocked.
+func checkWithRateLimitInfo(ctx *base.EntryContext, tc *TrafficShapingController) *base.TokenResult {
+	actual := selectNodeByRelStrategy(tc.rule, ctx.StatNode)
+	if actual == nil {
+		return checkInLocal(tc, ctx.StatNode, ctx.Input.BatchCount, 0)
+	}
+	r, info := tc.performCheckingWithRateLimitInfo(actual, ctx.Input.BatchCount, 0)
+	if info == nil {
+		return r
+	}
+	if r != nil && r.IsBlocked() {
+		r.BlockError().ResetBlockError(base.WithRateLimitInfo(info))
+	} else {
+		ctx.MergeRateLimitInfo(*info)
+	}
+	return r
+}
+
+// reserveWithContext reserves the tokens of rule and records the reservation to the entry context.
+func reserveWithContext(ctx *base.EntryContext, tc *TrafficShapingController) *base.TokenResult {
+	actual := selectNodeByRelStrategy(tc.rule, ctx.StatNode)
+	if actual == nil {
+		return checkInLocal(tc, ctx.StatNode, ctx.Input.BatchCount, 0)
+	}
+	r, cancel := tc.performReserving(actual, ctx.Input.BatchCount, 0)
+	if r != nil && r.IsBlocked() {
+		return r
+	}
+	var wait time.Duration
+	if r != nil && r.Status() == base.ResultStatusShouldWait {
+		wait = r.NanosToWait()
+	}
+	ctx.AddReservation(wait, cancel)
+	return r
+}
+
 func selectNodeByRelStrategy(rule *Rule, node base.StatNode) base.StatNode {
 	if rule.RelationStrategy == AssociatedResource {
 		return stat.GetResourceNode(rule.RefResource)
@@ -102,3 +172,43 @@ func checkInLocal(tc *TrafficShapingController, resStat base.StatNode, batchCoun
 	}
 	return tc.PerformChecking(actual, batchCount, flag)
 }
+
+// setCheckedTrafficControllers records the controllers selected for the entry, so that the StandaloneStatSlot
+// doesn't select them again.
+// holdingTrafficControllers returns the controllers whose rule condition holds on the entry,
+// tcs itself is returned if all the conditions hold.
+func holdingTrafficControllers(ctx *base.EntryContext, tcs []*TrafficShapingController) []*TrafficShapingController {
+	for i, tc := range tcs {
+		if tc == nil || tc.rule.conditionHolds(ctx) {
+			continue
+		}
+		ret := make([]*TrafficShapingController, i, len(tcs)-1)
+		copy(ret, tcs[:i])
+		for _, tc := range tcs[i+1:] {
+			if tc == nil || tc.rule.conditionHolds(ctx) {
+				ret = append(ret, tc)
+			}
+		}
+		return ret
+	}
+	return tcs
+}
+
+func setCheckedTrafficControllers(ctx *base.EntryContext, tcs []*TrafficShapingController) {
+	if len(tcs) == 0 {
+		return
+	}
+	if ctx.Data == nil {
+		ctx.Data = make(map[interface{}]interface{})
+	}
+	ctx.SetPair(trafficControllersKey, tcs)
+}
+
+// checkedTrafficControllers returns the controllers selected for the entry in Slot.Check, whose condition holds.
+func checkedTrafficControllers(ctx *base.EntryContext) []*TrafficShapingController {
+	if ctx.Data == nil {
+		return nil
+	}
+	tcs, _ := ctx.GetPair(trafficControllersKey).([]*TrafficShapingController)
+	return tcs
+}
@@ -16,10 +16,15 @@ package flow
 
 import (
 	"testing"
+	"time"
 
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/config"
+	"github.com/alibaba/sentinel-golang/core/hotspot/cache"
+	"github.com/alibaba/sentinel-golang/core/schedule"
 	"github.com/alibaba/sentinel-golang/core/stat"
 	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/alibaba/sentinel-golang/util"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -131,3 +136,443 @@ func Test_RegexFlowSlot_StandaloneStat(t *testing.T) {
 	assert.True(t, getTrafficControllerListFor("abc/456")[0].boundStat.readOnlyMetric.GetSum(base.
 		MetricEventPass) == 80)
 }
+
+func Test_FlowSlot_RateLimitInfo(t *testing.T) {
+	defer ClearRules()
+
+	slot := &Slot{}
+	statSlot := &StandaloneStatSlot{}
+	res := base.NewResourceWrapper("abc-rate-limit-info", base.ResTypeCommon, base.Inbound)
+	resNode := stat.GetOrCreateResourceNode("abc-rate-limit-info", base.ResTypeCommon)
+	newCtx := func() *base.EntryContext {
+		return &base.EntryContext{
+			Resource: res,
+			StatNode: resNode,
+			Input: &base.SentinelInput{
+				BatchCount:        1,
+				NeedRateLimitInfo: true,
+			},
+		}
+	}
+
+	_, err := LoadRules([]*Rule{
+		{
+			Resource:               "abc-rate-limit-info",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			StatIntervalInMs:       20000,
+			Threshold:              2,
+		},
+		{
+			Resource:               "abc-rate-limit-info",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        GCRA,
+			Threshold:              100,
+			BurstSize:              10,
+		},
+	})
+	assert.Nil(t, err)
+
+	for i := 1; i >= 0; i-- {
+		ctx := newCtx()
+		assert.Nil(t, slot.Check(ctx))
+		info := ctx.RateLimitInfo()
+		if assert.NotNil(t, info) {
+			assert.Equal(t, 2.0, info.Limit)
+			assert.Equal(t, float64(i), info.Remaining)
+			assert.Equal(t, 20*time.Second, info.Window)
+		}
+		statSlot.OnEntryPassed(ctx)
+	}
+
+	ctx := newCtx()
+	r := slot.Check(ctx)
+	if assert.NotNil(t, r) && assert.True(t, r.IsBlocked()) {
+		info := r.BlockError().RateLimitInfo()
+		if assert.NotNil(t, info) {
+			assert.Equal(t, 2.0, info.Limit)
+			assert.Equal(t, 0.0, info.Remaining)
+		}
+	}
+
+	// The quota state is collected only on demand.
+	ctx = newCtx()
+	ctx.Input.NeedRateLimitInfo = false
+	assert.NotNil(t, slot.Check(ctx))
+	assert.Nil(t, ctx.RateLimitInfo())
+}
+
+func Test_FlowSlot_Schedule(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	begin := util.Now().Add(2 * time.Second)
+	r := &Rule{
+		Resource:               "abc-schedule",
+		TokenCalculateStrategy: Direct,
+		ControlBehavior:        Reject,
+		Threshold:              1,
+		StatIntervalInMs:       1000,
+		Schedule: &schedule.Schedule{
+			Windows: []schedule.Window{
+				{Begin: begin.Format(time.RFC3339), End: begin.Add(time.Minute).Format(time.RFC3339), Threshold: 3},
+			},
+		},
+	}
+	_, err := LoadRules([]*Rule{r})
+	assert.Nil(t, err)
+	defer ClearRules()
+
+	tcs := getTrafficControllerListFor("abc-schedule")
+	assert.Equal(t, 1, len(tcs))
+	assert.Equal(t, 1.0, tcs[0].flowCalculator.CalculateAllowedTokens(1, 0))
+	util.Sleep(3 * time.Second)
+	assert.Equal(t, 3.0, tcs[0].flowCalculator.CalculateAllowedTokens(1, 0))
+	util.Sleep(time.Minute)
+	assert.Equal(t, 1.0, tcs[0].flowCalculator.CalculateAllowedTokens(1, 0))
+
+	warmUp := *r
+	warmUp.TokenCalculateStrategy = WarmUp
+	warmUp.WarmUpPeriodSec = 10
+	warmUp.WarmUpColdFactor = 3
+	assert.Error(t, IsValidRule(&warmUp))
+}
+
+func Test_FlowSlot_Shadow(t *testing.T) {
+	slot := &Slot{}
+	res := base.NewResourceWrapper("abc-shadow", base.ResTypeCommon, base.Inbound)
+	resNode := stat.GetOrCreateResourceNode("abc-shadow", base.ResTypeCommon)
+	r := &Rule{
+		Resource:               "abc-shadow",
+		TokenCalculateStrategy: Direct,
+		ControlBehavior:        Reject,
+		Threshold:              0,
+		StatIntervalInMs:       1000,
+		Mode:                   base.Shadow,
+	}
+	_, err := LoadRules([]*Rule{r})
+	assert.Nil(t, err)
+	defer ClearRules()
+
+	ctx := &base.EntryContext{
+		Resource: res,
+		StatNode: resNode,
+		Input: &base.SentinelInput{
+			BatchCount: 1,
+		},
+	}
+	assert.Nil(t, slot.Check(ctx))
+	shadowBlocks := ctx.ShadowBlocks()
+	assert.Equal(t, 1, len(shadowBlocks))
+	assert.Equal(t, base.BlockTypeFlow, shadowBlocks[0].BlockType())
+	assert.Equal(t, r, shadowBlocks[0].TriggeredRule())
+
+	r2 := *r
+	r2.Mode = base.RuleMode(2)
+	assert.Error(t, IsValidRule(&r2))
+}
+
+func Test_FlowSlot_EnforcePercent(t *testing.T) {
+	slot := &Slot{}
+	res := base.NewResourceWrapper("abc-enforce-percent", base.ResTypeCommon, base.Inbound)
+	resNode := stat.GetOrCreateResourceNode("abc-enforce-percent", base.ResTypeCommon)
+	r := &Rule{
+		Resource:               "abc-enforce-percent",
+		TokenCalculateStrategy: Direct,
+		ControlBehavior:        Reject,
+		Threshold:              0,
+		StatIntervalInMs:       1000,
+		EnforcePercent:         50,
+		EnforceKey:             "uid",
+	}
+	_, err := LoadRules([]*Rule{r})
+	assert.Nil(t, err)
+	defer ClearRules()
+
+	blocked, shadowed := 0, 0
+	for i := 0; i < 100; i++ {
+		ctx := &base.EntryContext{
+			Resource: res,
+			StatNode: resNode,
+			Input: &base.SentinelInput{
+				BatchCount:  1,
+				Attachments: map[interface{}]interface{}{"uid": i},
+			},
+		}
+		ret := slot.Check(ctx)
+		assert.Equal(t, r.isEnforcedOn(ctx), ret != nil && ret.IsBlocked())
+		if ret != nil && ret.IsBlocked() {
+			blocked++
+		} else {
+			assert.Equal(t, 1, len(ctx.ShadowBlocks()))
+			shadowed++
+		}
+	}
+	assert.True(t, blocked > 0 && shadowed > 0)
+
+	r2 := *r
+	r2.EnforcePercent = 120
+	assert.Error(t, IsValidRule(&r2))
+}
+
+func Test_FlowSlot_Condition(t *testing.T) {
+	slot := &Slot{}
+	r := &Rule{
+		Resource:               "abc-condition",
+		TokenCalculateStrategy: Direct,
+		ControlBehavior:        Reject,
+		Threshold:              0,
+		StatIntervalInMs:       1000,
+		Condition:              `attachments.caller != "internal"`,
+	}
+	_, err := LoadRules([]*Rule{r})
+	assert.Nil(t, err)
+	defer ClearRules()
+	// the condition is compiled into the copy of the rule held by the rule manager
+	assert.Nil(t, r.conditionProgram)
+	loaded := getRulesOfResource("abc-condition")[0]
+	assert.Equal(t, r.Condition, loaded.conditionProgram.String(), "the condition should be compiled on load")
+
+	newCtx := func(caller string) *base.EntryContext {
+		return &base.EntryContext{
+			Resource: base.NewResourceWrapper("abc-condition", base.ResTypeCommon, base.Inbound),
+			StatNode: stat.GetOrCreateResourceNode("abc-condition", base.ResTypeCommon),
+			Input: &base.SentinelInput{
+				BatchCount:  1,
+				Attachments: map[interface{}]interface{}{"caller": caller},
+			},
+		}
+	}
+	assert.Nil(t, slot.Check(newCtx("internal")))
+	ret := slot.Check(newCtx("web"))
+	assert.True(t, ret != nil && ret.IsBlocked())
+
+	r2 := *r
+	r2.Condition = `attachments.caller !=`
+	assert.Error(t, IsValidRule(&r2))
+}
+
+func Test_FlowSlot_ConditionStatistic(t *testing.T) {
+	slot := &Slot{}
+	statSlot := &StandaloneStatSlot{}
+	_, err := LoadRules([]*Rule{
+		{
+			Resource:               "abc-condition-stat",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			Threshold:              2,
+			StatIntervalInMs:       1000,
+			Condition:              `attachments.caller == "web"`,
+		},
+	})
+	assert.Nil(t, err)
+	defer ClearRules()
+	tc := getTrafficControllerListFor("abc-condition-stat")[0]
+	assert.False(t, tc.boundStat.reuseResourceStat, "the rule with condition shouldn't reuse the resource statistic")
+
+	pass := func(caller string) *base.TokenResult {
+		ctx := &base.EntryContext{
+			Resource: base.NewResourceWrapper("abc-condition-stat", base.ResTypeCommon, base.Inbound),
+			StatNode: stat.GetOrCreateResourceNode("abc-condition-stat", base.ResTypeCommon),
+			Input: &base.SentinelInput{
+				BatchCount:  1,
+				Attachments: map[interface{}]interface{}{"caller": caller},
+			},
+		}
+		ret := slot.Check(ctx)
+		if ret == nil || ret.IsPass() {
+			statSlot.OnEntryPassed(ctx)
+		}
+		return ret
+	}
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, pass("internal"))
+	}
+	assert.Equal(t, int64(0), tc.boundStat.readOnlyMetric.GetSum(base.MetricEventPass), "unmatched entries shouldn't be counted")
+	assert.Nil(t, pass("web"))
+	assert.Nil(t, pass("web"))
+	assert.Equal(t, int64(2), tc.boundStat.readOnlyMetric.GetSum(base.MetricEventPass))
+	ret := pass("web")
+	assert.True(t, ret != nil && ret.IsBlocked())
+	assert.Nil(t, pass("internal"))
+}
+
+func Test_FlowSlot_Selector(t *testing.T) {
+	slot := &Slot{}
+	_, err := LoadRules([]*Rule{
+		{
+			Resource:               "outbound-db",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			Threshold:              0,
+			StatIntervalInMs:       1000,
+			Selector: &base.ResourceSelector{
+				ResourceTypes: []base.ResourceType{base.ResTypeDBSQL},
+				TrafficTypes:  []base.TrafficType{base.Outbound},
+			},
+		},
+		{
+			Resource:               "outbound-db-batch",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			Threshold:              100,
+			StatIntervalInMs:       1000,
+			Selector: &base.ResourceSelector{
+				ResourceTypes: []base.ResourceType{base.ResTypeDBSQL},
+				TrafficTypes:  []base.TrafficType{base.Outbound},
+				Tags:          []string{"batch"},
+			},
+		},
+		{
+			Resource:               "select-orders",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			Threshold:              100,
+			StatIntervalInMs:       1000,
+		},
+	})
+	assert.Nil(t, err)
+	defer ClearRules()
+
+	newCtx := func(name string, resType base.ResourceType, trafficType base.TrafficType, tags ...string) *base.EntryContext {
+		return &base.EntryContext{
+			Resource: base.NewResourceWrapper(name, resType, trafficType),
+			StatNode: stat.GetOrCreateResourceNode(name, resType),
+			Input: &base.SentinelInput{
+				BatchCount: 1,
+				Tags:       tags,
+			},
+		}
+	}
+	ret := slot.Check(newCtx("select-users", base.ResTypeDBSQL, base.Outbound))
+	assert.True(t, ret != nil && ret.IsBlocked())
+	assert.Equal(t, "outbound-db", ret.BlockError().TriggeredRule().(*Rule).Resource)
+	// the rule targeting the resource by name takes precedence
+	assert.Nil(t, slot.Check(newCtx("select-orders", base.ResTypeDBSQL, base.Outbound)))
+	// the more specific selector takes precedence
+	assert.Nil(t, slot.Check(newCtx("select-users", base.ResTypeDBSQL, base.Outbound, "batch")))
+	// not selected
+	assert.Nil(t, slot.Check(newCtx("select-users", base.ResTypeDBSQL, base.Inbound)))
+	assert.Nil(t, slot.Check(newCtx("get-user", base.ResTypeRPC, base.Outbound)))
+
+	assert.Equal(t, 3, len(GetRules()))
+	assert.Error(t, IsValidRule(&Rule{
+		Resource: "outbound-db",
+		Regex:    true,
+		Selector: &base.ResourceSelector{TrafficTypes: []base.TrafficType{base.Outbound}},
+	}))
+	assert.Error(t, IsValidRule(&Rule{
+		Resource: "outbound-db",
+		Selector: &base.ResourceSelector{Tags: []string{""}},
+	}))
+}
+
+func Test_FlowSlot_SelectorOverflow(t *testing.T) {
+	conf := config.NewDefaultConfig()
+	conf.Sentinel.Stat.MaxResourceAmount = 1
+	config.ResetGlobalConfig(conf)
+	defer config.ResetGlobalConfig(config.NewDefaultConfig())
+	stat.ResetResourceNodeMap()
+	defer stat.ResetResourceNodeMap()
+
+	slot := &Slot{}
+	statSlot := &StandaloneStatSlot{}
+	_, err := LoadRules([]*Rule{
+		{
+			Resource:               "outbound-db",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			Threshold:              2,
+			StatIntervalInMs:       1000,
+			Selector: &base.ResourceSelector{
+				ResourceTypes: []base.ResourceType{base.ResTypeDBSQL},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	defer ClearRules()
+	_ = stat.GetOrCreateResourceNode("select-users", base.ResTypeDBSQL)
+
+	res := base.NewResourceWrapper("select-overflowed", base.ResTypeDBSQL, base.Outbound)
+	resNode := stat.GetOrCreateResourceNode(res.Name(), base.ResTypeDBSQL)
+	assert.True(t, resNode == stat.OverflowNode())
+	// the statistic of the overflow node is shared by the resources beyond the max resource amount
+	stat.OverflowNode().AddCount(base.MetricEventPass, 10)
+	tcs := selectorTcsOf(res)
+	assert.Equal(t, 1, len(tcs))
+	assert.False(t, tcs[0].boundStat.reuseResourceStat, "the overflowed resource should have its own statistic")
+
+	pass := func() *base.TokenResult {
+		ctx := &base.EntryContext{
+			Resource: res,
+			StatNode: resNode,
+			Input:    &base.SentinelInput{BatchCount: 1},
+		}
+		ret := slot.Check(ctx)
+		if ret == nil || ret.IsPass() {
+			statSlot.OnEntryPassed(ctx)
+		}
+		return ret
+	}
+	assert.Nil(t, pass())
+	assert.Nil(t, pass())
+	ret := pass()
+	assert.True(t, ret != nil && ret.IsBlocked())
+}
+
+func Test_FlowSlot_SelectorCache(t *testing.T) {
+	slot := &Slot{}
+	_, err := LoadRules([]*Rule{
+		{
+			Resource:               "outbound-cache",
+			TokenCalculateStrategy: Direct,
+			ControlBehavior:        Reject,
+			Threshold:              0,
+			StatIntervalInMs:       1000,
+			Selector: &base.ResourceSelector{
+				ResourceTypes: []base.ResourceType{base.ResTypeCache},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	defer ClearRules()
+
+	selectorCacheMux.Lock()
+	origin := tcSelectorCache
+	tcSelectorCache, _ = cache.NewLRU(2, nil)
+	selectorCacheMux.Unlock()
+	defer func() {
+		selectorCacheMux.Lock()
+		tcSelectorCache = origin
+		selectorCacheMux.Unlock()
+	}()
+
+	newCtx := func(name string) *base.EntryContext {
+		return &base.EntryContext{
+			Resource: base.NewResourceWrapper(name, base.ResTypeCache, base.Outbound),
+			StatNode: stat.GetOrCreateResourceNode(name, base.ResTypeCache),
+			Input:    &base.SentinelInput{BatchCount: 1},
+		}
+	}
+	for _, name := range []string{"get-a", "get-b", "get-c"} {
+		ret := slot.Check(newCtx(name))
+		assert.True(t, ret != nil && ret.IsBlocked())
+	}
+	// the least recently used resource is evicted
+	assert.Equal(t, 2, tcSelectorCache.Len())
+	assert.False(t, tcSelectorCache.Contains(selectorCacheKey{name: "get-a", resourceType: base.ResTypeCache, trafficType: base.Outbound}))
+	// the nodes of the selected resources are evictable
+	assert.False(t, stat.GetResourceNode("get-a").IsRetained())
+
+	// the controllers are selected once in Check and reused by the following slots of the entry
+	ctx := newCtx("get-d")
+	assert.True(t, slot.Check(ctx).IsBlocked())
+	tcs := checkedTrafficControllers(ctx)
+	assert.Equal(t, 1, len(tcs))
+	assert.Equal(t, "outbound-cache", tcs[0].BoundRule().Resource)
+	assert.Nil(t, checkedTrafficControllers(newCtx("get-e")))
+
+	_, err = LoadRules(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, tcSelectorCache.Len())
+}
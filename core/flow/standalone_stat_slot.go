@@ -36,8 +36,10 @@ func (s *StandaloneStatSlot) Order() uint32 {
 }
 
 func (s StandaloneStatSlot) OnEntryPassed(ctx *base.EntryContext) {
-	res := ctx.Resource.Name()
-	for _, tc := range getTrafficControllerListFor(res) {
+	for _, tc := range checkedTrafficControllers(ctx) {
+		if tc = tc.controllerFor(ctx); tc == nil {
+			continue
+		}
 		if !tc.boundStat.reuseResourceStat {
 			if tc.boundStat.writeOnlyMetric != nil {
 				tc.boundStat.writeOnlyMetric.AddCount(base.MetricEventPass, int64(ctx.Input.BatchCount))
@@ -49,9 +51,22 @@ func (s StandaloneStatSlot) OnEntryPassed(ctx *base.EntryContext) {
 }
 
 func (s StandaloneStatSlot) OnEntryBlocked(ctx *base.EntryContext, blockError *base.BlockError) {
-	// Do nothing
+	if ctx.Input.Reserve {
+		// The blocked entry must not hold the tokens reserved from flow rules.
+		ctx.CancelReservations()
+	}
 }
 
 func (s StandaloneStatSlot) OnCompleted(ctx *base.EntryContext) {
-	// Do nothing
+	if ctx.IsReservation() {
+		return
+	}
+	for _, tc := range checkedTrafficControllers(ctx) {
+		if tc = tc.controllerFor(ctx); tc == nil {
+			continue
+		}
+		if checker, ok := tc.flowChecker.(*BBRTrafficShapingChecker); ok {
+			checker.limiter.OnCompleted(ctx.Rt(), ctx.Input.BatchCount)
+		}
+	}
 }
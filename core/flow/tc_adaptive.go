@@ -51,3 +51,51 @@ func (m *MemoryAdaptiveTrafficShapingCalculator) CalculateAllowedTokens(_ uint32
 	}
 	return threshold
 }
+
+// CpuAdaptiveTrafficShapingCalculator is a CPU usage adaptive traffic shaping calculator
+//
+// adaptive flow control algorithm
+// If the CPU usage is less than Rule.CpuLowWaterMark, the threshold is Rule.LowCpuUsageThreshold.
+// If the CPU usage is bigger than Rule.CpuHighWaterMark, the threshold is Rule.HighCpuUsageThreshold.
+// Otherwise, the threshold is ((usage - CpuLowWaterMark)/(CpuHighWaterMark - CpuLowWaterMark)) *
+//
+//	(HighCpuUsageThreshold - LowCpuUsageThreshold) + LowCpuUsageThreshold.
+type CpuAdaptiveTrafficShapingCalculator struct {
+	owner                 *TrafficShapingController
+	lowCpuUsageThreshold  int64
+	highCpuUsageThreshold int64
+	cpuLowWaterMark       float64
+	cpuHighWaterMark      float64
+}
+
+func NewCpuAdaptiveTrafficShapingCalculator(owner *TrafficShapingController, r *Rule) *CpuAdaptiveTrafficShapingCalculator {
+	return &CpuAdaptiveTrafficShapingCalculator{
+		owner:                 owner,
+		lowCpuUsageThreshold:  r.LowCpuUsageThreshold,
+		highCpuUsageThreshold: r.HighCpuUsageThreshold,
+		cpuLowWaterMark:       r.CpuLowWaterMark,
+		cpuHighWaterMark:      r.CpuHighWaterMark,
+	}
+}
+
+func (c *CpuAdaptiveTrafficShapingCalculator) BoundOwner() *TrafficShapingController {
+	return c.owner
+}
+
+func (c *CpuAdaptiveTrafficShapingCalculator) CalculateAllowedTokens(_ uint32, _ int32) float64 {
+	var threshold float64
+	// the CPU usage ratio in [0.0, 1.0], the same unit as the water marks
+	cpu := system_metric.CurrentCpuUsage()
+	if cpu == system_metric.NotRetrievedCpuUsageValue {
+		logging.Warn("[CpuAdaptiveTrafficShapingCalculator CalculateAllowedTokens]Fail to load cpu usage")
+		return float64(c.lowCpuUsageThreshold)
+	}
+	if cpu <= c.cpuLowWaterMark {
+		threshold = float64(c.lowCpuUsageThreshold)
+	} else if cpu >= c.cpuHighWaterMark {
+		threshold = float64(c.highCpuUsageThreshold)
+	} else {
+		threshold = (float64(c.highCpuUsageThreshold-c.lowCpuUsageThreshold)/(c.cpuHighWaterMark-c.cpuLowWaterMark))*(cpu-c.cpuLowWaterMark) + float64(c.lowCpuUsageThreshold)
+	}
+	return threshold
+}
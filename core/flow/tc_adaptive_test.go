@@ -27,3 +27,31 @@ func TestMemoryAdaptiveTrafficShapingCalculator_CalculateAllowedTokens(t *testin
 	system_metric.SetSystemMemoryUsage(3072)
 	assert.True(t, util.Float64Equals(tc1.CalculateAllowedTokens(0, 0), 100))
 }
+
+func TestCpuAdaptiveTrafficShapingCalculator_CalculateAllowedTokens(t *testing.T) {
+	tc1 := &CpuAdaptiveTrafficShapingCalculator{
+		owner:                 nil,
+		lowCpuUsageThreshold:  1000,
+		highCpuUsageThreshold: 100,
+		cpuLowWaterMark:       0.4,
+		cpuHighWaterMark:      0.8,
+	}
+	defer system_metric.SetSystemCpuUsage(system_metric.NotRetrievedCpuUsageValue)
+
+	system_metric.SetSystemCpuUsage(system_metric.NotRetrievedCpuUsageValue)
+	assert.True(t, util.Float64Equals(tc1.CalculateAllowedTokens(0, 0), float64(tc1.lowCpuUsageThreshold)))
+	system_metric.SetSystemCpuUsage(0.1)
+	assert.True(t, util.Float64Equals(tc1.CalculateAllowedTokens(0, 0), float64(tc1.lowCpuUsageThreshold)))
+	system_metric.SetSystemCpuUsage(0.4)
+	assert.True(t, util.Float64Equals(tc1.CalculateAllowedTokens(0, 0), float64(tc1.lowCpuUsageThreshold)))
+	system_metric.SetSystemCpuUsage(0.6)
+	assert.True(t, util.Float64Equals(tc1.CalculateAllowedTokens(0, 0), 550))
+	system_metric.SetSystemCpuUsage(0.8)
+	assert.True(t, util.Float64Equals(tc1.CalculateAllowedTokens(0, 0), 100))
+	system_metric.SetSystemCpuUsage(0.95)
+	assert.True(t, util.Float64Equals(tc1.CalculateAllowedTokens(0, 0), 100))
+
+	// a process using 4.8 of 8 CPUs (480%) is collected as the ratio 0.6 rather than reaching the high water mark
+	system_metric.SetSystemCpuUsage(480.0 / 100 / 8)
+	assert.True(t, util.Float64Equals(tc1.CalculateAllowedTokens(0, 0), 550))
+}
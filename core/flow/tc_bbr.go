@@ -0,0 +1,78 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import (
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/bbr"
+)
+
+// BBRTrafficShapingCalculator calculates the estimated capacity of in-flight requests of the resource.
+type BBRTrafficShapingCalculator struct {
+	owner   *TrafficShapingController
+	limiter *bbr.Limiter
+}
+
+func NewBBRTrafficShapingCalculator(owner *TrafficShapingController, limiter *bbr.Limiter) *BBRTrafficShapingCalculator {
+	return &BBRTrafficShapingCalculator{
+		owner:   owner,
+		limiter: limiter,
+	}
+}
+
+func (c *BBRTrafficShapingCalculator) BoundOwner() *TrafficShapingController {
+	return c.owner
+}
+
+func (c *BBRTrafficShapingCalculator) CalculateAllowedTokens(uint32, int32) float64 {
+	return float64(c.limiter.MaxInFlight())
+}
+
+// BBRTrafficShapingChecker drops the requests if the resource is overloaded (or in cool-down)
+// and the in-flight requests of the resource exceed the estimated capacity.
+type BBRTrafficShapingChecker struct {
+	owner        *TrafficShapingController
+	rule         *Rule
+	limiter      *bbr.Limiter
+	cpuThreshold float64
+	// cpuUsage retrieves the smoothed CPU usage.
+	cpuUsage func() float64
+}
+
+func NewBBRTrafficShapingChecker(owner *TrafficShapingController, rule *Rule, limiter *bbr.Limiter) *BBRTrafficShapingChecker {
+	return &BBRTrafficShapingChecker{
+		owner:        owner,
+		rule:         rule,
+		limiter:      limiter,
+		cpuThreshold: rule.BbrCpuThreshold,
+		cpuUsage:     bbr.CurrentCpuUsage,
+	}
+}
+
+func (c *BBRTrafficShapingChecker) BoundOwner() *TrafficShapingController {
+	return c.owner
+}
+
+func (c *BBRTrafficShapingChecker) DoCheck(resStat base.StatNode, _ uint32, _ float64) *base.TokenResult {
+	if resStat == nil {
+		return nil
+	}
+	inFlight := int64(resStat.CurrentConcurrency())
+	if c.limiter.ShouldDrop(inFlight, c.cpuUsage() > c.cpuThreshold) {
+		msg := "flow bbr check blocked"
+		return base.NewTokenResultBlockedWithCause(base.BlockTypeFlow, msg, c.rule, float64(inFlight))
+	}
+	return nil
+}
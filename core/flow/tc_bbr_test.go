@@ -0,0 +1,134 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/bbr"
+	"github.com/alibaba/sentinel-golang/core/stat"
+	"github.com/alibaba/sentinel-golang/core/system_metric"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBBRTrafficShapingController(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	rule := &Rule{
+		Resource:               "abc-bbr",
+		TokenCalculateStrategy: BBR,
+		ControlBehavior:        Reject,
+		BbrCpuThreshold:        0.8,
+		BbrWindowMs:            1000,
+		BbrBucketCount:         10,
+	}
+	assert.Nil(t, IsValidRule(rule))
+	generator := tcGenFuncMap[trafficControllerGenKey{tokenCalculateStrategy: BBR, controlBehavior: Reject}]
+	tc, err := generator(rule, nil)
+	assert.Nil(t, err)
+	checker := tc.flowChecker.(*BBRTrafficShapingChecker)
+	cpu := 0.5
+	checker.cpuUsage = func() float64 {
+		return cpu
+	}
+
+	// 20 requests completed with 50 ms RT in a bucket of 100 ms, so the capacity is 200 QPS * 50 ms = 10.
+	for i := 0; i < 20; i++ {
+		checker.limiter.OnCompleted(50, 1)
+	}
+	util.Sleep(100 * time.Millisecond)
+	assert.True(t, util.Float64Equals(10, tc.flowCalculator.CalculateAllowedTokens(1, 0)))
+
+	node := stat.NewResourceNode("abc-bbr", base.ResTypeCommon)
+	for i := 0; i < 11; i++ {
+		node.IncreaseConcurrency()
+	}
+
+	assert.Nil(t, tc.PerformChecking(node, 1, 0))
+
+	cpu = 0.9
+	r := tc.PerformChecking(node, 1, 0)
+	assert.NotNil(t, r)
+	assert.True(t, r.IsBlocked())
+	assert.Equal(t, base.BlockTypeFlow, r.BlockError().BlockType())
+
+	// Keep dropping in the cool-down period.
+	cpu = 0.5
+	util.Sleep(500 * time.Millisecond)
+	assert.NotNil(t, tc.PerformChecking(node, 1, 0))
+	util.Sleep(600 * time.Millisecond)
+	assert.Nil(t, tc.PerformChecking(node, 1, 0))
+}
+
+func TestBBRTrafficShapingController_RealCpuUsage(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+	// a process using 2.8 of 8 CPUs, which is collected as the ratio rather than the percentage 280
+	system_metric.SetSystemCpuUsage(0.35)
+	defer system_metric.SetSystemCpuUsage(system_metric.NotRetrievedCpuUsageValue)
+
+	rule := &Rule{
+		Resource:               "abc-bbr-real-cpu",
+		TokenCalculateStrategy: BBR,
+		ControlBehavior:        Reject,
+		BbrCpuThreshold:        0.8,
+		BbrWindowMs:            1000,
+		BbrBucketCount:         10,
+	}
+	generator := tcGenFuncMap[trafficControllerGenKey{tokenCalculateStrategy: BBR, controlBehavior: Reject}]
+	tc, err := generator(rule, nil)
+	assert.Nil(t, err)
+	checker := tc.flowChecker.(*BBRTrafficShapingChecker)
+	for i := 0; i < 20; i++ {
+		checker.limiter.OnCompleted(50, 1)
+	}
+	util.Sleep(100 * time.Millisecond)
+
+	node := stat.NewResourceNode("abc-bbr-real-cpu", base.ResTypeCommon)
+	for i := 0; i < 11; i++ {
+		node.IncreaseConcurrency()
+	}
+	// the in-flight requests exceed the capacity, but the CPU usage is below the threshold
+	assert.True(t, bbr.CurrentCpuUsage() < rule.BbrCpuThreshold)
+	assert.Nil(t, tc.PerformChecking(node, 1, 0))
+}
+
+func TestIsValidRule_BBR(t *testing.T) {
+	rule := &Rule{
+		Resource:               "abc-bbr",
+		TokenCalculateStrategy: BBR,
+		ControlBehavior:        Reject,
+		BbrCpuThreshold:        0.8,
+	}
+	assert.Nil(t, IsValidRule(rule))
+
+	rule.ControlBehavior = Throttling
+	assert.NotNil(t, IsValidRule(rule))
+	rule.ControlBehavior = Reject
+
+	rule.BbrCpuThreshold = 80
+	assert.NotNil(t, IsValidRule(rule))
+	rule.BbrCpuThreshold = 0.8
+
+	rule.BbrWindowMs = 1000
+	rule.BbrBucketCount = 3
+	assert.NotNil(t, IsValidRule(rule))
+	rule.BbrBucketCount = 10
+	assert.Nil(t, IsValidRule(rule))
+}
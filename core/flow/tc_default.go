@@ -15,23 +15,40 @@
 package flow
 
 import (
+	"math"
+	"time"
+
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/config"
+	"github.com/alibaba/sentinel-golang/core/schedule"
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/alibaba/sentinel-golang/util"
 )
 
 type DirectTrafficShapingCalculator struct {
 	owner     *TrafficShapingController
 	threshold float64
+	// schedule overrides the threshold in the scheduled time windows of rule, nil if the rule has no schedule.
+	schedule *schedule.Evaluator
 }
 
 func NewDirectTrafficShapingCalculator(owner *TrafficShapingController, threshold float64) *DirectTrafficShapingCalculator {
-	return &DirectTrafficShapingCalculator{
+	d := &DirectTrafficShapingCalculator{
 		owner:     owner,
 		threshold: threshold,
 	}
+	if owner != nil && owner.BoundRule() != nil {
+		evaluator, err := schedule.NewEvaluator(owner.BoundRule().Schedule)
+		if err != nil {
+			logging.Warn("[DirectTrafficShapingCalculator] Ignoring the invalid schedule of rule", "rule", owner.BoundRule(), "reason", err.Error())
+		}
+		d.schedule = evaluator
+	}
+	return d
 }
 
 func (d *DirectTrafficShapingCalculator) CalculateAllowedTokens(uint32, int32) float64 {
-	return d.threshold
+	return d.schedule.Threshold(d.threshold)
 }
 
 func (d *DirectTrafficShapingCalculator) BoundOwner() *TrafficShapingController {
@@ -66,3 +83,25 @@ func (d *RejectTrafficShapingChecker) DoCheck(resStat base.StatNode, batchCount
 	}
 	return nil
 }
+
+func (d *RejectTrafficShapingChecker) RateLimitInfo(_ base.StatNode, batchCount uint32, threshold float64, passed bool) base.RateLimitInfo {
+	windowMs := uint64(d.rule.StatIntervalInMs)
+	if windowMs == 0 {
+		windowMs = uint64(config.MetricStatisticIntervalMs())
+	}
+	var curCount float64
+	if metricReadonlyStat := d.BoundOwner().boundStat.readOnlyMetric; metricReadonlyStat != nil {
+		curCount = float64(metricReadonlyStat.GetSum(base.MetricEventPass))
+	}
+	// The pass of current request isn't counted yet.
+	if passed {
+		curCount += float64(batchCount)
+	}
+	return base.RateLimitInfo{
+		Limit:     threshold,
+		Remaining: math.Max(0, threshold-curCount),
+		Window:    time.Duration(windowMs) * time.Millisecond,
+		// The pass count is sliding, so the end of the current window is an estimation.
+		Reset: time.Duration(windowMs-util.CurrentTimeMillis()%windowMs) * time.Millisecond,
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/util"
+)
+
+const (
+	// SlidingLogMaxThreshold is the max threshold of SlidingLog control behavior, which bounds the memory of log.
+	SlidingLogMaxThreshold = 10000
+
+	BlockMsgSlidingLog = "flow sliding log check blocked, requests in the window exceed threshold"
+)
+
+func slidingLogTrafficShapingGenerator(rule *Rule, _ *standaloneStatistic) (*TrafficShapingController, error) {
+	// SlidingLog control behavior logs the pass time by itself, so we just give a nop stat.
+	tsc, err := NewTrafficShapingController(rule, nopStat)
+	if err != nil || tsc == nil {
+		return nil, err
+	}
+	tsc.flowCalculator = NewDirectTrafficShapingCalculator(tsc, rule.Threshold)
+	// the log must be able to hold the max scheduled threshold
+	tsc.flowChecker = NewSlidingLogChecker(tsc, uint32(rule.Schedule.MaxThreshold(rule.Threshold)), rule.StatIntervalInMs)
+	return tsc, nil
+}
+
+// SlidingLogChecker logs the pass time of the latest capacity requests in a ring buffer, the request passes
+// if the count of logged requests in the last stat interval plus batch count doesn't exceed threshold.
+type SlidingLogChecker struct {
+	owner          *TrafficShapingController
+	statIntervalNs int64
+
+	mux sync.Mutex
+	// log is the ring buffer of the pass time (in nanoseconds), ordered from the oldest at head.
+	log  []int64
+	head int
+}
+
+// NewSlidingLogChecker creates the SlidingLogChecker which logs at most capacity requests,
+// the threshold beyond capacity is truncated.
+func NewSlidingLogChecker(owner *TrafficShapingController, capacity uint32, statIntervalMs uint32) *SlidingLogChecker {
+	log := make([]int64, capacity)
+	for i := range log {
+		log[i] = math.MinInt64
+	}
+	return &SlidingLogChecker{
+		owner:          owner,
+		statIntervalNs: statIntervalNsOf(statIntervalMs),
+		log:            log,
+	}
+}
+
+func (c *SlidingLogChecker) BoundOwner() *TrafficShapingController {
+	return c.owner
+}
+
+// limitOf returns the count of requests allowed in the window, which is bounded by the capacity of log.
+func (c *SlidingLogChecker) limitOf(threshold float64) int {
+	if threshold >= float64(len(c.log)) {
+		return len(c.log)
+	}
+	return int(threshold)
+}
+
+// at returns the i-th oldest pass time in log.
+func (c *SlidingLogChecker) at(i int) int64 {
+	return c.log[(c.head+i)%len(c.log)]
+}
+
+func (c *SlidingLogChecker) DoCheck(_ base.StatNode, batchCount uint32, threshold float64) *base.TokenResult {
+	// Pass when batch count is less or equal than 0.
+	if batchCount <= 0 {
+		return nil
+	}
+
+	var rule *Rule
+	if c.BoundOwner() != nil {
+		rule = c.BoundOwner().BoundRule()
+	}
+
+	limit := c.limitOf(threshold)
+	if limit <= 0 {
+		msg := "flow sliding log check blocked, threshold is <= 0.0"
+		return base.NewTokenResultBlockedWithCause(base.BlockTypeFlow, msg, rule, nil)
+	}
+	batch := int(batchCount)
+	if batch > limit {
+		msg := "flow sliding log check blocked, batch count exceeds threshold"
+		return base.NewTokenResultBlockedWithCause(base.BlockTypeFlow, msg, rule, nil)
+	}
+
+	capacity := len(c.log)
+	curNano := int64(util.CurrentTimeNano())
+	windowStart := curNano - c.statIntervalNs
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	// The requests pass if at least (capacity - limit + batch) logged requests are out of the window,
+	// i.e. the (capacity - limit + batch)-th oldest one.
+	oldest := c.at(capacity - limit + batch - 1)
+	if oldest > windowStart {
+		return base.NewTokenResult(base.ResultStatusBlocked, base.WithBlockType(base.BlockTypeFlow),
+			base.WithBlockMsg(BlockMsgSlidingLog), base.WithRule(rule), base.WithRetryAfter(time.Duration(oldest-windowStart)))
+	}
+	// Replace the oldest ones with the current time, so the log keeps ordered.
+	for i := 0; i < batch; i++ {
+		c.log[c.head] = curNano
+		c.head = (c.head + 1) % capacity
+	}
+	// nil means pass
+	return nil
+}
+
+func (c *SlidingLogChecker) RateLimitInfo(_ base.StatNode, _ uint32, threshold float64, _ bool) base.RateLimitInfo {
+	limit := c.limitOf(threshold)
+	info := base.RateLimitInfo{
+		Limit:  float64(limit),
+		Window: time.Duration(c.statIntervalNs),
+	}
+	if limit <= 0 {
+		return info
+	}
+	capacity := len(c.log)
+	windowStart := int64(util.CurrentTimeNano()) - c.statIntervalNs
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	inWindow := 0
+	for i := capacity - 1; i >= 0 && c.at(i) > windowStart; i-- {
+		inWindow++
+	}
+	if inWindow < limit {
+		info.Remaining = float64(limit - inWindow)
+	}
+	if newest := c.at(capacity - 1); newest > windowStart {
+		info.Reset = time.Duration(newest - windowStart)
+	}
+	return info
+}
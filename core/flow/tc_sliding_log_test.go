@@ -0,0 +1,136 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingLogChecker_DoCheck(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	// 3 requests per minute.
+	tc := NewSlidingLogChecker(nil, 3, 60000)
+	threshold := 3.0
+
+	assert.True(t, tc.DoCheck(nil, 4, threshold).IsBlocked())
+	assert.True(t, tc.DoCheck(nil, 1, 0).IsBlocked())
+
+	assert.Nil(t, tc.DoCheck(nil, 1, threshold))
+	util.Sleep(50 * time.Second)
+	assert.Nil(t, tc.DoCheck(nil, 2, threshold))
+	res := tc.DoCheck(nil, 1, threshold)
+	if assert.NotNil(t, res) && assert.True(t, res.IsBlocked()) {
+		assert.Equal(t, 10*time.Second, res.RetryAfter())
+		assert.Equal(t, BlockMsgSlidingLog, res.BlockError().BlockMsg())
+	}
+
+	// The window slides exactly, no burst is allowed at any boundary.
+	util.Sleep(10*time.Second - time.Millisecond)
+	assert.True(t, tc.DoCheck(nil, 1, threshold).IsBlocked())
+	util.Sleep(time.Millisecond)
+	assert.Nil(t, tc.DoCheck(nil, 1, threshold))
+	assert.True(t, tc.DoCheck(nil, 1, threshold).IsBlocked())
+
+	// The threshold lower than capacity takes effect, and the one beyond capacity is truncated.
+	util.Sleep(50 * time.Second)
+	assert.True(t, tc.DoCheck(nil, 2, 2).IsBlocked())
+	assert.Nil(t, tc.DoCheck(nil, 1, 2))
+	util.Sleep(time.Minute)
+	assert.Nil(t, tc.DoCheck(nil, 3, 10))
+	assert.True(t, tc.DoCheck(nil, 1, 10).IsBlocked())
+}
+
+func TestSlidingLogChecker_RateLimitInfo(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	tc := NewSlidingLogChecker(nil, 5, 60000)
+	info := tc.RateLimitInfo(nil, 1, 5, true)
+	assert.Equal(t, 5.0, info.Limit)
+	assert.Equal(t, 5.0, info.Remaining)
+	assert.Equal(t, time.Duration(0), info.Reset)
+
+	assert.Nil(t, tc.DoCheck(nil, 2, 5))
+	util.Sleep(20 * time.Second)
+	assert.Nil(t, tc.DoCheck(nil, 1, 5))
+	info = tc.RateLimitInfo(nil, 1, 5, true)
+	assert.Equal(t, 2.0, info.Remaining)
+	assert.Equal(t, time.Minute, info.Window)
+	assert.Equal(t, time.Minute, info.Reset)
+
+	util.Sleep(40 * time.Second)
+	info = tc.RateLimitInfo(nil, 1, 5, true)
+	assert.Equal(t, 4.0, info.Remaining)
+	assert.Equal(t, 20*time.Second, info.Reset)
+}
+
+func TestSlidingLogChecker_DoCheckParallel(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	tc := NewSlidingLogChecker(nil, 5, 60000)
+	var passed int64
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if r := tc.DoCheck(nil, 1, 5); r == nil {
+					atomic.AddInt64(&passed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(5), passed)
+}
+
+func TestSlidingLogRule(t *testing.T) {
+	defer ClearRules()
+
+	_, err := LoadRules([]*Rule{{
+		Resource:               "abc-sliding-log",
+		TokenCalculateStrategy: Direct,
+		ControlBehavior:        SlidingLog,
+		Threshold:              2,
+		StatIntervalInMs:       60000,
+	}})
+	assert.Nil(t, err)
+	tcs := getTrafficControllerListFor("abc-sliding-log")
+	if assert.Equal(t, 1, len(tcs)) {
+		_, ok := tcs[0].FlowChecker().(*SlidingLogChecker)
+		assert.True(t, ok)
+		assert.Nil(t, tcs[0].PerformChecking(nil, 2, 0))
+		r := tcs[0].PerformChecking(nil, 1, 0)
+		assert.True(t, r.IsBlocked())
+		assert.Equal(t, base.BlockTypeFlow, r.BlockError().BlockType())
+	}
+
+	assert.NotNil(t, IsValidRule(&Rule{
+		Resource:        "abc-sliding-log",
+		ControlBehavior: SlidingLog,
+		Threshold:       SlidingLogMaxThreshold + 1,
+	}))
+}
@@ -107,3 +107,35 @@ func (c *ThrottlingChecker) DoCheck(_ base.StatNode, batchCount uint32, threshol
 		return base.NewTokenResultShouldWait(0)
 	}
 }
+
+// DoReserve is the same as DoCheck, as the throttling checker has already reserved the pass time for the waiting requests.
+func (c *ThrottlingChecker) DoReserve(node base.StatNode, batchCount uint32, threshold float64) *base.TokenResult {
+	return c.DoCheck(node, batchCount, threshold)
+}
+
+// CancelReservation takes back the pass time reserved for batchCount, so that the following requests could pass earlier.
+func (c *ThrottlingChecker) CancelReservation(batchCount uint32, threshold float64) {
+	if batchCount <= 0 || threshold <= 0.0 {
+		return
+	}
+	intervalNs := int64(math.Ceil(float64(batchCount) / threshold * float64(c.statIntervalNs)))
+	atomic.AddInt64(&c.lastPassedTime, -intervalNs)
+}
+
+func (c *ThrottlingChecker) RateLimitInfo(_ base.StatNode, _ uint32, threshold float64, _ bool) base.RateLimitInfo {
+	info := base.RateLimitInfo{
+		Limit:  threshold,
+		Window: time.Duration(c.statIntervalNs),
+	}
+	if threshold <= 0 {
+		return info
+	}
+	intervalNs := int64(math.Ceil(float64(c.statIntervalNs) / threshold))
+	// The requests are paced evenly, so at most one request could pass without waiting.
+	if wait := atomic.LoadInt64(&c.lastPassedTime) + intervalNs - int64(util.CurrentTimeNano()); wait > 0 {
+		info.Reset = time.Duration(wait)
+	} else {
+		info.Remaining = 1
+	}
+	return info
+}
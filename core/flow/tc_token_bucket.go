@@ -0,0 +1,279 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/util"
+)
+
+const (
+	BlockMsgTokenBucket = "flow token bucket check blocked, no enough tokens"
+	BlockMsgGCRA        = "flow GCRA check blocked, request arrives too early"
+)
+
+func burstTrafficShapingGenerator(rule *Rule, _ *standaloneStatistic) (*TrafficShapingController, error) {
+	// TokenBucket and GCRA control behavior don't use stat, so we just give a nop stat.
+	tsc, err := NewTrafficShapingController(rule, nopStat)
+	if err != nil || tsc == nil {
+		return nil, err
+	}
+	switch rule.TokenCalculateStrategy {
+	case MemoryAdaptive:
+		tsc.flowCalculator = NewMemoryAdaptiveTrafficShapingCalculator(tsc, rule)
+	case CpuAdaptive:
+		tsc.flowCalculator = NewCpuAdaptiveTrafficShapingCalculator(tsc, rule)
+	default:
+		tsc.flowCalculator = NewDirectTrafficShapingCalculator(tsc, rule.Threshold)
+	}
+	if rule.ControlBehavior == GCRA {
+		tsc.flowChecker = NewGCRAChecker(tsc, rule.BurstSize, rule.StatIntervalInMs)
+	} else {
+		tsc.flowChecker = NewTokenBucketChecker(tsc, rule.BurstSize, rule.StatIntervalInMs)
+	}
+	return tsc, nil
+}
+
+func statIntervalNsOf(statIntervalMs uint32) int64 {
+	if statIntervalMs == 0 {
+		return 1000 * MillisToNanosOffset
+	}
+	return int64(statIntervalMs) * MillisToNanosOffset
+}
+
+// maxQueueingTimeNsOf returns the max duration (in nanoseconds) that the reservation could wait for the tokens.
+func maxQueueingTimeNsOf(owner *TrafficShapingController) int64 {
+	if owner == nil || owner.BoundRule() == nil {
+		return 0
+	}
+	return int64(owner.BoundRule().MaxQueueingTimeMs) * MillisToNanosOffset
+}
+
+// TokenBucketChecker generates threshold tokens during every stat interval into the bucket,
+// which holds at most burstSize tokens. The request passes if there are enough tokens in bucket.
+type TokenBucketChecker struct {
+	owner          *TrafficShapingController
+	burstSize      uint32
+	statIntervalNs int64
+	// zeroTime is the time (in nanoseconds) when the bucket was empty, so the tokens in bucket
+	// at time t are min(burstSize, (t - zeroTime) / interval). Keeping a single timestamp
+	// makes the bucket updatable by CAS without lock.
+	zeroTime int64
+}
+
+func NewTokenBucketChecker(owner *TrafficShapingController, burstSize uint32, statIntervalMs uint32) *TokenBucketChecker {
+	return &TokenBucketChecker{
+		owner:          owner,
+		burstSize:      burstSize,
+		statIntervalNs: statIntervalNsOf(statIntervalMs),
+	}
+}
+
+func (c *TokenBucketChecker) BoundOwner() *TrafficShapingController {
+	return c.owner
+}
+
+func (c *TokenBucketChecker) DoCheck(_ base.StatNode, batchCount uint32, threshold float64) *base.TokenResult {
+	return c.acquire(batchCount, threshold, 0)
+}
+
+// DoReserve reserves the tokens generated in the future, the reservation waits at most MaxQueueingTimeMs of rule.
+func (c *TokenBucketChecker) DoReserve(_ base.StatNode, batchCount uint32, threshold float64) *base.TokenResult {
+	return c.acquire(batchCount, threshold, maxQueueingTimeNsOf(c.owner))
+}
+
+// CancelReservation puts the reserved tokens back to the bucket.
+func (c *TokenBucketChecker) CancelReservation(batchCount uint32, threshold float64) {
+	if batchCount <= 0 || threshold <= 0.0 {
+		return
+	}
+	costNs := int64(math.Ceil(float64(batchCount) * float64(c.statIntervalNs) / threshold))
+	atomic.AddInt64(&c.zeroTime, -costNs)
+}
+
+// acquire takes batchCount tokens from the bucket, the tokens generated within maxWaitNs could be taken in advance.
+func (c *TokenBucketChecker) acquire(batchCount uint32, threshold float64, maxWaitNs int64) *base.TokenResult {
+	// Pass when batch count is less or equal than 0.
+	if batchCount <= 0 {
+		return nil
+	}
+
+	var rule *Rule
+	if c.BoundOwner() != nil {
+		rule = c.BoundOwner().BoundRule()
+	}
+
+	if threshold <= 0.0 {
+		msg := "flow token bucket check blocked, threshold is <= 0.0"
+		return base.NewTokenResultBlockedWithCause(base.BlockTypeFlow, msg, rule, nil)
+	}
+	if batchCount > c.burstSize {
+		msg := "flow token bucket check blocked, batch count exceeds burst size"
+		return base.NewTokenResultBlockedWithCause(base.BlockTypeFlow, msg, rule, nil)
+	}
+	// The interval (in nanoseconds) to generate a token.
+	intervalNs := float64(c.statIntervalNs) / threshold
+	fullNs := int64(math.Ceil(float64(c.burstSize) * intervalNs))
+	costNs := int64(math.Ceil(float64(batchCount) * intervalNs))
+	for {
+		curNano := int64(util.CurrentTimeNano())
+		zeroTime := atomic.LoadInt64(&c.zeroTime)
+		// The tokens exceeding the capacity of bucket are discarded.
+		start := zeroTime
+		if minZeroTime := curNano - fullNs; start < minZeroTime {
+			start = minZeroTime
+		}
+		newZeroTime := start + costNs
+		if newZeroTime-curNano > maxWaitNs {
+			return base.NewTokenResult(base.ResultStatusBlocked, base.WithBlockType(base.BlockTypeFlow),
+				base.WithBlockMsg(BlockMsgTokenBucket), base.WithRule(rule), base.WithRetryAfter(time.Duration(newZeroTime-curNano-maxWaitNs)))
+		}
+		if atomic.CompareAndSwapInt64(&c.zeroTime, zeroTime, newZeroTime) {
+			if newZeroTime > curNano {
+				return base.NewTokenResultShouldWait(time.Duration(newZeroTime - curNano))
+			}
+			// nil means pass
+			return nil
+		}
+	}
+}
+
+func (c *TokenBucketChecker) RateLimitInfo(_ base.StatNode, _ uint32, threshold float64, _ bool) base.RateLimitInfo {
+	info := base.RateLimitInfo{
+		Limit:  threshold,
+		Window: time.Duration(c.statIntervalNs),
+	}
+	if threshold <= 0 {
+		return info
+	}
+	intervalNs := float64(c.statIntervalNs) / threshold
+	elapsed := int64(util.CurrentTimeNano()) - atomic.LoadInt64(&c.zeroTime)
+	info.Remaining = math.Min(float64(c.burstSize), math.Floor(float64(elapsed)/intervalNs))
+	if fullNs := int64(math.Ceil(float64(c.burstSize)*intervalNs)) - elapsed; fullNs > 0 {
+		info.Reset = time.Duration(fullNs)
+	}
+	return info
+}
+
+// GCRAChecker implements the generic cell rate algorithm. The requests are expected to arrive
+// every interval (statInterval / threshold), and at most burstSize requests could arrive ahead of schedule.
+type GCRAChecker struct {
+	owner          *TrafficShapingController
+	burstSize      uint32
+	statIntervalNs int64
+	// tat is the theoretical arrival time (in nanoseconds) of the next request.
+	tat int64
+}
+
+func NewGCRAChecker(owner *TrafficShapingController, burstSize uint32, statIntervalMs uint32) *GCRAChecker {
+	return &GCRAChecker{
+		owner:          owner,
+		burstSize:      burstSize,
+		statIntervalNs: statIntervalNsOf(statIntervalMs),
+	}
+}
+
+func (c *GCRAChecker) BoundOwner() *TrafficShapingController {
+	return c.owner
+}
+
+func (c *GCRAChecker) DoCheck(_ base.StatNode, batchCount uint32, threshold float64) *base.TokenResult {
+	return c.acquire(batchCount, threshold, 0)
+}
+
+// DoReserve reserves the arrival time in the future, the reservation waits at most MaxQueueingTimeMs of rule.
+func (c *GCRAChecker) DoReserve(_ base.StatNode, batchCount uint32, threshold float64) *base.TokenResult {
+	return c.acquire(batchCount, threshold, maxQueueingTimeNsOf(c.owner))
+}
+
+// CancelReservation takes back the theoretical arrival time reserved for batchCount.
+func (c *GCRAChecker) CancelReservation(batchCount uint32, threshold float64) {
+	if batchCount <= 0 || threshold <= 0.0 {
+		return
+	}
+	costNs := int64(math.Ceil(float64(batchCount) * float64(c.statIntervalNs) / threshold))
+	atomic.AddInt64(&c.tat, -costNs)
+}
+
+// acquire checks whether batchCount requests could arrive now, the requests allowed within maxWaitNs could be admitted in advance.
+func (c *GCRAChecker) acquire(batchCount uint32, threshold float64, maxWaitNs int64) *base.TokenResult {
+	// Pass when batch count is less or equal than 0.
+	if batchCount <= 0 {
+		return nil
+	}
+
+	var rule *Rule
+	if c.BoundOwner() != nil {
+		rule = c.BoundOwner().BoundRule()
+	}
+
+	if threshold <= 0.0 {
+		msg := "flow GCRA check blocked, threshold is <= 0.0"
+		return base.NewTokenResultBlockedWithCause(base.BlockTypeFlow, msg, rule, nil)
+	}
+	if batchCount > c.burstSize+1 {
+		msg := "flow GCRA check blocked, batch count exceeds burst size"
+		return base.NewTokenResultBlockedWithCause(base.BlockTypeFlow, msg, rule, nil)
+	}
+	// The emission interval (in nanoseconds) of requests.
+	intervalNs := float64(c.statIntervalNs) / threshold
+	// The request passes if it doesn't push the theoretical arrival time beyond now + limitNs.
+	limitNs := int64(math.Ceil(float64(c.burstSize+1) * intervalNs))
+	costNs := int64(math.Ceil(float64(batchCount) * intervalNs))
+	for {
+		curNano := int64(util.CurrentTimeNano())
+		tat := atomic.LoadInt64(&c.tat)
+		newTat := tat
+		if newTat < curNano {
+			newTat = curNano
+		}
+		newTat += costNs
+		allowAt := newTat - limitNs
+		if allowAt-curNano > maxWaitNs {
+			return base.NewTokenResult(base.ResultStatusBlocked, base.WithBlockType(base.BlockTypeFlow),
+				base.WithBlockMsg(BlockMsgGCRA), base.WithRule(rule), base.WithRetryAfter(time.Duration(allowAt-curNano-maxWaitNs)))
+		}
+		if atomic.CompareAndSwapInt64(&c.tat, tat, newTat) {
+			if allowAt > curNano {
+				return base.NewTokenResultShouldWait(time.Duration(allowAt - curNano))
+			}
+			// nil means pass
+			return nil
+		}
+	}
+}
+
+func (c *GCRAChecker) RateLimitInfo(_ base.StatNode, _ uint32, threshold float64, _ bool) base.RateLimitInfo {
+	info := base.RateLimitInfo{
+		Limit:  threshold,
+		Window: time.Duration(c.statIntervalNs),
+	}
+	if threshold <= 0 {
+		return info
+	}
+	intervalNs := float64(c.statIntervalNs) / threshold
+	limitNs := int64(math.Ceil(float64(c.burstSize+1) * intervalNs))
+	ahead := atomic.LoadInt64(&c.tat) - int64(util.CurrentTimeNano())
+	if ahead < 0 {
+		ahead = 0
+	}
+	info.Remaining = math.Max(0, math.Floor(float64(limitNs-ahead)/intervalNs))
+	info.Reset = time.Duration(ahead)
+	return info
+}
@@ -0,0 +1,172 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucketChecker_DoCheck(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	// 10 tokens per second, at most 5 tokens in bucket.
+	tc := NewTokenBucketChecker(nil, 5, 1000)
+	threshold := 10.0
+
+	assert.True(t, tc.DoCheck(nil, 6, threshold).IsBlocked())
+	assert.True(t, tc.DoCheck(nil, 1, 0).IsBlocked())
+
+	// The bucket is full initially, so a burst of 5 passes.
+	assert.Nil(t, tc.DoCheck(nil, 3, threshold))
+	assert.Nil(t, tc.DoCheck(nil, 2, threshold))
+	res := tc.DoCheck(nil, 1, threshold)
+	if assert.NotNil(t, res) && assert.True(t, res.IsBlocked()) {
+		assert.Equal(t, 100*time.Millisecond, res.RetryAfter())
+		assert.Equal(t, BlockMsgTokenBucket, res.BlockError().BlockMsg())
+	}
+
+	util.Sleep(50 * time.Millisecond)
+	res = tc.DoCheck(nil, 2, threshold)
+	if assert.NotNil(t, res) && assert.True(t, res.IsBlocked()) {
+		assert.Equal(t, 150*time.Millisecond, res.RetryAfter())
+	}
+	util.Sleep(50 * time.Millisecond)
+	assert.Nil(t, tc.DoCheck(nil, 1, threshold))
+	assert.True(t, tc.DoCheck(nil, 1, threshold).IsBlocked())
+
+	// The tokens exceeding the capacity are discarded.
+	util.Sleep(10 * time.Second)
+	assert.Nil(t, tc.DoCheck(nil, 5, threshold))
+	assert.True(t, tc.DoCheck(nil, 1, threshold).IsBlocked())
+}
+
+func TestGCRAChecker_DoCheck(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	// One request every 100ms, at most 2 requests ahead of schedule.
+	tc := NewGCRAChecker(nil, 2, 1000)
+	threshold := 10.0
+
+	assert.True(t, tc.DoCheck(nil, 4, threshold).IsBlocked())
+	assert.True(t, tc.DoCheck(nil, 1, 0).IsBlocked())
+
+	assert.Nil(t, tc.DoCheck(nil, 1, threshold))
+	assert.Nil(t, tc.DoCheck(nil, 2, threshold))
+	res := tc.DoCheck(nil, 1, threshold)
+	if assert.NotNil(t, res) && assert.True(t, res.IsBlocked()) {
+		assert.Equal(t, 100*time.Millisecond, res.RetryAfter())
+		assert.Equal(t, BlockMsgGCRA, res.BlockError().BlockMsg())
+	}
+
+	util.Sleep(100 * time.Millisecond)
+	assert.Nil(t, tc.DoCheck(nil, 1, threshold))
+	assert.True(t, tc.DoCheck(nil, 1, threshold).IsBlocked())
+
+	util.Sleep(10 * time.Second)
+	assert.Nil(t, tc.DoCheck(nil, 3, threshold))
+	assert.True(t, tc.DoCheck(nil, 1, threshold).IsBlocked())
+}
+
+func TestTokenBucketChecker_DoCheckParallel(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	for _, tc := range []TrafficShapingChecker{NewTokenBucketChecker(nil, 100, 1000), NewGCRAChecker(nil, 99, 1000)} {
+		var passed int64
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					if r := tc.DoCheck(nil, 1, 1); r == nil {
+						atomic.AddInt64(&passed, 1)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int64(100), passed)
+	}
+}
+
+func TestLoadTokenBucketRules(t *testing.T) {
+	defer ClearRules()
+
+	assert.NotNil(t, IsValidRule(&Rule{Resource: "abc", Threshold: 10, ControlBehavior: TokenBucket}))
+	_, err := LoadRules([]*Rule{
+		{Resource: "abc", Threshold: 10, ControlBehavior: TokenBucket, BurstSize: 5},
+		{Resource: "abc", Threshold: 10, ControlBehavior: GCRA},
+	})
+	assert.Nil(t, err)
+	tcs := getTrafficControllerListFor("abc")
+	if assert.Len(t, tcs, 2) {
+		assert.IsType(t, &TokenBucketChecker{}, tcs[0].FlowChecker())
+		assert.IsType(t, &GCRAChecker{}, tcs[1].FlowChecker())
+	}
+
+	r := tcs[1].PerformChecking(nil, 1, 0)
+	assert.Nil(t, r)
+	r = tcs[1].PerformChecking(nil, 1, 0)
+	if assert.NotNil(t, r) {
+		assert.Equal(t, base.ResultStatusBlocked, r.Status())
+		assert.True(t, r.RetryAfter() > 0)
+	}
+}
+
+func TestTokenReserver_DoReserve(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	rule := &Rule{
+		Resource:          "abc",
+		ControlBehavior:   TokenBucket,
+		Threshold:         10,
+		BurstSize:         2,
+		MaxQueueingTimeMs: 300,
+	}
+	owner := &TrafficShapingController{rule: rule}
+	for _, tc := range []TokenReserver{NewTokenBucketChecker(owner, 2, 1000), NewGCRAChecker(owner, 1, 1000)} {
+		// The burst passes without waiting.
+		assert.Nil(t, tc.DoReserve(nil, 2, rule.Threshold))
+		// The tokens in the future are reserved within MaxQueueingTimeMs.
+		res := tc.DoReserve(nil, 2, rule.Threshold)
+		if assert.NotNil(t, res) {
+			assert.Equal(t, base.ResultStatusShouldWait, res.Status())
+			assert.Equal(t, 200*time.Millisecond, res.NanosToWait())
+		}
+		res = tc.DoReserve(nil, 2, rule.Threshold)
+		if assert.NotNil(t, res) && assert.True(t, res.IsBlocked()) {
+			assert.Equal(t, 100*time.Millisecond, res.RetryAfter())
+		}
+
+		// The cancelled tokens are taken back.
+		tc.CancelReservation(2, rule.Threshold)
+		res = tc.DoReserve(nil, 1, rule.Threshold)
+		if assert.NotNil(t, res) {
+			assert.Equal(t, 100*time.Millisecond, res.NanosToWait())
+		}
+		util.Sleep(time.Second)
+	}
+}
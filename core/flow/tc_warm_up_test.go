@@ -0,0 +1,80 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmUpThrottlingPacing(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	rule := &Rule{
+		Resource:               "abc-warm-up-throttling",
+		TokenCalculateStrategy: WarmUp,
+		ControlBehavior:        Throttling,
+		Threshold:              100,
+		WarmUpPeriodSec:        10,
+		WarmUpColdFactor:       3,
+		MaxQueueingTimeMs:      1000,
+		StatIntervalInMs:       1000,
+	}
+	generator := tcGenFuncMap[trafficControllerGenKey{tokenCalculateStrategy: WarmUp, controlBehavior: Throttling}]
+	tc, err := generator(rule, nil)
+	assert.Nil(t, err)
+	calculator := tc.FlowCalculator().(*WarmUpTrafficShapingCalculator)
+
+	// The resource is cold at the beginning, so the requests are paced at Threshold/ColdFactor,
+	// i.e. the interval is 30ms instead of 10ms.
+	assert.Nil(t, tc.PerformChecking(nil, 1, 0))
+	r := tc.PerformChecking(nil, 1, 0)
+	if assert.NotNil(t, r) && assert.Equal(t, base.ResultStatusShouldWait, r.Status()) {
+		assert.InDelta(t, float64(30*time.Millisecond), float64(r.NanosToWait()), float64(time.Millisecond))
+	}
+
+	// The interval shrinks along the warm-up curve as the stored tokens are consumed.
+	util.Sleep(time.Second)
+	syncFilledTime(calculator)
+	midToken := (calculator.warningToken + calculator.maxToken) / 2
+	atomic.StoreInt64(&calculator.storedTokens, int64(midToken))
+	assert.Nil(t, tc.PerformChecking(nil, 1, 0))
+	r = tc.PerformChecking(nil, 1, 0)
+	if assert.NotNil(t, r) && assert.Equal(t, base.ResultStatusShouldWait, r.Status()) {
+		assert.InDelta(t, float64(20*time.Millisecond), float64(r.NanosToWait()), float64(time.Millisecond))
+	}
+
+	// The requests are paced at Threshold once warmed up.
+	util.Sleep(time.Second)
+	syncFilledTime(calculator)
+	atomic.StoreInt64(&calculator.storedTokens, int64(calculator.warningToken))
+	assert.Nil(t, tc.PerformChecking(nil, 1, 0))
+	r = tc.PerformChecking(nil, 1, 0)
+	if assert.NotNil(t, r) && assert.Equal(t, base.ResultStatusShouldWait, r.Status()) {
+		assert.InDelta(t, float64(10*time.Millisecond), float64(r.NanosToWait()), float64(time.Millisecond))
+	}
+}
+
+// syncFilledTime marks the stored tokens of calculator as synced in current second, so the tokens set by test won't be refilled.
+func syncFilledTime(c *WarmUpTrafficShapingCalculator) {
+	now := util.CurrentTimeMillis()
+	atomic.StoreUint64(&c.lastFilledTime, now-now%1000)
+}
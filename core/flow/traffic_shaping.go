@@ -16,6 +16,7 @@ package flow
 
 import (
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/stat"
 	metric_exporter "github.com/alibaba/sentinel-golang/exporter/metric"
 )
 
@@ -44,6 +45,24 @@ type TrafficShapingChecker interface {
 	DoCheck(resStat base.StatNode, batchCount uint32, threshold float64) *base.TokenResult
 }
 
+// RateLimitInfoProvider is the optional interface of TrafficShapingChecker, which reports the quota state
+// of rule after checking, so that the rate limit headers could be written.
+type RateLimitInfoProvider interface {
+	// RateLimitInfo returns the quota state after checking batchCount with the given threshold,
+	// passed indicates whether the checking passed.
+	RateLimitInfo(resStat base.StatNode, batchCount uint32, threshold float64, passed bool) base.RateLimitInfo
+}
+
+// TokenReserver is the optional interface of TrafficShapingChecker, which reserves the tokens available
+// in the future rather than blocking, and takes back the tokens of cancelled reservations.
+type TokenReserver interface {
+	// DoReserve reserves batchCount tokens with the given threshold, and yields the ShouldWait result with the
+	// duration until the tokens are available, or the blocked result if the tokens could not be reserved.
+	DoReserve(resStat base.StatNode, batchCount uint32, threshold float64) *base.TokenResult
+	// CancelReservation takes back batchCount tokens reserved with the given threshold.
+	CancelReservation(batchCount uint32, threshold float64)
+}
+
 // standaloneStatistic indicates the independent statistic for each TrafficShapingController
 type standaloneStatistic struct {
 	// reuseResourceStat indicates whether current standaloneStatistic reuse the current resource's global statistic
@@ -56,6 +75,16 @@ type standaloneStatistic struct {
 	// if reuseResourceStat is true, it would be nil
 	// if reuseResourceStat is false, it would be the BucketLeapArray
 	writeOnlyMetric base.WriteStat
+	// resNode is the resource node which the statistic is generated against,
+	// the statistic of the node is reused if reuseResourceStat is true.
+	resNode *stat.ResourceNode
+}
+
+// isStale reports whether the resource node has been replaced, e.g. recreated due to the change
+// of the statistic config of the resource or evicted when idle, so the statistic must be regenerated.
+func (s *standaloneStatistic) isStale() bool {
+	return s != nil && s.resNode != nil && s.resNode != stat.OverflowNode() &&
+		stat.GetResourceNode(s.resNode.ResourceName()) != s.resNode
 }
 
 type TrafficShapingController struct {
@@ -65,6 +94,8 @@ type TrafficShapingController struct {
 	rule *Rule
 	// boundStat is the statistic of current TrafficShapingController
 	boundStat standaloneStatistic
+	// labels holds the controllers of label sets if the rule has LabelMatchers, otherwise nil.
+	labels *labelControllers
 }
 
 func NewTrafficShapingController(rule *Rule, boundStat *standaloneStatistic) (*TrafficShapingController, error) {
@@ -83,6 +114,19 @@ func (t *TrafficShapingController) FlowCalculator() TrafficShapingCalculator {
 	return t.flowCalculator
 }
 
+// controllerFor returns the controller checking the entry. For the rule with LabelMatchers, it's the controller
+// of the label set of entry, or nil if the entry doesn't match the rule.
+func (t *TrafficShapingController) controllerFor(ctx *base.EntryContext) *TrafficShapingController {
+	if t.labels == nil {
+		return t
+	}
+	labelSet, ok := t.labels.labelSetOf(ctx)
+	if !ok {
+		return nil
+	}
+	return t.labels.controllerOf(labelSet)
+}
+
 func (t *TrafficShapingController) PerformChecking(resStat base.StatNode, batchCount uint32, flag int32) *base.TokenResult {
 	allowedTokens := t.flowCalculator.CalculateAllowedTokens(batchCount, flag)
 
@@ -90,3 +134,39 @@ func (t *TrafficShapingController) PerformChecking(resStat base.StatNode, batchC
 
 	return t.flowChecker.DoCheck(resStat, batchCount, allowedTokens)
 }
+
+// performCheckingWithRateLimitInfo performs checking like PerformChecking, and returns the quota state of rule
+// if the checker implements RateLimitInfoProvider.
+func (t *TrafficShapingController) performCheckingWithRateLimitInfo(resStat base.StatNode, batchCount uint32, flag int32) (*base.TokenResult, *base.RateLimitInfo) {
+	allowedTokens := t.flowCalculator.CalculateAllowedTokens(batchCount, flag)
+
+	resourceFlowThresholdGauge.Set(float64(allowedTokens), t.rule.Resource)
+
+	r := t.flowChecker.DoCheck(resStat, batchCount, allowedTokens)
+	provider, ok := t.flowChecker.(RateLimitInfoProvider)
+	if !ok {
+		return r, nil
+	}
+	info := provider.RateLimitInfo(resStat, batchCount, allowedTokens, r == nil || !r.IsBlocked())
+	return r, &info
+}
+
+// performReserving reserves the tokens if the checker implements TokenReserver, otherwise it performs checking
+// like PerformChecking. The returned function cancels the reservation, which is nil if nothing could be taken back.
+func (t *TrafficShapingController) performReserving(resStat base.StatNode, batchCount uint32, flag int32) (*base.TokenResult, func()) {
+	allowedTokens := t.flowCalculator.CalculateAllowedTokens(batchCount, flag)
+
+	resourceFlowThresholdGauge.Set(float64(allowedTokens), t.rule.Resource)
+
+	reserver, ok := t.flowChecker.(TokenReserver)
+	if !ok {
+		return t.flowChecker.DoCheck(resStat, batchCount, allowedTokens), nil
+	}
+	r := reserver.DoReserve(resStat, batchCount, allowedTokens)
+	if r != nil && r.IsBlocked() {
+		return r, nil
+	}
+	return r, func() {
+		reserver.CancelReservation(batchCount, allowedTokens)
+	}
+}
@@ -22,6 +22,8 @@ type ConcurrentCounterCache interface {
 
 	// If the key is not existed in the cache, adds a value to the cache then return nil. And updates the "recently used"-ness of the key
 	// If the key is already existed in the cache, do nothing and return the prior value
+	// A nil return always means the value is added, the cache never rejects the new key itself,
+	// it may only be evicted by the later additions.
 	AddIfAbsent(key interface{}, value *int64) (priorValue *int64)
 
 	// Get returns key's value from the cache and updates the "recently used"-ness of the key.
@@ -0,0 +1,163 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+const (
+	// maxShardCount is the upper bound of the shard count.
+	maxShardCount = 64
+	// minShardCapacity is the minimum capacity of each shard, small caches use fewer shards
+	// to keep the frequency estimation meaningful.
+	minShardCapacity = 128
+)
+
+type tinyLfuShard struct {
+	// Not thread safe
+	tinyLfu *TinyLFU
+	lock    sync.Mutex
+}
+
+// TinyLfuCacheMap is a sharded, scan-resistant ConcurrentCounterCache based on W-TinyLFU.
+// Keys are distributed to shards by hash, each shard holds an independent lock,
+// so that high-cardinality parameters neither contend on one lock nor flush the real hot keys.
+type TinyLfuCacheMap struct {
+	shards []*tinyLfuShard
+	// shift selects the high bits of the hash as the shard index, since the count-min sketch
+	// of each shard indexes by the low bits.
+	shift uint
+}
+
+func (c *TinyLfuCacheMap) shardOf(key interface{}) *tinyLfuShard {
+	return c.shards[hashKey(key)>>c.shift]
+}
+
+func (c *TinyLfuCacheMap) Add(key interface{}, value *int64) {
+	s := c.shardOf(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tinyLfu.Add(key, value)
+}
+
+func (c *TinyLfuCacheMap) AddIfAbsent(key interface{}, value *int64) (priorValue *int64) {
+	s := c.shardOf(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	val := s.tinyLfu.AddIfAbsent(key, value)
+	if val == nil {
+		return nil
+	}
+	priorValue = val.(*int64)
+	return
+}
+
+func (c *TinyLfuCacheMap) Get(key interface{}) (value *int64, isFound bool) {
+	s := c.shardOf(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	val, found := s.tinyLfu.Get(key)
+	if found {
+		return val.(*int64), true
+	}
+	return nil, false
+}
+
+func (c *TinyLfuCacheMap) Remove(key interface{}) (isFound bool) {
+	s := c.shardOf(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.tinyLfu.Remove(key)
+}
+
+func (c *TinyLfuCacheMap) Contains(key interface{}) (ok bool) {
+	s := c.shardOf(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.tinyLfu.Contains(key)
+}
+
+// Keys returns a slice of the keys in the cache, the keys are ordered from oldest to newest within each shard.
+func (c *TinyLfuCacheMap) Keys() []interface{} {
+	keys := make([]interface{}, 0, c.Len())
+	for _, s := range c.shards {
+		s.lock.Lock()
+		keys = append(keys, s.tinyLfu.Keys()...)
+		s.lock.Unlock()
+	}
+	return keys
+}
+
+func (c *TinyLfuCacheMap) Len() int {
+	l := 0
+	for _, s := range c.shards {
+		s.lock.Lock()
+		l += s.tinyLfu.Len()
+		s.lock.Unlock()
+	}
+	return l
+}
+
+func (c *TinyLfuCacheMap) Purge() {
+	for _, s := range c.shards {
+		s.lock.Lock()
+		s.tinyLfu.Purge()
+		s.lock.Unlock()
+	}
+}
+
+// shardCount returns a power of two shard count according to GOMAXPROCS and the total capacity.
+func shardCount(size int) int {
+	n := 1
+	for n < runtime.GOMAXPROCS(0)*4 && n < maxShardCount {
+		n <<= 1
+	}
+	for n > 1 && size/n < minShardCapacity {
+		n >>= 1
+	}
+	return n
+}
+
+func NewTinyLfuCacheMap(size int) ConcurrentCounterCache {
+	if size <= 0 {
+		return nil
+	}
+	n := shardCount(size)
+	shards := make([]*tinyLfuShard, n)
+	for i := 0; i < n; i++ {
+		// Spread the remainder so that the total capacity equals to size.
+		shardSize := size / n
+		if i < size%n {
+			shardSize++
+		}
+		tinyLfu, err := NewTinyLFU(shardSize, nil)
+		if err != nil {
+			return nil
+		}
+		shards[i] = &tinyLfuShard{tinyLfu: tinyLfu}
+	}
+	return &TinyLfuCacheMap{
+		shards: shards,
+		shift:  uint(64 - bits.TrailingZeros(uint(n))),
+	}
+}
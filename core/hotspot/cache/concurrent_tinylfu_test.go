@@ -0,0 +1,223 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_concurrentTinyLfuCounterCacheMap_Add_Get(t *testing.T) {
+	t.Run("Test_concurrentTinyLfuCounterCacheMap_Add_Get", func(t *testing.T) {
+		c := NewTinyLfuCacheMap(100)
+		for i := 1; i <= 100; i++ {
+			val := int64(i)
+			c.Add(strconv.Itoa(i), &val)
+		}
+		assert.True(t, c.Len() == 100)
+		val, found := c.Get("1")
+		assert.True(t, found && *val == 1)
+
+		newVal := int64(1000)
+		c.Add("1", &newVal)
+		val, found = c.Get("1")
+		assert.True(t, found && *val == 1000)
+		assert.True(t, c.Len() == 100)
+	})
+}
+
+func Test_concurrentTinyLfuCounterCacheMap_AddIfAbsent(t *testing.T) {
+	t.Run("Test_concurrentTinyLfuCounterCacheMap_AddIfAbsent", func(t *testing.T) {
+		c := NewTinyLfuCacheMap(100)
+		for i := 1; i <= 99; i++ {
+			val := int64(i)
+			c.Add(strconv.Itoa(i), &val)
+		}
+		v := int64(100)
+		prior := c.AddIfAbsent("100", &v)
+		assert.True(t, prior == nil)
+		v2 := int64(1000)
+		prior2 := c.AddIfAbsent("100", &v2)
+		assert.True(t, *prior2 == 100)
+	})
+}
+
+func Test_concurrentTinyLfuCounterCacheMap_Remove_Purge(t *testing.T) {
+	t.Run("Test_concurrentTinyLfuCounterCacheMap_Remove_Purge", func(t *testing.T) {
+		c := NewTinyLfuCacheMap(100)
+		for i := 1; i <= 100; i++ {
+			val := int64(i)
+			c.Add(strconv.Itoa(i), &val)
+		}
+		assert.True(t, c.Contains("100"))
+		assert.True(t, c.Remove("100"))
+		assert.False(t, c.Remove("100"))
+		assert.True(t, c.Len() == 99)
+		assert.True(t, len(c.Keys()) == 99)
+		val, existed := c.Get("100")
+		assert.True(t, existed == false && val == nil)
+
+		c.Purge()
+		assert.True(t, c.Len() == 0)
+	})
+}
+
+func Test_concurrentTinyLfuCounterCacheMap_Capacity(t *testing.T) {
+	t.Run("Test_concurrentTinyLfuCounterCacheMap_Capacity", func(t *testing.T) {
+		size := 1000
+		c := NewTinyLfuCacheMap(size)
+		for i := 0; i < size*10; i++ {
+			val := int64(i)
+			c.Add(i, &val)
+		}
+		assert.True(t, c.Len() == size)
+	})
+}
+
+func Test_concurrentTinyLfuCounterCacheMap_ShardSketchIndex(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	c := NewTinyLfuCacheMap(4096).(*TinyLfuCacheMap)
+	assert.Equal(t, 16, len(c.shards))
+	s := c.shards[0]
+	// the keys of one shard must spread across all the counters of the row 0 of the sketch
+	used := make(map[uint64]struct{})
+	for i := 0; i < 100000; i++ {
+		if c.shardOf(i) != s {
+			continue
+		}
+		used[s.tinyLfu.sketch.index(hashKey(i), 0)] = struct{}{}
+	}
+	width := int(s.tinyLfu.sketch.mask + 1)
+	assert.True(t, len(used) > width/2, "used %d of %d counters", len(used), width)
+}
+
+func Test_concurrentTinyLfuCounterCacheMap_Concurrent(t *testing.T) {
+	size := 2000
+	c := NewTinyLfuCacheMap(size)
+	wg := &sync.WaitGroup{}
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 10000; i++ {
+				val := int64(i)
+				c.AddIfAbsent(g*10000+i, &val)
+				c.Get(i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.True(t, c.Len() <= size)
+}
+
+func TestTinyLFU_ScanResistant(t *testing.T) {
+	c, err := NewTinyLFU(100, nil)
+	assert.Nil(t, err)
+	// Warm up the hot keys.
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 50; i++ {
+			c.Add("hot"+strconv.Itoa(i), i)
+		}
+	}
+	// Scan with a large number of one-off keys.
+	for i := 0; i < 10000; i++ {
+		c.Add("scan"+strconv.Itoa(i), i)
+	}
+	hits := 0
+	for i := 0; i < 50; i++ {
+		if c.Contains("hot" + strconv.Itoa(i)) {
+			hits++
+		}
+	}
+	assert.True(t, hits >= 45, "hot keys should survive the scan, hits: %d", hits)
+	assert.True(t, c.Len() == 100)
+
+	lru, _ := NewLRU(100, nil)
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 50; i++ {
+			lru.Add("hot"+strconv.Itoa(i), i)
+		}
+	}
+	for i := 0; i < 10000; i++ {
+		lru.Add("scan"+strconv.Itoa(i), i)
+	}
+	assert.False(t, lru.Contains("hot0"))
+}
+
+func TestTinyLFU_AddIfAbsent_Admitted(t *testing.T) {
+	for _, size := range []int{1, 10, 100} {
+		c, err := NewTinyLFU(size, nil)
+		assert.Nil(t, err)
+		// Warm up the hot keys, so that the one-off keys lose the admission.
+		for round := 0; round < 5; round++ {
+			for i := 0; i < size; i++ {
+				c.Add("hot"+strconv.Itoa(i), i)
+			}
+		}
+		for i := 0; i < 1000; i++ {
+			key := "scan" + strconv.Itoa(i)
+			assert.Nil(t, c.AddIfAbsent(key, i))
+			// The nil return means the value is added, even if it's evicted by the later additions.
+			v, found := c.Peek(key)
+			assert.True(t, found, "size: %d, key: %s", size, key)
+			assert.Equal(t, i, v)
+		}
+		assert.True(t, c.Len() == size)
+	}
+}
+
+func TestTinyLFU_Evict(t *testing.T) {
+	evicted := 0
+	c, err := NewTinyLFU(10, func(key interface{}, value interface{}) {
+		evicted++
+	})
+	assert.Nil(t, err)
+	for i := 0; i < 20; i++ {
+		c.Add(i, i)
+	}
+	assert.True(t, c.Len() == 10)
+	assert.True(t, evicted == 10)
+
+	_, err = NewTinyLFU(0, nil)
+	assert.NotNil(t, err)
+}
+
+func TestCountMinSketch(t *testing.T) {
+	s := newCountMinSketch(16)
+	h := hashKey("a")
+	for i := 0; i < 20; i++ {
+		s.increment(h)
+	}
+	assert.True(t, s.estimate(h) == sketchMaxCount)
+	assert.True(t, s.estimate(hashKey("b")) == 0)
+	s.reset()
+	assert.True(t, s.estimate(h) == sketchMaxCount/2)
+	s.clear()
+	assert.True(t, s.estimate(h) == 0)
+}
+
+func TestHashKey(t *testing.T) {
+	assert.Equal(t, hashKey("abc"), hashKey("abc"))
+	assert.NotEqual(t, hashKey("abc"), hashKey("abd"))
+	assert.Equal(t, hashKey(1), hashKey(int64(1)))
+	assert.NotEqual(t, hashKey(1), hashKey(2))
+	type customKey struct{ a int }
+	assert.Equal(t, hashKey(customKey{1}), hashKey(customKey{1}))
+}
@@ -0,0 +1,410 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// windowPercent is the percentage of capacity used by the admission window.
+	windowPercent = 1
+	// protectedPercent is the percentage of the main space used by the protected segment.
+	protectedPercent = 80
+	// sketchDepth is the number of hash rows of the count-min sketch.
+	sketchDepth = 4
+	// sketchMaxCount is the saturation value of a sketch counter (4 bits).
+	sketchMaxCount = 15
+	// sketchWidthFactor decides the counters of each sketch row relative to the capacity,
+	// wider rows reduce the over-estimation caused by hash collisions of one-off keys.
+	sketchWidthFactor = 8
+	// sketchResetFactor decides how many samples trigger the aging of the sketch.
+	sketchResetFactor = 10
+)
+
+type segment uint8
+
+const (
+	windowSegment segment = iota
+	probationSegment
+	protectedSegment
+)
+
+// tinyLfuEntry is used to hold a value in the segment lists
+type tinyLfuEntry struct {
+	key     interface{}
+	value   interface{}
+	keyHash uint64
+	segment segment
+}
+
+// TinyLFU implements a non-thread safe fixed size W-TinyLFU cache.
+//
+// New entries are admitted into a small LRU window. Candidates evicted from the window must
+// compete with the victim of the main segmented LRU, and only the one with higher estimated
+// access frequency is retained. This keeps the frequently accessed keys from being flushed
+// by a large number of one-off keys.
+type TinyLFU struct {
+	size         int
+	windowSize   int
+	protectedCap int
+	window       *list.List
+	probation    *list.List
+	protected    *list.List
+	items        map[interface{}]*list.Element
+	sketch       *countMinSketch
+	onEvict      EvictCallback
+}
+
+// NewTinyLFU constructs a TinyLFU of the given size
+func NewTinyLFU(size int, onEvict EvictCallback) (*TinyLFU, error) {
+	if size <= 0 {
+		return nil, errors.New("must provide a positive size")
+	}
+	windowSize := size * windowPercent / 100
+	if windowSize < 1 {
+		windowSize = 1
+	}
+	c := &TinyLFU{
+		size:         size,
+		windowSize:   windowSize,
+		protectedCap: (size - windowSize) * protectedPercent / 100,
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		items:        make(map[interface{}]*list.Element, 64),
+		sketch:       newCountMinSketch(size),
+		onEvict:      onEvict,
+	}
+	return c, nil
+}
+
+// Purge is used to completely clear the cache.
+func (c *TinyLFU) Purge() {
+	for k, v := range c.items {
+		if c.onEvict != nil {
+			c.onEvict(k, v.Value.(*tinyLfuEntry).value)
+		}
+		delete(c.items, k)
+	}
+	c.window.Init()
+	c.probation.Init()
+	c.protected.Init()
+	c.sketch.clear()
+}
+
+// Add adds a value to the cache, or updates the value if the key is already existed.
+func (c *TinyLFU) Add(key, value interface{}) {
+	h := hashKey(key)
+	c.sketch.increment(h)
+	if ent, ok := c.items[key]; ok {
+		ent.Value.(*tinyLfuEntry).value = value
+		c.onHit(ent)
+		return
+	}
+	c.addNew(key, value, h)
+}
+
+// AddIfAbsent adds item only if key is not existed, returns nil if added.
+// The new key is always admitted into the window, the admission policy only picks
+// the entry to evict between the candidate from the window and the victim of probation.
+func (c *TinyLFU) AddIfAbsent(key interface{}, value interface{}) (priorValue interface{}) {
+	h := hashKey(key)
+	c.sketch.increment(h)
+	if ent, ok := c.items[key]; ok {
+		c.onHit(ent)
+		return ent.Value.(*tinyLfuEntry).value
+	}
+	c.addNew(key, value, h)
+	return nil
+}
+
+// Get looks up a key's value from the cache.
+func (c *TinyLFU) Get(key interface{}) (value interface{}, isFound bool) {
+	h := hashKey(key)
+	c.sketch.increment(h)
+	if ent, ok := c.items[key]; ok {
+		c.onHit(ent)
+		return ent.Value.(*tinyLfuEntry).value, true
+	}
+	return
+}
+
+// Contains checks if a key is in the cache, without updating the frequency or recent-ness.
+func (c *TinyLFU) Contains(key interface{}) (ok bool) {
+	_, ok = c.items[key]
+	return ok
+}
+
+// Peek returns the key value (or undefined if not found) without updating
+// the frequency or recent-ness of the key.
+func (c *TinyLFU) Peek(key interface{}) (value interface{}, isFound bool) {
+	var ent *list.Element
+	if ent, isFound = c.items[key]; isFound {
+		return ent.Value.(*tinyLfuEntry).value, isFound
+	}
+	return nil, isFound
+}
+
+// Remove removes the provided key from the cache, returning if the
+// key was contained.
+func (c *TinyLFU) Remove(key interface{}) (isFound bool) {
+	var ent *list.Element
+	if ent, isFound = c.items[key]; isFound {
+		c.removeElement(ent)
+	}
+	return
+}
+
+// Keys returns a slice of the keys in the cache, from oldest to newest.
+// The protected segment is considered older than the probation segment,
+// and the probation segment is considered older than the window.
+func (c *TinyLFU) Keys() []interface{} {
+	keys := make([]interface{}, 0, len(c.items))
+	for _, l := range []*list.List{c.protected, c.probation, c.window} {
+		for ent := l.Back(); ent != nil; ent = ent.Prev() {
+			keys = append(keys, ent.Value.(*tinyLfuEntry).key)
+		}
+	}
+	return keys
+}
+
+// Len returns the number of items in the cache.
+func (c *TinyLFU) Len() int {
+	return len(c.items)
+}
+
+// Frequency returns the estimated access frequency of the key.
+func (c *TinyLFU) Frequency(key interface{}) int {
+	return int(c.sketch.estimate(hashKey(key)))
+}
+
+func (c *TinyLFU) listOf(s segment) *list.List {
+	switch s {
+	case windowSegment:
+		return c.window
+	case probationSegment:
+		return c.probation
+	default:
+		return c.protected
+	}
+}
+
+func (c *TinyLFU) onHit(ent *list.Element) {
+	e := ent.Value.(*tinyLfuEntry)
+	switch e.segment {
+	case windowSegment:
+		c.window.MoveToFront(ent)
+	case probationSegment:
+		// Promote to the protected segment, demote the oldest protected entry if overflowed.
+		c.probation.Remove(ent)
+		e.segment = protectedSegment
+		c.items[e.key] = c.protected.PushFront(e)
+		if c.protected.Len() > c.protectedCap {
+			if oldest := c.protected.Back(); oldest != nil {
+				oe := c.protected.Remove(oldest).(*tinyLfuEntry)
+				oe.segment = probationSegment
+				c.items[oe.key] = c.probation.PushFront(oe)
+			}
+		}
+	case protectedSegment:
+		c.protected.MoveToFront(ent)
+	}
+}
+
+func (c *TinyLFU) addNew(key, value interface{}, h uint64) {
+	c.items[key] = c.window.PushFront(&tinyLfuEntry{key: key, value: value, keyHash: h, segment: windowSegment})
+	if c.window.Len() <= c.windowSize {
+		return
+	}
+	// Move the oldest entry of window into the probation segment as candidate.
+	candidateEnt := c.window.Back()
+	candidate := c.window.Remove(candidateEnt).(*tinyLfuEntry)
+	candidate.segment = probationSegment
+	candidateEnt = c.probation.PushFront(candidate)
+	c.items[candidate.key] = candidateEnt
+	if len(c.items) <= c.size {
+		return
+	}
+
+	victimEnt := c.probation.Back()
+	if victimEnt == candidateEnt {
+		// Probation segment is empty except the candidate, so evict from protected segment.
+		if victimEnt = c.protected.Back(); victimEnt == nil {
+			c.removeElement(candidateEnt)
+			return
+		}
+	}
+	victim := victimEnt.Value.(*tinyLfuEntry)
+	if c.sketch.estimate(candidate.keyHash) > c.sketch.estimate(victim.keyHash) {
+		c.removeElement(victimEnt)
+	} else {
+		c.removeElement(candidateEnt)
+	}
+}
+
+// removeElement is used to remove a given list element from the cache
+func (c *TinyLFU) removeElement(ent *list.Element) {
+	e := ent.Value.(*tinyLfuEntry)
+	c.listOf(e.segment).Remove(ent)
+	delete(c.items, e.key)
+	if c.onEvict != nil {
+		c.onEvict(e.key, e.value)
+	}
+}
+
+// countMinSketch is a 4-bit count-min sketch with periodic aging,
+// used to estimate the access frequency of keys.
+type countMinSketch struct {
+	rows       [sketchDepth][]uint8
+	mask       uint64
+	additions  int
+	resetLimit int
+}
+
+func newCountMinSketch(size int) *countMinSketch {
+	width := 1 << bits.Len(uint(size*sketchWidthFactor-1))
+	if width < 16 {
+		width = 16
+	}
+	s := &countMinSketch{
+		mask:       uint64(width - 1),
+		resetLimit: sketchResetFactor * size,
+	}
+	for i := 0; i < sketchDepth; i++ {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *countMinSketch) index(h uint64, row int) uint64 {
+	// Double hashing to derive an independent index for each row.
+	return (h + uint64(row)*((h>>32)|1)) & s.mask
+}
+
+func (s *countMinSketch) increment(h uint64) {
+	added := false
+	for i := 0; i < sketchDepth; i++ {
+		idx := s.index(h, i)
+		if s.rows[i][idx] < sketchMaxCount {
+			s.rows[i][idx]++
+			added = true
+		}
+	}
+	if added {
+		s.additions++
+		if s.additions >= s.resetLimit {
+			s.reset()
+		}
+	}
+}
+
+func (s *countMinSketch) estimate(h uint64) uint8 {
+	min := uint8(math.MaxUint8)
+	for i := 0; i < sketchDepth; i++ {
+		if v := s.rows[i][s.index(h, i)]; v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// reset halves all counters so that the historic popularity decays.
+func (s *countMinSketch) reset() {
+	for i := 0; i < sketchDepth; i++ {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+func (s *countMinSketch) clear() {
+	for i := 0; i < sketchDepth; i++ {
+		for j := range s.rows[i] {
+			s.rows[i][j] = 0
+		}
+	}
+	s.additions = 0
+}
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+func hashString(s string) uint64 {
+	h := uint64(fnvOffset64)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime64
+	}
+	return h
+}
+
+func hashUint64(v uint64) uint64 {
+	// The finalizer of splitmix64.
+	v ^= v >> 30
+	v *= 0xbf58476d1ce4e5b9
+	v ^= v >> 27
+	v *= 0x94d049bb133111eb
+	v ^= v >> 31
+	return v
+}
+
+// hashKey hashes the hotspot parameter. The common parameter kinds are hashed directly,
+// other kinds fall back to hashing their string representation.
+func hashKey(key interface{}) uint64 {
+	switch k := key.(type) {
+	case string:
+		return hashString(k)
+	case int:
+		return hashUint64(uint64(k))
+	case int8:
+		return hashUint64(uint64(k))
+	case int16:
+		return hashUint64(uint64(k))
+	case int32:
+		return hashUint64(uint64(k))
+	case int64:
+		return hashUint64(uint64(k))
+	case uint:
+		return hashUint64(uint64(k))
+	case uint8:
+		return hashUint64(uint64(k))
+	case uint16:
+		return hashUint64(uint64(k))
+	case uint32:
+		return hashUint64(uint64(k))
+	case uint64:
+		return hashUint64(k)
+	case float32:
+		return hashUint64(uint64(math.Float32bits(k)))
+	case float64:
+		return hashUint64(math.Float64bits(k))
+	case bool:
+		if k {
+			return hashUint64(1)
+		}
+		return hashUint64(0)
+	default:
+		return hashString(fmt.Sprintf("%T:%v", key, key))
+	}
+}
@@ -18,6 +18,10 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
+	"github.com/alibaba/sentinel-golang/core/schedule"
 )
 
 // ControlBehavior indicates the traffic shaping behaviour.
@@ -60,12 +64,37 @@ func (t MetricType) String() string {
 	}
 }
 
+// CacheType indicates the cache strategy of the hotspot parameter statistic.
+type CacheType int32
+
+const (
+	// LRUCache evicts the least recently used parameter, guarded by a single lock.
+	LRUCache CacheType = iota
+	// TinyLFUCache is a sharded W-TinyLFU cache, which is resistant to high-cardinality parameter scans
+	// and keeps the frequently accessed parameters.
+	TinyLFUCache
+)
+
+func (t CacheType) String() string {
+	switch t {
+	case LRUCache:
+		return "LRU"
+	case TinyLFUCache:
+		return "TinyLFU"
+	default:
+		return strconv.Itoa(int(t))
+	}
+}
+
 // Rule represents the hotspot(frequent) parameter flow control rule
 type Rule struct {
 	// ID is the unique id
 	ID string `json:"id,omitempty"`
 	// Resource is the resource name
 	Resource string `json:"resource"`
+	// MatchStrategy indicates how Resource matches the resource names. For the strategies other than ExactMatch,
+	// Resource is the pattern of the names and each matched resource has its own parameter statistic.
+	MatchStrategy base.ResourceMatchStrategy `json:"matchStrategy,omitempty"`
 	// MetricType indicates the metric type for checking logic.
 	// For Concurrency metric, hotspot module will check the each hot parameter's concurrency,
 	//		if concurrency exceeds the Threshold, reject the traffic directly.
@@ -95,8 +124,32 @@ type Rule struct {
 	DurationInSec int64 `json:"durationInSec"`
 	// ParamsMaxCapacity is the max capacity of cache statistic
 	ParamsMaxCapacity int64 `json:"paramsMaxCapacity"`
+	// CacheType is the cache strategy of the statistic of parameters, LRUCache by default.
+	CacheType CacheType `json:"cacheType"`
 	// SpecificItems indicates the special threshold for specific value
 	SpecificItems map[interface{}]int64 `json:"specificItems"`
+	// Schedule overrides Threshold in the scheduled time windows, SpecificItems are not affected.
+	Schedule *schedule.Schedule `json:"schedule,omitempty"`
+	// Mode indicates whether the rule blocks the requests (Enforce) or only records the would-be blocked ones (Shadow).
+	Mode base.RuleMode `json:"mode,omitempty"`
+	// EnforcePercent is the percentage of traffic the rule is enforced on, the valid range is [0.0, 100.0] and 0 means all the traffic.
+	// The rest of the traffic is checked in the Shadow mode.
+	EnforcePercent float64 `json:"enforcePercent,omitempty"`
+	// EnforceKey is the attachment key whose value selects the enforced traffic by hash, the traffic is selected randomly if empty.
+	EnforceKey string `json:"enforceKey,omitempty"`
+	// Canary selects the instances the rule is enforced on, nil means all the instances.
+	Canary *base.CanarySelector `json:"canary,omitempty"`
+	// Condition is the optional expression the rule only takes effect when it holds, e.g. `attachments.caller != "internal"`,
+	// see package condition for the syntax.
+	Condition string `json:"condition,omitempty"`
+	// conditionProgram is the compiled Condition, which is compiled when the rule is loaded.
+	conditionProgram *condition.Program
+	// ExpireAt is the Unix timestamp in milliseconds when the rule expires and is dropped automatically, 0 means never.
+	ExpireAt uint64 `json:"expireAt,omitempty"`
+	// TTLSec is the lifetime in seconds of the rule since it's loaded, 0 means forever.
+	// If both ExpireAt and TTLSec are set, the earlier one takes effect.
+	// A rule expired by TTLSec comes back with a new lifetime if it's loaded again, so prefer ExpireAt for rules from data sources.
+	TTLSec uint32 `json:"ttlSec,omitempty"`
 }
 
 func (r *Rule) String() string {
@@ -109,14 +162,37 @@ func (r *Rule) ResourceName() string {
 	return r.Resource
 }
 
+// Lifetime returns the ExpireAt and TTLSec of the rule.
+func (r *Rule) Lifetime() (uint64, uint32) {
+	return r.ExpireAt, r.TTLSec
+}
+
+// isEnforcedOn reports whether the rule is enforced on the entry, otherwise the rule is checked in the Shadow mode.
+func (r *Rule) isEnforcedOn(ctx *base.EntryContext) bool {
+	return base.IsEnforcedOn(ctx, r.Mode, r.EnforcePercent, r.EnforceKey, r.Canary)
+}
+
+// conditionHolds reports whether the Condition of the rule holds on the entry.
+func (r *Rule) conditionHolds(ctx *base.EntryContext) bool {
+	return r.conditionProgram.Holds(ctx)
+}
+
+func (r *Rule) setConditionProgram(program *condition.Program) {
+	r.conditionProgram = program
+}
+
+func (r *Rule) setCanary(canary *base.CanarySelector) {
+	r.Canary = canary
+}
+
 // IsStatReusable checks whether current rule is "statistically" equal to the given rule.
 func (r *Rule) IsStatReusable(newRule *Rule) bool {
-	return r.Resource == newRule.Resource && r.ControlBehavior == newRule.ControlBehavior && r.ParamsMaxCapacity == newRule.ParamsMaxCapacity && r.DurationInSec == newRule.DurationInSec && r.MetricType == newRule.MetricType
+	return r.Resource == newRule.Resource && r.MatchStrategy == newRule.MatchStrategy && r.ControlBehavior == newRule.ControlBehavior && r.ParamsMaxCapacity == newRule.ParamsMaxCapacity && r.DurationInSec == newRule.DurationInSec && r.MetricType == newRule.MetricType && r.CacheType == newRule.CacheType
 }
 
 // Equals checks whether current rule is consistent with the given rule.
 func (r *Rule) Equals(newRule *Rule) bool {
-	baseCheck := r.Resource == newRule.Resource && r.MetricType == newRule.MetricType && r.ControlBehavior == newRule.ControlBehavior && r.ParamsMaxCapacity == newRule.ParamsMaxCapacity && r.ParamIndex == newRule.ParamIndex && r.ParamKey == newRule.ParamKey && r.Threshold == newRule.Threshold && r.DurationInSec == newRule.DurationInSec && reflect.DeepEqual(r.SpecificItems, newRule.SpecificItems)
+	baseCheck := r.Resource == newRule.Resource && r.MatchStrategy == newRule.MatchStrategy && r.MetricType == newRule.MetricType && r.ControlBehavior == newRule.ControlBehavior && r.ParamsMaxCapacity == newRule.ParamsMaxCapacity && r.ParamIndex == newRule.ParamIndex && r.ParamKey == newRule.ParamKey && r.Threshold == newRule.Threshold && r.DurationInSec == newRule.DurationInSec && r.CacheType == newRule.CacheType && reflect.DeepEqual(r.SpecificItems, newRule.SpecificItems) && reflect.DeepEqual(r.Schedule, newRule.Schedule) && r.Mode == newRule.Mode && r.EnforcePercent == newRule.EnforcePercent && r.EnforceKey == newRule.EnforceKey && r.Canary.Equals(newRule.Canary) && r.Condition == newRule.Condition
 	if !baseCheck {
 		return false
 	}
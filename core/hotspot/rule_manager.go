@@ -19,6 +19,9 @@ import (
 	"reflect"
 	"sync"
 
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
+	"github.com/alibaba/sentinel-golang/core/hotspot/cache"
 	"github.com/alibaba/sentinel-golang/logging"
 	"github.com/alibaba/sentinel-golang/util"
 	"github.com/pkg/errors"
@@ -31,14 +34,43 @@ type TrafficControllerGenFunc func(r *Rule, reuseMetric *ParamsMetric) TrafficSh
 type trafficControllerMap map[string][]TrafficShapingController
 
 var (
-	tcGenFuncMap  = make(map[ControlBehavior]TrafficControllerGenFunc, 4)
-	tcMap         = make(trafficControllerMap)
-	tcMux         = new(sync.RWMutex)
-	currentRules  = make(map[string][]*Rule, 0)
-	updateRuleMux = new(sync.Mutex)
+	tcGenFuncMap = make(map[ControlBehavior]TrafficControllerGenFunc, 4)
+	tcMap        = make(trafficControllerMap)
+	// patternTcMap holds the controllers of the rules matching resources by pattern, which are keyed by the pattern
+	// and only used to generate the controllers of each matched resource cached in patternTcCache.
+	patternTcMap = make(trafficControllerMap)
+	// patternTcCache is the LRU cache of the controllers generated for each resource matched by the pattern rules,
+	// which is guarded by patternCacheMux. The resources matched by no pattern rule are not cached.
+	patternTcCache  = newPatternTcCache()
+	patternCacheMux = new(sync.Mutex)
+	tcMatcher       *base.ResourceMatcher
+	tcMux           = new(sync.RWMutex)
+	currentRules    = make(map[string][]*Rule, 0)
+	updateRuleMux   = new(sync.Mutex)
+	ruleExpiration  *base.RuleExpirationManager[Rule, *Rule]
 )
 
+// patternCacheCapacity is the max count of the resources whose controllers generated by the pattern rules are cached.
+const patternCacheCapacity = 10000
+
+func newPatternTcCache() *cache.LRU {
+	c, err := cache.NewLRU(patternCacheCapacity, nil)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
 func init() {
+	ruleExpiration = base.NewRuleExpirationManager("[HotSpot]", updateRuleMux,
+		func() map[string][]*Rule {
+			return currentRules
+		},
+		onRuleUpdate,
+		func(rule *Rule, expireAt uint64, ttlSec uint32) {
+			rule.ExpireAt, rule.TTLSec = expireAt, ttlSec
+		})
+
 	// Initialize the traffic shaping controller generator map for existing control behaviors.
 	tcGenFuncMap[Reject] = func(r *Rule, reuseMetric *ParamsMetric) TrafficShapingController {
 		var baseTc *baseTrafficShapingController
@@ -78,7 +110,110 @@ func getTrafficControllersFor(res string) []TrafficShapingController {
 	tcMux.RLock()
 	defer tcMux.RUnlock()
 
-	return tcMap[res]
+	resTcs := tcMap[res]
+	if tcMatcher.IsEmpty() {
+		return resTcs
+	}
+	patternTcs := cachedPatternTcsOf(res)
+	if len(patternTcs) == 0 {
+		return resTcs
+	}
+	ret := make([]TrafficShapingController, 0, len(resTcs)+len(patternTcs))
+	ret = append(ret, resTcs...)
+	return append(ret, patternTcs...)
+}
+
+// cachedPatternTcsOf returns the controllers generated for the resource by the pattern rules,
+// which are generated and cached if absent. It must be called with tcMux locked.
+func cachedPatternTcsOf(res string) []TrafficShapingController {
+	patternCacheMux.Lock()
+	defer patternCacheMux.Unlock()
+
+	if cached, ok := patternTcCache.Get(res); ok {
+		return cached.([]TrafficShapingController)
+	}
+	patternTcs := patternTcsOf(res, nil)
+	if len(patternTcs) > 0 {
+		patternTcCache.Add(res, patternTcs)
+	}
+	return patternTcs
+}
+
+// patternTcsOf generates the controllers of the rules whose patterns match the resource, the controllers in oldTcs
+// bound to the same rules are reused. It must be called with tcMux locked.
+func patternTcsOf(res string, oldTcs []TrafficShapingController) []TrafficShapingController {
+	var ret []TrafficShapingController
+	for _, pattern := range tcMatcher.Match(res) {
+		for _, tc := range patternTcMap[pattern.Pattern] {
+			rule := tc.BoundRule()
+			if rule.MatchStrategy != pattern.Strategy {
+				continue
+			}
+			if oldTc := tcBoundTo(oldTcs, rule); oldTc != nil {
+				ret = append(ret, oldTc)
+				continue
+			}
+			generator, supported := tcGenFuncMap[rule.ControlBehavior]
+			if !supported {
+				continue
+			}
+			if newTc := generator(rule, nil); newTc != nil {
+				ret = append(ret, newTc)
+			}
+		}
+	}
+	return ret
+}
+
+func tcBoundTo(tcs []TrafficShapingController, rule *Rule) TrafficShapingController {
+	for _, tc := range tcs {
+		if tc.BoundRule() == rule {
+			return tc
+		}
+	}
+	return nil
+}
+
+// splitPatternTcs splits the controllers of the rules matching resources by pattern from the others.
+func splitPatternTcs(tcs []TrafficShapingController) (exactTcs, patternTcs []TrafficShapingController) {
+	for _, tc := range tcs {
+		if tc.BoundRule().MatchStrategy == base.ExactMatch {
+			exactTcs = append(exactTcs, tc)
+		} else {
+			patternTcs = append(patternTcs, tc)
+		}
+	}
+	return exactTcs, patternTcs
+}
+
+// resetPatternTcs rebuilds the matcher of patternTcMap and regenerates the cached controllers,
+// it must be called with tcMux locked.
+func resetPatternTcs() {
+	patterns := make([]base.ResourcePattern, 0, len(patternTcMap))
+	for res, tcs := range patternTcMap {
+		for _, tc := range tcs {
+			patterns = append(patterns, base.ResourcePattern{Strategy: tc.BoundRule().MatchStrategy, Pattern: res})
+		}
+	}
+	matcher, err := base.NewResourceMatcher(patterns)
+	if err != nil {
+		logging.Error(err, "Fail to build the matcher of pattern rules in hotspot.resetPatternTcs()")
+	}
+	tcMatcher = matcher
+
+	// The controllers of the unchanged rules are kept, so that their param statistics
+	// (e.g. the concurrency of in-flight requests) are not lost.
+	patternCacheMux.Lock()
+	defer patternCacheMux.Unlock()
+	for _, key := range patternTcCache.Keys() {
+		res := key.(string)
+		oldTcs, _ := patternTcCache.Peek(res)
+		if tcs := patternTcsOf(res, oldTcs.([]TrafficShapingController)); len(tcs) > 0 {
+			patternTcCache.Add(res, tcs)
+		} else {
+			patternTcCache.Remove(res)
+		}
+	}
 }
 
 // LoadRules replaces all old hotspot param flow rules with the given rules.
@@ -105,6 +240,7 @@ func LoadRules(rules []*Rule) (bool, error) {
 	}
 
 	err := onRuleUpdate(resRulesMap)
+	ruleExpiration.Track()
 	return true, err
 }
 
@@ -112,31 +248,37 @@ func LoadRules(rules []*Rule) (bool, error) {
 // It doesn't take effect for hotspot module if user changes the returned rules.
 // GetRules need to compete hotspot module's global lock and the high performance losses of copy,
 // reduce or do not call GetRules if possible.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
 func GetRules() []Rule {
 	tcMux.RLock()
-	rules := rulesFrom(tcMap)
+	rules := append(rulesFrom(tcMap), rulesFrom(patternTcMap)...)
 	tcMux.RUnlock()
 
+	now := util.CurrentTimeMillis()
 	ret := make([]Rule, 0, len(rules))
 	for _, rule := range rules {
-		ret = append(ret, *rule)
+		ret = append(ret, ruleExpiration.CopyWithLifetime(rule, now))
 	}
 	return ret
 }
 
 // GetRulesOfResource returns specific resource's hotspot parameter flow control rules based on copy.
 // It doesn't take effect for hotspot module if user changes the returned rules.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
 // GetRulesOfResource need to compete hotspot module's global lock and the high performance losses of copy,
 //
 //	reduce or do not call GetRulesOfResource frequently if possible.
 func GetRulesOfResource(res string) []Rule {
 	tcMux.RLock()
-	resTcs := tcMap[res]
+	resTcs := make([]TrafficShapingController, 0, len(tcMap[res])+len(patternTcMap[res]))
+	resTcs = append(resTcs, tcMap[res]...)
+	resTcs = append(resTcs, patternTcMap[res]...)
 	tcMux.RUnlock()
 
+	now := util.CurrentTimeMillis()
 	ret := make([]Rule, 0, len(resTcs))
 	for _, tc := range resTcs {
-		ret = append(ret, *tc.BoundRule())
+		ret = append(ret, ruleExpiration.CopyWithLifetime(tc.BoundRule(), now))
 	}
 	return ret
 }
@@ -173,6 +315,8 @@ func onRuleUpdate(rawResRulesMap map[string][]*Rule) (err error) {
 				logging.Warn("[HotSpot onRuleUpdate] Ignoring invalid hotspot param flow rule when loading new rules", "rule", rule, "err", err.Error())
 				continue
 			}
+			rule = condition.Prepare(rule, rule.Condition, (*Rule).setConditionProgram)
+			rule = base.ResolveCanary(rule, rule.Canary, (*Rule).setCanary)
 			validResRules = append(validResRules, rule)
 		}
 		if len(validResRules) > 0 {
@@ -189,15 +333,27 @@ func onRuleUpdate(rawResRulesMap map[string][]*Rule) (err error) {
 		resTcClone = append(resTcClone, tcs...)
 		tcMapClone[res] = resTcClone
 	}
+	for res, tcs := range patternTcMap {
+		tcMapClone[res] = append(tcMapClone[res], tcs...)
+	}
 	tcMux.RUnlock()
 
 	m := make(trafficControllerMap, len(validResRulesMap))
+	patternM := make(trafficControllerMap)
 	for res, rules := range validResRulesMap {
-		m[res] = buildResourceTrafficShapingController(res, rules, tcMapClone[res])
+		resTcs, patternResTcs := splitPatternTcs(buildResourceTrafficShapingController(res, rules, tcMapClone[res]))
+		if len(resTcs) > 0 {
+			m[res] = resTcs
+		}
+		if len(patternResTcs) > 0 {
+			patternM[res] = patternResTcs
+		}
 	}
 
 	tcMux.Lock()
 	tcMap = m
+	patternTcMap = patternM
+	resetPatternTcs()
 	tcMux.Unlock()
 
 	currentRules = rawResRulesMap
@@ -224,6 +380,8 @@ func onResourceRuleUpdate(res string, rawResRules []*Rule) (err error) {
 			logging.Warn("[HotSpot onResourceRuleUpdate] Ignoring invalid hotspot param flow rule", "rule", rule, "reason", err.Error())
 			continue
 		}
+		rule = condition.Prepare(rule, rule.Condition, (*Rule).setConditionProgram)
+		rule = base.ResolveCanary(rule, rule.Canary, (*Rule).setCanary)
 		validResRules = append(validResRules, rule)
 	}
 
@@ -231,9 +389,10 @@ func onResourceRuleUpdate(res string, rawResRules []*Rule) (err error) {
 	oldResTcs := make([]TrafficShapingController, 0, 8)
 	tcMux.RLock()
 	oldResTcs = append(oldResTcs, tcMap[res]...)
+	oldResTcs = append(oldResTcs, patternTcMap[res]...)
 	tcMux.RUnlock()
 
-	newResTcs := buildResourceTrafficShapingController(res, validResRules, oldResTcs)
+	newResTcs, newPatternResTcs := splitPatternTcs(buildResourceTrafficShapingController(res, validResRules, oldResTcs))
 
 	tcMux.Lock()
 	if len(newResTcs) == 0 {
@@ -241,6 +400,15 @@ func onResourceRuleUpdate(res string, rawResRules []*Rule) (err error) {
 	} else {
 		tcMap[res] = newResTcs
 	}
+	_, hadPattern := patternTcMap[res]
+	if len(newPatternResTcs) == 0 {
+		delete(patternTcMap, res)
+	} else {
+		patternTcMap[res] = newPatternResTcs
+	}
+	if hadPattern || len(newPatternResTcs) > 0 {
+		resetPatternTcs()
+	}
 	tcMux.Unlock()
 
 	currentRules[res] = rawResRules
@@ -268,7 +436,12 @@ func LoadRulesOfResource(res string, rules []*Rule) (bool, error) {
 		// clear tcMap
 		tcMux.Lock()
 		delete(tcMap, res)
+		if _, ok := patternTcMap[res]; ok {
+			delete(patternTcMap, res)
+			resetPatternTcs()
+		}
 		tcMux.Unlock()
+		ruleExpiration.Track()
 		logging.Info("[HotSpot] clear resource level hotspot param flow rules", "resource", res)
 		return true, nil
 	}
@@ -281,6 +454,7 @@ func LoadRulesOfResource(res string, rules []*Rule) (bool, error) {
 	}
 
 	err := onResourceRuleUpdate(res, rules)
+	ruleExpiration.Track()
 	return true, err
 }
 
@@ -394,6 +568,26 @@ func IsValidRule(rule *Rule) error {
 	if len(rule.Resource) == 0 {
 		return errors.New("empty resource name")
 	}
+	if rule.MatchStrategy != base.ExactMatch {
+		if err := (base.ResourcePattern{Strategy: rule.MatchStrategy, Pattern: rule.Resource}).IsValid(); err != nil {
+			return err
+		}
+	}
+	if rule.Mode != base.Enforce && rule.Mode != base.Shadow {
+		return errors.New("invalid mode")
+	}
+	if err := base.CheckEnforcePercent(rule.EnforcePercent); err != nil {
+		return err
+	}
+	if err := rule.Canary.IsValid(); err != nil {
+		return err
+	}
+	if err := condition.IsValid(rule.Condition); err != nil {
+		return errors.Wrap(err, "invalid Condition")
+	}
+	if rule.ExpireAt > 0 && rule.ExpireAt <= util.CurrentTimeMillis() {
+		return errors.New("rule already expired")
+	}
 	if rule.Threshold < 0 {
 		return errors.New("negative threshold")
 	}
@@ -403,12 +597,18 @@ func IsValidRule(rule *Rule) error {
 	if rule.ControlBehavior < 0 {
 		return errors.New("invalid control strategy")
 	}
+	if rule.CacheType != LRUCache && rule.CacheType != TinyLFUCache {
+		return errors.New("invalid cache type")
+	}
 	if rule.MetricType == QPS && rule.DurationInSec <= 0 {
 		return errors.New("invalid duration")
 	}
 	if rule.ParamIndex > 0 && rule.ParamKey != "" {
 		return errors.New("invalid param index and param key are mutually exclusive")
 	}
+	if err := rule.Schedule.IsValid(); err != nil {
+		return errors.Wrap(err, "invalid schedule")
+	}
 	return checkControlBehaviorField(rule)
 }
 
@@ -19,12 +19,15 @@ import (
 	"math"
 	"testing"
 
+	"github.com/alibaba/sentinel-golang/core/base"
 	"github.com/alibaba/sentinel-golang/core/hotspot/cache"
 	"github.com/stretchr/testify/assert"
 )
 
 func clearData() {
 	tcMap = make(trafficControllerMap)
+	patternTcMap = make(trafficControllerMap)
+	resetPatternTcs()
 	currentRules = make(map[string][]*Rule, 0)
 }
 
@@ -152,6 +155,19 @@ func Test_IsValidRule(t *testing.T) {
 		}
 		assert.True(t, IsValidRule(r1) == nil)
 	})
+
+	t.Run("Test_InValidRule_CacheType", func(t *testing.T) {
+		r1 := &Rule{
+			Resource:      "abc",
+			MetricType:    QPS,
+			Threshold:     110,
+			DurationInSec: 1,
+			CacheType:     TinyLFUCache,
+		}
+		assert.True(t, IsValidRule(r1) == nil)
+		r1.CacheType = 10
+		assert.True(t, IsValidRule(r1) != nil)
+	})
 }
 
 func Test_onRuleUpdate(t *testing.T) {
@@ -567,3 +583,95 @@ func TestClearRulesOfResource(t *testing.T) {
 		clearData()
 	})
 }
+
+func TestPatternRules(t *testing.T) {
+	clearData()
+	defer clearData()
+
+	r1 := &Rule{
+		ID:              "1",
+		Resource:        "^/api/users/[0-9]+$",
+		MatchStrategy:   base.RegexMatch,
+		MetricType:      QPS,
+		ControlBehavior: Reject,
+		ParamIndex:      0,
+		Threshold:       10,
+		DurationInSec:   1,
+	}
+	r2 := &Rule{
+		ID:              "2",
+		Resource:        "/api/users/1",
+		MetricType:      QPS,
+		ControlBehavior: Reject,
+		ParamIndex:      0,
+		Threshold:       100,
+		DurationInSec:   1,
+	}
+	_, err := LoadRules([]*Rule{r1, r2})
+	assert.Nil(t, err)
+
+	tcs1 := getTrafficControllersFor("/api/users/1")
+	assert.True(t, len(tcs1) == 2 && tcs1[0].BoundRule() == r2 && tcs1[1].BoundRule() == r1)
+	// each matched resource has its own parameter statistic
+	tcs2 := getTrafficControllersFor("/api/users/2")
+	assert.True(t, len(tcs2) == 1 && tcs2[0].BoundRule() == r1 && tcs2[0].BoundMetric() != tcs1[1].BoundMetric())
+	assert.True(t, getTrafficControllersFor("/api/users/2")[0] == tcs2[0])
+	assert.Empty(t, getTrafficControllersFor("/api/users/abc"))
+	// the resources matched by no pattern rule are not cached
+	assert.False(t, patternTcCache.Contains("/api/users/abc"))
+	assert.Equal(t, 2, len(GetRules()))
+	assert.Equal(t, 1, len(GetRulesOfResource(r1.Resource)))
+
+	// the cached controllers of the unchanged pattern rules are kept on rule update
+	r2Updated := *r2
+	r2Updated.Threshold = 200
+	_, err = LoadRules([]*Rule{r1, &r2Updated})
+	assert.Nil(t, err)
+	assert.True(t, getTrafficControllersFor("/api/users/2")[0] == tcs2[0])
+	tcs1 = getTrafficControllersFor("/api/users/1")
+	assert.True(t, len(tcs1) == 2 && tcs1[0].BoundRule() == &r2Updated && tcs1[1].BoundRule() == r1)
+
+	// the cached controllers of the removed pattern rules are dropped on rule update
+	_, err = LoadRulesOfResource(r1.Resource, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, getTrafficControllersFor("/api/users/2"))
+	assert.Equal(t, 0, patternTcCache.Len())
+
+	r3 := *r1
+	r3.Resource = "/api/("
+	assert.Error(t, IsValidRule(&r3))
+	r3.MatchStrategy = base.PrefixMatch
+	assert.Nil(t, IsValidRule(&r3))
+}
+
+func TestPatternRules_CacheCapacity(t *testing.T) {
+	clearData()
+	defer clearData()
+
+	patternCacheMux.Lock()
+	origin := patternTcCache
+	patternTcCache, _ = cache.NewLRU(2, nil)
+	patternCacheMux.Unlock()
+	defer func() {
+		patternCacheMux.Lock()
+		patternTcCache = origin
+		patternCacheMux.Unlock()
+	}()
+
+	_, err := LoadRules([]*Rule{{
+		Resource:        "/api/orders/",
+		MatchStrategy:   base.PrefixMatch,
+		MetricType:      QPS,
+		ControlBehavior: Reject,
+		ParamIndex:      0,
+		Threshold:       10,
+		DurationInSec:   1,
+	}})
+	assert.Nil(t, err)
+	for _, res := range []string{"/api/orders/1", "/api/orders/2", "/api/orders/3"} {
+		assert.Equal(t, 1, len(getTrafficControllersFor(res)))
+	}
+	// the least recently used resource is evicted
+	assert.Equal(t, 2, patternTcCache.Len())
+	assert.False(t, patternTcCache.Contains("/api/orders/1"))
+}
@@ -41,11 +41,24 @@ func (s *Slot) Check(ctx *base.EntryContext) *base.TokenResult {
 	result := ctx.RuleCheckResult
 	tcs := getTrafficControllersFor(res)
 	for _, tc := range tcs {
+		if !tc.BoundRule().conditionHolds(ctx) {
+			continue
+		}
 		arg := tc.ExtractArgs(ctx)
 		if arg == nil {
 			continue
 		}
 		r := canPassCheck(tc, arg, batch)
+		if !tc.BoundRule().isEnforcedOn(ctx) {
+			// Shadow rules only record the would-be blocked requests, and never make the requests wait.
+			if r != nil && r.IsBlocked() {
+				ctx.AddShadowBlock(r.BlockError())
+			}
+			continue
+		}
+		if ctx.Input.NeedRateLimitInfo {
+			recordRateLimitInfo(ctx, tc, arg, batch, r)
+		}
 		if r == nil {
 			continue
 		}
@@ -63,6 +76,21 @@ func (s *Slot) Check(ctx *base.EntryContext) *base.TokenResult {
 	return result
 }
 
+// recordRateLimitInfo records the quota state of arg to the entry context, or to the block error if blocked.
+func recordRateLimitInfo(ctx *base.EntryContext, tc TrafficShapingController, arg interface{}, batch int64, r *base.TokenResult) {
+	provider, ok := tc.(RateLimitInfoProvider)
+	if !ok {
+		return
+	}
+	blocked := r != nil && r.IsBlocked()
+	info := provider.RateLimitInfo(arg, batch, !blocked)
+	if blocked {
+		r.BlockError().ResetBlockError(base.WithRateLimitInfo(&info))
+	} else {
+		ctx.MergeRateLimitInfo(info)
+	}
+}
+
 func canPassCheck(tc TrafficShapingController, arg interface{}, batch int64) *base.TokenResult {
 	return canPassLocalCheck(tc, arg, batch)
 }
@@ -23,6 +23,7 @@ import (
 
 	"github.com/alibaba/sentinel-golang/core/base"
 	"github.com/alibaba/sentinel-golang/core/hotspot/cache"
+	"github.com/alibaba/sentinel-golang/core/schedule"
 	"github.com/alibaba/sentinel-golang/logging"
 	"github.com/alibaba/sentinel-golang/util"
 	"github.com/pkg/errors"
@@ -40,6 +41,13 @@ type TrafficShapingController interface {
 	BoundRule() *Rule
 }
 
+// RateLimitInfoProvider is the optional interface of TrafficShapingController, which reports the quota state
+// of the given arg after checking, so that the rate limit headers could be written.
+type RateLimitInfoProvider interface {
+	// RateLimitInfo returns the quota state of arg after checking, passed indicates whether the checking passed.
+	RateLimitInfo(arg interface{}, batchCount int64, passed bool) base.RateLimitInfo
+}
+
 type baseTrafficShapingController struct {
 	r *Rule
 
@@ -50,6 +58,8 @@ type baseTrafficShapingController struct {
 	threshold     int64
 	specificItems map[interface{}]int64
 	durationInSec int64
+	// schedule overrides the threshold in the scheduled time windows, nil if the rule has no schedule.
+	schedule *schedule.Evaluator
 
 	metric *ParamsMetric
 }
@@ -58,6 +68,10 @@ func newBaseTrafficShapingControllerWithMetric(r *Rule, metric *ParamsMetric) *b
 	if r.SpecificItems == nil {
 		r.SpecificItems = make(map[interface{}]int64)
 	}
+	evaluator, err := schedule.NewEvaluator(r.Schedule)
+	if err != nil {
+		logging.Warn("[HotSpot newBaseTrafficShapingControllerWithMetric] Ignoring the invalid schedule of rule", "rule", r, "reason", err.Error())
+	}
 	return &baseTrafficShapingController{
 		r:             r,
 		res:           r.Resource,
@@ -67,10 +81,19 @@ func newBaseTrafficShapingControllerWithMetric(r *Rule, metric *ParamsMetric) *b
 		threshold:     r.Threshold,
 		specificItems: r.SpecificItems,
 		durationInSec: r.DurationInSec,
+		schedule:      evaluator,
 		metric:        metric,
 	}
 }
 
+// currentThreshold returns the threshold overridden by the schedule at current time.
+func (c *baseTrafficShapingController) currentThreshold() int64 {
+	if c.schedule == nil {
+		return c.threshold
+	}
+	return int64(c.schedule.Threshold(float64(c.threshold)))
+}
+
 func newBaseTrafficShapingController(r *Rule) *baseTrafficShapingController {
 	switch r.MetricType {
 	case QPS:
@@ -88,8 +111,8 @@ func newBaseTrafficShapingController(r *Rule) *baseTrafficShapingController {
 			size = ParamsMaxCapacity
 		}
 		metric := &ParamsMetric{
-			RuleTimeCounter:  cache.NewLRUCacheMap(size),
-			RuleTokenCounter: cache.NewLRUCacheMap(size),
+			RuleTimeCounter:  newCounterCache(r.CacheType, size),
+			RuleTokenCounter: newCounterCache(r.CacheType, size),
 		}
 		return newBaseTrafficShapingControllerWithMetric(r, metric)
 	case Concurrency:
@@ -100,7 +123,7 @@ func newBaseTrafficShapingController(r *Rule) *baseTrafficShapingController {
 			size = ConcurrencyMaxCount
 		}
 		metric := &ParamsMetric{
-			ConcurrencyCounter: cache.NewLRUCacheMap(size),
+			ConcurrencyCounter: newCounterCache(r.CacheType, size),
 		}
 		return newBaseTrafficShapingControllerWithMetric(r, metric)
 	default:
@@ -109,6 +132,14 @@ func newBaseTrafficShapingController(r *Rule) *baseTrafficShapingController {
 	}
 }
 
+// newCounterCache creates the counter cache of parameters according to the cache type of rule.
+func newCounterCache(cacheType CacheType, size int) cache.ConcurrentCounterCache {
+	if cacheType == TinyLFUCache {
+		return cache.NewTinyLfuCacheMap(size)
+	}
+	return cache.NewLRUCacheMap(size)
+}
+
 func (c *baseTrafficShapingController) BoundMetric() *ParamsMetric {
 	return c.metric
 }
@@ -130,7 +161,7 @@ func (c *baseTrafficShapingController) performCheckingForConcurrencyMetric(arg i
 		msg := fmt.Sprintf("hotspot specific concurrency check blocked, arg: %v", arg)
 		return base.NewTokenResultBlockedWithCause(base.BlockTypeHotSpotParamFlow, msg, c.BoundRule(), concurrency)
 	}
-	threshold := c.threshold
+	threshold := c.currentThreshold()
 	if concurrency <= threshold {
 		return nil
 	}
@@ -243,7 +274,7 @@ func (c *rejectTrafficShapingController) PerformChecking(arg interface{}, batchC
 	}
 
 	// calculate available token
-	tokenCount := c.threshold
+	tokenCount := c.currentThreshold()
 	val, existed := c.specificItems[arg]
 	if existed {
 		tokenCount = val
@@ -319,6 +350,49 @@ func (c *rejectTrafficShapingController) PerformChecking(arg interface{}, batchC
 	}
 }
 
+func (c *rejectTrafficShapingController) RateLimitInfo(arg interface{}, _ int64, passed bool) base.RateLimitInfo {
+	threshold := c.currentThreshold()
+	if val, existed := c.specificItems[arg]; existed {
+		threshold = val
+	}
+	info := base.RateLimitInfo{
+		Limit: float64(threshold),
+	}
+	metric := c.metric
+	if metric == nil {
+		return info
+	}
+
+	if c.metricType == Concurrency {
+		var concurrency int64
+		if concurrencyPtr, found := metric.ConcurrencyCounter.Get(arg); found {
+			concurrency = atomic.LoadInt64(concurrencyPtr)
+		}
+		// The concurrency of current request isn't counted yet.
+		if passed {
+			concurrency++
+		}
+		info.Remaining = math.Max(0, float64(threshold-concurrency))
+		return info
+	}
+
+	info.Window = time.Duration(c.durationInSec) * time.Second
+	if metric.RuleTimeCounter == nil || metric.RuleTokenCounter == nil {
+		return info
+	}
+	info.Remaining = float64(threshold + c.burstCount)
+	if restTokenPtr, found := metric.RuleTokenCounter.Get(arg); found {
+		info.Remaining = math.Max(0, float64(atomic.LoadInt64(restTokenPtr)))
+	}
+	if lastAddTokenTimePtr, found := metric.RuleTimeCounter.Get(arg); found {
+		passTime := int64(util.CurrentTimeMillis()) - atomic.LoadInt64(lastAddTokenTimePtr)
+		if reset := c.durationInSec*1000 - passTime; reset > 0 {
+			info.Reset = time.Duration(reset) * time.Millisecond
+		}
+	}
+	return info
+}
+
 func (c *throttlingTrafficShapingController) PerformChecking(arg interface{}, batchCount int64) *base.TokenResult {
 	metric := c.metric
 	if metric == nil {
@@ -338,7 +412,7 @@ func (c *throttlingTrafficShapingController) PerformChecking(arg interface{}, ba
 	}
 
 	// calculate available token
-	tokenCount := c.threshold
+	tokenCount := c.currentThreshold()
 	val, existed := c.specificItems[arg]
 	if existed {
 		tokenCount = val
@@ -21,6 +21,8 @@ import (
 	"time"
 
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/hotspot/cache"
+	"github.com/alibaba/sentinel-golang/core/schedule"
 	"github.com/alibaba/sentinel-golang/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -324,6 +326,31 @@ func Test_newBaseTrafficShapingController(t *testing.T) {
 	})
 }
 
+func Test_newBaseTrafficShapingController_TinyLFUCache(t *testing.T) {
+	t.Run("Test_newBaseTrafficShapingController_TinyLFUCache", func(t *testing.T) {
+		tc := newBaseTrafficShapingController(&Rule{
+			MetricType:        QPS,
+			DurationInSec:     1,
+			ParamsMaxCapacity: 1000,
+			CacheType:         TinyLFUCache,
+		})
+		_, ok := tc.metric.RuleTimeCounter.(*cache.TinyLfuCacheMap)
+		assert.True(t, ok)
+		for i := 0; i < 10000; i++ {
+			initCount := new(int64)
+			tc.metric.RuleTimeCounter.AddIfAbsent(i, initCount)
+		}
+		assert.True(t, tc.metric.RuleTimeCounter.Len() == 1000)
+
+		tc = newBaseTrafficShapingController(&Rule{
+			MetricType: Concurrency,
+			CacheType:  TinyLFUCache,
+		})
+		_, ok = tc.metric.ConcurrencyCounter.(*cache.TinyLfuCacheMap)
+		assert.True(t, ok)
+	})
+}
+
 func Test_baseTrafficShapingController_ExtractArgs(t *testing.T) {
 	t.Run("Test_baseTrafficShapingController_ExtractArgs", func(t *testing.T) {
 
@@ -374,3 +401,98 @@ func Test_baseTrafficShapingController_ExtractArgs(t *testing.T) {
 		assert.Nil(t, ret)
 	})
 }
+
+func Test_rejectTrafficShapingController_RateLimitInfo(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	t.Run("QPS", func(t *testing.T) {
+		r := &Rule{
+			Resource:          "res_a",
+			MetricType:        QPS,
+			ControlBehavior:   Reject,
+			ParamIndex:        0,
+			Threshold:         10,
+			DurationInSec:     2,
+			ParamsMaxCapacity: 100,
+		}
+		c := &rejectTrafficShapingController{
+			baseTrafficShapingController: *newBaseTrafficShapingController(r),
+		}
+		info := c.RateLimitInfo("a", 1, true)
+		assert.Equal(t, 10.0, info.Limit)
+		assert.Equal(t, 10.0, info.Remaining)
+		assert.Equal(t, 2*time.Second, info.Window)
+
+		assert.Nil(t, c.PerformChecking("a", 4))
+		util.Sleep(500 * time.Millisecond)
+		info = c.RateLimitInfo("a", 4, true)
+		assert.Equal(t, 6.0, info.Remaining)
+		assert.Equal(t, 1500*time.Millisecond, info.Reset)
+
+		assert.True(t, c.PerformChecking("a", 7).IsBlocked())
+		info = c.RateLimitInfo("a", 7, false)
+		assert.Equal(t, 6.0, info.Remaining)
+	})
+
+	t.Run("Concurrency", func(t *testing.T) {
+		r := &Rule{
+			Resource:          "res_a",
+			MetricType:        Concurrency,
+			ControlBehavior:   Reject,
+			ParamIndex:        0,
+			Threshold:         3,
+			SpecificItems:     map[interface{}]int64{"b": 1},
+			ParamsMaxCapacity: 100,
+		}
+		c := &rejectTrafficShapingController{
+			baseTrafficShapingController: *newBaseTrafficShapingController(r),
+		}
+		concurrency := int64(1)
+		c.metric.ConcurrencyCounter.Add("a", &concurrency)
+		info := c.RateLimitInfo("a", 1, true)
+		assert.Equal(t, 3.0, info.Limit)
+		assert.Equal(t, 1.0, info.Remaining)
+
+		info = c.RateLimitInfo("b", 1, false)
+		assert.Equal(t, 1.0, info.Limit)
+		assert.Equal(t, 1.0, info.Remaining)
+	})
+}
+
+func Test_baseTrafficShapingController_Schedule(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	begin := util.Now().Add(2 * time.Second)
+	r := &Rule{
+		Resource:          "res_a",
+		MetricType:        Concurrency,
+		ControlBehavior:   Reject,
+		ParamIndex:        0,
+		Threshold:         1,
+		ParamsMaxCapacity: 100,
+		SpecificItems:     map[interface{}]int64{"b": 5},
+		Schedule: &schedule.Schedule{
+			Windows: []schedule.Window{
+				{Begin: begin.Format(time.RFC3339), End: begin.Add(time.Minute).Format(time.RFC3339), Threshold: 2},
+			},
+		},
+	}
+	c := &rejectTrafficShapingController{
+		baseTrafficShapingController: *newBaseTrafficShapingController(r),
+	}
+	assert.Equal(t, int64(1), c.currentThreshold())
+	for _, arg := range []string{"a", "b"} {
+		concurrency := int64(1)
+		c.metric.ConcurrencyCounter.AddIfAbsent(arg, &concurrency)
+	}
+	assert.True(t, c.PerformChecking("a", 1).IsBlocked())
+	assert.Nil(t, c.PerformChecking("b", 1))
+
+	util.Sleep(3 * time.Second)
+	assert.Equal(t, int64(2), c.currentThreshold())
+	assert.Nil(t, c.PerformChecking("a", 1))
+	util.Sleep(time.Minute)
+	assert.True(t, c.PerformChecking("a", 1).IsBlocked())
+}
@@ -0,0 +1,413 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolation
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	metric_exporter "github.com/alibaba/sentinel-golang/exporter/metric"
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	queueFullMsg     = "isolation queue is full"
+	queueTimeoutMsg  = "isolation queueing timeout"
+	queueCanceledMsg = "isolation queueing canceled"
+	queueDroppedMsg  = "isolation queueing dropped due to rule update"
+	queuePermitsKey  = "isolation-queue-permits"
+	queuesKey        = "isolation-queues"
+)
+
+var (
+	queueMap = make(map[string]map[*Rule]*waitQueue)
+	queueMux = &sync.RWMutex{}
+
+	queueDepthGauge = metric_exporter.NewGauge(
+		"isolation_queue_depth",
+		"Isolation queue depth",
+		[]string{"resource"})
+	queueWaitTimeHistogram = metric_exporter.NewHistogram(
+		"isolation_queue_wait_time_ms",
+		"Isolation queueing wait time in milliseconds",
+		[]float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000},
+		[]string{"resource", "result"})
+)
+
+func init() {
+	metric_exporter.Register(queueDepthGauge)
+	metric_exporter.Register(queueWaitTimeHistogram)
+}
+
+type waiterState int8
+
+const (
+	waiterWaiting waiterState = iota
+	waiterReleased
+	waiterDropped
+)
+
+type waiter struct {
+	cost uint32
+	// ready is closed when the waiter is released or dropped.
+	ready chan struct{}
+	state waiterState
+}
+
+// waitQueue is the bounded FIFO queue of a queueing rule on a specific resource.
+// An entry holds a permit from being admitted until it's counted in the concurrency of resource,
+// so that the permit handed over to a waiter won't be taken by newcomers.
+type waitQueue struct {
+	res  string
+	rule *Rule
+	// weighted is the weighted counter of resource, only for WeightedConcurrency rule.
+	weighted *weightedCounter
+
+	mux     sync.Mutex
+	waiters *list.List
+	// inTransit is the count of permits admitted but not counted in the concurrency of resource yet.
+	inTransit uint32
+	// dropped marks the queue removed by rule updates.
+	dropped bool
+}
+
+type queuePermit struct {
+	q    *waitQueue
+	cost uint32
+}
+
+func newWaitQueue(res string, rule *Rule) *waitQueue {
+	q := &waitQueue{
+		res:     res,
+		rule:    rule,
+		waiters: list.New(),
+	}
+	if rule.MetricType == WeightedConcurrency {
+		q.weighted = getOrCreateWeightedCounter(res)
+	}
+	return q
+}
+
+// currentConcurrency returns the real-time concurrency of the given stat node.
+func currentConcurrency(node base.StatNode) uint32 {
+	if node == nil {
+		return 0
+	}
+	cur := node.CurrentConcurrency()
+	if cur < 0 {
+		logging.Error(errors.New("negative concurrency"), "Negative concurrency in isolation.currentConcurrency()", "concurrency", cur)
+		return 0
+	}
+	return uint32(cur)
+}
+
+// entryCost returns the permits the entry needs according to the metric type of rule.
+func entryCost(ctx *base.EntryContext, rule *Rule) uint32 {
+	if rule.MetricType == WeightedConcurrency {
+		return ctx.Input.EntryWeight()
+	}
+	return ctx.Input.BatchCount
+}
+
+// usage returns the permits in use of the resource.
+func (q *waitQueue) usage(node base.StatNode) uint32 {
+	if q.weighted != nil {
+		return q.weighted.current()
+	}
+	return currentConcurrency(node)
+}
+
+// availableLocked checks whether the given count of permits are available, must be guarded by q.mux.
+func (q *waitQueue) availableLocked(node base.StatNode, cost uint32) bool {
+	return q.usage(node)+q.inTransit+cost <= thresholdOf(q.rule)
+}
+
+// acquire tries to acquire permits for the entry, waits in queue if no permit available.
+// Returns the block message if failed.
+func (q *waitQueue) acquire(ctx *base.EntryContext) (bool, string) {
+	cost := entryCost(ctx, q.rule)
+	q.mux.Lock()
+	if q.dropped {
+		q.mux.Unlock()
+		return false, queueDroppedMsg
+	}
+	if q.waiters.Len() == 0 && q.availableLocked(ctx.StatNode, cost) {
+		q.inTransit += cost
+		q.mux.Unlock()
+		recordPermit(ctx, q, cost)
+		return true, ""
+	}
+	if q.waiters.Len() >= int(q.rule.MaxQueueSize) {
+		q.mux.Unlock()
+		return false, queueFullMsg
+	}
+	w := &waiter{
+		cost:  cost,
+		ready: make(chan struct{}),
+	}
+	elem := q.waiters.PushBack(w)
+	queueDepthGauge.Set(float64(q.waiters.Len()), q.res)
+	q.mux.Unlock()
+
+	start := util.CurrentTimeMillis()
+	timer := time.NewTimer(time.Duration(q.rule.MaxQueueingTimeMs) * time.Millisecond)
+	defer timer.Stop()
+	var done <-chan struct{}
+	if ctx.Input.Context != nil {
+		done = ctx.Input.Context.Done()
+	}
+	msg := ""
+	select {
+	case <-w.ready:
+	case <-timer.C:
+		msg = queueTimeoutMsg
+	case <-done:
+		msg = queueCanceledMsg
+	}
+
+	q.mux.Lock()
+	switch w.state {
+	case waiterReleased:
+		if ctx.Input.Context != nil && ctx.Input.Context.Err() != nil {
+			// The permit is handed over but the caller is gone, so give it back.
+			q.inTransit -= cost
+			q.dispatchLocked(ctx.StatNode)
+			q.mux.Unlock()
+			queueWaitTimeHistogram.Observe(float64(util.CurrentTimeMillis()-start), q.res, "canceled")
+			return false, queueCanceledMsg
+		}
+		q.mux.Unlock()
+		recordPermit(ctx, q, cost)
+		queueWaitTimeHistogram.Observe(float64(util.CurrentTimeMillis()-start), q.res, "pass")
+		return true, ""
+	case waiterDropped:
+		q.mux.Unlock()
+		queueWaitTimeHistogram.Observe(float64(util.CurrentTimeMillis()-start), q.res, "dropped")
+		return false, queueDroppedMsg
+	default:
+		q.waiters.Remove(elem)
+		queueDepthGauge.Set(float64(q.waiters.Len()), q.res)
+		q.mux.Unlock()
+		result := "timeout"
+		if msg == queueCanceledMsg {
+			result = "canceled"
+		}
+		queueWaitTimeHistogram.Observe(float64(util.CurrentTimeMillis()-start), q.res, result)
+		return false, msg
+	}
+}
+
+// dispatchLocked releases the waiters in FIFO order as long as the permits are available,
+// must be guarded by q.mux.
+func (q *waitQueue) dispatchLocked(node base.StatNode) {
+	released := false
+	for front := q.waiters.Front(); front != nil; front = q.waiters.Front() {
+		w := front.Value.(*waiter)
+		if !q.availableLocked(node, w.cost) {
+			break
+		}
+		q.waiters.Remove(front)
+		q.inTransit += w.cost
+		w.state = waiterReleased
+		close(w.ready)
+		released = true
+	}
+	if released {
+		queueDepthGauge.Set(float64(q.waiters.Len()), q.res)
+	}
+}
+
+// dispatch releases the waiters after permits are freed.
+// Returns false if the queue has been dropped by rule updates.
+func (q *waitQueue) dispatch(node base.StatNode) bool {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	if q.dropped {
+		return false
+	}
+	q.dispatchLocked(node)
+	return true
+}
+
+// settle marks the admitted permits as counted in the concurrency of resource,
+// or as given up if the entry is blocked by other slots.
+func (q *waitQueue) settle(cost uint32) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	if q.inTransit < cost {
+		q.inTransit = 0
+		return
+	}
+	q.inTransit -= cost
+}
+
+// dropAll rejects all waiters in queue.
+func (q *waitQueue) dropAll() {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	q.dropped = true
+	for front := q.waiters.Front(); front != nil; front = q.waiters.Front() {
+		w := q.waiters.Remove(front).(*waiter)
+		w.state = waiterDropped
+		close(w.ready)
+	}
+	queueDepthGauge.Set(0, q.res)
+}
+
+// depth returns the count of waiting entries.
+func (q *waitQueue) depth() int {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	return q.waiters.Len()
+}
+
+func recordPermit(ctx *base.EntryContext, q *waitQueue, cost uint32) {
+	if ctx.Data == nil {
+		ctx.Data = make(map[interface{}]interface{})
+	}
+	permits, _ := ctx.GetPair(queuePermitsKey).([]queuePermit)
+	ctx.SetPair(queuePermitsKey, append(permits, queuePermit{q: q, cost: cost}))
+}
+
+func permitsOf(ctx *base.EntryContext) []queuePermit {
+	if ctx.Data == nil {
+		return nil
+	}
+	permits, _ := ctx.GetPair(queuePermitsKey).([]queuePermit)
+	return permits
+}
+
+// recordQueue records the wait queue checked by the entry, whose waiters are dispatched once the entry completes.
+func recordQueue(ctx *base.EntryContext, q *waitQueue) {
+	if ctx.Data == nil {
+		ctx.Data = make(map[interface{}]interface{})
+	}
+	queues, _ := ctx.GetPair(queuesKey).([]*waitQueue)
+	ctx.SetPair(queuesKey, append(queues, q))
+}
+
+// queuesOf returns the wait queues checked by the entry.
+func queuesOf(ctx *base.EntryContext) []*waitQueue {
+	if ctx.Data == nil {
+		return nil
+	}
+	queues, _ := ctx.GetPair(queuesKey).([]*waitQueue)
+	return queues
+}
+
+// getOrCreateQueue returns the wait queue of rule on the given resource.
+func getOrCreateQueue(res string, rule *Rule) *waitQueue {
+	queueMux.RLock()
+	q, ok := queueMap[res][rule]
+	queueMux.RUnlock()
+	if ok {
+		return q
+	}
+
+	queueMux.Lock()
+	defer queueMux.Unlock()
+	resQueues, ok := queueMap[res]
+	if !ok {
+		resQueues = make(map[*Rule]*waitQueue)
+		queueMap[res] = resQueues
+	}
+	if q, ok = resQueues[rule]; !ok {
+		q = newWaitQueue(res, rule)
+		resQueues[rule] = q
+	}
+	return q
+}
+
+// getQueuesOf returns all the wait queues of the given resource.
+func getQueuesOf(res string) []*waitQueue {
+	queueMux.RLock()
+	defer queueMux.RUnlock()
+
+	resQueues := queueMap[res]
+	if len(resQueues) == 0 {
+		return nil
+	}
+	ret := make([]*waitQueue, 0, len(resQueues))
+	for _, q := range resQueues {
+		ret = append(ret, q)
+	}
+	return ret
+}
+
+// resetQueues removes the wait queues of the rules matching the filter, and drops their waiters.
+// The queue of the rule equal to one of the given loaded rules is handed over to the loaded rule and kept
+// with its waiters, so that only the queues of the replaced rules are reset.
+func resetQueues(loaded []*Rule, filter func(rule *Rule) bool) {
+	dropped := make([]*waitQueue, 0)
+	queueMux.Lock()
+	for res, resQueues := range queueMap {
+		kept := make(map[*Rule]*waitQueue)
+		for rule, q := range resQueues {
+			if !filter(rule) {
+				continue
+			}
+			delete(resQueues, rule)
+			if r := equalRuleOf(rule, loaded, resQueues, kept); r != nil {
+				kept[r] = q
+				continue
+			}
+			dropped = append(dropped, q)
+		}
+		for rule, q := range kept {
+			resQueues[rule] = q
+		}
+		if len(resQueues) == 0 {
+			delete(queueMap, res)
+		}
+	}
+	queueMux.Unlock()
+
+	for _, q := range dropped {
+		q.dropAll()
+	}
+}
+
+// equalRuleOf returns the rule in loaded equal to the given rule, which doesn't own a queue of the resource yet.
+func equalRuleOf(rule *Rule, loaded []*Rule, resQueues, kept map[*Rule]*waitQueue) *Rule {
+	for _, r := range loaded {
+		if _, ok := resQueues[r]; ok {
+			continue
+		}
+		if _, ok := kept[r]; ok {
+			continue
+		}
+		if r == rule || base.RuleDefinitionEquals(r, rule) {
+			return r
+		}
+	}
+	return nil
+}
+
+// CurrentQueueDepth returns the count of entries waiting in the isolation queues of the given resource.
+func CurrentQueueDepth(res string) uint32 {
+	depth := uint32(0)
+	for _, q := range getQueuesOf(res) {
+		depth += uint32(q.depth())
+	}
+	return depth
+}
@@ -0,0 +1,333 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/stat"
+	"github.com/stretchr/testify/assert"
+)
+
+type queueingEntry struct {
+	ctx    *base.EntryContext
+	result *base.TokenResult
+}
+
+func newQueueingCtx(res string, goCtx context.Context) *base.EntryContext {
+	return &base.EntryContext{
+		Resource: base.NewResourceWrapper(res, base.ResTypeCommon, base.Inbound),
+		StatNode: stat.GetOrCreateResourceNode(res, base.ResTypeCommon),
+		Input: &base.SentinelInput{
+			BatchCount: 1,
+			Context:    goCtx,
+		},
+		Data: make(map[interface{}]interface{}),
+	}
+}
+
+// doEntry simulates the entry through the isolation slot and stat slots.
+func doEntry(ctx *base.EntryContext) *base.TokenResult {
+	ret := DefaultSlot.Check(ctx)
+	if ret != nil && ret.IsBlocked() {
+		DefaultStatSlot.OnEntryBlocked(ctx, ret.BlockError())
+		return ret
+	}
+	stat.DefaultSlot.OnEntryPassed(ctx)
+	DefaultStatSlot.OnEntryPassed(ctx)
+	return ret
+}
+
+// doExit simulates the exit through the stat slots.
+func doExit(ctx *base.EntryContext) {
+	stat.DefaultSlot.OnCompleted(ctx)
+	DefaultStatSlot.OnCompleted(ctx)
+}
+
+func Test_QueueingIsolation_FIFO(t *testing.T) {
+	defer clearData()
+	res := "queueing-fifo"
+	_, err := LoadRules([]*Rule{{
+		Resource:          res,
+		MetricType:        Concurrency,
+		Threshold:         1,
+		ControlBehavior:   Queueing,
+		MaxQueueSize:      2,
+		MaxQueueingTimeMs: 2000,
+	}})
+	assert.Nil(t, err)
+
+	first := newQueueingCtx(res, nil)
+	assert.Nil(t, doEntry(first))
+
+	released := make(chan *queueingEntry, 2)
+	for i := 0; i < 2; i++ {
+		e := &queueingEntry{ctx: newQueueingCtx(res, nil)}
+		go func() {
+			e.result = doEntry(e.ctx)
+			released <- e
+		}()
+		// Make sure the waiters are queued in order.
+		assert.Eventually(t, func() bool {
+			return CurrentQueueDepth(res) == uint32(i+1)
+		}, time.Second, time.Millisecond)
+	}
+
+	// The queue is full.
+	ret := doEntry(newQueueingCtx(res, nil))
+	assert.True(t, ret.IsBlocked())
+	assert.Contains(t, ret.BlockError().Error(), queueFullMsg)
+
+	doExit(first)
+	second := <-released
+	assert.Nil(t, second.result)
+	assert.Equal(t, uint32(1), CurrentQueueDepth(res))
+	select {
+	case <-released:
+		t.Fatal("the third entry should not be released before the second exits")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	doExit(second.ctx)
+	third := <-released
+	assert.Nil(t, third.result)
+	assert.Equal(t, uint32(0), CurrentQueueDepth(res))
+	doExit(third.ctx)
+	assert.Equal(t, int32(0), third.ctx.StatNode.CurrentConcurrency())
+}
+
+func Test_QueueingIsolation_Timeout(t *testing.T) {
+	defer clearData()
+	res := "queueing-timeout"
+	_, err := LoadRules([]*Rule{{
+		Resource:          res,
+		MetricType:        Concurrency,
+		Threshold:         1,
+		ControlBehavior:   Queueing,
+		MaxQueueSize:      1,
+		MaxQueueingTimeMs: 20,
+	}})
+	assert.Nil(t, err)
+
+	first := newQueueingCtx(res, nil)
+	assert.Nil(t, doEntry(first))
+	ret := doEntry(newQueueingCtx(res, nil))
+	assert.True(t, ret.IsBlocked())
+	assert.Contains(t, ret.BlockError().Error(), queueTimeoutMsg)
+	assert.Equal(t, uint32(0), CurrentQueueDepth(res))
+	doExit(first)
+
+	assert.Nil(t, doEntry(newQueueingCtx(res, nil)))
+}
+
+func Test_QueueingIsolation_ContextCanceled(t *testing.T) {
+	defer clearData()
+	res := "queueing-canceled"
+	_, err := LoadRules([]*Rule{{
+		Resource:          res,
+		MetricType:        Concurrency,
+		Threshold:         1,
+		ControlBehavior:   Queueing,
+		MaxQueueSize:      1,
+		MaxQueueingTimeMs: 5000,
+	}})
+	assert.Nil(t, err)
+
+	first := newQueueingCtx(res, nil)
+	assert.Nil(t, doEntry(first))
+
+	goCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	ret := doEntry(newQueueingCtx(res, goCtx))
+	assert.True(t, ret.IsBlocked())
+	assert.Contains(t, ret.BlockError().Error(), queueCanceledMsg)
+	assert.True(t, time.Since(start) < time.Second)
+	doExit(first)
+}
+
+func Test_QueueingIsolation_RuleUpdate(t *testing.T) {
+	defer clearData()
+	res := "queueing-update"
+	rule := &Rule{
+		Resource:          res,
+		MetricType:        Concurrency,
+		Threshold:         1,
+		ControlBehavior:   Queueing,
+		MaxQueueSize:      1,
+		MaxQueueingTimeMs: 5000,
+	}
+	_, err := LoadRules([]*Rule{rule})
+	assert.Nil(t, err)
+
+	first := newQueueingCtx(res, nil)
+	assert.Nil(t, doEntry(first))
+	done := make(chan *base.TokenResult)
+	go func() {
+		done <- doEntry(newQueueingCtx(res, nil))
+	}()
+	assert.Eventually(t, func() bool {
+		return CurrentQueueDepth(res) == 1
+	}, time.Second, time.Millisecond)
+
+	assert.Nil(t, ClearRulesOfResource(res))
+	ret := <-done
+	assert.True(t, ret.IsBlocked())
+	assert.Contains(t, ret.BlockError().Error(), queueDroppedMsg)
+	doExit(first)
+}
+
+func Test_QueueingIsolation_RuleUpdate_KeepUnchanged(t *testing.T) {
+	defer clearData()
+	newRule := func(res string, maxQueueSize uint32) *Rule {
+		return &Rule{
+			Resource:          res,
+			MetricType:        Concurrency,
+			Threshold:         1,
+			ControlBehavior:   Queueing,
+			MaxQueueSize:      maxQueueSize,
+			MaxQueueingTimeMs: 5000,
+		}
+	}
+	changedRes, unchangedRes := "queueing-update-changed", "queueing-update-unchanged"
+	_, err := LoadRules([]*Rule{newRule(changedRes, 1), newRule(unchangedRes, 1)})
+	assert.Nil(t, err)
+
+	firsts := make(map[string]*base.EntryContext)
+	dones := make(map[string]chan *base.TokenResult)
+	for _, res := range []string{changedRes, unchangedRes} {
+		firsts[res] = newQueueingCtx(res, nil)
+		assert.Nil(t, doEntry(firsts[res]))
+		done := make(chan *base.TokenResult, 1)
+		dones[res] = done
+		waiterCtx := newQueueingCtx(res, nil)
+		go func() {
+			ret := doEntry(waiterCtx)
+			if ret == nil || !ret.IsBlocked() {
+				doExit(waiterCtx)
+			}
+			done <- ret
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		return CurrentQueueDepth(changedRes) == 1 && CurrentQueueDepth(unchangedRes) == 1
+	}, time.Second, time.Millisecond)
+
+	// the rule of unchangedRes is reloaded with an equal copy
+	_, err = LoadRules([]*Rule{newRule(changedRes, 2), newRule(unchangedRes, 1)})
+	assert.Nil(t, err)
+	ret := <-dones[changedRes]
+	assert.True(t, ret.IsBlocked())
+	assert.Contains(t, ret.BlockError().Error(), queueDroppedMsg)
+	assert.Equal(t, uint32(1), CurrentQueueDepth(unchangedRes))
+
+	doExit(firsts[unchangedRes])
+	ret = <-dones[unchangedRes]
+	assert.True(t, ret == nil || !ret.IsBlocked())
+	doExit(firsts[changedRes])
+}
+
+func Test_QueueingIsolation_QueuesRecordedInContext(t *testing.T) {
+	defer clearData()
+	queueingRes, rejectRes := "queueing-recorded", "queueing-not-recorded"
+	_, err := LoadRules([]*Rule{
+		{
+			Resource:          queueingRes,
+			MetricType:        Concurrency,
+			Threshold:         1,
+			ControlBehavior:   Queueing,
+			MaxQueueSize:      1,
+			MaxQueueingTimeMs: 5000,
+		},
+		{
+			Resource:   rejectRes,
+			MetricType: Concurrency,
+			Threshold:  1,
+		},
+	})
+	assert.Nil(t, err)
+
+	first := newQueueingCtx(queueingRes, nil)
+	assert.Nil(t, doEntry(first))
+	queues := queuesOf(first)
+	assert.Equal(t, 1, len(queues))
+	assert.Same(t, getQueuesOf(queueingRes)[0], queues[0])
+
+	rejectCtx := newQueueingCtx(rejectRes, nil)
+	assert.Nil(t, doEntry(rejectCtx))
+	assert.Empty(t, queuesOf(rejectCtx))
+	doExit(rejectCtx)
+
+	done := make(chan *base.TokenResult)
+	waiterCtx := newQueueingCtx(queueingRes, nil)
+	go func() {
+		done <- doEntry(waiterCtx)
+	}()
+	assert.Eventually(t, func() bool {
+		return CurrentQueueDepth(queueingRes) == 1
+	}, time.Second, time.Millisecond)
+
+	// the completion dispatches the queue recorded by the entry
+	doExit(first)
+	assert.Nil(t, <-done)
+	doExit(waiterCtx)
+}
+
+func Test_QueueingIsolation_RuleUpdate_DispatchReplacedQueue(t *testing.T) {
+	defer clearData()
+	res := "queueing-update-dispatch"
+	newRule := func(maxQueueSize uint32) *Rule {
+		return &Rule{
+			Resource:          res,
+			MetricType:        Concurrency,
+			Threshold:         1,
+			ControlBehavior:   Queueing,
+			MaxQueueSize:      maxQueueSize,
+			MaxQueueingTimeMs: 5000,
+		}
+	}
+	_, err := LoadRules([]*Rule{newRule(1)})
+	assert.Nil(t, err)
+
+	first := newQueueingCtx(res, nil)
+	assert.Nil(t, doEntry(first))
+	_, err = LoadRules([]*Rule{newRule(2)})
+	assert.Nil(t, err)
+	passed, msg := queuesOf(first)[0].acquire(newQueueingCtx(res, nil))
+	assert.False(t, passed)
+	assert.Equal(t, queueDroppedMsg, msg)
+
+	done := make(chan *base.TokenResult)
+	waiterCtx := newQueueingCtx(res, nil)
+	go func() {
+		done <- doEntry(waiterCtx)
+	}()
+	assert.Eventually(t, func() bool {
+		return CurrentQueueDepth(res) == 1
+	}, time.Second, time.Millisecond)
+
+	// the entry checked against the replaced queue still dispatches the waiters of the current one
+	doExit(first)
+	select {
+	case ret := <-done:
+		assert.Nil(t, ret)
+	case <-time.After(time.Second):
+		t.Fatal("the waiter should be released once the first entry exits")
+	}
+	doExit(waiterCtx)
+}
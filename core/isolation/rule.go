@@ -17,6 +17,11 @@ package isolation
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
+	"github.com/alibaba/sentinel-golang/core/schedule"
 )
 
 // MetricType represents the target metric type.
@@ -25,17 +30,43 @@ type MetricType int32
 const (
 	// Concurrency represents concurrency (in-flight requests).
 	Concurrency MetricType = iota
+	// WeightedConcurrency represents the sum of weight of in-flight requests,
+	// the weight of each request is set by api.WithWeight (BatchCount by default).
+	WeightedConcurrency
 )
 
 func (s MetricType) String() string {
 	switch s {
 	case Concurrency:
 		return "Concurrency"
+	case WeightedConcurrency:
+		return "WeightedConcurrency"
 	default:
 		return "Undefined"
 	}
 }
 
+// ControlBehavior indicates the behavior when the metric exceeds the threshold.
+type ControlBehavior int32
+
+const (
+	// Reject rejects the entry immediately.
+	Reject ControlBehavior = iota
+	// Queueing makes the entry wait in a bounded FIFO queue until a permit is freed by another entry's exit.
+	Queueing
+)
+
+func (b ControlBehavior) String() string {
+	switch b {
+	case Reject:
+		return "Reject"
+	case Queueing:
+		return "Queueing"
+	default:
+		return strconv.Itoa(int(b))
+	}
+}
+
 // Rule describes the isolation policy (e.g. semaphore isolation).
 type Rule struct {
 	// ID represents the unique ID of the rule (optional).
@@ -43,19 +74,56 @@ type Rule struct {
 	// Resource represents the target resource definition.
 	Resource string `json:"resource"`
 	// MetricType indicates the metric type for checking logic.
-	// Currently Concurrency is supported for concurrency limiting.
+	// Concurrency limits the count of in-flight requests,
+	// while WeightedConcurrency limits the sum of weight of in-flight requests.
 	MetricType MetricType `json:"metricType"`
 	Threshold  uint32     `json:"threshold"`
 	// Regex indicates whether the rule is a regex rule
 	Regex bool `json:"regex"`
+	// Selector selects the resources by classification, traffic type and tags instead of Resource,
+	// in which case Resource is only the name of the rule group. The threshold applies to each selected resource.
+	// The Concurrency rules with Regex or Selector don't check the resources beyond the max resource amount
+	// (see config.MaxResourceAmount), whose concurrency is recorded in the shared overflow node.
+	Selector *base.ResourceSelector `json:"selector,omitempty"`
+	// ControlBehavior indicates the behavior when the concurrency reaches Threshold, Reject by default.
+	ControlBehavior ControlBehavior `json:"controlBehavior,omitempty"`
+	// MaxQueueSize is the max count of waiting entries.
+	// MaxQueueSize only takes effect when ControlBehavior is Queueing.
+	MaxQueueSize uint32 `json:"maxQueueSize,omitempty"`
+	// MaxQueueingTimeMs is the max waiting time of an entry in queue.
+	// MaxQueueingTimeMs only takes effect when ControlBehavior is Queueing.
+	MaxQueueingTimeMs uint32 `json:"maxQueueingTimeMs,omitempty"`
+	// Schedule overrides Threshold in the scheduled time windows.
+	Schedule *schedule.Schedule `json:"schedule,omitempty"`
+	// Mode indicates whether the rule blocks the requests (Enforce) or only records the would-be blocked ones (Shadow).
+	// Shadow rules never queue the requests even if ControlBehavior is Queueing.
+	Mode base.RuleMode `json:"mode,omitempty"`
+	// EnforcePercent is the percentage of traffic the rule is enforced on, the valid range is [0.0, 100.0] and 0 means all the traffic.
+	// The rest of the traffic is checked in the Shadow mode.
+	EnforcePercent float64 `json:"enforcePercent,omitempty"`
+	// EnforceKey is the attachment key whose value selects the enforced traffic by hash, the traffic is selected randomly if empty.
+	EnforceKey string `json:"enforceKey,omitempty"`
+	// Canary selects the instances the rule is enforced on, nil means all the instances.
+	Canary *base.CanarySelector `json:"canary,omitempty"`
+	// Condition is the optional expression the rule only takes effect when it holds, e.g. `attachments.caller != "internal"`,
+	// see package condition for the syntax.
+	Condition string `json:"condition,omitempty"`
+	// conditionProgram is the compiled Condition, which is compiled when the rule is loaded.
+	conditionProgram *condition.Program
+	// ExpireAt is the Unix timestamp in milliseconds when the rule expires and is dropped automatically, 0 means never.
+	ExpireAt uint64 `json:"expireAt,omitempty"`
+	// TTLSec is the lifetime in seconds of the rule since it's loaded, 0 means forever.
+	// If both ExpireAt and TTLSec are set, the earlier one takes effect.
+	// A rule expired by TTLSec comes back with a new lifetime if it's loaded again, so prefer ExpireAt for rules from data sources.
+	TTLSec uint32 `json:"ttlSec,omitempty"`
 }
 
 func (r *Rule) String() string {
 	b, err := json.Marshal(r)
 	if err != nil {
 		// Return the fallback string
-		return fmt.Sprintf("{Id=%s, Resource=%s, MetricType=%s, Threshold=%d, Regex=%v}", r.ID, r.Resource,
-			r.MetricType.String(), r.Threshold, r.Regex)
+		return fmt.Sprintf("{Id=%s, Resource=%s, MetricType=%s, Threshold=%d, Regex=%v, ControlBehavior=%s, MaxQueueSize=%d, MaxQueueingTimeMs=%d}",
+			r.ID, r.Resource, r.MetricType.String(), r.Threshold, r.Regex, r.ControlBehavior.String(), r.MaxQueueSize, r.MaxQueueingTimeMs)
 	}
 	return string(b)
 }
@@ -63,3 +131,26 @@ func (r *Rule) String() string {
 func (r *Rule) ResourceName() string {
 	return r.Resource
 }
+
+// Lifetime returns the ExpireAt and TTLSec of the rule.
+func (r *Rule) Lifetime() (uint64, uint32) {
+	return r.ExpireAt, r.TTLSec
+}
+
+// isEnforcedOn reports whether the rule is enforced on the entry, otherwise the rule is checked in the Shadow mode.
+func (r *Rule) isEnforcedOn(ctx *base.EntryContext) bool {
+	return base.IsEnforcedOn(ctx, r.Mode, r.EnforcePercent, r.EnforceKey, r.Canary)
+}
+
+// conditionHolds reports whether the Condition of the rule holds on the entry.
+func (r *Rule) conditionHolds(ctx *base.EntryContext) bool {
+	return r.conditionProgram.Holds(ctx)
+}
+
+func (r *Rule) setConditionProgram(program *condition.Program) {
+	r.conditionProgram = program
+}
+
+func (r *Rule) setCanary(canary *base.CanarySelector) {
+	r.Canary = canary
+}
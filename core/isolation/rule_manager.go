@@ -16,9 +16,11 @@ package isolation
 
 import (
 	"reflect"
-	"regexp"
 	"sync"
 
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
+	"github.com/alibaba/sentinel-golang/core/stat"
 	"github.com/alibaba/sentinel-golang/logging"
 	"github.com/alibaba/sentinel-golang/util"
 	"github.com/pkg/errors"
@@ -28,11 +30,30 @@ var (
 	ruleMap           = make(map[string][]*Rule)
 	regexRuleMap      = make(map[string][]*Rule)
 	regexCacheRuleMap = make(map[string][]*Rule)
-	rwMux             = &sync.RWMutex{}
-	currentRules      = make(map[string][]*Rule, 0)
-	updateRuleMux     = new(sync.Mutex)
+	// regexMatcher indexes the patterns of regexRuleMap.
+	regexMatcher *base.ResourceMatcher
+	// selectorRuleMap holds the rules with Selector, which are keyed by the name of rule group.
+	selectorRuleMap = make(map[string][]*Rule)
+	rwMux           = &sync.RWMutex{}
+	currentRules    = make(map[string][]*Rule, 0)
+	updateRuleMux   = new(sync.Mutex)
+	ruleExpiration  *base.RuleExpirationManager[Rule, *Rule]
 )
 
+// statNodeOwner is the owner of the resource nodes retained by the isolation rules, see stat.RetainResourceNodes.
+const statNodeOwner = "isolation"
+
+func init() {
+	ruleExpiration = base.NewRuleExpirationManager("[Isolation]", updateRuleMux,
+		func() map[string][]*Rule {
+			return currentRules
+		},
+		onRuleUpdate,
+		func(rule *Rule, expireAt uint64, ttlSec uint32) {
+			rule.ExpireAt, rule.TTLSec = expireAt, ttlSec
+		})
+}
+
 // LoadRules loads the given isolation rules to the rule manager, while all previous rules will be replaced.
 // the first returned value indicates whether do real load operation, if the rules is the same with previous rules, return false
 func LoadRules(rules []*Rule) (bool, error) {
@@ -54,12 +75,14 @@ func LoadRules(rules []*Rule) (bool, error) {
 	}
 
 	err := onRuleUpdate(resRulesMap)
+	ruleExpiration.Track()
 	return true, err
 }
 
 func onRuleUpdate(rawResRulesMap map[string][]*Rule) (err error) {
 	validResRulesMap := make(map[string][]*Rule)
 	validRegexResRulesMap := make(map[string][]*Rule)
+	validSelectorRulesMap := make(map[string][]*Rule)
 	for res, rules := range rawResRulesMap {
 		validResRules := make([]*Rule, 0)
 		validRegexResRules := make([]*Rule, 0)
@@ -68,8 +91,12 @@ func onRuleUpdate(rawResRulesMap map[string][]*Rule) (err error) {
 				logging.Warn("[Isolation onRuleUpdate] Ignoring invalid isolation rule", "rule", rule, "reason", err.Error())
 				continue
 			}
+			rule = condition.Prepare(rule, rule.Condition, (*Rule).setConditionProgram)
+			rule = base.ResolveCanary(rule, rule.Canary, (*Rule).setCanary)
 			if rule.Regex {
 				validRegexResRules = append(validRegexResRules, rule)
+			} else if rule.Selector != nil {
+				validSelectorRulesMap[res] = append(validSelectorRulesMap[res], rule)
 			} else {
 				validResRules = append(validResRules, rule)
 			}
@@ -85,13 +112,31 @@ func onRuleUpdate(rawResRulesMap map[string][]*Rule) (err error) {
 	start := util.CurrentTimeNano()
 	rwMux.Lock()
 	ruleMap = validResRulesMap
+	retainStatNodes()
 	regexRuleMap = validRegexResRulesMap
 	regexCacheRuleMap = make(map[string][]*Rule)
+	regexMatcher = buildRegexMatcher(regexRuleMap)
+	selectorRuleMap = validSelectorRulesMap
 	rwMux.Unlock()
 	currentRules = rawResRulesMap
+	loaded := make([]*Rule, 0)
+	for _, m := range []map[string][]*Rule{validResRulesMap, validRegexResRulesMap, validSelectorRulesMap} {
+		for _, rules := range m {
+			loaded = append(loaded, rules...)
+		}
+	}
+	resetQueues(loaded, func(rule *Rule) bool {
+		return true
+	})
+	resetSchedules(func(rule *Rule) bool {
+		return true
+	})
 
 	logging.Debug("[Isolation onRuleUpdate] Time statistic(ns) for updating isolation rule", "timeCost", util.CurrentTimeNano()-start)
 	logRuleUpdate(validResRulesMap, validRegexResRulesMap)
+	if len(validSelectorRulesMap) > 0 {
+		logging.Info("[IsolationRuleManager] Isolation selector rules were loaded", "rules", rulesFrom(validSelectorRulesMap))
+	}
 	return
 }
 
@@ -111,8 +156,18 @@ func LoadRulesOfResource(res string, rules []*Rule) (bool, error) {
 		rwMux.Lock()
 		delete(ruleMap, res)
 		delete(regexRuleMap, res)
+		delete(selectorRuleMap, res)
+		retainStatNodes()
 		regexCacheRuleMap = make(map[string][]*Rule)
+		regexMatcher = buildRegexMatcher(regexRuleMap)
 		rwMux.Unlock()
+		resetQueues(nil, func(rule *Rule) bool {
+			return rule.Resource == res
+		})
+		resetSchedules(func(rule *Rule) bool {
+			return rule.Resource == res
+		})
+		ruleExpiration.Track()
 		logging.Info("[Isolation] clear resource level rules", "resource", res)
 		return true, nil
 	}
@@ -124,19 +179,25 @@ func LoadRulesOfResource(res string, rules []*Rule) (bool, error) {
 	}
 
 	err := onResourceRuleUpdate(res, rules)
+	ruleExpiration.Track()
 	return true, err
 }
 
 func onResourceRuleUpdate(res string, rawResRules []*Rule) (err error) {
 	validResRules := make([]*Rule, 0)
 	validRegexResRules := make([]*Rule, 0)
+	validSelectorRules := make([]*Rule, 0)
 	for _, rule := range rawResRules {
 		if err := IsValidRule(rule); err != nil {
 			logging.Warn("[Isolation onResourceRuleUpdate] Ignoring invalid isolation rule", "rule", rule, "reason", err.Error())
 			continue
 		}
+		rule = condition.Prepare(rule, rule.Condition, (*Rule).setConditionProgram)
+		rule = base.ResolveCanary(rule, rule.Canary, (*Rule).setCanary)
 		if rule.Regex {
 			validRegexResRules = append(validRegexResRules, rule)
+		} else if rule.Selector != nil {
+			validSelectorRules = append(validSelectorRules, rule)
 		} else {
 			validResRules = append(validResRules, rule)
 		}
@@ -151,14 +212,39 @@ func onResourceRuleUpdate(res string, rawResRules []*Rule) (err error) {
 		ruleMap[res] = validResRules
 		regexRuleMap[res] = validRegexResRules
 	}
+	retainStatNodes()
+	if len(validSelectorRules) == 0 {
+		delete(selectorRuleMap, res)
+	} else {
+		selectorRuleMap[res] = validSelectorRules
+	}
 	regexCacheRuleMap = make(map[string][]*Rule)
+	regexMatcher = buildRegexMatcher(regexRuleMap)
 	rwMux.Unlock()
 	currentRules[res] = rawResRules
+	loaded := make([]*Rule, 0, len(validResRules)+len(validRegexResRules)+len(validSelectorRules))
+	loaded = append(append(append(loaded, validResRules...), validRegexResRules...), validSelectorRules...)
+	resetQueues(loaded, func(rule *Rule) bool {
+		return rule.Resource == res
+	})
+	resetSchedules(func(rule *Rule) bool {
+		return rule.Resource == res
+	})
 	logging.Debug("[Isolation onResourceRuleUpdate] Time statistic(ns) for updating isolation rule", "timeCost", util.CurrentTimeNano()-start)
 	logging.Info("[Isolation] load resource level rules", "resource", res, "validResRules", validResRules)
 	return nil
 }
 
+// retainStatNodes retains the nodes of the resources in ruleMap, whose concurrency the rules check, so the nodes are
+// never evicted or overflowed. The nodes of the resources without rules are released. It must be called with rwMux locked.
+func retainStatNodes() {
+	resources := make([]string, 0, len(ruleMap))
+	for res := range ruleMap {
+		resources = append(resources, res)
+	}
+	stat.RetainResourceNodes(statNodeOwner, resources)
+}
+
 // ClearRules clears all the rules in isolation module.
 func ClearRules() error {
 	_, err := LoadRules(nil)
@@ -173,22 +259,30 @@ func ClearRulesOfResource(res string) error {
 
 // GetRules returns all the rules based on copy.
 // It doesn't take effect for isolation module if user changes the rule.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
 func GetRules() []Rule {
 	rules := getRules()
+	now := util.CurrentTimeMillis()
 	ret := make([]Rule, 0, len(rules))
 	for _, rule := range rules {
-		ret = append(ret, *rule)
+		ret = append(ret, ruleExpiration.CopyWithLifetime(rule, now))
 	}
 	return ret
 }
 
-// GetRulesOfResource returns specific resource's rules based on copy.
+// GetRulesOfResource returns specific resource's rules based on copy, the rules with Selector are returned by
+// the name of rule group.
 // It doesn't take effect for isolation module if user changes the rule.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
 func GetRulesOfResource(res string) []Rule {
 	rules := getRulesOfResource(res)
+	rwMux.RLock()
+	rules = append(rules, selectorRuleMap[res]...)
+	rwMux.RUnlock()
+	now := util.CurrentTimeMillis()
 	ret := make([]Rule, 0, len(rules))
 	for _, rule := range rules {
-		ret = append(ret, *rule)
+		ret = append(ret, ruleExpiration.CopyWithLifetime(rule, now))
 	}
 	return ret
 }
@@ -199,7 +293,7 @@ func getRules() []*Rule {
 	rwMux.RLock()
 	defer rwMux.RUnlock()
 
-	return rulesFrom(ruleMap)
+	return append(rulesFrom(ruleMap), rulesFrom(selectorRuleMap)...)
 }
 
 // getRulesOfResource returns specific resource's rules。Any changes of rules take effect for isolation module
@@ -273,25 +367,100 @@ func IsValidRule(r *Rule) error {
 	if len(r.Resource) == 0 {
 		return errors.New("empty resource of isolation rule")
 	}
-	if r.MetricType != Concurrency {
+	if r.Regex {
+		if err := (base.ResourcePattern{Strategy: base.RegexMatch, Pattern: r.Resource}).IsValid(); err != nil {
+			return err
+		}
+	}
+	if r.Mode != base.Enforce && r.Mode != base.Shadow {
+		return errors.New("invalid mode")
+	}
+	if err := base.CheckEnforcePercent(r.EnforcePercent); err != nil {
+		return err
+	}
+	if err := r.Canary.IsValid(); err != nil {
+		return err
+	}
+	if err := condition.IsValid(r.Condition); err != nil {
+		return errors.Wrap(err, "invalid Condition")
+	}
+	if r.Selector != nil {
+		if r.Regex {
+			return errors.New("Regex and Selector are mutually exclusive")
+		}
+		if err := r.Selector.IsValid(); err != nil {
+			return errors.Wrap(err, "invalid Selector")
+		}
+	}
+	if r.ExpireAt > 0 && r.ExpireAt <= util.CurrentTimeMillis() {
+		return errors.New("rule already expired")
+	}
+	if r.MetricType != Concurrency && r.MetricType != WeightedConcurrency {
 		return errors.Errorf("unsupported metric type: %d", r.MetricType)
 	}
 	if r.Threshold == 0 {
 		return errors.New("zero threshold")
 	}
+	if err := r.Schedule.IsValid(); err != nil {
+		return errors.Wrap(err, "invalid schedule")
+	}
+	switch r.ControlBehavior {
+	case Reject:
+	case Queueing:
+		if r.MaxQueueSize == 0 {
+			return errors.New("zero MaxQueueSize for queueing isolation rule")
+		}
+		if r.MaxQueueingTimeMs == 0 {
+			return errors.New("zero MaxQueueingTimeMs for queueing isolation rule")
+		}
+	default:
+		return errors.Errorf("unsupported control behavior: %d", r.ControlBehavior)
+	}
 	return nil
 }
 
-func regexRuleMatches(resource string) []*Rule {
-	result := make([]*Rule, 0)
-	for pattern, regexRules := range regexRuleMap {
-		re := regexp.MustCompile(pattern)
-		if !re.MatchString(resource) {
-			continue
-		}
-		for _, rule := range regexRules {
+// selectorRulesOf returns the rules selecting the resource of entry with the most specific selectors.
+func selectorRulesOf(ctx *base.EntryContext) []*Rule {
+	rwMux.RLock()
+	defer rwMux.RUnlock()
+
+	var result []*Rule
+	specificity := -1
+	for _, rules := range selectorRuleMap {
+		for _, rule := range rules {
+			if !rule.Selector.Matches(ctx) {
+				continue
+			}
+			if s := rule.Selector.Specificity(); s > specificity {
+				specificity = s
+				result = result[:0]
+			} else if s < specificity {
+				continue
+			}
 			result = append(result, rule)
 		}
 	}
 	return result
 }
+
+func regexRuleMatches(resource string) []*Rule {
+	result := make([]*Rule, 0)
+	for _, pattern := range regexMatcher.Match(resource) {
+		result = append(result, regexRuleMap[pattern.Pattern]...)
+	}
+	return result
+}
+
+func buildRegexMatcher(regexRules map[string][]*Rule) *base.ResourceMatcher {
+	patterns := make([]base.ResourcePattern, 0, len(regexRules))
+	for pattern, rules := range regexRules {
+		if len(rules) > 0 {
+			patterns = append(patterns, base.ResourcePattern{Strategy: base.RegexMatch, Pattern: pattern})
+		}
+	}
+	matcher, err := base.NewResourceMatcher(patterns)
+	if err != nil {
+		logging.Error(err, "Fail to build the matcher of regex rules in isolation.buildRegexMatcher()")
+	}
+	return matcher
+}
@@ -16,8 +16,12 @@ package isolation
 
 import (
 	"testing"
+	"time"
 
+	"github.com/alibaba/sentinel-golang/core/schedule"
+	"github.com/alibaba/sentinel-golang/core/stat"
 	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/alibaba/sentinel-golang/util"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,7 +45,7 @@ func TestLoadRules(t *testing.T) {
 		}
 		r3 := &Rule{
 			Resource:   "abc3",
-			MetricType: MetricType(1),
+			MetricType: MetricType(100),
 			Threshold:  200,
 		}
 		_, err := LoadRules([]*Rule{r1, r2, r3})
@@ -89,7 +93,7 @@ func TestLoadRules(t *testing.T) {
 		}
 		r3 := &Rule{
 			Resource:   "abc3",
-			MetricType: MetricType(1),
+			MetricType: MetricType(100),
 			Threshold:  200,
 		}
 
@@ -121,13 +125,13 @@ func TestLoadRulesOfResource(t *testing.T) {
 	}
 	r3 := &Rule{
 		Resource:   "abc3",
-		MetricType: MetricType(1),
+		MetricType: MetricType(100),
 		Threshold:  200,
 	}
 
 	r4 := &Rule{
 		Resource:   "abc2",
-		MetricType: MetricType(1),
+		MetricType: MetricType(100),
 		Threshold:  300,
 	}
 
@@ -151,7 +155,12 @@ func TestLoadRulesOfResource(t *testing.T) {
 		assert.True(t, succ && err == nil)
 		assert.True(t, len(ruleMap["abc1"]) == 0 && len(currentRules["abc1"]) == 0)
 		assert.True(t, len(ruleMap["abc2"]) == 1 && len(currentRules["abc2"]) == 2)
+		// the node of the resource without rules is released
+		assert.False(t, stat.GetResourceNode("abc1").IsRetained())
+		assert.True(t, stat.GetResourceNode("abc2").IsRetained())
 	})
+	assert.Nil(t, ClearRules())
+	assert.False(t, stat.GetResourceNode("abc2").IsRetained())
 	clearData()
 }
 
@@ -170,7 +179,7 @@ func Test_ResourceRuleUpdate(t *testing.T) {
 		}
 		r3 := &Rule{
 			Resource:   "abc3",
-			MetricType: MetricType(1),
+			MetricType: MetricType(100),
 			Threshold:  200,
 		}
 
@@ -205,7 +214,7 @@ func Test_ResourceRuleUpdate(t *testing.T) {
 		}
 		r3 := &Rule{
 			Resource:   "abc3",
-			MetricType: MetricType(1),
+			MetricType: MetricType(100),
 			Threshold:  200,
 		}
 
@@ -223,3 +232,71 @@ func Test_ResourceRuleUpdate(t *testing.T) {
 		clearData()
 	})
 }
+
+func TestIsValidRule(t *testing.T) {
+	t.Run("TestIsValidRule_Queueing", func(t *testing.T) {
+		r := &Rule{
+			Resource:          "abc",
+			MetricType:        Concurrency,
+			Threshold:         10,
+			ControlBehavior:   Queueing,
+			MaxQueueSize:      10,
+			MaxQueueingTimeMs: 100,
+		}
+		assert.Nil(t, IsValidRule(r))
+
+		r.MaxQueueSize = 0
+		assert.NotNil(t, IsValidRule(r))
+		r.MaxQueueSize = 10
+		r.MaxQueueingTimeMs = 0
+		assert.NotNil(t, IsValidRule(r))
+		r.ControlBehavior = ControlBehavior(10)
+		assert.NotNil(t, IsValidRule(r))
+	})
+
+	t.Run("TestIsValidRule_Regex", func(t *testing.T) {
+		r := &Rule{
+			Resource:   "abc/(",
+			MetricType: Concurrency,
+			Threshold:  10,
+		}
+		assert.Nil(t, IsValidRule(r))
+		r.Regex = true
+		assert.NotNil(t, IsValidRule(r))
+		r.Resource = "abc/[0-9]+"
+		assert.Nil(t, IsValidRule(r))
+	})
+}
+
+func TestThresholdOf(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+	defer clearData()
+
+	begin := util.Now().Add(2 * time.Second)
+	r := &Rule{
+		Resource:   "abc",
+		MetricType: Concurrency,
+		Threshold:  10,
+		Schedule: &schedule.Schedule{
+			Windows: []schedule.Window{
+				{Begin: begin.Format(time.RFC3339), End: begin.Add(time.Minute).Format(time.RFC3339), Threshold: 2},
+			},
+		},
+	}
+	_, err := LoadRules([]*Rule{r})
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(10), thresholdOf(r))
+	util.Sleep(3 * time.Second)
+	assert.Equal(t, uint32(2), thresholdOf(r))
+	assert.Equal(t, 1, len(scheduleMap))
+	util.Sleep(time.Minute)
+	assert.Equal(t, uint32(10), thresholdOf(r))
+
+	_, err = LoadRules(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(scheduleMap))
+
+	r.Schedule.Windows[0].Begin = "invalid"
+	assert.Error(t, IsValidRule(r))
+}
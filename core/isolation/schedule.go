@@ -0,0 +1,67 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolation
+
+import (
+	"sync"
+
+	"github.com/alibaba/sentinel-golang/core/schedule"
+	"github.com/alibaba/sentinel-golang/logging"
+)
+
+var (
+	// scheduleMap caches the compiled schedule of rules, since the rules are used directly rather than wrapped by controllers.
+	scheduleMap = make(map[*Rule]*schedule.Evaluator)
+	scheduleMux = &sync.RWMutex{}
+)
+
+// thresholdOf returns the threshold of the rule overridden by its schedule at current time.
+func thresholdOf(rule *Rule) uint32 {
+	if rule.Schedule == nil {
+		return rule.Threshold
+	}
+	return uint32(getOrCreateSchedule(rule).Threshold(float64(rule.Threshold)))
+}
+
+func getOrCreateSchedule(rule *Rule) *schedule.Evaluator {
+	scheduleMux.RLock()
+	e, ok := scheduleMap[rule]
+	scheduleMux.RUnlock()
+	if ok {
+		return e
+	}
+
+	scheduleMux.Lock()
+	defer scheduleMux.Unlock()
+	if e, ok = scheduleMap[rule]; !ok {
+		var err error
+		if e, err = schedule.NewEvaluator(rule.Schedule); err != nil {
+			logging.Warn("[Isolation getOrCreateSchedule] Ignoring the invalid schedule of rule", "rule", rule, "reason", err.Error())
+		}
+		scheduleMap[rule] = e
+	}
+	return e
+}
+
+// resetSchedules removes the compiled schedules of the rules matching the filter.
+func resetSchedules(filter func(rule *Rule) bool) {
+	scheduleMux.Lock()
+	defer scheduleMux.Unlock()
+	for rule := range scheduleMap {
+		if filter(rule) {
+			delete(scheduleMap, rule)
+		}
+	}
+}
@@ -15,9 +15,11 @@
 package isolation
 
 import (
+	"sync"
+
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/stat"
 	"github.com/alibaba/sentinel-golang/logging"
-	"github.com/pkg/errors"
 )
 
 const (
@@ -26,6 +28,8 @@ const (
 
 var (
 	DefaultSlot = &Slot{}
+
+	overflowWarnOnce sync.Once
 )
 
 type Slot struct {
@@ -41,8 +45,7 @@ func (s *Slot) Check(ctx *base.EntryContext) *base.TokenResult {
 	if len(resource) == 0 {
 		return result
 	}
-	if passed, rule, snapshot := checkPass(ctx); !passed {
-		msg := "concurrency exceeds threshold"
+	if passed, rule, snapshot, msg := checkPass(ctx); !passed {
 		if result == nil {
 			result = base.NewTokenResultBlockedWithCause(base.BlockTypeIsolation, msg, rule, snapshot)
 		} else {
@@ -52,23 +55,75 @@ func (s *Slot) Check(ctx *base.EntryContext) *base.TokenResult {
 	return result
 }
 
-func checkPass(ctx *base.EntryContext) (bool, *Rule, uint32) {
+func checkPass(ctx *base.EntryContext) (bool, *Rule, uint32, string) {
 	statNode := ctx.StatNode
-	batchCount := ctx.Input.BatchCount
+	res := ctx.Resource.Name()
 	curCount := uint32(0)
-	for _, rule := range getRulesOfResource(ctx.Resource.Name()) {
-		threshold := rule.Threshold
-		if rule.MetricType == Concurrency {
-			if cur := statNode.CurrentConcurrency(); cur >= 0 {
-				curCount = uint32(cur)
-			} else {
-				curCount = 0
-				logging.Error(errors.New("negative concurrency"), "Negative concurrency in isolation.checkPass()", "rule", rule)
+	rules := getRulesOfResource(res)
+	if len(rules) == 0 {
+		// The rules targeting the resource by name take precedence over the rules with Selector.
+		rules = selectorRulesOf(ctx)
+	}
+	for _, rule := range rules {
+		var q *waitQueue
+		if rule.ControlBehavior == Queueing {
+			// the waiters of the queue wait for the concurrency freed by the entry, whether it's queued or not
+			q = getOrCreateQueue(res, rule)
+			recordQueue(ctx, q)
+		}
+		if !rule.conditionHolds(ctx) {
+			continue
+		}
+		threshold := thresholdOf(rule)
+		// Shadow rules never queue the requests, they're checked as Reject rules.
+		shadow := !rule.isEnforcedOn(ctx)
+		switch rule.MetricType {
+		case Concurrency:
+			if statNode == base.StatNode(stat.OverflowNode()) {
+				// the resource is beyond the max resource amount, and the concurrency of the overflow node is combined
+				// from all these resources, so the rule selecting the resource by Regex or Selector can't check it
+				overflowWarnOnce.Do(func() {
+					logging.Warn("[Isolation] The Concurrency rules don't check the resources beyond the max resource amount", "rule", rule)
+				})
+				continue
 			}
-			if curCount+batchCount > threshold {
-				return false, rule, curCount
+			if q != nil && !shadow {
+				if passed, msg := q.acquire(ctx); !passed {
+					return false, rule, currentConcurrency(statNode), msg
+				}
+				continue
+			}
+			curCount = currentConcurrency(statNode)
+			if curCount+ctx.Input.BatchCount > threshold {
+				if shadow {
+					addShadowBlock(ctx, rule, curCount, "concurrency exceeds threshold")
+					continue
+				}
+				return false, rule, curCount, "concurrency exceeds threshold"
+			}
+		case WeightedConcurrency:
+			counter := getOrCreateWeightedCounter(res)
+			markWeighted(ctx, counter)
+			if q != nil && !shadow {
+				if passed, msg := q.acquire(ctx); !passed {
+					return false, rule, counter.current(), msg
+				}
+				continue
+			}
+			curCount = counter.current()
+			if curCount+ctx.Input.EntryWeight() > threshold {
+				if shadow {
+					addShadowBlock(ctx, rule, curCount, "weighted concurrency exceeds threshold")
+					continue
+				}
+				return false, rule, curCount, "weighted concurrency exceeds threshold"
 			}
 		}
 	}
-	return true, nil, curCount
+	return true, nil, curCount, ""
+}
+
+// addShadowBlock records the request which would have been blocked by the shadow rule.
+func addShadowBlock(ctx *base.EntryContext, rule *Rule, snapshot uint32, msg string) {
+	ctx.AddShadowBlock(base.NewBlockErrorWithCause(base.BlockTypeIsolation, msg, rule, snapshot))
 }
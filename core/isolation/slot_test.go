@@ -153,3 +153,79 @@ func Test_RegexIsolation_Pass(t *testing.T) {
 		statSlot2.OnEntryPassed(ctx2)
 	}
 }
+
+func Test_SelectorIsolation_Pass(t *testing.T) {
+	slot := &Slot{}
+	_, err := LoadRules([]*Rule{
+		{
+			Resource:   "outbound-db",
+			MetricType: Concurrency,
+			Threshold:  50,
+			Selector: &base.ResourceSelector{
+				ResourceTypes: []base.ResourceType{base.ResTypeDBSQL},
+				TrafficTypes:  []base.TrafficType{base.Outbound},
+			},
+		},
+		{
+			Resource:   "outbound-db-batch",
+			MetricType: Concurrency,
+			Threshold:  1,
+			Selector: &base.ResourceSelector{
+				ResourceTypes: []base.ResourceType{base.ResTypeDBSQL},
+				Tags:          []string{"batch"},
+			},
+		},
+		{
+			Resource:   "select-orders",
+			MetricType: Concurrency,
+			Threshold:  100,
+		},
+	})
+	assert.Nil(t, err)
+	defer ClearRules()
+
+	newCtx := func(name string, concurrency int, tags ...string) *base.EntryContext {
+		resNode := stat.GetOrCreateResourceNode(name, base.ResTypeDBSQL)
+		for resNode.CurrentConcurrency() < int32(concurrency) {
+			resNode.IncreaseConcurrency()
+		}
+		return &base.EntryContext{
+			Resource: base.NewResourceWrapper(name, base.ResTypeDBSQL, base.Outbound),
+			StatNode: resNode,
+			Input: &base.SentinelInput{
+				BatchCount: 1,
+				Tags:       tags,
+			},
+		}
+	}
+	assert.Nil(t, slot.Check(newCtx("select-users", 49)))
+	ret := slot.Check(newCtx("select-users", 50))
+	assert.True(t, ret != nil && ret.IsBlocked())
+	// the threshold applies to each selected resource
+	assert.Nil(t, slot.Check(newCtx("select-items", 0)))
+	// the rule targeting the resource by name takes precedence
+	assert.Nil(t, slot.Check(newCtx("select-orders", 50)))
+	// the more specific selector takes precedence
+	ret = slot.Check(newCtx("select-items", 1, "batch"))
+	assert.True(t, ret != nil && ret.IsBlocked())
+	assert.Equal(t, "outbound-db-batch", ret.BlockError().TriggeredRule().(*Rule).Resource)
+	// the concurrency of the overflow node is combined from the resources beyond the max resource amount
+	overflowCtx := newCtx("select-overflowed", 0)
+	overflowCtx.StatNode = stat.OverflowNode()
+	for i := 0; i < 60; i++ {
+		stat.OverflowNode().IncreaseConcurrency()
+	}
+	assert.Nil(t, slot.Check(overflowCtx))
+	for i := 0; i < 60; i++ {
+		stat.OverflowNode().DecreaseConcurrency()
+	}
+
+	assert.Equal(t, 1, len(GetRulesOfResource("outbound-db")))
+	assert.Error(t, IsValidRule(&Rule{
+		Resource:   "outbound-db",
+		MetricType: Concurrency,
+		Threshold:  1,
+		Regex:      true,
+		Selector:   &base.ResourceSelector{TrafficTypes: []base.TrafficType{base.Outbound}},
+	}))
+}
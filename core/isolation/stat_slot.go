@@ -0,0 +1,73 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolation
+
+import (
+	"github.com/alibaba/sentinel-golang/core/base"
+)
+
+const (
+	StatSlotOrder = 3000
+)
+
+var (
+	DefaultStatSlot = &StatSlot{}
+)
+
+// StatSlot records the weighted concurrency and hands over the permits of queueing isolation rules.
+// It must be placed after stat.Slot, which updates the concurrency of resource.
+type StatSlot struct {
+}
+
+func (s *StatSlot) Order() uint32 {
+	return StatSlotOrder
+}
+
+func (s *StatSlot) OnEntryPassed(ctx *base.EntryContext) {
+	if c := weightedCounterOf(ctx); c != nil {
+		c.add(int64(ctx.Input.EntryWeight()))
+	}
+	// The admitted permits are counted in the concurrency of resource now.
+	for _, p := range permitsOf(ctx) {
+		p.q.settle(p.cost)
+	}
+}
+
+func (s *StatSlot) OnEntryBlocked(ctx *base.EntryContext, blockError *base.BlockError) {
+	// The entry is blocked by other rules, so give back the admitted permits.
+	for _, p := range permitsOf(ctx) {
+		p.q.settle(p.cost)
+		p.q.dispatch(ctx.StatNode)
+	}
+}
+
+func (s *StatSlot) OnCompleted(ctx *base.EntryContext) {
+	if c := weightedCounterOf(ctx); c != nil {
+		c.add(-int64(ctx.Input.EntryWeight()))
+	}
+	// The queues are recorded when the entry is checked, so that the completion only looks up the queues of resource
+	// if the recorded ones have been replaced by rule updates in the meantime.
+	replaced := false
+	for _, q := range queuesOf(ctx) {
+		if !q.dispatch(ctx.StatNode) {
+			replaced = true
+		}
+	}
+	if replaced {
+		for _, q := range getQueuesOf(ctx.Resource.Name()) {
+			q.dispatch(ctx.StatNode)
+		}
+	}
+}
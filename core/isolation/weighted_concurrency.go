@@ -0,0 +1,97 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolation
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/pkg/errors"
+)
+
+const (
+	weightedCounterKey = "isolation-weighted-counter"
+)
+
+var (
+	weightedCounterMap = make(map[string]*weightedCounter)
+	weightedCounterMux = &sync.RWMutex{}
+)
+
+// weightedCounter records the sum of weight of in-flight entries of a resource.
+type weightedCounter struct {
+	inFlight int64
+}
+
+func (c *weightedCounter) current() uint32 {
+	cur := atomic.LoadInt64(&c.inFlight)
+	if cur < 0 {
+		logging.Error(errors.New("negative weighted concurrency"), "Negative weighted concurrency in isolation.weightedCounter.current()", "weightedConcurrency", cur)
+		return 0
+	}
+	return uint32(cur)
+}
+
+func (c *weightedCounter) add(weight int64) {
+	atomic.AddInt64(&c.inFlight, weight)
+}
+
+// getOrCreateWeightedCounter returns the weighted counter of the given resource.
+func getOrCreateWeightedCounter(res string) *weightedCounter {
+	weightedCounterMux.RLock()
+	c, ok := weightedCounterMap[res]
+	weightedCounterMux.RUnlock()
+	if ok {
+		return c
+	}
+
+	weightedCounterMux.Lock()
+	defer weightedCounterMux.Unlock()
+	if c, ok = weightedCounterMap[res]; !ok {
+		c = &weightedCounter{}
+		weightedCounterMap[res] = c
+	}
+	return c
+}
+
+// CurrentWeightedConcurrency returns the sum of weight of in-flight entries of the given resource.
+// Only the entries checked by WeightedConcurrency rules are counted.
+func CurrentWeightedConcurrency(res string) uint32 {
+	weightedCounterMux.RLock()
+	c, ok := weightedCounterMap[res]
+	weightedCounterMux.RUnlock()
+	if !ok {
+		return 0
+	}
+	return c.current()
+}
+
+// markWeighted marks the entry to be counted in the weighted counter when passed.
+func markWeighted(ctx *base.EntryContext, c *weightedCounter) {
+	if ctx.Data == nil {
+		ctx.Data = make(map[interface{}]interface{})
+	}
+	ctx.SetPair(weightedCounterKey, c)
+}
+
+func weightedCounterOf(ctx *base.EntryContext) *weightedCounter {
+	if ctx.Data == nil {
+		return nil
+	}
+	c, _ := ctx.GetPair(weightedCounterKey).(*weightedCounter)
+	return c
+}
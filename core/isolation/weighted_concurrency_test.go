@@ -0,0 +1,93 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isolation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WeightedConcurrency(t *testing.T) {
+	defer clearData()
+	res := "weighted-reject"
+	_, err := LoadRules([]*Rule{{
+		Resource:   res,
+		MetricType: WeightedConcurrency,
+		Threshold:  100,
+	}})
+	assert.Nil(t, err)
+
+	big := newQueueingCtx(res, nil)
+	big.Input.Weight = 80
+	assert.Nil(t, doEntry(big))
+	assert.Equal(t, uint32(80), CurrentWeightedConcurrency(res))
+
+	// 80 + 30 exceeds the threshold, although the concurrency is only 2.
+	medium := newQueueingCtx(res, nil)
+	medium.Input.Weight = 30
+	ret := doEntry(medium)
+	assert.True(t, ret.IsBlocked())
+	assert.Equal(t, uint32(80), ret.BlockError().TriggeredValue())
+
+	// Weight falls back to BatchCount.
+	small := newQueueingCtx(res, nil)
+	small.Input.BatchCount = 20
+	assert.Nil(t, doEntry(small))
+	assert.Equal(t, uint32(100), CurrentWeightedConcurrency(res))
+
+	doExit(big)
+	doExit(small)
+	assert.Equal(t, uint32(0), CurrentWeightedConcurrency(res))
+	assert.Nil(t, doEntry(medium))
+	doExit(medium)
+	assert.Equal(t, uint32(0), CurrentWeightedConcurrency(res))
+}
+
+func Test_WeightedConcurrency_Queueing(t *testing.T) {
+	defer clearData()
+	res := "weighted-queueing"
+	_, err := LoadRules([]*Rule{{
+		Resource:          res,
+		MetricType:        WeightedConcurrency,
+		Threshold:         100,
+		ControlBehavior:   Queueing,
+		MaxQueueSize:      1,
+		MaxQueueingTimeMs: 2000,
+	}})
+	assert.Nil(t, err)
+
+	first := newQueueingCtx(res, nil)
+	first.Input.Weight = 60
+	assert.Nil(t, doEntry(first))
+
+	second := newQueueingCtx(res, nil)
+	second.Input.Weight = 50
+	done := make(chan *base.TokenResult)
+	go func() {
+		done <- doEntry(second)
+	}()
+	assert.Eventually(t, func() bool {
+		return CurrentQueueDepth(res) == 1
+	}, time.Second, time.Millisecond)
+
+	doExit(first)
+	assert.Nil(t, <-done)
+	assert.Equal(t, uint32(50), CurrentWeightedConcurrency(res))
+	doExit(second)
+	assert.Equal(t, uint32(0), CurrentWeightedConcurrency(res))
+}
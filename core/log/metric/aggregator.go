@@ -136,7 +136,7 @@ func aggregateIntoMap(mm metricTimeMap, metrics map[uint64]*base.MetricItem, nod
 
 func isActiveMetricItem(item *base.MetricItem) bool {
 	return item.PassQps > 0 || item.BlockQps > 0 || item.CompleteQps > 0 || item.ErrorQps > 0 ||
-		item.AvgRt > 0 || item.Concurrency > 0
+		item.AvgRt > 0 || item.Concurrency > 0 || item.ShadowBlockQps > 0
 }
 
 func isItemTimestampInTime(ts uint64, currentSecStart uint64) bool {
@@ -15,7 +15,9 @@
 package outlier
 
 import (
+	"github.com/alibaba/sentinel-golang/core/base"
 	"github.com/alibaba/sentinel-golang/core/circuitbreaker"
+	"github.com/alibaba/sentinel-golang/core/condition"
 )
 
 type RecoveryCheckFunc func(address string) bool
@@ -44,4 +46,25 @@ type Rule struct {
 	// RecoveryCheckFunc is used to determine whether a node is healthy in
 	// the active recovery mode.
 	RecoveryCheckFunc RecoveryCheckFunc
+
+	// conditionProgram is the compiled Condition of the embedded circuit breaker rule,
+	// which is compiled when the rule is loaded.
+	conditionProgram *condition.Program
+}
+
+// Lifetime returns the ExpireAt and TTLSec of the embedded circuit breaker rule.
+func (r *Rule) Lifetime() (uint64, uint32) {
+	if r.Rule == nil {
+		return 0, 0
+	}
+	return r.Rule.Lifetime()
+}
+
+// conditionHolds reports whether the Condition of the embedded circuit breaker rule holds on the entry.
+func (r *Rule) conditionHolds(ctx *base.EntryContext) bool {
+	return r.conditionProgram.Holds(ctx)
+}
+
+func (r *Rule) setConditionProgram(program *condition.Program) {
+	r.conditionProgram = program
 }
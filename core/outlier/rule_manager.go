@@ -20,7 +20,9 @@ import (
 	"reflect"
 	"sync"
 
+	"github.com/alibaba/sentinel-golang/core/base"
 	"github.com/alibaba/sentinel-golang/core/circuitbreaker"
+	"github.com/alibaba/sentinel-golang/core/condition"
 	"github.com/alibaba/sentinel-golang/logging"
 	"github.com/alibaba/sentinel-golang/util"
 )
@@ -30,14 +32,43 @@ var (
 	outlierRules = make(map[string]*Rule)
 	// resource name ---> circuitbreaker rule
 	breakerRules = make(map[string]*circuitbreaker.Rule)
-	// resource name ---> address ---> circuitbreaker
+	// resource name ---> address ---> circuitbreaker, the resources matched by the pattern of rules have their own breakers
 	nodeBreakers = make(map[string]map[string]circuitbreaker.CircuitBreaker)
+	// outlierMatcher indexes the patterns of the rules matching resources by pattern
+	outlierMatcher *base.ResourceMatcher
 	// resource name ---> outlier ejection rule
-	currentRules  = make(map[string]*Rule)
-	updateMux     = new(sync.RWMutex)
-	updateRuleMux = new(sync.Mutex)
+	currentRules   = make(map[string]*Rule)
+	updateMux      = new(sync.RWMutex)
+	updateRuleMux  = new(sync.Mutex)
+	ruleExpiration *base.RuleExpirationManager[Rule, *Rule]
 )
 
+func init() {
+	ruleExpiration = base.NewRuleExpirationManager("[Outlier]", updateRuleMux,
+		func() map[string][]*Rule {
+			resRulesMap := make(map[string][]*Rule, len(currentRules))
+			for res, rule := range currentRules {
+				resRulesMap[res] = []*Rule{rule}
+			}
+			return resRulesMap
+		},
+		func(resRulesMap map[string][]*Rule) error {
+			rulesMap := make(map[string]*Rule, len(resRulesMap))
+			for res, rules := range resRulesMap {
+				rulesMap[res] = rules[0]
+			}
+			return onRuleUpdate(rulesMap)
+		},
+		func(rule *Rule, expireAt uint64, ttlSec uint32) {
+			// copy the embedded circuit breaker rule rather than modifying the loaded one
+			if rule.Rule != nil {
+				cbRule := *rule.Rule
+				cbRule.ExpireAt, cbRule.TTLSec = expireAt, ttlSec
+				rule.Rule = &cbRule
+			}
+		})
+}
+
 func getNodeBreakersOfResource(resource string) map[string]circuitbreaker.CircuitBreaker {
 	updateMux.RLock()
 	nodes := nodeBreakers[resource]
@@ -59,8 +90,12 @@ func deleteNodeBreakerOfResource(resource string, address string) {
 }
 
 func addNodeBreakerOfResource(resource string, address string) {
+	rule := getBreakerRuleOfResource(resource)
+	if rule == nil {
+		return
+	}
 	newBreakers := circuitbreaker.BuildResourceCircuitBreaker(resource,
-		[]*circuitbreaker.Rule{getBreakerRuleOfResource(resource)}, []circuitbreaker.CircuitBreaker{})
+		[]*circuitbreaker.Rule{rule}, []circuitbreaker.CircuitBreaker{})
 	if len(newBreakers) > 0 {
 		updateMux.Lock()
 		if nodeBreakers[resource] == nil {
@@ -74,20 +109,59 @@ func addNodeBreakerOfResource(resource string, address string) {
 
 func getOutlierRuleOfResource(resource string) *Rule {
 	updateMux.RLock()
-	rule := outlierRules[resource]
+	rule := outlierRules[ruleKeyOf(resource)]
 	updateMux.RUnlock()
 	return rule
 }
 
+// getBreakerRuleOfResource returns the circuitbreaker rule of the resource, the rule matching the resource
+// by pattern is copied with the resource name to build the breakers of the resource.
 func getBreakerRuleOfResource(resource string) *circuitbreaker.Rule {
 	updateMux.RLock()
-	rule := breakerRules[resource]
+	rule := breakerRules[ruleKeyOf(resource)]
 	updateMux.RUnlock()
-	return rule
+	if rule == nil || rule.Resource == resource {
+		return rule
+	}
+	ruleOfRes := *rule
+	ruleOfRes.Resource = resource
+	ruleOfRes.MatchStrategy = base.ExactMatch
+	return &ruleOfRes
+}
+
+// ruleKeyOf returns the key of the rule taking effect on the resource, the rule of the resource name takes precedence,
+// otherwise the first rule whose pattern matches the resource. It must be called with updateMux locked.
+func ruleKeyOf(resource string) string {
+	if rule, ok := outlierRules[resource]; ok && (rule.Rule == nil || rule.MatchStrategy == base.ExactMatch) {
+		return resource
+	}
+	for _, pattern := range outlierMatcher.Match(resource) {
+		if rule, ok := outlierRules[pattern.Pattern]; ok && rule.Rule != nil && rule.MatchStrategy == pattern.Strategy {
+			return pattern.Pattern
+		}
+	}
+	return ""
+}
+
+// resetOutlierMatcher rebuilds the matcher of the rules matching resources by pattern,
+// it must be called with updateMux locked.
+func resetOutlierMatcher() {
+	patterns := make([]base.ResourcePattern, 0)
+	for resource, rule := range outlierRules {
+		if rule.Rule != nil && rule.MatchStrategy != base.ExactMatch {
+			patterns = append(patterns, base.ResourcePattern{Strategy: rule.MatchStrategy, Pattern: resource})
+		}
+	}
+	matcher, err := base.NewResourceMatcher(patterns)
+	if err != nil {
+		logging.Error(err, "Fail to build the matcher of pattern rules in outlier.resetOutlierMatcher()")
+	}
+	outlierMatcher = matcher
 }
 
 // GetRules returns all the rules based on copy.
 // It doesn't take effect for outlier ejection module if user changes the rule.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
 // GetRules need to compete outlier ejection module's global lock and the high performance losses of copy,
 //
 //	reduce or do not call GetRules if possible
@@ -95,9 +169,10 @@ func GetRules() []Rule {
 	updateMux.RLock()
 	rules := rulesFrom(outlierRules)
 	updateMux.RUnlock()
+	now := util.CurrentTimeMillis()
 	ret := make([]Rule, 0, len(rules))
 	for _, rule := range rules {
-		ret = append(ret, *rule)
+		ret = append(ret, ruleExpiration.CopyWithLifetime(rule, now))
 	}
 	return ret
 }
@@ -140,6 +215,7 @@ func LoadRules(rules []*Rule) (bool, error) {
 		return false, nil
 	}
 	err := onRuleUpdate(rulesMap)
+	ruleExpiration.Track()
 	return true, err
 }
 
@@ -158,7 +234,10 @@ func LoadRuleOfResource(res string, rule *Rule) (bool, error) {
 		delete(nodeBreakers, res)
 		delete(breakerRules, res)
 		delete(outlierRules, res)
+		resetOutlierMatcher()
 		updateMux.Unlock()
+		updateAllBreakers()
+		ruleExpiration.Track()
 		logging.Info("[Outlier] clear resource level rule", "resource", res)
 		return true, nil
 	}
@@ -169,6 +248,7 @@ func LoadRuleOfResource(res string, rule *Rule) (bool, error) {
 		return false, nil
 	}
 	err := onResourceRuleUpdate(res, rule)
+	ruleExpiration.Track()
 	return true, err
 }
 
@@ -192,23 +272,16 @@ func onResourceRuleUpdate(res string, rule *Rule) (err error) {
 		logging.Warn("[Outlier onRuleUpdate] Ignoring invalid rule when loading new rules", "rule", rule, "err", err.Error())
 		return
 	}
+	rule = condition.Prepare(rule, circuitRule.Condition, (*Rule).setConditionProgram)
 
 	start := util.CurrentTimeNano()
-	breakers := getNodeBreakersOfResource(res)
-	newBreakers := make(map[string]circuitbreaker.CircuitBreaker)
-	for address, breaker := range breakers {
-		newCbsOfRes := circuitbreaker.BuildResourceCircuitBreaker(res,
-			[]*circuitbreaker.Rule{circuitRule}, []circuitbreaker.CircuitBreaker{breaker})
-		if len(newCbsOfRes) > 0 {
-			newBreakers[address] = newCbsOfRes[0]
-		}
-	}
-
 	updateMux.Lock()
 	outlierRules[res] = rule
 	breakerRules[res] = circuitRule
-	nodeBreakers[res] = newBreakers
+	resetOutlierMatcher()
 	updateMux.Unlock()
+	// the rule may take effect on the resources matched by its pattern
+	updateAllBreakers()
 	currentRules[res] = rule
 
 	logging.Debug("[Outlier onResourceRuleUpdate] Time statistics(ns) for updating outlier ejection rule", "timeCost", util.CurrentTimeNano()-start)
@@ -241,6 +314,7 @@ func onRuleUpdate(rulesMap map[string]*Rule) (err error) {
 			logging.Warn("[Outlier onRuleUpdate] Ignoring invalid rule when loading new rules", "rule", rule, "err", err.Error())
 			continue
 		}
+		rule = condition.Prepare(rule, circuitRule.Condition, (*Rule).setConditionProgram)
 		validCircuitRulesMap[resource] = circuitRule
 		validRulesMap[resource] = rule
 	}
@@ -249,6 +323,7 @@ func onRuleUpdate(rulesMap map[string]*Rule) (err error) {
 	updateMux.Lock()
 	breakerRules = validCircuitRulesMap
 	outlierRules = validRulesMap
+	resetOutlierMatcher()
 	updateMux.Unlock()
 
 	updateAllBreakers()
@@ -287,8 +362,12 @@ func updateAllBreakers() {
 	}
 	updateMux.RUnlock()
 
-	newBreakers := make(map[string]map[string]circuitbreaker.CircuitBreaker, len(breakerRules))
-	for resource, rule := range breakerRules {
+	newBreakers := make(map[string]map[string]circuitbreaker.CircuitBreaker, len(breakersClone))
+	for resource := range breakersClone {
+		rule := getBreakerRuleOfResource(resource)
+		if rule == nil {
+			continue
+		}
 		newBreakers[resource] = make(map[string]circuitbreaker.CircuitBreaker)
 		for address, breaker := range breakersClone[resource] {
 			newCbsOfRes := circuitbreaker.BuildResourceCircuitBreaker(resource,
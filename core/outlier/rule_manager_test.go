@@ -15,10 +15,13 @@ package outlier
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/alibaba/sentinel-golang/core/base"
 	"github.com/alibaba/sentinel-golang/core/circuitbreaker"
+	"github.com/alibaba/sentinel-golang/util"
 )
 
 func clearData() {
@@ -28,6 +31,7 @@ func clearData() {
 	breakerRules = make(map[string]*circuitbreaker.Rule)
 	// resource name ---> address ---> circuitbreaker
 	nodeBreakers = make(map[string]map[string]circuitbreaker.CircuitBreaker)
+	outlierMatcher = nil
 	// resource name ---> outlier ejection rule
 	currentRules = make(map[string]*Rule)
 }
@@ -230,3 +234,87 @@ func TestClearRuleOfResource(t *testing.T) {
 		clearData()
 	})
 }
+
+func TestExpireRules(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+	defer clearData()
+
+	r := &Rule{
+		Rule: &circuitbreaker.Rule{
+			Resource:         "example.expire",
+			Strategy:         circuitbreaker.ErrorCount,
+			RetryTimeoutMs:   3000,
+			MinRequestAmount: 1,
+			StatIntervalMs:   1000,
+			Threshold:        1.0,
+			TTLSec:           10,
+		},
+		MaxEjectionPercent: 1.0,
+	}
+	_, err := LoadRules([]*Rule{r})
+	assert.Nil(t, err)
+	util.Sleep(2 * time.Second)
+	rules := GetRules()
+	assert.Equal(t, 1, len(rules))
+	assert.Equal(t, uint32(8), rules[0].TTLSec)
+	// the embedded circuit breaker rule is not modified
+	assert.Equal(t, uint32(10), r.TTLSec)
+
+	util.Sleep(8 * time.Second)
+	ruleExpiration.Expire()
+	assert.Equal(t, 0, len(GetRules()))
+	assert.Nil(t, getBreakerRuleOfResource("example.expire"))
+}
+
+func TestPatternRule(t *testing.T) {
+	defer clearData()
+	r1 := &Rule{
+		Rule: &circuitbreaker.Rule{
+			Resource:         "example.",
+			MatchStrategy:    base.PrefixMatch,
+			Strategy:         circuitbreaker.ErrorCount,
+			RetryTimeoutMs:   3000,
+			MinRequestAmount: 1,
+			StatIntervalMs:   1000,
+			Threshold:        1.0,
+		},
+		MaxEjectionPercent: 1.0,
+		RecoveryIntervalMs: 2000,
+	}
+	_, err := LoadRules([]*Rule{r1})
+	assert.Nil(t, err)
+
+	assert.True(t, getOutlierRuleOfResource("example.helloworld") == r1)
+	assert.Nil(t, getOutlierRuleOfResource("demo.helloworld"))
+	addNodeBreakerOfResource("example.helloworld", "node0")
+	addNodeBreakerOfResource("example.goodbye", "node0")
+	cbs1 := getNodeBreakersOfResource("example.helloworld")
+	cbs2 := getNodeBreakersOfResource("example.goodbye")
+	assert.True(t, len(cbs1) == 1 && cbs1["node0"].BoundRule().Resource == "example.helloworld")
+	assert.True(t, len(cbs2) == 1 && cbs1["node0"] != cbs2["node0"])
+
+	// the rule of the resource name takes precedence
+	r2 := &Rule{
+		Rule: &circuitbreaker.Rule{
+			Resource:         "example.helloworld",
+			Strategy:         circuitbreaker.ErrorCount,
+			RetryTimeoutMs:   3000,
+			MinRequestAmount: 1,
+			StatIntervalMs:   1000,
+			Threshold:        2.0,
+		},
+		MaxEjectionPercent: 1.0,
+		RecoveryIntervalMs: 2000,
+	}
+	_, err = LoadRuleOfResource(r2.Resource, r2)
+	assert.Nil(t, err)
+	assert.True(t, getOutlierRuleOfResource("example.helloworld") == r2)
+	assert.True(t, getNodeBreakersOfResource("example.helloworld")["node0"].BoundRule() == r2.Rule)
+	assert.True(t, getNodeBreakersOfResource("example.goodbye")["node0"] == cbs2["node0"])
+
+	_, err = LoadRuleOfResource(r1.Resource, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, getOutlierRuleOfResource("example.goodbye"))
+	assert.Empty(t, getNodeBreakersOfResource("example.goodbye"))
+}
@@ -41,12 +41,22 @@ func (s *Slot) Check(ctx *base.EntryContext) *base.TokenResult {
 		return result
 	}
 
+	rule := getOutlierRuleOfResource(resource)
+	if rule != nil && rule.Rule != nil && !rule.conditionHolds(ctx) {
+		return result
+	}
 	filterNodes, outlierNodes, halfOpenNodes := checkAllNodes(ctx)
-	result.SetFilterNodes(filterNodes)
-	result.SetHalfOpenNodes(halfOpenNodes)
+	if rule != nil && rule.Rule != nil && !base.IsEnforcedOn(ctx, rule.Mode, rule.EnforcePercent, rule.EnforceKey, rule.Canary) {
+		// Shadow rules never eject the nodes, they only record the requests which would have filtered nodes.
+		if len(filterNodes) != 0 {
+			ctx.AddShadowBlock(base.NewBlockErrorWithCause(base.BlockTypeCircuitBreaking, "outlier ejection", rule, filterNodes))
+		}
+	} else {
+		result.SetFilterNodes(filterNodes)
+		result.SetHalfOpenNodes(halfOpenNodes)
+	}
 
 	if len(outlierNodes) != 0 {
-		rule := getOutlierRuleOfResource(resource)
 		if rule.EnableActiveRecovery && len(retryerCh) < capacity {
 			retryerCh <- task{outlierNodes, resource}
 		}
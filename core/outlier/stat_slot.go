@@ -47,6 +47,9 @@ func (c *MetricStatSlot) OnEntryBlocked(_ *base.EntryContext, _ *base.BlockError
 }
 
 func (c *MetricStatSlot) OnCompleted(ctx *base.EntryContext) {
+	if ctx.IsReservation() {
+		return
+	}
 	res := ctx.Resource.Name()
 	err := ctx.Err()
 	nodeBreakers := getNodeBreakersOfResource(res)
@@ -0,0 +1,232 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/hotspot/cache"
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/pkg/errors"
+)
+
+// controller counts the requests of a quota rule, the counters are keyed by the formatted parameter value.
+type controller struct {
+	rule *Rule
+	// key is the key of counters in Store
+	key string
+	loc *time.Location
+
+	mux      sync.Mutex
+	counters *cache.LRU
+	changed  bool
+}
+
+// newController creates the controller of rule, the counters are inherited from prev if they share the same key,
+// otherwise restored from the store.
+func newController(rule *Rule, prev *controller) (*controller, error) {
+	loc := time.Local
+	if len(rule.TimeZone) > 0 {
+		var err error
+		if loc, err = time.LoadLocation(rule.TimeZone); err != nil {
+			return nil, errors.Wrapf(err, "invalid time zone %s", rule.TimeZone)
+		}
+	}
+	counters, err := cache.NewLRU(int(rule.paramsMaxCapacity()), nil)
+	if err != nil {
+		return nil, err
+	}
+	c := &controller{
+		rule:     rule,
+		key:      rule.storeKey(),
+		loc:      loc,
+		counters: counters,
+	}
+	var snapshots []CounterSnapshot
+	if prev != nil && prev.key == c.key {
+		snapshots = prev.snapshot()
+	} else {
+		snapshots = restoreFromStore(c.key)
+	}
+	c.restore(snapshots)
+	return c, nil
+}
+
+// paramOf returns the formatted parameter value of entry, the second returned value is false if the entry
+// is not limited by the rule.
+func (c *controller) paramOf(ctx *base.EntryContext) (string, bool) {
+	if !c.rule.PerParam {
+		return "", true
+	}
+	if len(c.rule.ParamKey) > 0 {
+		if arg, ok := ctx.Input.Attachments[c.rule.ParamKey]; ok && arg != nil {
+			return fmt.Sprint(arg), true
+		}
+		return "", false
+	}
+	args := ctx.Input.Args
+	idx := c.rule.ParamIndex
+	if idx < 0 {
+		idx = len(args) + idx
+	}
+	if idx < 0 || idx >= len(args) || args[idx] == nil {
+		if logging.DebugEnabled() {
+			logging.Debug("[Quota paramOf] The argument in index doesn't exist", "args", args, "paramIndex", c.rule.ParamIndex)
+		}
+		return "", false
+	}
+	return fmt.Sprint(args[idx]), true
+}
+
+// counterOf returns the counter of param rolled to the current window, the lock must be held.
+func (c *controller) counterOf(param string, nowMs int64) *counter {
+	var cnt *counter
+	if val, ok := c.counters.Get(param); ok {
+		cnt = val.(*counter)
+	} else {
+		cnt = &counter{}
+		c.counters.Add(param, cnt)
+	}
+	cnt.roll(fixedWindowOf(c.rule.WindowUnit, c.rule.WindowMode, c.loc, nowMs))
+	return cnt
+}
+
+// acquisition is the requests counted by a controller, which could be given back if the entry is blocked by other rules.
+type acquisition struct {
+	c           *controller
+	param       string
+	count       int64
+	windowStart int64
+}
+
+// tryAcquire counts batchCount requests of param if the quota is not exceeded, nil acquisition means the quota is exceeded
+// and the returned duration is the time to wait before retry. The quota state is returned if needInfo is true.
+func (c *controller) tryAcquire(param string, batchCount int64, needInfo bool) (*acquisition, time.Duration, *base.RateLimitInfo) {
+	nowMs := int64(util.CurrentTimeMillis())
+	mode := c.rule.WindowMode
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	cnt := c.counterOf(param, nowMs)
+	var acquired *acquisition
+	var retryAfter time.Duration
+	if cnt.estimated(mode, nowMs)+float64(batchCount) <= float64(c.rule.Threshold) {
+		cnt.count += batchCount
+		c.changed = true
+		acquired = &acquisition{c: c, param: param, count: batchCount, windowStart: cnt.windowStart}
+	} else {
+		retryAfter = time.Duration(cnt.retryAfter(mode, c.rule.Threshold, batchCount, nowMs)) * time.Millisecond
+	}
+	if !needInfo {
+		return acquired, retryAfter, nil
+	}
+	return acquired, retryAfter, &base.RateLimitInfo{
+		Limit:     float64(c.rule.Threshold),
+		Remaining: math.Max(0, math.Floor(float64(c.rule.Threshold)-cnt.estimated(mode, nowMs))),
+		Window:    time.Duration(cnt.windowEnd-cnt.windowStart) * time.Millisecond,
+		Reset:     time.Duration(cnt.resetAfter(mode, nowMs)) * time.Millisecond,
+	}
+}
+
+// release gives back the counted requests, if the counter is still in the same window.
+func (a *acquisition) release() {
+	c := a.c
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	val, ok := c.counters.Peek(a.param)
+	if !ok {
+		return
+	}
+	cnt := val.(*counter)
+	if cnt.windowStart != a.windowStart {
+		return
+	}
+	cnt.count -= a.count
+	if cnt.count < 0 {
+		cnt.count = 0
+	}
+	c.changed = true
+}
+
+// snapshot returns the states of all counters.
+func (c *controller) snapshot() []CounterSnapshot {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.snapshotLocked()
+}
+
+// snapshotIfChanged returns the states of all counters if they changed since last time, and resets the changed flag.
+func (c *controller) snapshotIfChanged() ([]CounterSnapshot, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if !c.changed {
+		return nil, false
+	}
+	c.changed = false
+	return c.snapshotLocked(), true
+}
+
+func (c *controller) snapshotLocked() []CounterSnapshot {
+	// The keys are ordered from the least recently used, so that the order is kept after restoring.
+	keys := c.counters.Keys()
+	ret := make([]CounterSnapshot, 0, len(keys))
+	for _, key := range keys {
+		val, ok := c.counters.Peek(key)
+		if !ok {
+			continue
+		}
+		cnt := val.(*counter)
+		ret = append(ret, CounterSnapshot{
+			Param:       key.(string),
+			WindowStart: cnt.windowStart,
+			WindowEnd:   cnt.windowEnd,
+			Count:       cnt.count,
+			PrevCount:   cnt.prevCount,
+		})
+	}
+	return ret
+}
+
+func (c *controller) markChanged() {
+	c.mux.Lock()
+	c.changed = true
+	c.mux.Unlock()
+}
+
+func (c *controller) restore(snapshots []CounterSnapshot) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	for _, s := range snapshots {
+		if !c.rule.PerParam && len(s.Param) > 0 {
+			continue
+		}
+		c.counters.Add(s.Param, &counter{
+			windowStart: s.WindowStart,
+			windowEnd:   s.WindowEnd,
+			count:       s.Count,
+			prevCount:   s.PrevCount,
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestController_TryAcquire(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	c, err := newController(&Rule{
+		Resource:   "abc",
+		Threshold:  3,
+		WindowUnit: Minute,
+		WindowMode: Calendar,
+		TimeZone:   "UTC",
+		PerParam:   true,
+	}, nil)
+	assert.Nil(t, err)
+
+	// Start at the beginning of a minute.
+	nowMs := int64(util.CurrentTimeMillis())
+	_, end := fixedWindowOf(Minute, Calendar, time.UTC, nowMs)
+	util.Sleep(time.Duration(end-nowMs) * time.Millisecond)
+
+	a, _, info := c.tryAcquire("a", 2, true)
+	assert.NotNil(t, a)
+	if assert.NotNil(t, info) {
+		assert.Equal(t, 3.0, info.Limit)
+		assert.Equal(t, 1.0, info.Remaining)
+		assert.Equal(t, time.Minute, info.Window)
+		assert.Equal(t, time.Minute, info.Reset)
+	}
+	util.Sleep(20 * time.Second)
+	acquired, _, _ := c.tryAcquire("a", 1, false)
+	assert.NotNil(t, acquired)
+	acquired, retryAfter, info := c.tryAcquire("a", 1, true)
+	assert.Nil(t, acquired)
+	assert.Equal(t, 40*time.Second, retryAfter)
+	assert.Equal(t, 0.0, info.Remaining)
+
+	// The quota is counted per parameter.
+	acquired, _, _ = c.tryAcquire("b", 3, false)
+	assert.NotNil(t, acquired)
+
+	// The released requests could be counted again.
+	a.release()
+	acquired, _, _ = c.tryAcquire("a", 2, false)
+	assert.NotNil(t, acquired)
+
+	// The quota is reset in the next window, and the release of previous window is ignored.
+	util.Sleep(40 * time.Second)
+	acquired, _, _ = c.tryAcquire("a", 3, false)
+	assert.NotNil(t, acquired)
+	a.release()
+	acquired, _, _ = c.tryAcquire("a", 1, false)
+	assert.Nil(t, acquired)
+}
+
+func TestController_Rolling(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	c, err := newController(&Rule{
+		Resource:   "abc",
+		Threshold:  100,
+		WindowUnit: Minute,
+		WindowMode: Rolling,
+	}, nil)
+	assert.Nil(t, err)
+
+	nowMs := int64(util.CurrentTimeMillis())
+	_, end := fixedWindowOf(Minute, Rolling, nil, nowMs)
+	util.Sleep(time.Duration(end-nowMs)*time.Millisecond + 30*time.Second)
+	acquired, _, _ := c.tryAcquire("", 100, false)
+	assert.NotNil(t, acquired)
+
+	// Half of the previous window overlaps with the rolling window.
+	util.Sleep(60 * time.Second)
+	acquired, _, info := c.tryAcquire("", 50, true)
+	assert.NotNil(t, acquired)
+	assert.Equal(t, 0.0, info.Remaining)
+	acquired, retryAfter, _ := c.tryAcquire("", 10, false)
+	assert.Nil(t, acquired)
+	assert.Equal(t, 6*time.Second, retryAfter)
+
+	util.Sleep(6 * time.Second)
+	acquired, _, _ = c.tryAcquire("", 10, false)
+	assert.NotNil(t, acquired)
+}
+
+func TestController_ParamOf(t *testing.T) {
+	newCtx := func(args []interface{}, attachments map[interface{}]interface{}) *base.EntryContext {
+		return &base.EntryContext{
+			Input: &base.SentinelInput{Args: args, Attachments: attachments},
+		}
+	}
+	byIndex, err := newController(&Rule{Resource: "abc", Threshold: 1, PerParam: true, ParamIndex: -1}, nil)
+	assert.Nil(t, err)
+	param, ok := byIndex.paramOf(newCtx([]interface{}{"x", 10}, nil))
+	assert.True(t, ok)
+	assert.Equal(t, "10", param)
+	_, ok = byIndex.paramOf(newCtx(nil, nil))
+	assert.False(t, ok)
+
+	byKey, err := newController(&Rule{Resource: "abc", Threshold: 1, PerParam: true, ParamKey: "tenant"}, nil)
+	assert.Nil(t, err)
+	param, ok = byKey.paramOf(newCtx([]interface{}{"x"}, map[interface{}]interface{}{"tenant": "t1"}))
+	assert.True(t, ok)
+	assert.Equal(t, "t1", param)
+	_, ok = byKey.paramOf(newCtx([]interface{}{"x"}, nil))
+	assert.False(t, ok)
+
+	resourceLevel, err := newController(&Rule{Resource: "abc", Threshold: 1}, nil)
+	assert.Nil(t, err)
+	param, ok = resourceLevel.paramOf(newCtx(nil, nil))
+	assert.True(t, ok)
+	assert.Equal(t, "", param)
+}
+
+func TestController_ParamsMaxCapacity(t *testing.T) {
+	c, err := newController(&Rule{Resource: "abc", Threshold: 1, PerParam: true, ParamsMaxCapacity: 2}, nil)
+	assert.Nil(t, err)
+	for _, param := range []string{"a", "b", "c"} {
+		acquired, _, _ := c.tryAcquire(param, 1, false)
+		assert.NotNil(t, acquired)
+	}
+	// The counter of the least recently used parameter is evicted.
+	snapshots := c.snapshot()
+	assert.Equal(t, 2, len(snapshots))
+	assert.Equal(t, "b", snapshots[0].Param)
+	assert.Equal(t, "c", snapshots[1].Param)
+}
@@ -0,0 +1,29 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quota provides the long-window quota control, e.g. 10k calls per day per tenant.
+//
+// Different from the flow rules based on the sliding window statistic in memory, the quota rules count the
+// requests in the windows of minute, hour or day with a few counters, which could be persisted to a pluggable
+// Store (FileStore is built in), so that the usage is not reset after restart.
+//
+// The quota window could be:
+//
+//   - Calendar: aligned to the calendar in the time zone of rule, e.g. the quota of a day is reset at 00:00.
+//   - Rolling: the window ends at the current time, the count is estimated by weighting the count of the
+//     previous fixed window with its overlap with the rolling window.
+//
+// The quota could be counted per value of the parameter (e.g. tenant ID), which is extracted by ParamKey
+// or ParamIndex like the hotspot rules.
+package quota
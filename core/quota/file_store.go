@@ -0,0 +1,87 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/pkg/errors"
+)
+
+// FileStore saves the counters of each rule as a JSON file in the directory.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates the FileStore in dir, the directory is created if not exists.
+func NewFileStore(dir string) (*FileStore, error) {
+	if len(dir) == 0 {
+		return nil, errors.New("empty directory of quota file store")
+	}
+	if err := util.CreateDirIfNotExists(dir); err != nil {
+		return nil, errors.Wrapf(err, "fail to create directory %s", dir)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) fileOf(key string) string {
+	return filepath.Join(s.dir, url.QueryEscape(key)+".json")
+}
+
+func (s *FileStore) Load(key string) ([]CounterSnapshot, error) {
+	content, err := ioutil.ReadFile(s.fileOf(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	counters := make([]CounterSnapshot, 0)
+	if err := json.Unmarshal(content, &counters); err != nil {
+		return nil, errors.Wrapf(err, "fail to parse quota counters of %s", key)
+	}
+	return counters, nil
+}
+
+// Save writes the counters to a temporary file and then renames it, so that the file is never half written.
+func (s *FileStore) Save(key string, counters []CounterSnapshot) error {
+	content, err := json.Marshal(counters)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(s.dir, ".quota-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), s.fileOf(key)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
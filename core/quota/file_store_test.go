@@ -0,0 +1,91 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sentinel-quota")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := NewFileStore(dir)
+	assert.Nil(t, err)
+	counters, err := s.Load("abc|Day")
+	assert.Nil(t, err)
+	assert.Nil(t, counters)
+
+	saved := []CounterSnapshot{
+		{Param: "t1", WindowStart: 1000, WindowEnd: 2000, Count: 10},
+		{Param: "t/2", WindowStart: 1000, WindowEnd: 2000, Count: 20, PrevCount: 5},
+	}
+	assert.Nil(t, s.Save("abc|Day", saved))
+	counters, err = s.Load("abc|Day")
+	assert.Nil(t, err)
+	assert.Equal(t, saved, counters)
+
+	// The file is replaced on saving.
+	assert.Nil(t, s.Save("abc|Day", saved[:1]))
+	counters, err = s.Load("abc|Day")
+	assert.Nil(t, err)
+	assert.Equal(t, saved[:1], counters)
+
+	_, err = NewFileStore("")
+	assert.NotNil(t, err)
+}
+
+func TestStore_RestoreAfterRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sentinel-quota")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	s, err := NewFileStore(dir)
+	assert.Nil(t, err)
+	SetStore(s, time.Hour)
+	defer SetStore(nil, 0)
+	defer ClearRules()
+
+	rule := &Rule{Resource: "abc", Threshold: 10, WindowUnit: Day, PerParam: true, ParamIndex: 0}
+	_, err = LoadRules([]*Rule{rule})
+	assert.Nil(t, err)
+	acquired, _, _ := getControllersOfResource("abc")[0].tryAcquire("t1", 8, false)
+	assert.NotNil(t, acquired)
+	assert.Nil(t, Flush())
+
+	// The rule is loaded by another process, the usage is restored from the store.
+	assert.Nil(t, ClearRules())
+	_, err = LoadRules([]*Rule{{Resource: "abc", Threshold: 10, WindowUnit: Day, PerParam: true, ParamIndex: 0}})
+	assert.Nil(t, err)
+	c := getControllersOfResource("abc")[0]
+	acquired, _, _ = c.tryAcquire("t1", 3, false)
+	assert.Nil(t, acquired)
+	acquired, _, _ = c.tryAcquire("t1", 2, false)
+	assert.NotNil(t, acquired)
+
+	// The usage is inherited when the threshold is updated.
+	_, err = LoadRules([]*Rule{{Resource: "abc", Threshold: 12, WindowUnit: Day, PerParam: true, ParamIndex: 0}})
+	assert.Nil(t, err)
+	c = getControllersOfResource("abc")[0]
+	acquired, _, _ = c.tryAcquire("t1", 3, false)
+	assert.Nil(t, acquired)
+	acquired, _, _ = c.tryAcquire("t1", 2, false)
+	assert.NotNil(t, acquired)
+}
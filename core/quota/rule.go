@@ -0,0 +1,199 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
+)
+
+const (
+	DefaultParamsMaxCapacity int64 = 10000
+)
+
+// WindowUnit is the time unit of quota window.
+type WindowUnit int32
+
+const (
+	Minute WindowUnit = iota
+	Hour
+	Day
+)
+
+func (u WindowUnit) String() string {
+	switch u {
+	case Minute:
+		return "Minute"
+	case Hour:
+		return "Hour"
+	case Day:
+		return "Day"
+	default:
+		return "Undefined"
+	}
+}
+
+func (u WindowUnit) duration() time.Duration {
+	switch u {
+	case Minute:
+		return time.Minute
+	case Hour:
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// WindowMode indicates how the quota window moves.
+type WindowMode int32
+
+const (
+	// Calendar windows are aligned to the calendar in the time zone of rule.
+	Calendar WindowMode = iota
+	// Rolling windows end at the current time.
+	Rolling
+)
+
+func (m WindowMode) String() string {
+	switch m {
+	case Calendar:
+		return "Calendar"
+	case Rolling:
+		return "Rolling"
+	default:
+		return "Undefined"
+	}
+}
+
+// Rule describes the quota of a resource in a long window.
+type Rule struct {
+	// ID represents the unique ID of the rule (optional).
+	// The counters are persisted under ID, or the key generated by the resource, window and parameter if ID is empty.
+	ID string `json:"id,omitempty"`
+	// Resource represents the target resource definition.
+	Resource string `json:"resource"`
+	// Threshold is the max count of requests in a window.
+	Threshold  int64      `json:"threshold"`
+	WindowUnit WindowUnit `json:"windowUnit"`
+	WindowMode WindowMode `json:"windowMode"`
+	// TimeZone is the IANA time zone name (e.g. "Asia/Shanghai") which the Calendar windows are aligned in,
+	// the local time zone by default.
+	TimeZone string `json:"timeZone"`
+	// PerParam indicates whether the quota is counted per value of parameter.
+	// The parameter is extracted from Attachments by ParamKey if ParamKey is not empty, or from Args by ParamIndex,
+	// the requests without the parameter are not limited by the rule.
+	PerParam   bool   `json:"perParam"`
+	ParamIndex int    `json:"paramIndex"`
+	ParamKey   string `json:"paramKey"`
+	// ParamsMaxCapacity is the max count of parameter values counted, 10000 by default.
+	// The counters of the least recently used values are evicted beyond the capacity.
+	ParamsMaxCapacity int64 `json:"paramsMaxCapacity"`
+	// Mode indicates whether the rule blocks the requests (Enforce) or only records the would-be blocked ones (Shadow).
+	// Shadow rules don't count the would-be blocked requests into the quota.
+	Mode base.RuleMode `json:"mode,omitempty"`
+	// EnforcePercent is the percentage of traffic the rule is enforced on, the valid range is [0.0, 100.0] and 0 means all the traffic.
+	// The rest of the traffic is checked in the Shadow mode.
+	EnforcePercent float64 `json:"enforcePercent,omitempty"`
+	// EnforceKey is the attachment key whose value selects the enforced traffic by hash, the traffic is selected randomly if empty.
+	EnforceKey string `json:"enforceKey,omitempty"`
+	// Canary selects the instances the rule is enforced on, nil means all the instances.
+	Canary *base.CanarySelector `json:"canary,omitempty"`
+	// Condition is the optional expression the rule only takes effect when it holds, e.g. `attachments.caller != "internal"`,
+	// see package condition for the syntax.
+	Condition string `json:"condition,omitempty"`
+	// conditionProgram is the compiled Condition, which is compiled when the rule is loaded.
+	conditionProgram *condition.Program
+	// ExpireAt is the Unix timestamp in milliseconds when the rule expires and is dropped automatically, 0 means never.
+	ExpireAt uint64 `json:"expireAt,omitempty"`
+	// TTLSec is the lifetime in seconds of the rule since it's loaded, 0 means forever.
+	// If both ExpireAt and TTLSec are set, the earlier one takes effect.
+	// A rule expired by TTLSec comes back with a new lifetime if it's loaded again, so prefer ExpireAt for rules from data sources.
+	TTLSec uint32 `json:"ttlSec,omitempty"`
+}
+
+// storeKey returns the key of the counters of rule in Store.
+func (r *Rule) storeKey() string {
+	if len(r.ID) > 0 {
+		return r.ID
+	}
+	key := fmt.Sprintf("%s|%s|%s|%s", r.Resource, r.WindowUnit, r.WindowMode, r.TimeZone)
+	if r.PerParam {
+		key += fmt.Sprintf("|%d|%s", r.ParamIndex, r.ParamKey)
+	}
+	return key
+}
+
+func (r *Rule) paramsMaxCapacity() int64 {
+	if !r.PerParam {
+		return 1
+	}
+	if r.ParamsMaxCapacity <= 0 {
+		return DefaultParamsMaxCapacity
+	}
+	return r.ParamsMaxCapacity
+}
+
+func (r *Rule) isEqualsTo(newRule *Rule) bool {
+	if newRule == nil {
+		return false
+	}
+	return r.ID == newRule.ID && r.Resource == newRule.Resource && r.Threshold == newRule.Threshold &&
+		r.WindowUnit == newRule.WindowUnit && r.WindowMode == newRule.WindowMode && r.TimeZone == newRule.TimeZone &&
+		r.PerParam == newRule.PerParam && r.ParamIndex == newRule.ParamIndex && r.ParamKey == newRule.ParamKey &&
+		r.ParamsMaxCapacity == newRule.ParamsMaxCapacity && r.Mode == newRule.Mode &&
+		r.EnforcePercent == newRule.EnforcePercent && r.EnforceKey == newRule.EnforceKey &&
+		r.Canary.Equals(newRule.Canary) && r.Condition == newRule.Condition
+}
+
+func (r *Rule) String() string {
+	b, err := json.Marshal(r)
+	if err != nil {
+		// Return the fallback string
+		return fmt.Sprintf("Rule{Resource=%s, Threshold=%d, WindowUnit=%s, WindowMode=%s, TimeZone=%s, PerParam=%t, ParamIndex=%d, ParamKey=%s}",
+			r.Resource, r.Threshold, r.WindowUnit, r.WindowMode, r.TimeZone, r.PerParam, r.ParamIndex, r.ParamKey)
+	}
+	return string(b)
+}
+
+func (r *Rule) ResourceName() string {
+	return r.Resource
+}
+
+// Lifetime returns the ExpireAt and TTLSec of the rule.
+func (r *Rule) Lifetime() (uint64, uint32) {
+	return r.ExpireAt, r.TTLSec
+}
+
+// isEnforcedOn reports whether the rule is enforced on the entry, otherwise the rule is checked in the Shadow mode.
+func (r *Rule) isEnforcedOn(ctx *base.EntryContext) bool {
+	return base.IsEnforcedOn(ctx, r.Mode, r.EnforcePercent, r.EnforceKey, r.Canary)
+}
+
+// conditionHolds reports whether the Condition of the rule holds on the entry.
+func (r *Rule) conditionHolds(ctx *base.EntryContext) bool {
+	return r.conditionProgram.Holds(ctx)
+}
+
+func (r *Rule) setConditionProgram(program *condition.Program) {
+	r.conditionProgram = program
+}
+
+func (r *Rule) setCanary(canary *base.CanarySelector) {
+	r.Canary = canary
+}
@@ -0,0 +1,301 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/pkg/errors"
+)
+
+var (
+	controllerMap  = make(map[string][]*controller)
+	rwMux          = &sync.RWMutex{}
+	currentRules   = make(map[string][]*Rule, 0)
+	updateRuleMux  = new(sync.Mutex)
+	ruleExpiration *base.RuleExpirationManager[Rule, *Rule]
+)
+
+func init() {
+	ruleExpiration = base.NewRuleExpirationManager("[Quota]", updateRuleMux,
+		func() map[string][]*Rule {
+			return currentRules
+		},
+		func(resRulesMap map[string][]*Rule) error {
+			onRuleUpdate(resRulesMap)
+			return nil
+		},
+		func(rule *Rule, expireAt uint64, ttlSec uint32) {
+			rule.ExpireAt, rule.TTLSec = expireAt, ttlSec
+		})
+}
+
+// LoadRules loads the given quota rules to the rule manager, while all previous rules will be replaced.
+// the first returned value indicates whether do real load operation, if the rules is the same with previous rules, return false
+func LoadRules(rules []*Rule) (bool, error) {
+	resRulesMap := make(map[string][]*Rule, 16)
+	for _, rule := range rules {
+		resRules, exist := resRulesMap[rule.Resource]
+		if !exist {
+			resRules = make([]*Rule, 0, 1)
+		}
+		resRulesMap[rule.Resource] = append(resRules, rule)
+	}
+
+	updateRuleMux.Lock()
+	defer updateRuleMux.Unlock()
+	isEqual := reflect.DeepEqual(currentRules, resRulesMap)
+	if isEqual {
+		logging.Info("[Quota] Load rules is the same with current rules, so ignore load operation.")
+		return false, nil
+	}
+
+	onRuleUpdate(resRulesMap)
+	ruleExpiration.Track()
+	return true, nil
+}
+
+// buildResourceControllers builds the controllers of the valid rules. The controller of the equal rule in oldControllers
+// is reused, and the counters of the old controller with the same store key are inherited, so the usage is kept.
+func buildResourceControllers(res string, rules []*Rule, oldControllers []*controller) []*controller {
+	newControllers := make([]*controller, 0, len(rules))
+	for _, rule := range rules {
+		if err := IsValidRule(rule); err != nil {
+			logging.Warn("[Quota buildResourceControllers] Ignoring invalid quota rule", "rule", rule, "reason", err.Error())
+			continue
+		}
+		rule = condition.Prepare(rule, rule.Condition, (*Rule).setConditionProgram)
+		rule = base.ResolveCanary(rule, rule.Canary, (*Rule).setCanary)
+		reuseIdx := -1
+		for i, old := range oldControllers {
+			if old != nil && old.rule.isEqualsTo(rule) {
+				reuseIdx = i
+				break
+			}
+		}
+		if reuseIdx >= 0 {
+			newControllers = append(newControllers, oldControllers[reuseIdx])
+			// Remove the reused controller, so the equal rules won't share the same counters.
+			oldControllers = append(oldControllers[:reuseIdx:reuseIdx], oldControllers[reuseIdx+1:]...)
+			continue
+		}
+		var prev *controller
+		key := rule.storeKey()
+		for _, old := range oldControllers {
+			if old != nil && old.key == key {
+				prev = old
+				break
+			}
+		}
+		c, err := newController(rule, prev)
+		if err != nil {
+			logging.Warn("[Quota buildResourceControllers] Ignoring the rule due to failure of creating controller", "rule", rule, "reason", err.Error())
+			continue
+		}
+		newControllers = append(newControllers, c)
+	}
+	if len(newControllers) == 0 {
+		logging.Info("[Quota] no valid rules of resource", "resource", res)
+	}
+	return newControllers
+}
+
+func onRuleUpdate(rawResRulesMap map[string][]*Rule) {
+	start := util.CurrentTimeNano()
+	rwMux.RLock()
+	oldControllerMap := controllerMap
+	rwMux.RUnlock()
+
+	newControllerMap := make(map[string][]*controller, len(rawResRulesMap))
+	for res, rules := range rawResRulesMap {
+		if controllers := buildResourceControllers(res, rules, oldControllerMap[res]); len(controllers) > 0 {
+			newControllerMap[res] = controllers
+		}
+	}
+
+	rwMux.Lock()
+	controllerMap = newControllerMap
+	rwMux.Unlock()
+	currentRules = rawResRulesMap
+
+	logging.Debug("[Quota onRuleUpdate] Time statistic(ns) for updating quota rule", "timeCost", util.CurrentTimeNano()-start)
+	logRuleUpdate(newControllerMap)
+}
+
+// LoadRulesOfResource loads the given resource's quota rules to the rule manager, while all previous resource's rules will be replaced.
+// the first returned value indicates whether do real load operation, if the rules is the same with previous resource's rules, return false
+func LoadRulesOfResource(res string, rules []*Rule) (bool, error) {
+	if len(res) == 0 {
+		return false, errors.New("empty resource")
+	}
+	updateRuleMux.Lock()
+	defer updateRuleMux.Unlock()
+	// clear resource rules
+	if len(rules) == 0 {
+		delete(currentRules, res)
+		rwMux.Lock()
+		delete(controllerMap, res)
+		rwMux.Unlock()
+		ruleExpiration.Track()
+		logging.Info("[Quota] clear resource level rules", "resource", res)
+		return true, nil
+	}
+	// load resource level rules
+	isEqual := reflect.DeepEqual(currentRules[res], rules)
+	if isEqual {
+		logging.Info("[Quota] Load resource level rules is the same with current resource level rules, so ignore load operation.")
+		return false, nil
+	}
+
+	rwMux.RLock()
+	oldControllers := controllerMap[res]
+	rwMux.RUnlock()
+	controllers := buildResourceControllers(res, rules, oldControllers)
+
+	rwMux.Lock()
+	if len(controllers) == 0 {
+		delete(controllerMap, res)
+	} else {
+		controllerMap[res] = controllers
+	}
+	rwMux.Unlock()
+	currentRules[res] = rules
+	ruleExpiration.Track()
+	logging.Info("[Quota] load resource level rules", "resource", res, "rules", rules)
+	return true, nil
+}
+
+// ClearRules clears all the rules in quota module.
+func ClearRules() error {
+	_, err := LoadRules(nil)
+	return err
+}
+
+// ClearRulesOfResource clears resource level rules in quota module.
+func ClearRulesOfResource(res string) error {
+	_, err := LoadRulesOfResource(res, nil)
+	return err
+}
+
+// GetRules returns all the rules based on copy.
+// It doesn't take effect for quota module if user changes the rule.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
+func GetRules() []Rule {
+	rwMux.RLock()
+	defer rwMux.RUnlock()
+
+	now := util.CurrentTimeMillis()
+	ret := make([]Rule, 0, len(controllerMap))
+	for _, controllers := range controllerMap {
+		for _, c := range controllers {
+			ret = append(ret, ruleExpiration.CopyWithLifetime(c.rule, now))
+		}
+	}
+	return ret
+}
+
+// GetRulesOfResource returns specific resource's rules based on copy.
+// It doesn't take effect for quota module if user changes the rule.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
+func GetRulesOfResource(res string) []Rule {
+	controllers := getControllersOfResource(res)
+	now := util.CurrentTimeMillis()
+	ret := make([]Rule, 0, len(controllers))
+	for _, c := range controllers {
+		ret = append(ret, ruleExpiration.CopyWithLifetime(c.rule, now))
+	}
+	return ret
+}
+
+func getControllersOfResource(res string) []*controller {
+	rwMux.RLock()
+	defer rwMux.RUnlock()
+
+	return controllerMap[res]
+}
+
+func allControllers() []*controller {
+	rwMux.RLock()
+	defer rwMux.RUnlock()
+
+	ret := make([]*controller, 0, len(controllerMap))
+	for _, controllers := range controllerMap {
+		ret = append(ret, controllers...)
+	}
+	return ret
+}
+
+func logRuleUpdate(m map[string][]*controller) {
+	rules := make([]*Rule, 0, len(m))
+	for _, controllers := range m {
+		for _, c := range controllers {
+			rules = append(rules, c.rule)
+		}
+	}
+	if len(rules) == 0 {
+		logging.Info("[QuotaRuleManager] Quota rules were cleared")
+	} else {
+		logging.Info("[QuotaRuleManager] Quota rules were loaded", "rules", rules)
+	}
+}
+
+// IsValidRule checks whether the given Rule is valid.
+func IsValidRule(r *Rule) error {
+	if r == nil {
+		return errors.New("nil quota rule")
+	}
+	if len(r.Resource) == 0 {
+		return errors.New("empty resource of quota rule")
+	}
+	if r.ExpireAt > 0 && r.ExpireAt <= util.CurrentTimeMillis() {
+		return errors.New("rule already expired")
+	}
+	if r.Threshold < 0 {
+		return errors.New("negative threshold")
+	}
+	if r.WindowUnit < Minute || r.WindowUnit > Day {
+		return errors.Errorf("invalid window unit: %d", r.WindowUnit)
+	}
+	if r.WindowMode < Calendar || r.WindowMode > Rolling {
+		return errors.Errorf("invalid window mode: %d", r.WindowMode)
+	}
+	if r.Mode != base.Enforce && r.Mode != base.Shadow {
+		return errors.Errorf("invalid mode: %d", r.Mode)
+	}
+	if err := base.CheckEnforcePercent(r.EnforcePercent); err != nil {
+		return err
+	}
+	if err := r.Canary.IsValid(); err != nil {
+		return err
+	}
+	if err := condition.IsValid(r.Condition); err != nil {
+		return errors.Wrap(err, "invalid Condition")
+	}
+	if len(r.TimeZone) > 0 {
+		if _, err := time.LoadLocation(r.TimeZone); err != nil {
+			return errors.Errorf("invalid time zone: %s", r.TimeZone)
+		}
+	}
+	if r.PerParam && r.ParamsMaxCapacity < 0 {
+		return errors.New("negative ParamsMaxCapacity")
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"github.com/alibaba/sentinel-golang/core/base"
+)
+
+const (
+	RuleCheckSlotOrder = 4500
+
+	acquisitionsKey = "quota-acquisitions"
+	blockMsgQuota   = "quota exceeded"
+)
+
+var (
+	DefaultSlot = &Slot{}
+)
+
+type Slot struct {
+}
+
+func (s *Slot) Order() uint32 {
+	return RuleCheckSlotOrder
+}
+
+func (s *Slot) Check(ctx *base.EntryContext) *base.TokenResult {
+	resource := ctx.Resource.Name()
+	result := ctx.RuleCheckResult
+	if len(resource) == 0 {
+		return result
+	}
+	controllers := getControllersOfResource(resource)
+	if len(controllers) == 0 {
+		return result
+	}
+
+	batch := int64(ctx.Input.BatchCount)
+	needInfo := ctx.Input.NeedRateLimitInfo
+	acquisitions := make([]*acquisition, 0, len(controllers))
+	for _, c := range controllers {
+		if !c.rule.conditionHolds(ctx) {
+			continue
+		}
+		param, ok := c.paramOf(ctx)
+		if !ok {
+			continue
+		}
+		shadow := !c.rule.isEnforcedOn(ctx)
+		acquired, retryAfter, info := c.tryAcquire(param, batch, needInfo && !shadow)
+		if acquired == nil && shadow {
+			// Shadow rules don't count the would-be blocked requests.
+			ctx.AddShadowBlock(base.NewBlockError(base.WithBlockType(base.BlockTypeQuota), base.WithBlockMsg(blockMsgQuota),
+				base.WithRule(c.rule), base.WithSnapshotValue(param), base.WithRetryAfter(retryAfter)))
+			continue
+		}
+		if acquired == nil {
+			// Give back the requests counted by the previous rules.
+			for _, a := range acquisitions {
+				a.release()
+			}
+			opts := []base.BlockErrorOption{base.WithBlockType(base.BlockTypeQuota), base.WithBlockMsg(blockMsgQuota),
+				base.WithRule(c.rule), base.WithSnapshotValue(param), base.WithRetryAfter(retryAfter)}
+			if info != nil {
+				opts = append(opts, base.WithRateLimitInfo(info))
+			}
+			if result == nil {
+				result = base.NewTokenResult(base.ResultStatusBlocked, opts...)
+			} else {
+				result.ResetToBlockedWith(opts...)
+			}
+			return result
+		}
+		if info != nil {
+			ctx.MergeRateLimitInfo(*info)
+		}
+		acquisitions = append(acquisitions, acquired)
+	}
+	if len(acquisitions) > 0 {
+		if ctx.Data == nil {
+			ctx.Data = make(map[interface{}]interface{})
+		}
+		ctx.SetPair(acquisitionsKey, acquisitions)
+	}
+	return result
+}
+
+func acquisitionsOf(ctx *base.EntryContext) []*acquisition {
+	if ctx.Data == nil {
+		return nil
+	}
+	acquisitions, _ := ctx.GetPair(acquisitionsKey).([]*acquisition)
+	return acquisitions
+}
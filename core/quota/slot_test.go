@@ -0,0 +1,76 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"testing"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlot_Check(t *testing.T) {
+	defer ClearRules()
+	_, err := LoadRules([]*Rule{
+		{Resource: "abc", Threshold: 5, WindowUnit: Day},
+		{Resource: "abc", Threshold: 2, WindowUnit: Hour, PerParam: true, ParamKey: "tenant"},
+	})
+	assert.Nil(t, err)
+
+	slot := &Slot{}
+	statSlot := &StatSlot{}
+	newCtx := func(tenant string) *base.EntryContext {
+		ctx := &base.EntryContext{
+			Resource:        base.NewResourceWrapper("abc", base.ResTypeCommon, base.Inbound),
+			RuleCheckResult: base.NewTokenResultPass(),
+			Input: &base.SentinelInput{
+				BatchCount:        1,
+				NeedRateLimitInfo: true,
+			},
+		}
+		if len(tenant) > 0 {
+			ctx.Input.Attachments = map[interface{}]interface{}{"tenant": tenant}
+		}
+		return ctx
+	}
+
+	ctx := newCtx("t1")
+	assert.True(t, slot.Check(ctx).IsPass())
+	if assert.NotNil(t, ctx.RateLimitInfo()) {
+		// The most restrictive rule is reported.
+		assert.Equal(t, 2.0, ctx.RateLimitInfo().Limit)
+		assert.Equal(t, 1.0, ctx.RateLimitInfo().Remaining)
+	}
+	assert.True(t, slot.Check(newCtx("t1")).IsPass())
+	r := slot.Check(newCtx("t1"))
+	if assert.True(t, r.IsBlocked()) {
+		assert.Equal(t, base.BlockTypeQuota, r.BlockError().BlockType())
+		assert.Equal(t, "t1", r.BlockError().TriggeredValue())
+		assert.True(t, r.BlockError().RetryAfter() > 0)
+		assert.NotNil(t, r.BlockError().RateLimitInfo())
+	}
+
+	// The entry blocked by the following rules gives back the quota.
+	ctx = newCtx("t2")
+	assert.True(t, slot.Check(ctx).IsPass())
+	statSlot.OnEntryBlocked(ctx, base.NewBlockError())
+	// The entries without the parameter are only limited by the resource level rule.
+	assert.True(t, slot.Check(newCtx("")).IsPass())
+	assert.True(t, slot.Check(newCtx("t2")).IsPass())
+	// 4 of the day quota are used: t1 * 2, "" * 1 and t2 * 1, the blocked t1 and t2 are given back.
+	assert.True(t, slot.Check(newCtx("t3")).IsPass())
+	r = slot.Check(newCtx(""))
+	assert.True(t, r.IsBlocked())
+}
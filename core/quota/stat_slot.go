@@ -0,0 +1,47 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"github.com/alibaba/sentinel-golang/core/base"
+)
+
+const (
+	StatSlotOrder = 4500
+)
+
+var (
+	DefaultStatSlot = &StatSlot{}
+)
+
+// StatSlot gives back the requests counted in Slot if the entry is blocked by the following rules.
+type StatSlot struct {
+}
+
+func (s *StatSlot) Order() uint32 {
+	return StatSlotOrder
+}
+
+func (s *StatSlot) OnEntryPassed(_ *base.EntryContext) {
+}
+
+func (s *StatSlot) OnEntryBlocked(ctx *base.EntryContext, _ *base.BlockError) {
+	for _, a := range acquisitionsOf(ctx) {
+		a.release()
+	}
+}
+
+func (s *StatSlot) OnCompleted(_ *base.EntryContext) {
+}
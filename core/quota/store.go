@@ -0,0 +1,130 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultFlushInterval = 5 * time.Second
+)
+
+// CounterSnapshot is the persisted state of a quota counter.
+type CounterSnapshot struct {
+	// Param is the parameter value formatted by fmt.Sprint, empty if the rule is not counted per parameter.
+	Param string `json:"param,omitempty"`
+	// WindowStart and WindowEnd are the time (in milliseconds) of current fixed window.
+	WindowStart int64 `json:"windowStart"`
+	WindowEnd   int64 `json:"windowEnd"`
+	Count       int64 `json:"count"`
+	PrevCount   int64 `json:"prevCount,omitempty"`
+}
+
+// Store persists the quota counters, so that the usage is not reset after restart.
+type Store interface {
+	// Load returns the counters saved under the key, nil if nothing saved.
+	Load(key string) ([]CounterSnapshot, error)
+	// Save replaces the counters saved under the key.
+	Save(key string, counters []CounterSnapshot) error
+}
+
+var (
+	store     Store
+	storeMux  = &sync.RWMutex{}
+	stopFlush chan struct{}
+)
+
+// SetStore sets the store persisting the quota counters, the changed counters are saved every flushInterval
+// (DefaultFlushInterval if not positive). nil store disables the persistence.
+// It should be called before loading rules, so that the counters of rules are restored from the store.
+func SetStore(s Store, flushInterval time.Duration) {
+	storeMux.Lock()
+	defer storeMux.Unlock()
+
+	if stopFlush != nil {
+		close(stopFlush)
+		stopFlush = nil
+	}
+	store = s
+	if s == nil {
+		return
+	}
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+	stop := make(chan struct{})
+	stopFlush = stop
+	go util.RunWithRecover(func() {
+		ticker := util.NewTicker(flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				if err := Flush(); err != nil {
+					logging.Warn("[Quota] Failed to flush quota counters", "reason", err.Error())
+				}
+			case <-stop:
+				return
+			}
+		}
+	})
+}
+
+func currentStore() Store {
+	storeMux.RLock()
+	defer storeMux.RUnlock()
+
+	return store
+}
+
+// Flush saves the changed counters of all rules to the store immediately, e.g. before the process exits.
+func Flush() error {
+	s := currentStore()
+	if s == nil {
+		return nil
+	}
+	var lastErr error
+	for _, c := range allControllers() {
+		counters, changed := c.snapshotIfChanged()
+		if !changed {
+			continue
+		}
+		if err := s.Save(c.key, counters); err != nil {
+			c.markChanged()
+			lastErr = errors.Wrapf(err, "fail to save quota counters of %s", c.key)
+		}
+	}
+	return lastErr
+}
+
+// restoreFromStore returns the counters saved in the store under the key.
+func restoreFromStore(key string) []CounterSnapshot {
+	s := currentStore()
+	if s == nil {
+		return nil
+	}
+	counters, err := s.Load(key)
+	if err != nil {
+		logging.Warn("[Quota] Failed to load quota counters from store", "key", key, "reason", err.Error())
+		return nil
+	}
+	return counters
+}
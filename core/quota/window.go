@@ -0,0 +1,101 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"math"
+	"time"
+)
+
+// fixedWindowOf returns the start and end time (in milliseconds) of the fixed window containing nowMs.
+// The Calendar windows are aligned in loc, and the Rolling windows are estimated on the fixed windows
+// aligned to the Unix epoch.
+func fixedWindowOf(unit WindowUnit, mode WindowMode, loc *time.Location, nowMs int64) (int64, int64) {
+	if mode == Rolling {
+		length := unit.duration().Milliseconds()
+		start := nowMs - nowMs%length
+		return start, start + length
+	}
+	t := time.Unix(0, nowMs*int64(time.Millisecond)).In(loc)
+	var start, end time.Time
+	switch unit {
+	case Minute:
+		start = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+		end = start.Add(time.Minute)
+	case Hour:
+		start = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		end = start.Add(time.Hour)
+	default:
+		// A calendar day might be 23 or 25 hours due to daylight saving time.
+		start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		end = start.AddDate(0, 0, 1)
+	}
+	return start.UnixNano() / int64(time.Millisecond), end.UnixNano() / int64(time.Millisecond)
+}
+
+// counter counts the requests in the current fixed window, and keeps the count of previous window
+// to estimate the count of rolling window.
+type counter struct {
+	windowStart int64
+	windowEnd   int64
+	count       int64
+	prevCount   int64
+}
+
+// roll moves the counter to the fixed window [start, end).
+func (c *counter) roll(start, end int64) {
+	if c.windowStart == start && c.windowEnd == end {
+		return
+	}
+	if c.windowEnd == start {
+		c.prevCount = c.count
+	} else {
+		c.prevCount = 0
+	}
+	c.windowStart = start
+	c.windowEnd = end
+	c.count = 0
+}
+
+// estimated returns the count of the window ending at nowMs.
+func (c *counter) estimated(mode WindowMode, nowMs int64) float64 {
+	if mode != Rolling || c.prevCount == 0 {
+		return float64(c.count)
+	}
+	length := c.windowEnd - c.windowStart
+	overlap := 1 - float64(nowMs-c.windowStart)/float64(length)
+	return float64(c.count) + float64(c.prevCount)*overlap
+}
+
+// retryAfter returns the duration (in milliseconds) after which batchCount requests could pass.
+func (c *counter) retryAfter(mode WindowMode, threshold int64, batchCount int64, nowMs int64) int64 {
+	toEnd := c.windowEnd - nowMs
+	if mode != Rolling || c.prevCount == 0 || c.count+batchCount > threshold {
+		return toEnd
+	}
+	// The weight of previous window decreases linearly to 0 at the end of current window.
+	length := c.windowEnd - c.windowStart
+	exceeded := c.estimated(mode, nowMs) + float64(batchCount) - float64(threshold)
+	return int64(math.Min(float64(toEnd), math.Ceil(exceeded*float64(length)/float64(c.prevCount))))
+}
+
+// resetAfter returns the duration (in milliseconds) after which the full quota is available.
+func (c *counter) resetAfter(mode WindowMode, nowMs int64) int64 {
+	toEnd := c.windowEnd - nowMs
+	if mode == Rolling && c.count > 0 {
+		return toEnd + c.windowEnd - c.windowStart
+	}
+	return toEnd
+}
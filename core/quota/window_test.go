@@ -0,0 +1,78 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixedWindowOf(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	assert.Nil(t, err)
+	now := time.Date(2020, 6, 1, 10, 20, 30, 0, loc)
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+	toMs := func(t time.Time) int64 {
+		return t.UnixNano() / int64(time.Millisecond)
+	}
+
+	start, end := fixedWindowOf(Minute, Calendar, loc, nowMs)
+	assert.Equal(t, toMs(time.Date(2020, 6, 1, 10, 20, 0, 0, loc)), start)
+	assert.Equal(t, toMs(time.Date(2020, 6, 1, 10, 21, 0, 0, loc)), end)
+
+	start, end = fixedWindowOf(Hour, Calendar, loc, nowMs)
+	assert.Equal(t, toMs(time.Date(2020, 6, 1, 10, 0, 0, 0, loc)), start)
+	assert.Equal(t, toMs(time.Date(2020, 6, 1, 11, 0, 0, 0, loc)), end)
+
+	start, end = fixedWindowOf(Day, Calendar, loc, nowMs)
+	assert.Equal(t, toMs(time.Date(2020, 6, 1, 0, 0, 0, 0, loc)), start)
+	assert.Equal(t, toMs(time.Date(2020, 6, 2, 0, 0, 0, 0, loc)), end)
+
+	// The Rolling windows are aligned to the Unix epoch.
+	start, end = fixedWindowOf(Day, Rolling, loc, nowMs)
+	assert.Equal(t, toMs(time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)), start)
+	assert.Equal(t, int64(24*time.Hour/time.Millisecond), end-start)
+
+	// The calendar day is 23 hours when daylight saving time begins.
+	ny, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+	start, end = fixedWindowOf(Day, Calendar, ny, toMs(time.Date(2020, 3, 8, 12, 0, 0, 0, ny)))
+	assert.Equal(t, int64(23*time.Hour/time.Millisecond), end-start)
+}
+
+func TestCounter(t *testing.T) {
+	c := &counter{}
+	c.roll(0, 1000)
+	c.count = 80
+
+	// The count of previous window is weighted by its overlap with the rolling window.
+	c.roll(1000, 2000)
+	assert.Equal(t, int64(80), c.prevCount)
+	assert.Equal(t, int64(0), c.count)
+	assert.InDelta(t, 60.0, c.estimated(Rolling, 1250), 1e-6)
+	assert.InDelta(t, 0.0, c.estimated(Calendar, 1250), 1e-6)
+
+	c.count = 10
+	// 70 + 10 exceeds 75 by 5, which decays after 5 / 80 of window.
+	assert.Equal(t, int64(63), c.retryAfter(Rolling, 75, 10, 1250))
+	assert.Equal(t, int64(750), c.retryAfter(Calendar, 75, 10, 1250))
+	assert.Equal(t, int64(1750), c.resetAfter(Rolling, 1250))
+
+	// The previous count is dropped if the windows are not adjacent.
+	c.roll(3000, 4000)
+	assert.Equal(t, int64(0), c.prevCount)
+}
@@ -0,0 +1,145 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 7},  // day of week, both 0 and 7 are Sunday
+}
+
+// cronExpr is the parsed standard 5-field cron expression.
+// Each field supports "*", "n", "a-b", "*/s", "a-b/s", "n/s" and comma-separated lists of them.
+// As in the classic cron, if both day-of-month and day-of-week are restricted,
+// a day matches when either of them matches.
+type cronExpr struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+func parseCron(expr string) (*cronExpr, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("cron expression must have 5 fields: %s", expr)
+	}
+	var bits [5]uint64
+	for i, f := range fields {
+		b, err := parseCronField(f, cronFields[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cron field %q", f)
+		}
+		bits[i] = b
+	}
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &cronExpr{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, r cronField) (uint64, error) {
+	var ret uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			s, err := strconv.Atoi(item[i+1:])
+			if err != nil || s <= 0 {
+				return 0, errors.Errorf("invalid step: %s", item)
+			}
+			rangePart, step = item[:i], s
+		}
+		lo, hi := r.min, r.max
+		switch {
+		case rangePart == "*":
+		case strings.IndexByte(rangePart, '-') > 0:
+			i := strings.IndexByte(rangePart, '-')
+			var err error
+			if lo, err = strconv.Atoi(rangePart[:i]); err != nil {
+				return 0, errors.Errorf("invalid range: %s", item)
+			}
+			if hi, err = strconv.Atoi(rangePart[i+1:]); err != nil {
+				return 0, errors.Errorf("invalid range: %s", item)
+			}
+		default:
+			v, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, errors.Errorf("invalid value: %s", item)
+			}
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+		if lo < r.min || hi > r.max || lo > hi {
+			return 0, errors.Errorf("value out of range [%d, %d]: %s", r.min, r.max, item)
+		}
+		for v := lo; v <= hi; v += step {
+			ret |= 1 << uint(v)
+		}
+	}
+	return ret, nil
+}
+
+func (c *cronExpr) matchDay(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// firedWithin checks whether the expression fired in (t-d, t].
+// It scans backwards minute by minute, skipping the unmatched months, days and hours.
+func (c *cronExpr) firedWithin(t time.Time, d time.Duration, loc *time.Location) bool {
+	limit := t.Add(-d)
+	cur := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	for cur.After(limit) {
+		switch {
+		case c.month&(1<<uint(cur.Month())) == 0:
+			cur = time.Date(cur.Year(), cur.Month(), 1, 0, 0, 0, 0, loc).Add(-time.Minute)
+		case !c.matchDay(cur):
+			cur = time.Date(cur.Year(), cur.Month(), cur.Day(), 0, 0, 0, 0, loc).Add(-time.Minute)
+		case c.hour&(1<<uint(cur.Hour())) == 0:
+			cur = time.Date(cur.Year(), cur.Month(), cur.Day(), cur.Hour(), 0, 0, 0, loc).Add(-time.Minute)
+		case c.minute&(1<<uint(cur.Minute())) == 0:
+			cur = cur.Add(-time.Minute)
+		default:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/pkg/errors"
+)
+
+const dailyTimeLayout = "15:04"
+
+// Window is a time window during which the threshold of the rule is overridden.
+// A window must be defined in exactly one of the following ways:
+//   - Cron and DurationSec: the window opens at every time matching the 5-field cron expression
+//     (minute hour day-of-month month day-of-week) and stays open for DurationSec seconds.
+//   - StartTime and EndTime: a daily window in the "15:04" format. The window spans midnight
+//     if EndTime is earlier than StartTime. Weekdays restricts the days on which the window opens,
+//     the window opens every day if Weekdays is empty.
+//   - Begin and End: an absolute time range in the RFC3339 format, e.g. a promotion.
+type Window struct {
+	Cron        string `json:"cron,omitempty"`
+	DurationSec uint32 `json:"durationSec,omitempty"`
+
+	StartTime string         `json:"startTime,omitempty"`
+	EndTime   string         `json:"endTime,omitempty"`
+	Weekdays  []time.Weekday `json:"weekdays,omitempty"`
+
+	Begin string `json:"begin,omitempty"`
+	End   string `json:"end,omitempty"`
+
+	// Threshold overrides the threshold of the rule while the window is open.
+	Threshold float64 `json:"threshold"`
+}
+
+func (w *Window) String() string {
+	switch {
+	case w.Cron != "":
+		return fmt.Sprintf("{Cron=%s, DurationSec=%d, Threshold=%.2f}", w.Cron, w.DurationSec, w.Threshold)
+	case w.StartTime != "" || w.EndTime != "":
+		return fmt.Sprintf("{StartTime=%s, EndTime=%s, Weekdays=%v, Threshold=%.2f}", w.StartTime, w.EndTime, w.Weekdays, w.Threshold)
+	default:
+		return fmt.Sprintf("{Begin=%s, End=%s, Threshold=%.2f}", w.Begin, w.End, w.Threshold)
+	}
+}
+
+// Schedule overrides the threshold of a rule in time windows.
+// When several windows are open at the same time, the first one in Windows takes effect.
+type Schedule struct {
+	// TimeZone is the IANA time zone name (e.g. "Asia/Shanghai") in which Cron and daily windows are evaluated.
+	// The local time zone is used if empty.
+	TimeZone string   `json:"timeZone,omitempty"`
+	Windows  []Window `json:"windows"`
+}
+
+func (s *Schedule) String() string {
+	return fmt.Sprintf("{TimeZone=%s, Windows=%v}", s.TimeZone, s.Windows)
+}
+
+// MaxThreshold returns the max value among defaultThreshold and the thresholds of all windows.
+func (s *Schedule) MaxThreshold(defaultThreshold float64) float64 {
+	ret := defaultThreshold
+	if s == nil {
+		return ret
+	}
+	for i := range s.Windows {
+		ret = math.Max(ret, s.Windows[i].Threshold)
+	}
+	return ret
+}
+
+// IsValid checks whether the schedule could be compiled.
+func (s *Schedule) IsValid() error {
+	_, err := NewEvaluator(s)
+	return err
+}
+
+type window interface {
+	openAt(t time.Time) bool
+}
+
+type compiledWindow struct {
+	window
+	threshold float64
+}
+
+type evaluation struct {
+	sec       int64
+	threshold float64
+	open      bool
+}
+
+// Evaluator is the compiled form of Schedule, it evaluates the effective threshold on util.CurrentTimeMillis().
+// The result is cached within a second, so the evaluation is cheap on the hot path.
+// A nil *Evaluator is valid and never overrides the threshold.
+type Evaluator struct {
+	loc     *time.Location
+	windows []compiledWindow
+	// last is the evaluation of the latest second
+	last atomic.Value
+}
+
+// NewEvaluator compiles the schedule, nil is returned if the schedule is nil or has no window.
+func NewEvaluator(s *Schedule) (*Evaluator, error) {
+	if s == nil || len(s.Windows) == 0 {
+		return nil, nil
+	}
+	loc := time.Local
+	if s.TimeZone != "" {
+		l, err := time.LoadLocation(s.TimeZone)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid TimeZone: %s", s.TimeZone)
+		}
+		loc = l
+	}
+	e := &Evaluator{
+		loc:     loc,
+		windows: make([]compiledWindow, 0, len(s.Windows)),
+	}
+	for i := range s.Windows {
+		w, err := compileWindow(&s.Windows[i], loc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid window[%d]: %s", i, s.Windows[i].String())
+		}
+		e.windows = append(e.windows, w)
+	}
+	return e, nil
+}
+
+// Threshold returns the threshold of the first open window at the current time,
+// or defaultThreshold if no window is open.
+func (e *Evaluator) Threshold(defaultThreshold float64) float64 {
+	if e == nil {
+		return defaultThreshold
+	}
+	if threshold, ok := e.ThresholdAt(util.CurrentTimeMillis()); ok {
+		return threshold
+	}
+	return defaultThreshold
+}
+
+// ThresholdAt returns the threshold of the first open window at nowMs, the second result is false if no window is open.
+func (e *Evaluator) ThresholdAt(nowMs uint64) (float64, bool) {
+	if e == nil {
+		return 0, false
+	}
+	sec := int64(nowMs / 1000)
+	if last, ok := e.last.Load().(*evaluation); ok && last.sec == sec {
+		return last.threshold, last.open
+	}
+	ret := &evaluation{sec: sec}
+	t := time.Unix(sec, 0).In(e.loc)
+	for i := range e.windows {
+		if e.windows[i].openAt(t) {
+			ret.threshold = e.windows[i].threshold
+			ret.open = true
+			break
+		}
+	}
+	e.last.Store(ret)
+	return ret.threshold, ret.open
+}
+
+func compileWindow(w *Window, loc *time.Location) (compiledWindow, error) {
+	ret := compiledWindow{threshold: w.Threshold}
+	if w.Threshold < 0 {
+		return ret, errors.New("negative Threshold")
+	}
+	hasCron := w.Cron != ""
+	hasDaily := w.StartTime != "" || w.EndTime != ""
+	hasAbsolute := w.Begin != "" || w.End != ""
+	kinds := 0
+	for _, has := range []bool{hasCron, hasDaily, hasAbsolute} {
+		if has {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return ret, errors.New("exactly one of Cron, StartTime/EndTime and Begin/End must be set")
+	}
+	switch {
+	case hasCron:
+		if w.DurationSec == 0 {
+			return ret, errors.New("DurationSec must be positive for cron window")
+		}
+		expr, err := parseCron(w.Cron)
+		if err != nil {
+			return ret, err
+		}
+		ret.window = &cronWindow{expr: expr, loc: loc, duration: time.Duration(w.DurationSec) * time.Second}
+	case hasDaily:
+		start, err := time.Parse(dailyTimeLayout, w.StartTime)
+		if err != nil {
+			return ret, errors.Wrap(err, "invalid StartTime")
+		}
+		end, err := time.Parse(dailyTimeLayout, w.EndTime)
+		if err != nil {
+			return ret, errors.Wrap(err, "invalid EndTime")
+		}
+		dw := &dailyWindow{
+			startSec: start.Hour()*3600 + start.Minute()*60,
+			endSec:   end.Hour()*3600 + end.Minute()*60,
+		}
+		if dw.startSec == dw.endSec {
+			return ret, errors.New("StartTime equals to EndTime")
+		}
+		for _, d := range w.Weekdays {
+			if d < time.Sunday || d > time.Saturday {
+				return ret, errors.Errorf("invalid weekday: %d", d)
+			}
+			dw.weekdays |= 1 << uint(d)
+		}
+		if dw.weekdays == 0 {
+			dw.weekdays = 1<<7 - 1
+		}
+		ret.window = dw
+	default:
+		begin, err := time.Parse(time.RFC3339, w.Begin)
+		if err != nil {
+			return ret, errors.Wrap(err, "invalid Begin")
+		}
+		end, err := time.Parse(time.RFC3339, w.End)
+		if err != nil {
+			return ret, errors.Wrap(err, "invalid End")
+		}
+		if !begin.Before(end) {
+			return ret, errors.New("Begin must be earlier than End")
+		}
+		ret.window = &absoluteWindow{begin: begin, end: end}
+	}
+	return ret, nil
+}
+
+type cronWindow struct {
+	expr     *cronExpr
+	loc      *time.Location
+	duration time.Duration
+}
+
+func (w *cronWindow) openAt(t time.Time) bool {
+	return w.expr.firedWithin(t, w.duration, w.loc)
+}
+
+type dailyWindow struct {
+	startSec int
+	endSec   int
+	// weekdays is the bitmap of time.Weekday on which the window opens
+	weekdays uint8
+}
+
+func (w *dailyWindow) openOn(d time.Weekday) bool {
+	return w.weekdays&(1<<uint(d)) != 0
+}
+
+func (w *dailyWindow) openAt(t time.Time) bool {
+	sec := t.Hour()*3600 + t.Minute()*60 + t.Second()
+	if w.startSec < w.endSec {
+		return sec >= w.startSec && sec < w.endSec && w.openOn(t.Weekday())
+	}
+	// the window spans midnight
+	if sec >= w.startSec {
+		return w.openOn(t.Weekday())
+	}
+	return sec < w.endSec && w.openOn((t.Weekday()+6)%7)
+}
+
+type absoluteWindow struct {
+	begin time.Time
+	end   time.Time
+}
+
+func (w *absoluteWindow) openAt(t time.Time) bool {
+	return !t.Before(w.begin) && t.Before(w.end)
+}
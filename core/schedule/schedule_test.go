@@ -0,0 +1,176 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func msOf(t *testing.T, s string) uint64 {
+	ts, err := time.Parse(time.RFC3339, s)
+	assert.NoError(t, err)
+	return uint64(ts.UnixNano() / int64(time.Millisecond))
+}
+
+func TestNewEvaluator(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		e, err := NewEvaluator(nil)
+		assert.Nil(t, err)
+		assert.Nil(t, e)
+		assert.Equal(t, 10.0, e.Threshold(10))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalids := []*Schedule{
+			{TimeZone: "Mars/Olympus", Windows: []Window{{StartTime: "09:00", EndTime: "10:00"}}},
+			{Windows: []Window{{}}},
+			{Windows: []Window{{Cron: "0 9 * * *", StartTime: "09:00", EndTime: "10:00"}}},
+			{Windows: []Window{{Cron: "0 9 * * *"}}},
+			{Windows: []Window{{Cron: "0 9 * *", DurationSec: 60}}},
+			{Windows: []Window{{Cron: "60 9 * * *", DurationSec: 60}}},
+			{Windows: []Window{{Cron: "*/0 9 * * *", DurationSec: 60}}},
+			{Windows: []Window{{StartTime: "9am", EndTime: "10:00"}}},
+			{Windows: []Window{{StartTime: "09:00", EndTime: "09:00"}}},
+			{Windows: []Window{{StartTime: "09:00", EndTime: "10:00", Weekdays: []time.Weekday{7}}}},
+			{Windows: []Window{{Begin: "2020-11-11T00:00:00+08:00", End: "2020-11-10T00:00:00+08:00"}}},
+			{Windows: []Window{{StartTime: "09:00", EndTime: "10:00", Threshold: -1}}},
+		}
+		for _, s := range invalids {
+			assert.Error(t, s.IsValid(), s.String())
+		}
+	})
+}
+
+func TestEvaluator_ThresholdAt(t *testing.T) {
+	t.Run("daily", func(t *testing.T) {
+		e, err := NewEvaluator(&Schedule{
+			TimeZone: "Asia/Shanghai",
+			Windows: []Window{
+				{StartTime: "09:00", EndTime: "18:00", Weekdays: []time.Weekday{time.Monday}, Threshold: 100},
+				{StartTime: "22:00", EndTime: "02:00", Weekdays: []time.Weekday{time.Friday}, Threshold: 5},
+			},
+		})
+		assert.NoError(t, err)
+		cases := []struct {
+			at        string
+			threshold float64
+			open      bool
+		}{
+			// 2020-11-09 is Monday
+			{"2020-11-09T08:59:59+08:00", 0, false},
+			{"2020-11-09T09:00:00+08:00", 100, true},
+			{"2020-11-09T17:59:59+08:00", 100, true},
+			{"2020-11-09T18:00:00+08:00", 0, false},
+			{"2020-11-10T10:00:00+08:00", 0, false},
+			{"2020-11-09T01:00:00Z", 100, true},
+			// spans midnight from Friday
+			{"2020-11-13T21:59:59+08:00", 0, false},
+			{"2020-11-13T22:00:00+08:00", 5, true},
+			{"2020-11-14T01:59:59+08:00", 5, true},
+			{"2020-11-14T02:00:00+08:00", 0, false},
+			{"2020-11-14T22:00:00+08:00", 0, false},
+			{"2020-11-13T01:00:00+08:00", 0, false},
+		}
+		for _, c := range cases {
+			threshold, open := e.ThresholdAt(msOf(t, c.at))
+			assert.Equal(t, c.open, open, c.at)
+			assert.Equal(t, c.threshold, threshold, c.at)
+		}
+	})
+
+	t.Run("cron", func(t *testing.T) {
+		e, err := NewEvaluator(&Schedule{
+			TimeZone: "UTC",
+			Windows: []Window{
+				// every half an hour on workdays from 09:00 to 11:59, lasts 10 minutes
+				{Cron: "0,30 9-11 * * 1-5", DurationSec: 600, Threshold: 50},
+				// the first day of every month, or every Sunday
+				{Cron: "0 0 1 * 0", DurationSec: 3600, Threshold: 1},
+			},
+		})
+		assert.NoError(t, err)
+		cases := []struct {
+			at        string
+			threshold float64
+			open      bool
+		}{
+			{"2020-11-09T08:59:59Z", 0, false},
+			{"2020-11-09T09:00:00Z", 50, true},
+			{"2020-11-09T09:09:59Z", 50, true},
+			{"2020-11-09T09:10:00Z", 0, false},
+			{"2020-11-09T11:35:00Z", 50, true},
+			{"2020-11-09T12:05:00Z", 0, false},
+			// Saturday
+			{"2020-11-14T09:05:00Z", 0, false},
+			// Sunday
+			{"2020-11-15T00:30:00Z", 1, true},
+			{"2020-11-15T01:00:00Z", 0, false},
+			// the first day of month, Tuesday
+			{"2020-12-01T00:59:59Z", 1, true},
+			{"2020-12-02T00:30:00Z", 0, false},
+		}
+		for _, c := range cases {
+			threshold, open := e.ThresholdAt(msOf(t, c.at))
+			assert.Equal(t, c.open, open, c.at)
+			assert.Equal(t, c.threshold, threshold, c.at)
+		}
+	})
+
+	t.Run("absolute", func(t *testing.T) {
+		e, err := NewEvaluator(&Schedule{
+			Windows: []Window{
+				{Begin: "2020-11-11T00:00:00+08:00", End: "2020-11-12T00:00:00+08:00", Threshold: 1000},
+			},
+		})
+		assert.NoError(t, err)
+		_, open := e.ThresholdAt(msOf(t, "2020-11-10T15:59:59Z"))
+		assert.False(t, open)
+		threshold, open := e.ThresholdAt(msOf(t, "2020-11-10T16:00:00Z"))
+		assert.True(t, open)
+		assert.Equal(t, 1000.0, threshold)
+		_, open = e.ThresholdAt(msOf(t, "2020-11-11T16:00:00Z"))
+		assert.False(t, open)
+	})
+}
+
+func TestEvaluator_Threshold(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+
+	now := util.CurrentTimeMillis()
+	begin := time.Unix(0, int64(now+2000)*int64(time.Millisecond))
+	e, err := NewEvaluator(&Schedule{
+		Windows: []Window{
+			{Begin: begin.Format(time.RFC3339), End: begin.Add(time.Minute).Format(time.RFC3339), Threshold: 20},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, e.Threshold(10))
+	util.Sleep(3 * time.Second)
+	assert.Equal(t, 20.0, e.Threshold(10))
+	util.Sleep(time.Minute)
+	assert.Equal(t, 10.0, e.Threshold(10))
+}
+
+func TestSchedule_MaxThreshold(t *testing.T) {
+	var s *Schedule
+	assert.Equal(t, 10.0, s.MaxThreshold(10))
+	s = &Schedule{Windows: []Window{{Threshold: 5}, {Threshold: 30}}}
+	assert.Equal(t, 30.0, s.MaxThreshold(10))
+}
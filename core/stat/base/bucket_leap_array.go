@@ -19,6 +19,7 @@ import (
 	"sync/atomic"
 
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/config"
 	"github.com/alibaba/sentinel-golang/logging"
 	"github.com/alibaba/sentinel-golang/util"
 	"github.com/pkg/errors"
@@ -30,15 +31,23 @@ import (
 type BucketLeapArray struct {
 	data     LeapArray
 	dataType string
+	// shardedCounter indicates whether the buckets record the counts in the striped counters
+	// regardless of config.ShardedCounter.
+	shardedCounter int32
 }
 
 func (bla *BucketLeapArray) NewEmptyBucket() interface{} {
-	return NewMetricBucket()
+	return newMetricBucket(counterStripes(bla.IsShardedCounter()))
 }
 
 func (bla *BucketLeapArray) ResetBucketTo(bw *BucketWrap, startTime uint64) *BucketWrap {
 	atomic.StoreUint64(&bw.BucketStart, startTime)
 	mb := bw.Value.Load().(*MetricBucket)
+	if sharded := bla.IsShardedCounter(); !mb.matchesCounterStripes(sharded) {
+		// the sharded counter has been switched, replace the bucket on its rotation
+		bw.Value.Store(newMetricBucket(counterStripes(sharded)))
+		return bw
+	}
 	mb.reset()
 	return bw
 }
@@ -50,6 +59,16 @@ func (bla *BucketLeapArray) ResetBucketTo(bw *BucketWrap, startTime uint64) *Buc
 // and satisfies the condition that intervalInMs%sampleCount == 0.
 // The validation must be done before call NewBucketLeapArray.
 func NewBucketLeapArray(sampleCount uint32, intervalInMs uint32) *BucketLeapArray {
+	return newBucketLeapArray(sampleCount, intervalInMs, false)
+}
+
+// NewShardedBucketLeapArray creates a BucketLeapArray recording the counts in the striped counters
+// even if config.ShardedCounter is disabled, see NewBucketLeapArray.
+func NewShardedBucketLeapArray(sampleCount uint32, intervalInMs uint32) *BucketLeapArray {
+	return newBucketLeapArray(sampleCount, intervalInMs, true)
+}
+
+func newBucketLeapArray(sampleCount uint32, intervalInMs uint32, shardedCounter bool) *BucketLeapArray {
 	// TODO: also check params here.
 	bucketLengthInMs := intervalInMs / sampleCount
 	ret := &BucketLeapArray{
@@ -61,11 +80,27 @@ func NewBucketLeapArray(sampleCount uint32, intervalInMs uint32) *BucketLeapArra
 		},
 		dataType: "MetricBucket",
 	}
+	ret.SetShardedCounter(shardedCounter)
 	arr := NewAtomicBucketWrapArray(int(sampleCount), bucketLengthInMs, ret)
 	ret.data.array = arr
 	return ret
 }
 
+// SetShardedCounter switches whether the buckets record the counts in the striped counters regardless of
+// config.ShardedCounter, the existing buckets are replaced on their rotation.
+func (bla *BucketLeapArray) SetShardedCounter(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&bla.shardedCounter, v)
+}
+
+// IsShardedCounter reports whether the new buckets record the counts in the striped counters.
+func (bla *BucketLeapArray) IsShardedCounter() bool {
+	return config.ShardedCounter() || atomic.LoadInt32(&bla.shardedCounter) == 1
+}
+
 func (bla *BucketLeapArray) SampleCount() uint32 {
 	return bla.data.sampleCount
 }
@@ -15,30 +15,84 @@
 package base
 
 import (
+	"runtime"
 	"sync/atomic"
+	"unsafe"
 
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/config"
 	"github.com/alibaba/sentinel-golang/logging"
 	"github.com/pkg/errors"
 )
 
+const (
+	// maxCounterStripes is the max amount of the striped counters per bucket, which bounds the memory of the bucket.
+	maxCounterStripes = 32
+	cacheLineSize     = 64
+)
+
+// counterStripe is one stripe of the striped counters, padded to its own cache lines to avoid false sharing.
+type counterStripe struct {
+	counter [base.MetricEventTotal]int64
+	_       [cacheLineSize - (int(base.MetricEventTotal)*8)%cacheLineSize]byte
+}
+
 // MetricBucket represents the entity to record metrics per minimum time unit (i.e. the bucket time span).
 // Note that all operations of the MetricBucket are required to be thread-safe.
 type MetricBucket struct {
 	// Value of statistic
-	counter        [base.MetricEventTotal]int64
+	counter [base.MetricEventTotal]int64
+	// stripes are the striped counters summed on read, which is used instead of counter if the sharded counter
+	// is enabled, see config.ShardedCounter and NewShardedBucketLeapArray. The length of stripes is the power of 2.
+	stripes        []counterStripe
 	minRt          int64
 	maxConcurrency int32
 }
 
 func NewMetricBucket() *MetricBucket {
+	return newMetricBucket(counterStripes(config.ShardedCounter()))
+}
+
+func newMetricBucket(stripes int) *MetricBucket {
 	mb := &MetricBucket{
 		minRt:          base.DefaultStatisticMaxRt,
 		maxConcurrency: 0,
 	}
+	if stripes > 0 {
+		mb.stripes = make([]counterStripe, stripes)
+	}
 	return mb
 }
 
+// counterStripes returns the amount of the striped counters of the new bucket, 0 means the striped counters are disabled.
+func counterStripes(sharded bool) int {
+	if !sharded {
+		return 0
+	}
+	procs := runtime.GOMAXPROCS(0)
+	if procs <= 1 {
+		return 0
+	}
+	n := 1
+	for n < procs && n < maxCounterStripes {
+		n <<= 1
+	}
+	return n
+}
+
+// matchesCounterStripes reports whether the bucket matches the given setting of the sharded counter.
+func (mb *MetricBucket) matchesCounterStripes(sharded bool) bool {
+	return len(mb.stripes) == counterStripes(sharded)
+}
+
+// stripeIndex picks the stripe by the address of the stack of current goroutine, so that the goroutine
+// keeps writing the same stripe while the concurrent goroutines are spread across the stripes.
+func (mb *MetricBucket) stripeIndex() int {
+	var marker byte
+	h := uint64(uintptr(unsafe.Pointer(&marker))>>10) * 0x9e3779b97f4a7c15
+	return int(h>>32) & (len(mb.stripes) - 1)
+}
+
 // Add statistic count for the given metric event.
 func (mb *MetricBucket) Add(event base.MetricEvent, count int64) {
 	if event >= base.MetricEventTotal || event < 0 {
@@ -53,6 +107,10 @@ func (mb *MetricBucket) Add(event base.MetricEvent, count int64) {
 }
 
 func (mb *MetricBucket) addCount(event base.MetricEvent, count int64) {
+	if len(mb.stripes) > 0 {
+		atomic.AddInt64(&mb.stripes[mb.stripeIndex()].counter[event], count)
+		return
+	}
 	atomic.AddInt64(&mb.counter[event], count)
 }
 
@@ -62,6 +120,13 @@ func (mb *MetricBucket) Get(event base.MetricEvent) int64 {
 		logging.Error(errors.Errorf("Unknown metric event: %v", event), "")
 		return 0
 	}
+	if len(mb.stripes) > 0 {
+		var sum int64
+		for i := range mb.stripes {
+			sum += atomic.LoadInt64(&mb.stripes[i].counter[event])
+		}
+		return sum
+	}
 	return atomic.LoadInt64(&mb.counter[event])
 }
 
@@ -69,6 +134,11 @@ func (mb *MetricBucket) reset() {
 	for i := 0; i < int(base.MetricEventTotal); i++ {
 		atomic.StoreInt64(&mb.counter[i], 0)
 	}
+	for i := range mb.stripes {
+		for j := 0; j < int(base.MetricEventTotal); j++ {
+			atomic.StoreInt64(&mb.stripes[i].counter[j], 0)
+		}
+	}
 	atomic.StoreInt64(&mb.minRt, base.DefaultStatisticMaxRt)
 	atomic.StoreInt32(&mb.maxConcurrency, int32(0))
 }
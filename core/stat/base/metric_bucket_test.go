@@ -15,11 +15,13 @@
 package base
 
 import (
+	"runtime"
 	"sync"
 	"testing"
 	"unsafe"
 
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,7 +29,8 @@ func Test_metricBucket_MemSize(t *testing.T) {
 	mb := NewMetricBucket()
 	t.Log("mb:", mb)
 	size := unsafe.Sizeof(*mb)
-	if size != 56 {
+	// the counters, the header of the striped counters, minRt and maxConcurrency
+	if size != 88 {
 		t.Error("unexpect memory size of MetricBucket")
 	}
 }
@@ -142,3 +145,75 @@ func Test_Reset(t *testing.T) {
 	assert.True(t, rt == base.DefaultStatisticMaxRt)
 	assert.True(t, mc == int32(0))
 }
+
+func Test_metricBucket_Sharded(t *testing.T) {
+	conf := config.NewDefaultConfig()
+	conf.Sentinel.Stat.ShardedCounter = true
+	config.ResetGlobalConfig(conf)
+	defer config.ResetGlobalConfig(config.NewDefaultConfig())
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(6))
+
+	assert.Equal(t, 64, int(unsafe.Sizeof(counterStripe{})))
+
+	mb := NewMetricBucket()
+	assert.Len(t, mb.stripes, 8)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1000)
+	for i := 0; i < 1000; i++ {
+		go func(c int64) {
+			mb.Add(base.MetricEventPass, 1)
+			mb.AddRt(c)
+			wg.Done()
+		}(int64(i))
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1000), mb.Get(base.MetricEventPass))
+	assert.Equal(t, int64((0+999)*1000/2), mb.Get(base.MetricEventRt))
+	assert.Equal(t, int64(0), mb.MinRt())
+
+	mb.reset()
+	assert.Equal(t, int64(0), mb.Get(base.MetricEventPass))
+	assert.Equal(t, int64(0), mb.Get(base.MetricEventRt))
+}
+
+func Test_metricBucket_SwitchSharded(t *testing.T) {
+	defer config.ResetGlobalConfig(config.NewDefaultConfig())
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	bla := NewBucketLeapArray(2, 1000)
+	bw := &BucketWrap{BucketStart: 0}
+	bw.Value.Store(bla.NewEmptyBucket())
+	bw.Value.Load().(*MetricBucket).Add(base.MetricEventPass, 1)
+
+	bla.ResetBucketTo(bw, 500)
+	mb := bw.Value.Load().(*MetricBucket)
+	assert.Equal(t, int64(0), mb.Get(base.MetricEventPass))
+	assert.Empty(t, mb.stripes)
+
+	conf := config.NewDefaultConfig()
+	conf.Sentinel.Stat.ShardedCounter = true
+	config.ResetGlobalConfig(conf)
+	// the bucket is replaced on its rotation once the setting is switched
+	bla.ResetBucketTo(bw, 1000)
+	assert.True(t, bw.Value.Load().(*MetricBucket) != mb)
+	assert.Len(t, bw.Value.Load().(*MetricBucket).stripes, 4)
+	assert.Equal(t, uint64(1000), bw.BucketStart)
+}
+
+func Test_metricBucket_ShardedLeapArray(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	// the sharded counter is opted in by the array regardless of the global setting
+	bla := NewShardedBucketLeapArray(2, 1000)
+	assert.True(t, bla.IsShardedCounter())
+	bw := &BucketWrap{BucketStart: 0}
+	bw.Value.Store(bla.NewEmptyBucket())
+	assert.Len(t, bw.Value.Load().(*MetricBucket).stripes, 4)
+	assert.Empty(t, NewBucketLeapArray(2, 1000).NewEmptyBucket().(*MetricBucket).stripes)
+
+	bla.SetShardedCounter(false)
+	assert.False(t, bla.IsShardedCounter())
+	bla.ResetBucketTo(bw, 500)
+	assert.Empty(t, bw.Value.Load().(*MetricBucket).stripes)
+}
@@ -238,6 +238,7 @@ func (m *SlidingWindowMetric) metricItemFromBuckets(ts uint64, ws []*BucketWrap)
 		}
 		item.PassQps += uint64(mb.Get(base.MetricEventPass))
 		item.BlockQps += uint64(mb.Get(base.MetricEventBlock))
+		item.ShadowBlockQps += uint64(mb.Get(base.MetricEventShadowBlock))
 		item.ErrorQps += uint64(mb.Get(base.MetricEventError))
 		item.CompleteQps += uint64(mb.Get(base.MetricEventComplete))
 		mc := uint32(mb.MaxConcurrency())
@@ -267,11 +268,12 @@ func (m *SlidingWindowMetric) metricItemFromBucket(w *BucketWrap) *base.MetricIt
 	}
 	completeQps := mb.Get(base.MetricEventComplete)
 	item := &base.MetricItem{
-		PassQps:     uint64(mb.Get(base.MetricEventPass)),
-		BlockQps:    uint64(mb.Get(base.MetricEventBlock)),
-		ErrorQps:    uint64(mb.Get(base.MetricEventError)),
-		CompleteQps: uint64(completeQps),
-		Timestamp:   w.BucketStart,
+		PassQps:        uint64(mb.Get(base.MetricEventPass)),
+		BlockQps:       uint64(mb.Get(base.MetricEventBlock)),
+		ErrorQps:       uint64(mb.Get(base.MetricEventError)),
+		CompleteQps:    uint64(completeQps),
+		ShadowBlockQps: uint64(mb.Get(base.MetricEventShadowBlock)),
+		Timestamp:      w.BucketStart,
 	}
 	if completeQps > 0 {
 		item.AvgRt = uint64(mb.Get(base.MetricEventRt) / completeQps)
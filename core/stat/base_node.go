@@ -23,8 +23,10 @@ import (
 )
 
 type BaseStatNode struct {
-	sampleCount uint32
-	intervalMs  uint32
+	sampleCountTotal uint32
+	intervalMsTotal  uint32
+	sampleCount      uint32
+	intervalMs       uint32
 
 	concurrency int32
 
@@ -32,18 +34,58 @@ type BaseStatNode struct {
 	metric *sbase.SlidingWindowMetric
 }
 
+// NewBaseStatNode creates the node based on the global sliding window,
+// the default metric of the node is the given sampleCount and intervalInMs.
 func NewBaseStatNode(sampleCount uint32, intervalInMs uint32) *BaseStatNode {
-	la := sbase.NewBucketLeapArray(config.GlobalStatisticSampleCountTotal(), config.GlobalStatisticIntervalMsTotal())
+	return newBaseStatNode(config.GlobalStatisticSampleCountTotal(), config.GlobalStatisticIntervalMsTotal(), sampleCount, intervalInMs, false)
+}
+
+// NewBaseStatNodeOf creates the node honoring the statistic config of the resource,
+// see LoadResourceStatConfigs and DeclareResourceStatConfig.
+func NewBaseStatNodeOf(resource string) *BaseStatNode {
+	c := effectiveStatConfigOf(resource)
+	return newBaseStatNode(c.SampleCountTotal, c.IntervalMsTotal, c.SampleCount, c.IntervalMs, c.ShardedCounter)
+}
+
+func newBaseStatNode(sampleCountTotal, intervalMsTotal, sampleCount, intervalInMs uint32, shardedCounter bool) *BaseStatNode {
+	var la *sbase.BucketLeapArray
+	if shardedCounter {
+		la = sbase.NewShardedBucketLeapArray(sampleCountTotal, intervalMsTotal)
+	} else {
+		la = sbase.NewBucketLeapArray(sampleCountTotal, intervalMsTotal)
+	}
 	metric, _ := sbase.NewSlidingWindowMetric(sampleCount, intervalInMs, la)
 	return &BaseStatNode{
-		concurrency: 0,
-		sampleCount: sampleCount,
-		intervalMs:  intervalInMs,
-		arr:         la,
-		metric:      metric,
+		concurrency:      0,
+		sampleCountTotal: sampleCountTotal,
+		intervalMsTotal:  intervalMsTotal,
+		sampleCount:      sampleCount,
+		intervalMs:       intervalInMs,
+		arr:              la,
+		metric:           metric,
 	}
 }
 
+// SampleCountTotal returns the bucket amount of the sliding window of the node.
+func (n *BaseStatNode) SampleCountTotal() uint32 {
+	return n.sampleCountTotal
+}
+
+// IntervalMsTotal returns the interval of the sliding window of the node.
+func (n *BaseStatNode) IntervalMsTotal() uint32 {
+	return n.intervalMsTotal
+}
+
+// SampleCount returns the bucket amount of the default metric of the node.
+func (n *BaseStatNode) SampleCount() uint32 {
+	return n.sampleCount
+}
+
+// IntervalMs returns the interval of the default metric of the node.
+func (n *BaseStatNode) IntervalMs() uint32 {
+	return n.intervalMs
+}
+
 func (n *BaseStatNode) MetricsOnCondition(predicate base.TimePredicate) []*base.MetricItem {
 	return n.metric.SecondMetricsOnCondition(predicate)
 }
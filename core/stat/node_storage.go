@@ -16,34 +16,72 @@ package stat
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/config"
+	metric_exporter "github.com/alibaba/sentinel-golang/exporter/metric"
 	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/alibaba/sentinel-golang/util"
 )
 
 type ResourceNodeMap map[string]*ResourceNode
 
 var (
 	inboundNode = NewResourceNode(base.TotalInBoundResourceName, base.ResTypeCommon)
+	// overflowNode records the statistic of the resources beyond the max resource amount.
+	overflowNode = NewResourceNode(base.OverflowResourceName, base.ResTypeCommon)
+	// overflowed indicates whether the resource amount has reached the max resource amount.
+	overflowed int32
 
 	resNodeMap = make(ResourceNodeMap)
 	rnsMux     = new(sync.RWMutex)
+	// retainedResources is the resources whose nodes are retained by each owner, e.g. the rule managers.
+	retainedResources = make(map[string]map[string]struct{})
+	// retainCounts is the amount of the owners retaining the node of each resource.
+	retainCounts = make(map[string]int)
+
+	evictorOnce     sync.Once
+	evictorStopChan = make(chan struct{})
+
+	resourceOverflowCounter = metric_exporter.NewCounter(
+		"resource_overflow_total",
+		"Total count of the lookups of the resources recorded in the overflow node due to exceeding the max resource amount",
+		[]string{"resource_type"})
+	resourceNodeEvictedCounter = metric_exporter.NewCounter(
+		"resource_node_evicted_total",
+		"Total count of the idle resource nodes evicted",
+		[]string{})
 )
 
+func init() {
+	metric_exporter.Register(resourceOverflowCounter)
+	metric_exporter.Register(resourceNodeEvictedCounter)
+}
+
 // InboundNode returns the global inbound statistic node.
 func InboundNode() *ResourceNode {
 	return inboundNode
 }
 
+// OverflowNode returns the node recording the statistic of the resources beyond the max resource amount.
+func OverflowNode() *ResourceNode {
+	return overflowNode
+}
+
 // ResourceNodeList returns the slice of all existing resource nodes.
 func ResourceNodeList() []*ResourceNode {
 	rnsMux.RLock()
 	defer rnsMux.RUnlock()
 
-	list := make([]*ResourceNode, 0, len(resNodeMap))
+	list := make([]*ResourceNode, 0, len(resNodeMap)+1)
 	for _, v := range resNodeMap {
 		list = append(list, v)
 	}
+	if atomic.LoadInt32(&overflowed) == 1 {
+		list = append(list, overflowNode)
+	}
 	return list
 }
 
@@ -54,29 +92,153 @@ func GetResourceNode(resource string) *ResourceNode {
 	return resNodeMap[resource]
 }
 
+// GetOrCreateResourceNode returns the node of the resource, the node is created if absent. Once the amount of nodes
+// reaches the max resource amount (see config.MaxResourceAmount), the shared overflow node is returned for the new resources.
+// The statistic of the overflow node is combined from all these resources, so the rules selecting resources by regex
+// or Selector must not read it as the statistic of a single resource.
 func GetOrCreateResourceNode(resource string, resourceType base.ResourceType) *ResourceNode {
-	node := GetResourceNode(resource)
+	rnsMux.RLock()
+	node := resNodeMap[resource]
+	if node != nil {
+		// touch the node before unlocking, so that it's not evicted as idle before the caller uses it
+		node.touch()
+	}
+	full := len(resNodeMap) >= int(maxResourceAmount())
+	rnsMux.RUnlock()
 	if node != nil {
 		return node
 	}
+	if full {
+		return overflowNodeFor(resource, resourceType)
+	}
+
 	rnsMux.Lock()
 	defer rnsMux.Unlock()
 
 	node = resNodeMap[resource]
 	if node != nil {
+		node.touch()
 		return node
 	}
-
-	if len(resNodeMap) >= int(base.DefaultMaxResourceAmount) {
-		logging.Warn("[GetOrCreateResourceNode] Resource amount exceeds the threshold", "maxResourceAmount", base.DefaultMaxResourceAmount)
+	if len(resNodeMap) >= int(maxResourceAmount()) {
+		return overflowNodeFor(resource, resourceType)
 	}
 	node = NewResourceNode(resource, resourceType)
 	resNodeMap[resource] = node
 	return node
 }
 
+// RetainResourceNodes retains the nodes of the resources from being evicted on behalf of the owner, e.g. the rule
+// manager whose rules hold the statistic of the nodes. The given resources replace the ones retained by the owner before,
+// so the nodes of the resources absent now are released, and an empty slice releases all of them. The retained nodes
+// are created regardless of the max resource amount.
+func RetainResourceNodes(owner string, resources []string) {
+	rnsMux.Lock()
+	defer rnsMux.Unlock()
+
+	oldRetained := retainedResources[owner]
+	newRetained := make(map[string]struct{}, len(resources))
+	for _, res := range resources {
+		if _, ok := newRetained[res]; ok {
+			continue
+		}
+		newRetained[res] = struct{}{}
+		if _, ok := oldRetained[res]; !ok {
+			retainCounts[res]++
+		}
+		if resNodeMap[res] == nil {
+			resNodeMap[res] = NewResourceNode(res, base.ResTypeCommon)
+		}
+	}
+	for res := range oldRetained {
+		if _, ok := newRetained[res]; ok {
+			continue
+		}
+		if retainCounts[res]--; retainCounts[res] <= 0 {
+			delete(retainCounts, res)
+		}
+	}
+	if len(newRetained) == 0 {
+		delete(retainedResources, owner)
+	} else {
+		retainedResources[owner] = newRetained
+	}
+}
+
+func overflowNodeFor(resource string, resourceType base.ResourceType) *ResourceNode {
+	if atomic.CompareAndSwapInt32(&overflowed, 0, 1) {
+		logging.Warn("[GetOrCreateResourceNode] Resource amount exceeds the threshold, the statistic of new resources is recorded in the overflow node",
+			"maxResourceAmount", maxResourceAmount(), "resource", resource)
+	}
+	resourceOverflowCounter.Add(1, resourceType.String())
+	overflowNode.touch()
+	return overflowNode
+}
+
+func maxResourceAmount() uint32 {
+	if amount := config.MaxResourceAmount(); amount > 0 {
+		return amount
+	}
+	return base.DefaultMaxResourceAmount
+}
+
+// EvictIdleResourceNodes evicts the resource nodes not accessed for idleTimeoutMs, except the nodes retained
+// by the rules or with concurrency, and prunes the declared statistic configs of the resources without nodes.
+// It returns the amount of the evicted nodes.
+func EvictIdleResourceNodes(idleTimeoutMs uint32) int {
+	now := util.CurrentTimeMillis()
+	rnsMux.Lock()
+	evicted := 0
+	for resource, node := range resNodeMap {
+		if retainCounts[resource] > 0 {
+			continue
+		}
+		if node.isIdle(now, idleTimeoutMs) {
+			delete(resNodeMap, resource)
+			evicted++
+		}
+	}
+	if len(resNodeMap) < int(maxResourceAmount()) {
+		atomic.StoreInt32(&overflowed, 0)
+	}
+	pruneDeclaredStatConfigs()
+	rnsMux.Unlock()
+
+	if evicted > 0 {
+		resourceNodeEvictedCounter.Add(float64(evicted))
+		logging.Info("[EvictIdleResourceNodes] Idle resource nodes were evicted", "evicted", evicted, "idleTimeoutMs", idleTimeoutMs)
+	}
+	return evicted
+}
+
+// InitResourceNodeEvictor starts the task evicting the idle resource nodes periodically, 0 idleTimeoutMs means never.
+func InitResourceNodeEvictor(idleTimeoutMs uint32) {
+	if idleTimeoutMs == 0 {
+		return
+	}
+	evictorOnce.Do(func() {
+		interval := time.Duration(idleTimeoutMs) * time.Millisecond / 2
+		if interval < time.Second {
+			interval = time.Second
+		}
+		ticker := util.NewTicker(interval)
+		go util.RunWithRecover(func() {
+			for {
+				select {
+				case <-ticker.C():
+					EvictIdleResourceNodes(idleTimeoutMs)
+				case <-evictorStopChan:
+					ticker.Stop()
+					return
+				}
+			}
+		})
+	})
+}
+
 func ResetResourceNodeMap() {
 	rnsMux.Lock()
 	defer rnsMux.Unlock()
 	resNodeMap = make(ResourceNodeMap)
+	atomic.StoreInt32(&overflowed, 0)
 }
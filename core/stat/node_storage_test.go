@@ -0,0 +1,113 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stat
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/config"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrCreateResourceNode_Overflow(t *testing.T) {
+	conf := config.NewDefaultConfig()
+	conf.Sentinel.Stat.MaxResourceAmount = 3
+	config.ResetGlobalConfig(conf)
+	defer config.ResetGlobalConfig(config.NewDefaultConfig())
+	ResetResourceNodeMap()
+	defer ResetResourceNodeMap()
+
+	for i := 0; i < 3; i++ {
+		node := GetOrCreateResourceNode(fmt.Sprintf("res-%d", i), base.ResTypeWeb)
+		assert.Equal(t, fmt.Sprintf("res-%d", i), node.ResourceName())
+	}
+	assert.True(t, GetOrCreateResourceNode("res-3", base.ResTypeWeb) == OverflowNode())
+	assert.True(t, GetOrCreateResourceNode("res-4", base.ResTypeWeb) == OverflowNode())
+	assert.Nil(t, GetResourceNode("res-3"))
+	assert.True(t, GetOrCreateResourceNode("res-0", base.ResTypeWeb) != OverflowNode())
+	assert.Equal(t, 4, len(ResourceNodeList()))
+
+	// the rules always get the node of the resource
+	RetainResourceNodes("test", []string{"res-3"})
+	defer RetainResourceNodes("test", nil)
+	node := GetOrCreateResourceNode("res-3", base.ResTypeWeb)
+	assert.True(t, node != OverflowNode() && node.IsRetained())
+}
+
+func TestRetainResourceNodes(t *testing.T) {
+	ResetResourceNodeMap()
+	defer ResetResourceNodeMap()
+
+	RetainResourceNodes("owner-1", []string{"abc", "def"})
+	RetainResourceNodes("owner-2", []string{"abc"})
+	abc, def := GetResourceNode("abc"), GetResourceNode("def")
+	assert.True(t, abc != nil && abc.IsRetained())
+	assert.True(t, def != nil && def.IsRetained())
+
+	// the resources absent now are released by the owner
+	RetainResourceNodes("owner-1", []string{"abc"})
+	assert.True(t, abc.IsRetained())
+	assert.False(t, def.IsRetained())
+	RetainResourceNodes("owner-1", nil)
+	assert.True(t, abc.IsRetained())
+	RetainResourceNodes("owner-2", nil)
+	assert.False(t, abc.IsRetained())
+	assert.Empty(t, retainedResources)
+	assert.Empty(t, retainCounts)
+}
+
+func TestEvictIdleResourceNodes(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+	conf := config.NewDefaultConfig()
+	conf.Sentinel.Stat.MaxResourceAmount = 3
+	config.ResetGlobalConfig(conf)
+	defer config.ResetGlobalConfig(config.NewDefaultConfig())
+	ResetResourceNodeMap()
+	defer ResetResourceNodeMap()
+
+	idle := GetOrCreateResourceNode("idle", base.ResTypeCommon)
+	busy := GetOrCreateResourceNode("busy", base.ResTypeCommon)
+	RetainResourceNodes("test", []string{"retained"})
+	retained := GetResourceNode("retained")
+	assert.True(t, GetOrCreateResourceNode("new", base.ResTypeCommon) == OverflowNode())
+	busy.IncreaseConcurrency()
+	defer busy.DecreaseConcurrency()
+
+	util.Sleep(30 * time.Second)
+	assert.True(t, GetOrCreateResourceNode("idle", base.ResTypeCommon) == idle)
+	assert.Equal(t, 0, EvictIdleResourceNodes(60000))
+	util.Sleep(40 * time.Second)
+	assert.Equal(t, 0, EvictIdleResourceNodes(60000))
+	util.Sleep(30 * time.Second)
+	assert.Equal(t, 1, EvictIdleResourceNodes(60000))
+	assert.Nil(t, GetResourceNode("idle"))
+	assert.True(t, GetResourceNode("busy") == busy)
+	assert.True(t, GetResourceNode("retained") == retained)
+
+	// the evicted resource gets a new node once there is room
+	assert.Equal(t, 2, len(ResourceNodeList()))
+	newNode := GetOrCreateResourceNode("idle", base.ResTypeCommon)
+	assert.True(t, newNode != idle && newNode != OverflowNode())
+
+	// the released node is evicted once idle
+	RetainResourceNodes("test", nil)
+	assert.Equal(t, 1, EvictIdleResourceNodes(60000))
+	assert.Nil(t, GetResourceNode("retained"))
+}
@@ -15,8 +15,10 @@
 package stat
 
 import (
+	"sync/atomic"
+
 	"github.com/alibaba/sentinel-golang/core/base"
-	"github.com/alibaba/sentinel-golang/core/config"
+	"github.com/alibaba/sentinel-golang/util"
 )
 
 type ResourceNode struct {
@@ -24,14 +26,18 @@ type ResourceNode struct {
 
 	resourceName string
 	resourceType base.ResourceType
+
+	// lastAccessMs is the last time the node is accessed by the entries, the idle node is evicted.
+	lastAccessMs int64
 }
 
 // NewResourceNode creates a new resource node with given name and classification.
 func NewResourceNode(resourceName string, resourceType base.ResourceType) *ResourceNode {
 	return &ResourceNode{
-		BaseStatNode: *NewBaseStatNode(config.MetricStatisticSampleCount(), config.MetricStatisticIntervalMs()),
+		BaseStatNode: *NewBaseStatNodeOf(resourceName),
 		resourceName: resourceName,
 		resourceType: resourceType,
+		lastAccessMs: int64(util.CurrentTimeMillis()),
 	}
 }
 
@@ -42,3 +48,29 @@ func (n *ResourceNode) ResourceType() base.ResourceType {
 func (n *ResourceNode) ResourceName() string {
 	return n.resourceName
 }
+
+// LastAccessMs returns the last time the node is accessed by the entries.
+func (n *ResourceNode) LastAccessMs() uint64 {
+	return uint64(atomic.LoadInt64(&n.lastAccessMs))
+}
+
+// IsRetained reports whether the node of the resource is held by the rules and never evicted, see RetainResourceNodes.
+func (n *ResourceNode) IsRetained() bool {
+	rnsMux.RLock()
+	defer rnsMux.RUnlock()
+
+	return retainCounts[n.resourceName] > 0
+}
+
+func (n *ResourceNode) touch() {
+	now := int64(util.CurrentTimeMillis())
+	// avoid writing the shared cache line on each entry
+	if now-atomic.LoadInt64(&n.lastAccessMs) >= 1000 {
+		atomic.StoreInt64(&n.lastAccessMs, now)
+	}
+}
+
+func (n *ResourceNode) isIdle(now uint64, idleTimeoutMs uint32) bool {
+	lastAccessMs := n.LastAccessMs()
+	return n.CurrentConcurrency() == 0 && now >= lastAccessMs && now-lastAccessMs >= uint64(idleTimeoutMs)
+}
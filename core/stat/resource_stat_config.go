@@ -0,0 +1,270 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stat
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/config"
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/pkg/errors"
+)
+
+// ResourceStatConfig declares the statistic window of the node of the resource,
+// the zero fields fall back to the global statistic config.
+type ResourceStatConfig struct {
+	// Resource represents the resource name.
+	Resource string `json:"resource"`
+	// SampleCountTotal and IntervalMsTotal represent the sliding window holding the statistic of the resource,
+	// which is config.GlobalStatisticSampleCountTotal and config.GlobalStatisticIntervalMsTotal by default.
+	SampleCountTotal uint32 `json:"sampleCountTotal,omitempty"`
+	IntervalMsTotal  uint32 `json:"intervalMsTotal,omitempty"`
+	// SampleCount and IntervalMs represent the default metric of the node which must be reusable
+	// based on the sliding window. By default it's config.MetricStatisticSampleCount and
+	// config.MetricStatisticIntervalMs if reusable, otherwise one bucket per the bucket length of the sliding window.
+	SampleCount uint32 `json:"sampleCount,omitempty"`
+	IntervalMs  uint32 `json:"intervalMs,omitempty"`
+	// ShardedCounter indicates whether the node of the resource records the counts in the striped counters,
+	// which is enabled for all the resources by config.ShardedCounter. It relieves the contention on the hot
+	// resource with high core count, at the cost of up to 2 KB per bucket of the sliding window.
+	ShardedCounter bool `json:"shardedCounter,omitempty"`
+}
+
+func (c *ResourceStatConfig) String() string {
+	return fmt.Sprintf("{Resource=%s, SampleCountTotal=%d, IntervalMsTotal=%d, SampleCount=%d, IntervalMs=%d, ShardedCounter=%t}",
+		c.Resource, c.SampleCountTotal, c.IntervalMsTotal, c.SampleCount, c.IntervalMs, c.ShardedCounter)
+}
+
+// resolved returns the config with the zero fields filled by the global statistic config.
+func (c ResourceStatConfig) resolved() ResourceStatConfig {
+	if c.SampleCountTotal == 0 {
+		c.SampleCountTotal = config.GlobalStatisticSampleCountTotal()
+	}
+	if c.IntervalMsTotal == 0 {
+		c.IntervalMsTotal = config.GlobalStatisticIntervalMsTotal()
+	}
+	if c.SampleCount == 0 && c.IntervalMs == 0 {
+		sampleCount, intervalMs := config.MetricStatisticSampleCount(), config.MetricStatisticIntervalMs()
+		if base.CheckValidityForReuseStatistic(sampleCount, intervalMs, c.SampleCountTotal, c.IntervalMsTotal) == nil {
+			c.SampleCount, c.IntervalMs = sampleCount, intervalMs
+			return c
+		}
+	}
+	if c.IntervalMs == 0 {
+		c.IntervalMs = config.MetricStatisticIntervalMs()
+	}
+	if c.SampleCount == 0 && c.SampleCountTotal > 0 && c.IntervalMsTotal >= c.SampleCountTotal {
+		c.SampleCount = c.IntervalMs / (c.IntervalMsTotal / c.SampleCountTotal)
+	}
+	return c
+}
+
+// IsValidResourceStatConfig checks whether the config is valid and its default metric is reusable
+// based on its sliding window.
+func IsValidResourceStatConfig(c *ResourceStatConfig) error {
+	if c == nil {
+		return errors.New("nil ResourceStatConfig")
+	}
+	if len(c.Resource) == 0 {
+		return errors.New("empty resource name")
+	}
+	r := c.resolved()
+	if err := base.CheckValidityForReuseStatistic(r.SampleCount, r.IntervalMs, r.SampleCountTotal, r.IntervalMsTotal); err != nil {
+		return errors.Wrapf(err, "invalid statistic window, resolved: %s", r.String())
+	}
+	return nil
+}
+
+var (
+	// loadedStatConfigs are the configs loaded by LoadResourceStatConfigs, which take precedence over the declared ones.
+	loadedStatConfigs = make(map[string]*ResourceStatConfig)
+	// declaredStatConfigs are the configs declared by the entries, the first declaration of the resource wins
+	// until it is pruned along with the node of the resource, see pruneDeclaredStatConfigs.
+	declaredStatConfigs = make(map[string]*ResourceStatConfig)
+	statConfigMux       = new(sync.RWMutex)
+	// loadStatConfigMux serializes the loading and the recreation of the nodes.
+	loadStatConfigMux = new(sync.Mutex)
+
+	nodeRecreatedHandlers = make([]func(resources []string), 0)
+)
+
+// RegisterNodeRecreatedHandler registers the handler invoked with the resources whose nodes are recreated
+// due to the change of the statistic config, so that the holders of the stale nodes could regenerate the statistic.
+// Note: this function is not thread-safe, it's expected to be called at initialization.
+func RegisterNodeRecreatedHandler(h func(resources []string)) {
+	if h == nil {
+		return
+	}
+	nodeRecreatedHandlers = append(nodeRecreatedHandlers, h)
+}
+
+// effectiveStatConfigOf returns the resolved statistic config of the resource.
+func effectiveStatConfigOf(resource string) ResourceStatConfig {
+	statConfigMux.RLock()
+	c, ok := loadedStatConfigs[resource]
+	if !ok {
+		c, ok = declaredStatConfigs[resource]
+	}
+	statConfigMux.RUnlock()
+	if !ok {
+		return ResourceStatConfig{Resource: resource}.resolved()
+	}
+	return c.resolved()
+}
+
+// GetResourceStatConfigs returns all the loaded statistic configs.
+func GetResourceStatConfigs() []ResourceStatConfig {
+	statConfigMux.RLock()
+	defer statConfigMux.RUnlock()
+
+	ret := make([]ResourceStatConfig, 0, len(loadedStatConfigs))
+	for _, c := range loadedStatConfigs {
+		ret = append(ret, *c)
+	}
+	return ret
+}
+
+// LoadResourceStatConfigs replaces all the loaded statistic configs with the given ones, the invalid configs are ignored.
+// The existing nodes whose statistic window is changed are recreated, and the registered handlers are notified.
+// Return value:
+//
+//	bool: was designed to indicate whether the internal map has been changed
+//	error: was designed to indicate whether occurs the error.
+func LoadResourceStatConfigs(configs []*ResourceStatConfig) (bool, error) {
+	m := make(map[string]*ResourceStatConfig, len(configs))
+	for _, c := range configs {
+		if err := IsValidResourceStatConfig(c); err != nil {
+			logging.Warn("[Stat LoadResourceStatConfigs] Ignoring invalid resource stat config", "config", c, "reason", err.Error())
+			continue
+		}
+		cp := *c
+		m[c.Resource] = &cp
+	}
+
+	loadStatConfigMux.Lock()
+	defer loadStatConfigMux.Unlock()
+
+	statConfigMux.Lock()
+	if reflect.DeepEqual(loadedStatConfigs, m) {
+		statConfigMux.Unlock()
+		logging.Info("[Stat] Load resource stat configs is the same with current configs, so ignore load operation.")
+		return false, nil
+	}
+	old := loadedStatConfigs
+	loadedStatConfigs = m
+	statConfigMux.Unlock()
+
+	changed := make(map[string]struct{})
+	for res := range old {
+		changed[res] = struct{}{}
+	}
+	for res := range m {
+		changed[res] = struct{}{}
+	}
+	recreateNodesOf(changed)
+	logging.Info("[Stat] Resource stat configs were loaded", "configs", m)
+	return true, nil
+}
+
+// ClearResourceStatConfigs clears all the loaded statistic configs.
+func ClearResourceStatConfigs() error {
+	_, err := LoadResourceStatConfigs(nil)
+	return err
+}
+
+// DeclareResourceStatConfig declares the statistic config of the resource, which is used by the entry options.
+// Only the first valid declaration of the resource takes effect, and the loaded config takes precedence.
+func DeclareResourceStatConfig(resource string, c ResourceStatConfig) {
+	statConfigMux.RLock()
+	_, declared := declaredStatConfigs[resource]
+	statConfigMux.RUnlock()
+	if declared {
+		return
+	}
+
+	c.Resource = resource
+	if err := IsValidResourceStatConfig(&c); err != nil {
+		logging.Warn("[Stat DeclareResourceStatConfig] Ignoring invalid resource stat config", "config", &c, "reason", err.Error())
+		// record the declaration to avoid checking on each entry
+		c = ResourceStatConfig{Resource: resource}
+	}
+
+	loadStatConfigMux.Lock()
+	defer loadStatConfigMux.Unlock()
+
+	statConfigMux.Lock()
+	if _, declared = declaredStatConfigs[resource]; declared {
+		statConfigMux.Unlock()
+		return
+	}
+	declaredStatConfigs[resource] = &c
+	statConfigMux.Unlock()
+
+	recreateNodesOf(map[string]struct{}{resource: {}})
+}
+
+// pruneDeclaredStatConfigs removes the declarations of the resources without nodes, e.g. the evicted or overflowed ones,
+// which are declared again by their next entries. It must be called with rnsMux locked.
+func pruneDeclaredStatConfigs() {
+	statConfigMux.Lock()
+	defer statConfigMux.Unlock()
+
+	for res := range declaredStatConfigs {
+		if _, ok := resNodeMap[res]; !ok {
+			delete(declaredStatConfigs, res)
+		}
+	}
+}
+
+// recreateNodesOf recreates the existing nodes of the resources whose statistic window differs from the effective config,
+// the sharded counter of the other nodes is switched in place. It must be called with loadStatConfigMux locked.
+func recreateNodesOf(resources map[string]struct{}) {
+	recreated := make([]string, 0)
+	rnsMux.Lock()
+	for res := range resources {
+		node := resNodeMap[res]
+		if node == nil {
+			continue
+		}
+		c := effectiveStatConfigOf(res)
+		if node.SampleCountTotal() == c.SampleCountTotal && node.IntervalMsTotal() == c.IntervalMsTotal &&
+			node.SampleCount() == c.SampleCount && node.IntervalMs() == c.IntervalMs {
+			node.arr.SetShardedCounter(c.ShardedCounter)
+			continue
+		}
+		resNodeMap[res] = NewResourceNode(res, node.ResourceType())
+		recreated = append(recreated, res)
+	}
+	rnsMux.Unlock()
+
+	if len(recreated) == 0 {
+		return
+	}
+	logging.Info("[Stat] Resource nodes were recreated due to the change of statistic config", "resources", recreated)
+	for _, h := range nodeRecreatedHandlers {
+		h(recreated)
+	}
+}
+
+func resetResourceStatConfigs() {
+	statConfigMux.Lock()
+	defer statConfigMux.Unlock()
+
+	loadedStatConfigs = make(map[string]*ResourceStatConfig)
+	declaredStatConfigs = make(map[string]*ResourceStatConfig)
+}
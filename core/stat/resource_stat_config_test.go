@@ -0,0 +1,174 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidResourceStatConfig(t *testing.T) {
+	assert.Error(t, IsValidResourceStatConfig(nil))
+	assert.Error(t, IsValidResourceStatConfig(&ResourceStatConfig{}))
+	assert.NoError(t, IsValidResourceStatConfig(&ResourceStatConfig{Resource: "abc"}))
+	// the default metric is one bucket per second
+	assert.NoError(t, IsValidResourceStatConfig(&ResourceStatConfig{Resource: "abc", SampleCountTotal: 60, IntervalMsTotal: 60000}))
+	assert.NoError(t, IsValidResourceStatConfig(&ResourceStatConfig{Resource: "abc", SampleCountTotal: 60, IntervalMsTotal: 60000, SampleCount: 10, IntervalMs: 10000}))
+	// the default metric is not reusable
+	assert.Error(t, IsValidResourceStatConfig(&ResourceStatConfig{Resource: "abc", SampleCountTotal: 60, IntervalMsTotal: 60000, SampleCount: 4, IntervalMs: 1000}))
+	assert.Error(t, IsValidResourceStatConfig(&ResourceStatConfig{Resource: "abc", SampleCountTotal: 7, IntervalMsTotal: 10000}))
+}
+
+func TestLoadResourceStatConfigs(t *testing.T) {
+	ResetResourceNodeMap()
+	resetResourceStatConfigs()
+	handlers := nodeRecreatedHandlers
+	defer func() {
+		nodeRecreatedHandlers = handlers
+		ResetResourceNodeMap()
+		resetResourceStatConfigs()
+	}()
+	recreated := make([]string, 0)
+	RegisterNodeRecreatedHandler(func(resources []string) {
+		recreated = append(recreated, resources...)
+	})
+
+	RetainResourceNodes("test", []string{"abc"})
+	defer RetainResourceNodes("test", nil)
+	node := GetResourceNode("abc")
+	GetOrCreateResourceNode("def", base.ResTypeCommon)
+
+	updated, err := LoadResourceStatConfigs([]*ResourceStatConfig{
+		{Resource: "abc", SampleCountTotal: 60, IntervalMsTotal: 60000},
+		{Resource: "xyz", SampleCountTotal: 10, IntervalMsTotal: 5000},
+		{Resource: "invalid", SampleCountTotal: 7, IntervalMsTotal: 10000},
+	})
+	assert.True(t, updated)
+	assert.NoError(t, err)
+	assert.Len(t, GetResourceStatConfigs(), 2)
+	assert.Equal(t, []string{"abc"}, recreated)
+
+	newNode := GetResourceNode("abc")
+	assert.True(t, newNode != node)
+	assert.True(t, newNode.IsRetained())
+	assert.Equal(t, uint32(60), newNode.SampleCountTotal())
+	assert.Equal(t, uint32(60000), newNode.IntervalMsTotal())
+	assert.Equal(t, uint32(1), newNode.SampleCount())
+	assert.Equal(t, uint32(1000), newNode.IntervalMs())
+
+	node = GetOrCreateResourceNode("xyz", base.ResTypeCommon)
+	assert.Equal(t, uint32(10), node.SampleCountTotal())
+	assert.Equal(t, uint32(5000), node.IntervalMsTotal())
+
+	updated, err = LoadResourceStatConfigs([]*ResourceStatConfig{
+		{Resource: "abc", SampleCountTotal: 60, IntervalMsTotal: 60000},
+		{Resource: "xyz", SampleCountTotal: 10, IntervalMsTotal: 5000},
+	})
+	assert.False(t, updated)
+	assert.NoError(t, err)
+
+	recreated = recreated[:0]
+	assert.NoError(t, ClearResourceStatConfigs())
+	assert.ElementsMatch(t, []string{"abc", "xyz"}, recreated)
+	assert.Equal(t, uint32(20), GetResourceNode("abc").SampleCountTotal())
+	assert.Empty(t, GetResourceStatConfigs())
+}
+
+func TestLoadResourceStatConfigs_ShardedCounter(t *testing.T) {
+	ResetResourceNodeMap()
+	resetResourceStatConfigs()
+	defer func() {
+		ResetResourceNodeMap()
+		resetResourceStatConfigs()
+	}()
+
+	node := GetOrCreateResourceNode("abc", base.ResTypeCommon)
+	assert.False(t, node.arr.IsShardedCounter())
+	_, err := LoadResourceStatConfigs([]*ResourceStatConfig{{Resource: "abc", ShardedCounter: true}})
+	assert.NoError(t, err)
+	// the sharded counter is switched without recreating the node
+	assert.True(t, GetResourceNode("abc") == node)
+	assert.True(t, node.arr.IsShardedCounter())
+	assert.True(t, GetOrCreateResourceNode("abc", base.ResTypeCommon).arr.IsShardedCounter())
+	assert.False(t, GetOrCreateResourceNode("def", base.ResTypeCommon).arr.IsShardedCounter())
+
+	assert.NoError(t, ClearResourceStatConfigs())
+	assert.True(t, GetResourceNode("abc") == node)
+	assert.False(t, node.arr.IsShardedCounter())
+}
+
+func TestDeclareResourceStatConfig(t *testing.T) {
+	ResetResourceNodeMap()
+	resetResourceStatConfigs()
+	defer func() {
+		ResetResourceNodeMap()
+		resetResourceStatConfigs()
+	}()
+
+	DeclareResourceStatConfig("abc", ResourceStatConfig{SampleCountTotal: 60, IntervalMsTotal: 60000})
+	// only the first declaration takes effect
+	DeclareResourceStatConfig("abc", ResourceStatConfig{SampleCountTotal: 10, IntervalMsTotal: 5000})
+	node := GetOrCreateResourceNode("abc", base.ResTypeCommon)
+	assert.Equal(t, uint32(60), node.SampleCountTotal())
+
+	// the loaded config takes precedence
+	_, err := LoadResourceStatConfigs([]*ResourceStatConfig{{Resource: "abc", SampleCountTotal: 10, IntervalMsTotal: 5000}})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(10), GetResourceNode("abc").SampleCountTotal())
+
+	// the invalid declaration falls back to the global config
+	DeclareResourceStatConfig("def", ResourceStatConfig{SampleCountTotal: 7, IntervalMsTotal: 10000})
+	assert.Equal(t, uint32(20), GetOrCreateResourceNode("def", base.ResTypeCommon).SampleCountTotal())
+
+	// the existing node is recreated once declared
+	GetOrCreateResourceNode("xyz", base.ResTypeCommon)
+	DeclareResourceStatConfig("xyz", ResourceStatConfig{SampleCountTotal: 10, IntervalMsTotal: 5000})
+	assert.Equal(t, uint32(10), GetResourceNode("xyz").SampleCountTotal())
+}
+
+func TestDeclareResourceStatConfig_PrunedOnEviction(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+	ResetResourceNodeMap()
+	resetResourceStatConfigs()
+	defer func() {
+		ResetResourceNodeMap()
+		resetResourceStatConfigs()
+	}()
+
+	DeclareResourceStatConfig("abc", ResourceStatConfig{SampleCountTotal: 10, IntervalMsTotal: 5000})
+	GetOrCreateResourceNode("abc", base.ResTypeCommon)
+	// the declaration without node, e.g. the overflowed resource
+	DeclareResourceStatConfig("def", ResourceStatConfig{SampleCountTotal: 7, IntervalMsTotal: 10000})
+	DeclareResourceStatConfig("xyz", ResourceStatConfig{SampleCountTotal: 10, IntervalMsTotal: 5000})
+	GetOrCreateResourceNode("xyz", base.ResTypeCommon)
+
+	util.Sleep(30 * time.Second)
+	GetOrCreateResourceNode("xyz", base.ResTypeCommon)
+	util.Sleep(40 * time.Second)
+	assert.Equal(t, 1, EvictIdleResourceNodes(60000))
+	statConfigMux.RLock()
+	assert.Equal(t, 1, len(declaredStatConfigs))
+	assert.NotNil(t, declaredStatConfigs["xyz"])
+	statConfigMux.RUnlock()
+
+	// the pruned resource is declared again by its next entry
+	DeclareResourceStatConfig("abc", ResourceStatConfig{SampleCountTotal: 60, IntervalMsTotal: 60000})
+	assert.Equal(t, uint32(60), GetOrCreateResourceNode("abc", base.ResTypeCommon).SampleCountTotal())
+}
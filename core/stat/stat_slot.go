@@ -24,6 +24,8 @@ const (
 	StatSlotOrder = 1000
 	ResultPass    = "pass"
 	ResultBlock   = "block"
+	// ResultShadowBlock means the request passed but would have been blocked by shadow rules.
+	ResultShadowBlock = "shadow_block"
 )
 
 var (
@@ -53,6 +55,15 @@ func (s *Slot) OnEntryPassed(ctx *base.EntryContext) {
 	}
 
 	handledCounter.Add(float64(ctx.Input.BatchCount), ctx.Resource.Name(), ResultPass, "")
+
+	if shadowBlocks := ctx.ShadowBlocks(); len(shadowBlocks) > 0 {
+		s.recordShadowBlockFor(ctx.StatNode, ctx.Input.BatchCount)
+		if ctx.Resource.FlowType() == base.Inbound {
+			s.recordShadowBlockFor(InboundNode(), ctx.Input.BatchCount)
+		}
+		// the first shadow rule is the one which would have blocked the request
+		handledCounter.Add(float64(ctx.Input.BatchCount), ctx.Resource.Name(), ResultShadowBlock, shadowBlocks[0].BlockType().String())
+	}
 }
 
 func (s *Slot) OnEntryBlocked(ctx *base.EntryContext, blockError *base.BlockError) {
@@ -65,6 +76,14 @@ func (s *Slot) OnEntryBlocked(ctx *base.EntryContext, blockError *base.BlockErro
 }
 
 func (s *Slot) OnCompleted(ctx *base.EntryContext) {
+	if ctx.IsReservation() {
+		// the reserved work has not run yet, only give back the concurrency
+		s.releaseConcurrencyOf(ctx.StatNode)
+		if ctx.Resource.FlowType() == base.Inbound {
+			s.releaseConcurrencyOf(InboundNode())
+		}
+		return
+	}
 	rt := util.CurrentTimeMillis() - ctx.StartTime()
 	ctx.PutRt(rt)
 	s.recordCompleteFor(ctx.StatNode, ctx.Input.BatchCount, rt, ctx.Err())
@@ -88,6 +107,20 @@ func (s *Slot) recordBlockFor(sn base.StatNode, count uint32) {
 	sn.AddCount(base.MetricEventBlock, int64(count))
 }
 
+func (s *Slot) recordShadowBlockFor(sn base.StatNode, count uint32) {
+	if sn == nil {
+		return
+	}
+	sn.AddCount(base.MetricEventShadowBlock, int64(count))
+}
+
+func (s *Slot) releaseConcurrencyOf(sn base.StatNode) {
+	if sn == nil {
+		return
+	}
+	sn.DecreaseConcurrency()
+}
+
 func (s *Slot) recordCompleteFor(sn base.StatNode, count uint32, rt uint64, err error) {
 	if sn == nil {
 		return
@@ -17,6 +17,9 @@ package system
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
 )
 
 type MetricType uint32
@@ -30,8 +33,20 @@ const (
 	Concurrency
 	// InboundQPS represents the QPS of all inbound requests.
 	InboundQPS
-	// CpuUsage represents the CPU usage percentage of the system.
+	// CpuUsage represents the CPU usage ratio of the process, valid range is [0.0, 1.0].
+	// In container with cgroup CPU limit, it's the ratio relative to the CPU limit of container.
 	CpuUsage
+	// Goroutines represents the count of goroutines of current process.
+	Goroutines
+	// GcPauseP99 represents the p99 of GC stop-the-world pause latency in milliseconds.
+	GcPauseP99
+	// SchedLatencyP99 represents the p99 of Go scheduler latency in milliseconds,
+	// i.e. the time goroutines spent in runnable state before actually running.
+	SchedLatencyP99
+	// HeapLiveBytes represents the heap memory occupied by live objects in bytes.
+	HeapLiveBytes
+	// GcCpuFraction represents the fraction of CPU time used by GC, valid range is [0.0, 1.0].
+	GcCpuFraction
 
 	// MetricTypeSize indicates the enum size of MetricType.
 	MetricTypeSize
@@ -49,6 +64,16 @@ func (t MetricType) String() string {
 		return "inboundQPS"
 	case CpuUsage:
 		return "cpuUsage"
+	case Goroutines:
+		return "goroutines"
+	case GcPauseP99:
+		return "gcPauseP99"
+	case SchedLatencyP99:
+		return "schedLatencyP99"
+	case HeapLiveBytes:
+		return "heapLiveBytes"
+	case GcCpuFraction:
+		return "gcCpuFraction"
 	default:
 		return fmt.Sprintf("unknown(%d)", t)
 	}
@@ -60,6 +85,10 @@ const (
 	NoAdaptive AdaptiveStrategy = -1
 	// BBR represents the adaptive strategy based on ideas of TCP BBR.
 	BBR AdaptiveStrategy = iota
+	// AdaptiveBBR represents the full BBR adaptive limiter, which tracks the max-pass and min-RT of inbound traffic
+	// over the rolling window, and holds a cool-down period after the latest drop. For CpuUsage rules, the
+	// exponentially weighted moving average of CPU usage is compared with the TriggerCount.
+	AdaptiveBBR
 )
 
 func (t AdaptiveStrategy) String() string {
@@ -68,6 +97,8 @@ func (t AdaptiveStrategy) String() string {
 		return "none"
 	case BBR:
 		return "bbr"
+	case AdaptiveBBR:
+		return "adaptiveBbr"
 	default:
 		return fmt.Sprintf("unknown(%d)", t)
 	}
@@ -84,6 +115,26 @@ type Rule struct {
 	TriggerCount float64 `json:"triggerCount"`
 	// Strategy represents the adaptive strategy.
 	Strategy AdaptiveStrategy `json:"strategy"`
+	// Mode indicates whether the rule blocks the requests (Enforce) or only records the would-be blocked ones (Shadow).
+	Mode base.RuleMode `json:"mode,omitempty"`
+	// EnforcePercent is the percentage of traffic the rule is enforced on, the valid range is [0.0, 100.0] and 0 means all the traffic.
+	// The rest of the traffic is checked in the Shadow mode.
+	EnforcePercent float64 `json:"enforcePercent,omitempty"`
+	// EnforceKey is the attachment key whose value selects the enforced traffic by hash, the traffic is selected randomly if empty.
+	EnforceKey string `json:"enforceKey,omitempty"`
+	// Canary selects the instances the rule is enforced on, nil means all the instances.
+	Canary *base.CanarySelector `json:"canary,omitempty"`
+	// Condition is the optional expression the rule only takes effect when it holds, e.g. `attachments.caller != "internal"`,
+	// see package condition for the syntax.
+	Condition string `json:"condition,omitempty"`
+	// conditionProgram is the compiled Condition, which is compiled when the rule is loaded.
+	conditionProgram *condition.Program
+	// ExpireAt is the Unix timestamp in milliseconds when the rule expires and is dropped automatically, 0 means never.
+	ExpireAt uint64 `json:"expireAt,omitempty"`
+	// TTLSec is the lifetime in seconds of the rule since it's loaded, 0 means forever.
+	// If both ExpireAt and TTLSec are set, the earlier one takes effect.
+	// A rule expired by TTLSec comes back with a new lifetime if it's loaded again, so prefer ExpireAt for rules from data sources.
+	TTLSec uint32 `json:"ttlSec,omitempty"`
 }
 
 func (r *Rule) String() string {
@@ -99,3 +150,26 @@ func (r *Rule) String() string {
 func (r *Rule) ResourceName() string {
 	return r.MetricType.String()
 }
+
+// Lifetime returns the ExpireAt and TTLSec of the rule.
+func (r *Rule) Lifetime() (uint64, uint32) {
+	return r.ExpireAt, r.TTLSec
+}
+
+// isEnforcedOn reports whether the rule is enforced on the entry, otherwise the rule is checked in the Shadow mode.
+func (r *Rule) isEnforcedOn(ctx *base.EntryContext) bool {
+	return base.IsEnforcedOn(ctx, r.Mode, r.EnforcePercent, r.EnforceKey, r.Canary)
+}
+
+// conditionHolds reports whether the Condition of the rule holds on the entry.
+func (r *Rule) conditionHolds(ctx *base.EntryContext) bool {
+	return r.conditionProgram.Holds(ctx)
+}
+
+func (r *Rule) setConditionProgram(program *condition.Program) {
+	r.conditionProgram = program
+}
+
+func (r *Rule) setCanary(canary *base.CanarySelector) {
+	r.Canary = canary
+}
@@ -18,6 +18,8 @@ import (
 	"reflect"
 	"sync"
 
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/condition"
 	"github.com/alibaba/sentinel-golang/logging"
 	"github.com/alibaba/sentinel-golang/util"
 	"github.com/pkg/errors"
@@ -27,14 +29,35 @@ type RuleMap map[MetricType][]*Rule
 
 // const
 var (
-	ruleMap       = make(RuleMap)
-	ruleMapMux    = new(sync.RWMutex)
-	currentRules  = make([]*Rule, 0)
-	updateRuleMux = new(sync.Mutex)
+	ruleMap        = make(RuleMap)
+	ruleMapMux     = new(sync.RWMutex)
+	currentRules   = make([]*Rule, 0)
+	updateRuleMux  = new(sync.Mutex)
+	ruleExpiration *base.RuleExpirationManager[Rule, *Rule]
 )
 
+func init() {
+	ruleExpiration = base.NewRuleExpirationManager("[System]", updateRuleMux,
+		func() map[string][]*Rule {
+			// system rules are global, so all of them are grouped into the empty resource
+			return map[string][]*Rule{"": currentRules}
+		},
+		func(resRulesMap map[string][]*Rule) error {
+			rules := resRulesMap[""]
+			if err := onRuleUpdate(buildRuleMap(rules)); err != nil {
+				return err
+			}
+			currentRules = rules
+			return nil
+		},
+		func(rule *Rule, expireAt uint64, ttlSec uint32) {
+			rule.ExpireAt, rule.TTLSec = expireAt, ttlSec
+		})
+}
+
 // GetRules returns all the rules based on copy.
 // It doesn't take effect for system module if user changes the rule.
+// ExpireAt and TTLSec of the returned rules are the resolved deadline and the remaining lifetime of the rules.
 // GetRules need to compete system module's global lock and the high performance losses of copy,
 //
 //	reduce or do not call GetRules if possible
@@ -46,9 +69,10 @@ func GetRules() []Rule {
 	}
 	ruleMapMux.RUnlock()
 
+	now := util.CurrentTimeMillis()
 	ret := make([]Rule, 0, len(rules))
 	for _, r := range rules {
-		ret = append(ret, *r)
+		ret = append(ret, ruleExpiration.CopyWithLifetime(r, now))
 	}
 	return ret
 }
@@ -83,6 +107,7 @@ func LoadRules(rules []*Rule) (bool, error) {
 		return false, err
 	}
 	currentRules = rules
+	ruleExpiration.Track()
 	return true, nil
 }
 
@@ -97,6 +122,7 @@ func onRuleUpdate(r RuleMap) error {
 	ruleMapMux.Lock()
 	ruleMap = r
 	ruleMapMux.Unlock()
+	updateAdaptiveBBREnabled(r)
 
 	logging.Debug("[System onRuleUpdate] Time statistic(ns) for updating system rule", "timeCost", util.CurrentTimeNano()-start)
 	if len(r) > 0 {
@@ -119,6 +145,8 @@ func buildRuleMap(rules []*Rule) RuleMap {
 			logging.Warn("[System buildRuleMap] Ignoring invalid system rule", "rule", rule, "err", err.Error())
 			continue
 		}
+		rule = condition.Prepare(rule, rule.Condition, (*Rule).setConditionProgram)
+		rule = base.ResolveCanary(rule, rule.Canary, (*Rule).setCanary)
 		rulesOfRes, exists := m[rule.MetricType]
 		if !exists {
 			m[rule.MetricType] = []*Rule{rule}
@@ -137,12 +165,30 @@ func IsValidSystemRule(rule *Rule) error {
 	if rule.TriggerCount < 0 {
 		return errors.New("negative threshold")
 	}
+	if rule.ExpireAt > 0 && rule.ExpireAt <= util.CurrentTimeMillis() {
+		return errors.New("rule already expired")
+	}
 	if rule.MetricType >= MetricTypeSize {
 		return errors.New("invalid metric type")
 	}
+	if rule.Mode != base.Enforce && rule.Mode != base.Shadow {
+		return errors.New("invalid mode")
+	}
+	if err := base.CheckEnforcePercent(rule.EnforcePercent); err != nil {
+		return err
+	}
+	if err := rule.Canary.IsValid(); err != nil {
+		return err
+	}
+	if err := condition.IsValid(rule.Condition); err != nil {
+		return errors.Wrap(err, "invalid Condition")
+	}
 
 	if rule.MetricType == CpuUsage && rule.TriggerCount > 1 {
 		return errors.New("invalid CPU usage, valid range is [0.0, 1.0]")
 	}
+	if rule.MetricType == GcCpuFraction && rule.TriggerCount > 1 {
+		return errors.New("invalid GC CPU fraction, valid range is [0.0, 1.0]")
+	}
 	return nil
 }
@@ -16,7 +16,9 @@ package system
 
 import (
 	"testing"
+	"time"
 
+	"github.com/alibaba/sentinel-golang/util"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -167,9 +169,40 @@ func TestIsValidSystemRule(t *testing.T) {
 		assert.EqualError(t, err, "invalid CPU usage, valid range is [0.0, 1.0]")
 	})
 
+	t.Run("InvalidGcCpuFraction", func(t *testing.T) {
+		sRule := &Rule{MetricType: GcCpuFraction, TriggerCount: 25}
+		err := IsValidSystemRule(sRule)
+		assert.EqualError(t, err, "invalid GC CPU fraction, valid range is [0.0, 1.0]")
+	})
+
 	t.Run("ValidSystemRule", func(t *testing.T) {
 		sRule := &Rule{MetricType: Load, TriggerCount: 12, Strategy: BBR}
 		err := IsValidSystemRule(sRule)
 		assert.NoError(t, err)
 	})
 }
+
+func TestExpireRules(t *testing.T) {
+	util.SetClock(util.NewMockClock())
+	defer util.SetClock(util.NewRealClock())
+	defer ClearRules()
+
+	now := util.CurrentTimeMillis()
+	r1 := &Rule{MetricType: InboundQPS, TriggerCount: 1, ExpireAt: now + 3000}
+	r2 := &Rule{MetricType: Concurrency, TriggerCount: 10}
+	_, err := LoadRules([]*Rule{r1, r2})
+	assert.Nil(t, err)
+	for _, r := range GetRules() {
+		if r.MetricType == InboundQPS {
+			assert.Equal(t, now+3000, r.ExpireAt)
+			assert.Equal(t, uint32(3), r.TTLSec)
+		}
+	}
+
+	util.Sleep(3 * time.Second)
+	ruleExpiration.Expire()
+	rules := GetRules()
+	assert.Equal(t, 1, len(rules))
+	assert.Equal(t, Concurrency, rules[0].MetricType)
+	assert.Equal(t, []*Rule{r2}, currentRules)
+}
@@ -46,9 +46,14 @@ func TestMetricTypeString(t *testing.T) {
 		assert.Equal(t, "cpuUsage", mt.String())
 	})
 
+	t.Run("GoroutinesMetricType", func(t *testing.T) {
+		mt := Goroutines
+		assert.Equal(t, "goroutines", mt.String())
+	})
+
 	t.Run("UnknownMetricType", func(t *testing.T) {
 		mt := MetricTypeSize
-		assert.Equal(t, "unknown(5)", mt.String())
+		assert.Equal(t, "unknown(10)", mt.String())
 	})
 }
 
@@ -63,9 +68,14 @@ func TestAdaptiveStrategyString(t *testing.T) {
 		assert.Equal(t, "bbr", as.String())
 	})
 
+	t.Run("AdaptiveBBRStrategy", func(t *testing.T) {
+		as := AdaptiveBBR
+		assert.Equal(t, "adaptiveBbr", as.String())
+	})
+
 	t.Run("UnknownAdaptiveStrategy", func(t *testing.T) {
-		as := AdaptiveStrategy(2)
-		assert.Equal(t, "unknown(2)", as.String())
+		as := AdaptiveStrategy(3)
+		assert.Equal(t, "unknown(3)", as.String())
 	})
 }
 
@@ -16,6 +16,7 @@ package system
 
 import (
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/bbr"
 	"github.com/alibaba/sentinel-golang/core/stat"
 	"github.com/alibaba/sentinel-golang/core/system_metric"
 )
@@ -42,10 +43,17 @@ func (s *AdaptiveSlot) Check(ctx *base.EntryContext) *base.TokenResult {
 	rules := getRules()
 	result := ctx.RuleCheckResult
 	for _, rule := range rules {
+		if !rule.conditionHolds(ctx) {
+			continue
+		}
 		passed, msg, snapshotValue := s.doCheckRule(rule)
 		if passed {
 			continue
 		}
+		if !rule.isEnforcedOn(ctx) {
+			ctx.AddShadowBlock(base.NewBlockErrorWithCause(base.BlockTypeSystemFlow, msg, rule, snapshotValue))
+			continue
+		}
 		if result == nil {
 			result = base.NewTokenResultBlockedWithCause(base.BlockTypeSystemFlow, msg, rule, snapshotValue)
 		} else {
@@ -83,29 +91,46 @@ func (s *AdaptiveSlot) doCheckRule(rule *Rule) (bool, string, float64) {
 		}
 		return res, msg, rt
 	case Load:
-		l := system_metric.CurrentLoad()
-		if l > threshold {
-			if rule.Strategy != BBR || !checkBbrSimple() {
-				msg = "system load check blocked"
-				return false, msg, l
-			}
-		}
-		return true, "", l
+		return checkAdaptiveMetric(rule, system_metric.CurrentLoad(), "system load check blocked")
 	case CpuUsage:
 		c := system_metric.CurrentCpuUsage()
-		if c > threshold {
-			if rule.Strategy != BBR || !checkBbrSimple() {
-				msg = "system cpu usage check blocked"
-				return false, msg, c
-			}
+		if rule.Strategy == AdaptiveBBR {
+			c = bbr.CurrentCpuUsage()
 		}
-		return true, "", c
+		return checkAdaptiveMetric(rule, c, "system cpu usage check blocked")
+	case Goroutines:
+		return checkAdaptiveMetric(rule, system_metric.CurrentGoroutines(), "system goroutines check blocked")
+	case GcPauseP99:
+		return checkAdaptiveMetric(rule, system_metric.CurrentGcPauseP99(), "system gc pause check blocked")
+	case SchedLatencyP99:
+		return checkAdaptiveMetric(rule, system_metric.CurrentSchedLatencyP99(), "system scheduler latency check blocked")
+	case HeapLiveBytes:
+		return checkAdaptiveMetric(rule, system_metric.CurrentHeapLiveBytes(), "system heap live bytes check blocked")
+	case GcCpuFraction:
+		return checkAdaptiveMetric(rule, system_metric.CurrentGcCpuFraction(), "system gc cpu fraction check blocked")
 	default:
 		msg = "system undefined metric type, pass by default"
 		return true, msg, 0.0
 	}
 }
 
+// checkAdaptiveMetric checks the system metric which the adaptive strategy is applicable to.
+func checkAdaptiveMetric(rule *Rule, v float64, blockedMsg string) (bool, string, float64) {
+	if rule.Strategy == AdaptiveBBR {
+		inFlight := int64(stat.InboundNode().CurrentConcurrency())
+		if bbrLimiter.ShouldDrop(inFlight, v > rule.TriggerCount) {
+			return false, blockedMsg, v
+		}
+		return true, "", v
+	}
+	if v > rule.TriggerCount {
+		if rule.Strategy != BBR || !checkBbrSimple() {
+			return false, blockedMsg, v
+		}
+	}
+	return true, "", v
+}
+
 func checkBbrSimple() bool {
 	concurrency := stat.InboundNode().CurrentConcurrency()
 	minRt := stat.InboundNode().MinRT()
@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/bbr"
 	"github.com/alibaba/sentinel-golang/core/stat"
 	"github.com/alibaba/sentinel-golang/core/system_metric"
 	"github.com/alibaba/sentinel-golang/util"
@@ -127,3 +128,64 @@ func TestDoCheckRuleDefault(t *testing.T) {
 	assert.Equal(t, true, isOK)
 	assert.True(t, util.Float64Equals(float64(0.0), v))
 }
+
+func TestDoCheckRuleRuntimeMetrics(t *testing.T) {
+	var sas *AdaptiveSlot
+	defer system_metric.SetGoroutines(system_metric.NotRetrievedRuntimeValue)
+
+	t.Run("NotRetrieved", func(t *testing.T) {
+		rule := &Rule{MetricType: GcPauseP99, TriggerCount: 10}
+		isOK, _, v := sas.doCheckRule(rule)
+		assert.True(t, isOK)
+		assert.True(t, util.Float64Equals(system_metric.NotRetrievedRuntimeValue, v))
+	})
+
+	t.Run("GoroutinesBlocked", func(t *testing.T) {
+		rule := &Rule{MetricType: Goroutines, TriggerCount: 1000}
+		system_metric.SetGoroutines(2000)
+		isOK, msg, v := sas.doCheckRule(rule)
+		assert.False(t, isOK)
+		assert.Equal(t, "system goroutines check blocked", msg)
+		assert.True(t, util.Float64Equals(2000, v))
+	})
+
+	t.Run("BBRGoroutinesPassed", func(t *testing.T) {
+		rule := &Rule{MetricType: Goroutines, TriggerCount: 1000, Strategy: BBR}
+		system_metric.SetGoroutines(2000)
+		isOK, _, v := sas.doCheckRule(rule)
+		assert.True(t, isOK)
+		assert.True(t, util.Float64Equals(2000, v))
+	})
+}
+
+func TestDoCheckRuleAdaptiveBBR(t *testing.T) {
+	var sas *AdaptiveSlot
+	defer func() {
+		system_metric.SetGoroutines(system_metric.NotRetrievedRuntimeValue)
+		bbrLimiter, _ = bbr.NewLimiter(bbr.DefaultWindowMs, bbr.DefaultBucketCount, bbr.DefaultCoolDownMs)
+	}()
+	rule := &Rule{MetricType: Goroutines, TriggerCount: 1000, Strategy: AdaptiveBBR}
+
+	system_metric.SetGoroutines(2000)
+	isOK, _, _ := sas.doCheckRule(rule)
+	assert.True(t, isOK)
+
+	for i := 0; i < 5; i++ {
+		stat.InboundNode().IncreaseConcurrency()
+	}
+	defer func() {
+		for i := 0; i < 5; i++ {
+			stat.InboundNode().DecreaseConcurrency()
+		}
+	}()
+	isOK, msg, v := sas.doCheckRule(rule)
+	assert.False(t, isOK)
+	assert.Equal(t, "system goroutines check blocked", msg)
+	assert.True(t, util.Float64Equals(2000, v))
+
+	// Still in cool-down period even if not overloaded.
+	system_metric.SetGoroutines(500)
+	assert.True(t, bbrLimiter.InCoolDown())
+	isOK, _, _ = sas.doCheckRule(rule)
+	assert.False(t, isOK)
+}
@@ -0,0 +1,68 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package system
+
+import (
+	"sync/atomic"
+
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/bbr"
+)
+
+const (
+	StatSlotOrder = 6000
+)
+
+var (
+	DefaultStatSlot = &StatSlot{}
+
+	bbrLimiter, _ = bbr.NewLimiter(bbr.DefaultWindowMs, bbr.DefaultBucketCount, bbr.DefaultCoolDownMs)
+	// adaptiveBBREnabled indicates whether there are rules with AdaptiveBBR strategy, 1 means true.
+	adaptiveBBREnabled int32
+)
+
+// StatSlot records the completed inbound requests for the AdaptiveBBR strategy.
+// It must be placed after stat.Slot, which calculates the response time.
+type StatSlot struct {
+}
+
+func (s *StatSlot) Order() uint32 {
+	return StatSlotOrder
+}
+
+func (s *StatSlot) OnEntryPassed(_ *base.EntryContext) {
+}
+
+func (s *StatSlot) OnEntryBlocked(_ *base.EntryContext, _ *base.BlockError) {
+}
+
+func (s *StatSlot) OnCompleted(ctx *base.EntryContext) {
+	if atomic.LoadInt32(&adaptiveBBREnabled) == 0 || ctx.Resource.FlowType() != base.Inbound || ctx.IsReservation() {
+		return
+	}
+	bbrLimiter.OnCompleted(ctx.Rt(), ctx.Input.BatchCount)
+}
+
+func updateAdaptiveBBREnabled(m RuleMap) {
+	enabled := int32(0)
+	for _, rules := range m {
+		for _, rule := range rules {
+			if rule.Strategy == AdaptiveBBR {
+				enabled = 1
+			}
+		}
+	}
+	atomic.StoreInt32(&adaptiveBBREnabled, enabled)
+}
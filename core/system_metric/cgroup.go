@@ -0,0 +1,294 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package system_metric
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultCgroupRoot = "/sys/fs/cgroup"
+	// cgroupV1UnlimitedMemory is the threshold above which the cgroup v1 memory limit means unlimited,
+	// the kernel reports the page aligned max int64 when no limit is set.
+	cgroupV1UnlimitedMemory uint64 = 1 << 62
+)
+
+// CgroupVersion represents the version of cgroup which current process belongs to.
+type CgroupVersion int32
+
+const (
+	// CgroupNone means no cgroup (e.g. not running in container or not on Linux).
+	CgroupNone CgroupVersion = iota
+	CgroupV1
+	CgroupV2
+)
+
+func (v CgroupVersion) String() string {
+	switch v {
+	case CgroupV1:
+		return "v1"
+	case CgroupV2:
+		return "v2"
+	default:
+		return "none"
+	}
+}
+
+var (
+	cgroupV1CpuDirs     = []string{"cpu", "cpu,cpuacct", "cpuacct,cpu"}
+	cgroupV1CpuacctDirs = []string{"cpuacct", "cpu,cpuacct", "cpuacct,cpu"}
+)
+
+// cgroupReader reads the resource limits and usages of the container from cgroupfs.
+// It expects the cgroup of current container is mounted at the root, which is the default
+// behavior of the container runtimes with cgroup namespace or private cgroup mounts.
+type cgroupReader struct {
+	root    string
+	version CgroupVersion
+
+	cpuMux        sync.Mutex
+	lastCpuUsage  uint64
+	lastCpuSample time.Time
+}
+
+func newCgroupReader(root string) *cgroupReader {
+	return &cgroupReader{
+		root:    root,
+		version: detectCgroupVersion(root),
+	}
+}
+
+func detectCgroupVersion(root string) CgroupVersion {
+	if fileExists(filepath.Join(root, "cgroup.controllers")) {
+		return CgroupV2
+	}
+	for _, dir := range append(cgroupV1CpuDirs, "memory") {
+		if fileExists(filepath.Join(root, dir)) {
+			return CgroupV1
+		}
+	}
+	return CgroupNone
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func readCgroupFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readCgroupUint(path string) (uint64, error) {
+	s, err := readCgroupFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// readCgroupStatValue reads the value of the given key from the flat keyed file (e.g. memory.stat, cpu.stat).
+func readCgroupStatValue(path, key string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.Errorf("key %s not found in %s", key, path)
+}
+
+// firstExisting returns the first existing file path of root/dir/name among the given dirs.
+func (r *cgroupReader) firstExisting(dirs []string, name string) (string, bool) {
+	for _, dir := range dirs {
+		path := filepath.Join(r.root, dir, name)
+		if fileExists(path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// cpuLimitCores returns the CPU limit in cores, the second returned value is false if no limit.
+func (r *cgroupReader) cpuLimitCores() (float64, bool) {
+	var quota, period int64
+	switch r.version {
+	case CgroupV2:
+		s, err := readCgroupFile(filepath.Join(r.root, "cpu.max"))
+		if err != nil {
+			return 0, false
+		}
+		fields := strings.Fields(s)
+		if len(fields) == 0 || fields[0] == "max" {
+			return 0, false
+		}
+		if quota, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+			return 0, false
+		}
+		period = 100000
+		if len(fields) > 1 {
+			if period, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+				return 0, false
+			}
+		}
+	case CgroupV1:
+		quotaPath, ok := r.firstExisting(cgroupV1CpuDirs, "cpu.cfs_quota_us")
+		if !ok {
+			return 0, false
+		}
+		periodPath, ok := r.firstExisting(cgroupV1CpuDirs, "cpu.cfs_period_us")
+		if !ok {
+			return 0, false
+		}
+		s, err := readCgroupFile(quotaPath)
+		if err != nil {
+			return 0, false
+		}
+		if quota, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return 0, false
+		}
+		s, err = readCgroupFile(periodPath)
+		if err != nil {
+			return 0, false
+		}
+		if period, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if quota <= 0 || period <= 0 {
+		return 0, false
+	}
+	return float64(quota) / float64(period), true
+}
+
+// cpuUsageNanos returns the accumulated CPU time of the cgroup in nanoseconds.
+func (r *cgroupReader) cpuUsageNanos() (uint64, error) {
+	switch r.version {
+	case CgroupV2:
+		usec, err := readCgroupStatValue(filepath.Join(r.root, "cpu.stat"), "usage_usec")
+		if err != nil {
+			return 0, err
+		}
+		return usec * 1000, nil
+	case CgroupV1:
+		path, ok := r.firstExisting(cgroupV1CpuacctDirs, "cpuacct.usage")
+		if !ok {
+			return 0, errors.New("cpuacct.usage not found")
+		}
+		return readCgroupUint(path)
+	default:
+		return 0, errors.New("no cgroup detected")
+	}
+}
+
+// cpuUsageRatio returns the CPU usage ratio in [0.0, 1.0] relative to the CPU limit since last sampling.
+// 1.0 means the CPU quota of container is fully used, the usage beyond the quota due to sampling is capped.
+func (r *cgroupReader) cpuUsageRatio(now time.Time) (float64, error) {
+	limit, ok := r.cpuLimitCores()
+	if !ok {
+		return 0, errors.New("no cgroup cpu limit")
+	}
+	usage, err := r.cpuUsageNanos()
+	if err != nil {
+		return 0, err
+	}
+
+	r.cpuMux.Lock()
+	defer r.cpuMux.Unlock()
+	lastUsage, lastSample := r.lastCpuUsage, r.lastCpuSample
+	r.lastCpuUsage, r.lastCpuSample = usage, now
+	if lastSample.IsZero() || !now.After(lastSample) || usage < lastUsage {
+		// The first sampling, no usage delta yet.
+		return 0, nil
+	}
+	elapsed := float64(now.Sub(lastSample).Nanoseconds())
+	return math.Min(float64(usage-lastUsage)/elapsed/limit, 1.0), nil
+}
+
+// memoryLimitBytes returns the memory limit in bytes, the second returned value is false if no limit.
+func (r *cgroupReader) memoryLimitBytes() (uint64, bool) {
+	switch r.version {
+	case CgroupV2:
+		s, err := readCgroupFile(filepath.Join(r.root, "memory.max"))
+		if err != nil || s == "max" {
+			return 0, false
+		}
+		limit, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return limit, true
+	case CgroupV1:
+		limit, err := readCgroupUint(filepath.Join(r.root, "memory", "memory.limit_in_bytes"))
+		if err != nil || limit >= cgroupV1UnlimitedMemory {
+			return 0, false
+		}
+		return limit, true
+	default:
+		return 0, false
+	}
+}
+
+// memoryUsageBytes returns the working set of the cgroup in bytes, i.e. the usage excluding the inactive page cache,
+// which is the same as what the kubelet uses for eviction.
+func (r *cgroupReader) memoryUsageBytes() (uint64, error) {
+	var usagePath, statPath, inactiveKey string
+	switch r.version {
+	case CgroupV2:
+		usagePath = filepath.Join(r.root, "memory.current")
+		statPath = filepath.Join(r.root, "memory.stat")
+		inactiveKey = "inactive_file"
+	case CgroupV1:
+		usagePath = filepath.Join(r.root, "memory", "memory.usage_in_bytes")
+		statPath = filepath.Join(r.root, "memory", "memory.stat")
+		inactiveKey = "total_inactive_file"
+	default:
+		return 0, errors.New("no cgroup detected")
+	}
+	usage, err := readCgroupUint(usagePath)
+	if err != nil {
+		return 0, err
+	}
+	inactive, err := readCgroupStatValue(statPath, inactiveKey)
+	if err != nil || inactive > usage {
+		return usage, nil
+	}
+	return usage - inactive, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package system_metric
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCgroupFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestCgroupReader_V2(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"cgroup.controllers": "cpu io memory pids\n",
+		"cpu.max":            "200000 100000\n",
+		"cpu.stat":           "usage_usec 1000000\nuser_usec 800000\nsystem_usec 200000\n",
+		"memory.max":         "1073741824\n",
+		"memory.current":     "536870912\n",
+		"memory.stat":        "anon 268435456\nfile 268435456\ninactive_file 134217728\n",
+	})
+
+	r := newCgroupReader(root)
+	assert.Equal(t, CgroupV2, r.version)
+
+	cores, ok := r.cpuLimitCores()
+	assert.True(t, ok)
+	assert.True(t, util.Float64Equals(2.0, cores))
+
+	limit, ok := r.memoryLimitBytes()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1073741824), limit)
+
+	usage, err := r.memoryUsageBytes()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(536870912-134217728), usage)
+
+	now := time.Now()
+	ratio, err := r.cpuUsageRatio(now)
+	assert.Nil(t, err)
+	assert.True(t, util.Float64Equals(0, ratio))
+
+	// 1 core used in 1 second with a limit of 2 cores.
+	writeCgroupFiles(t, root, map[string]string{
+		"cpu.stat": "usage_usec 2000000\n",
+	})
+	ratio, err = r.cpuUsageRatio(now.Add(time.Second))
+	assert.Nil(t, err)
+	assert.True(t, util.Float64Equals(0.5, ratio))
+
+	// 3 cores used in 1 second exceeds the limit of 2 cores due to sampling, which is capped.
+	writeCgroupFiles(t, root, map[string]string{
+		"cpu.stat": "usage_usec 5000000\n",
+	})
+	ratio, err = r.cpuUsageRatio(now.Add(2 * time.Second))
+	assert.Nil(t, err)
+	assert.True(t, util.Float64Equals(1.0, ratio))
+}
+
+func TestCgroupReader_V2_Unlimited(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"cgroup.controllers": "cpu memory\n",
+		"cpu.max":            "max 100000\n",
+		"memory.max":         "max\n",
+	})
+
+	r := newCgroupReader(root)
+	assert.Equal(t, CgroupV2, r.version)
+	_, ok := r.cpuLimitCores()
+	assert.False(t, ok)
+	_, ok = r.memoryLimitBytes()
+	assert.False(t, ok)
+	_, err := r.cpuUsageRatio(time.Now())
+	assert.NotNil(t, err)
+}
+
+func TestCgroupReader_V1(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"cpu,cpuacct/cpu.cfs_quota_us":  "50000\n",
+		"cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+		"cpu,cpuacct/cpuacct.usage":     "1000000000\n",
+		"memory/memory.limit_in_bytes":  "2147483648\n",
+		"memory/memory.usage_in_bytes":  "1073741824\n",
+		"memory/memory.stat":            "cache 536870912\ntotal_inactive_file 268435456\n",
+	})
+
+	r := newCgroupReader(root)
+	assert.Equal(t, CgroupV1, r.version)
+
+	cores, ok := r.cpuLimitCores()
+	assert.True(t, ok)
+	assert.True(t, util.Float64Equals(0.5, cores))
+
+	limit, ok := r.memoryLimitBytes()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2147483648), limit)
+
+	usage, err := r.memoryUsageBytes()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1073741824-268435456), usage)
+
+	now := time.Now()
+	_, err = r.cpuUsageRatio(now)
+	assert.Nil(t, err)
+	// 0.25 core used in 1 second with a limit of 0.5 core.
+	writeCgroupFiles(t, root, map[string]string{
+		"cpu,cpuacct/cpuacct.usage": "1250000000\n",
+	})
+	ratio, err := r.cpuUsageRatio(now.Add(time.Second))
+	assert.Nil(t, err)
+	assert.True(t, util.Float64Equals(0.5, ratio))
+}
+
+func TestCgroupReader_V1_Unlimited(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"cpu/cpu.cfs_quota_us":         "-1\n",
+		"cpu/cpu.cfs_period_us":        "100000\n",
+		"memory/memory.limit_in_bytes": "9223372036854771712\n",
+	})
+
+	r := newCgroupReader(root)
+	assert.Equal(t, CgroupV1, r.version)
+	_, ok := r.cpuLimitCores()
+	assert.False(t, ok)
+	_, ok = r.memoryLimitBytes()
+	assert.False(t, ok)
+}
+
+func TestCgroupReader_None(t *testing.T) {
+	r := newCgroupReader(t.TempDir())
+	assert.Equal(t, CgroupNone, r.version)
+	_, ok := r.cpuLimitCores()
+	assert.False(t, ok)
+	_, ok = r.memoryLimitBytes()
+	assert.False(t, ok)
+	_, err := r.memoryUsageBytes()
+	assert.NotNil(t, err)
+	_, err = r.cpuUsageNanos()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,303 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package system_metric
+
+import (
+	"math"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	metric_exporter "github.com/alibaba/sentinel-golang/exporter/metric"
+	"github.com/alibaba/sentinel-golang/logging"
+	"github.com/alibaba/sentinel-golang/util"
+)
+
+const (
+	NotRetrievedRuntimeValue float64 = -1.0
+
+	runtimeGoroutinesMetric = "/sched/goroutines:goroutines"
+	runtimeSchedLatencies   = "/sched/latencies:seconds"
+	runtimeGcCpuMetric      = "/cpu/classes/gc/total:cpu-seconds"
+	runtimeTotalCpuMetric   = "/cpu/classes/total:cpu-seconds"
+
+	runtimeQuantile = 0.99
+)
+
+var (
+	// The names of the runtime metrics differ among Go versions, the first supported one is used.
+	runtimeGcPausesMetrics   = []string{"/sched/pauses/total/gc:seconds", "/gc/pauses:seconds"}
+	runtimeHeapLiveMetrics   = []string{"/gc/heap/live:bytes", "/memory/classes/heap/objects:bytes"}
+	runtimeStatCollectorOnce sync.Once
+
+	currentGoroutines    atomic.Value
+	currentGcPauseP99    atomic.Value
+	currentSchedLatency  atomic.Value
+	currentHeapLiveBytes atomic.Value
+	currentGcCpuFraction atomic.Value
+
+	runtimeGauge = metric_exporter.NewGauge(
+		"go_runtime_metric",
+		"Go runtime metrics collected for system adaptive protection",
+		[]string{"metric"})
+)
+
+func init() {
+	currentGoroutines.Store(NotRetrievedRuntimeValue)
+	currentGcPauseP99.Store(NotRetrievedRuntimeValue)
+	currentSchedLatency.Store(NotRetrievedRuntimeValue)
+	currentHeapLiveBytes.Store(NotRetrievedRuntimeValue)
+	currentGcCpuFraction.Store(NotRetrievedRuntimeValue)
+
+	metric_exporter.Register(runtimeGauge)
+}
+
+// runtimeSampler reads the Go runtime metrics. The histograms and CPU classes of runtime/metrics
+// are cumulative since the process started, so the sampler keeps the last sample to calculate
+// the values within the latest collecting interval.
+type runtimeSampler struct {
+	samples []metrics.Sample
+	index   map[string]int
+
+	gcPausesName string
+	heapLiveName string
+
+	lastGcPauses     *metrics.Float64Histogram
+	lastSchedLatency *metrics.Float64Histogram
+	lastGcCpu        float64
+	lastTotalCpu     float64
+}
+
+func newRuntimeSampler() *runtimeSampler {
+	supported := make(map[string]bool)
+	for _, d := range metrics.All() {
+		supported[d.Name] = true
+	}
+	s := &runtimeSampler{
+		index: make(map[string]int),
+	}
+	add := func(name string) {
+		if _, ok := s.index[name]; ok || !supported[name] {
+			return
+		}
+		s.index[name] = len(s.samples)
+		s.samples = append(s.samples, metrics.Sample{Name: name})
+	}
+	for _, name := range runtimeGcPausesMetrics {
+		if supported[name] {
+			s.gcPausesName = name
+			add(name)
+			break
+		}
+	}
+	for _, name := range runtimeHeapLiveMetrics {
+		if supported[name] {
+			s.heapLiveName = name
+			add(name)
+			break
+		}
+	}
+	add(runtimeGoroutinesMetric)
+	add(runtimeSchedLatencies)
+	if supported[runtimeGcCpuMetric] && supported[runtimeTotalCpuMetric] {
+		add(runtimeGcCpuMetric)
+		add(runtimeTotalCpuMetric)
+	}
+	return s
+}
+
+func (s *runtimeSampler) value(name string) (metrics.Value, bool) {
+	i, ok := s.index[name]
+	if !ok {
+		return metrics.Value{}, false
+	}
+	v := s.samples[i].Value
+	if v.Kind() == metrics.KindBad {
+		return metrics.Value{}, false
+	}
+	return v, true
+}
+
+func (s *runtimeSampler) sample() {
+	if len(s.samples) == 0 {
+		return
+	}
+	metrics.Read(s.samples)
+
+	if v, ok := s.value(runtimeGoroutinesMetric); ok && v.Kind() == metrics.KindUint64 {
+		storeRuntimeValue(&currentGoroutines, "goroutines", float64(v.Uint64()))
+	}
+	if v, ok := s.value(s.heapLiveName); ok && v.Kind() == metrics.KindUint64 {
+		storeRuntimeValue(&currentHeapLiveBytes, "heap_live_bytes", float64(v.Uint64()))
+	}
+	if v, ok := s.value(s.gcPausesName); ok && v.Kind() == metrics.KindFloat64Histogram {
+		cur := copyHistogram(v.Float64Histogram())
+		storeRuntimeValue(&currentGcPauseP99, "gc_pause_p99_ms", histogramQuantile(cur, s.lastGcPauses, runtimeQuantile)*1000)
+		s.lastGcPauses = cur
+	}
+	if v, ok := s.value(runtimeSchedLatencies); ok && v.Kind() == metrics.KindFloat64Histogram {
+		cur := copyHistogram(v.Float64Histogram())
+		storeRuntimeValue(&currentSchedLatency, "sched_latency_p99_ms", histogramQuantile(cur, s.lastSchedLatency, runtimeQuantile)*1000)
+		s.lastSchedLatency = cur
+	}
+	gcCpu, ok1 := s.value(runtimeGcCpuMetric)
+	totalCpu, ok2 := s.value(runtimeTotalCpuMetric)
+	if ok1 && ok2 && gcCpu.Kind() == metrics.KindFloat64 && totalCpu.Kind() == metrics.KindFloat64 {
+		gc, total := gcCpu.Float64(), totalCpu.Float64()
+		if total > s.lastTotalCpu && gc >= s.lastGcCpu {
+			storeRuntimeValue(&currentGcCpuFraction, "gc_cpu_fraction", (gc-s.lastGcCpu)/(total-s.lastTotalCpu))
+		}
+		s.lastGcCpu, s.lastTotalCpu = gc, total
+	}
+}
+
+func storeRuntimeValue(v *atomic.Value, metric string, value float64) {
+	v.Store(value)
+	runtimeGauge.Set(value, metric)
+}
+
+// copyHistogram deep copies the histogram since the runtime may reuse the memory of sample values.
+func copyHistogram(h *metrics.Float64Histogram) *metrics.Float64Histogram {
+	return &metrics.Float64Histogram{
+		Counts:  append([]uint64(nil), h.Counts...),
+		Buckets: append([]float64(nil), h.Buckets...),
+	}
+}
+
+// histogramQuantile returns the q-quantile of the observations recorded in cur but not in prev.
+// The whole cur is used if prev is nil or has different buckets. The upper bound of the bucket
+// which the quantile falls into is returned, or the lower bound if the upper bound is infinite.
+func histogramQuantile(cur, prev *metrics.Float64Histogram, q float64) float64 {
+	if cur == nil || len(cur.Counts) == 0 || len(cur.Buckets) != len(cur.Counts)+1 {
+		return 0
+	}
+	usePrev := prev != nil && len(prev.Counts) == len(cur.Counts)
+	delta := func(i int) uint64 {
+		if usePrev && cur.Counts[i] >= prev.Counts[i] {
+			return cur.Counts[i] - prev.Counts[i]
+		}
+		return cur.Counts[i]
+	}
+	total := uint64(0)
+	for i := range cur.Counts {
+		total += delta(i)
+	}
+	if total == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(float64(total) * q))
+	acc := uint64(0)
+	for i := range cur.Counts {
+		acc += delta(i)
+		if acc >= target {
+			if upper := cur.Buckets[i+1]; !math.IsInf(upper, 1) {
+				return upper
+			}
+			if lower := cur.Buckets[i]; !math.IsInf(lower, -1) {
+				return lower
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+func InitRuntimeCollector(intervalMs uint32) {
+	if intervalMs == 0 {
+		return
+	}
+	runtimeStatCollectorOnce.Do(func() {
+		s := newRuntimeSampler()
+		if len(s.samples) == 0 {
+			logging.Warn("[InitRuntimeCollector] No supported runtime metrics, the collector won't start")
+			return
+		}
+		// Initial retrieval.
+		s.sample()
+
+		ticker := util.NewTicker(time.Duration(intervalMs) * time.Millisecond)
+		go util.RunWithRecover(func() {
+			for {
+				select {
+				case <-ticker.C():
+					s.sample()
+				case <-ssStopChan:
+					ticker.Stop()
+					return
+				}
+			}
+		})
+	})
+}
+
+func loadRuntimeValue(v *atomic.Value) float64 {
+	r, ok := v.Load().(float64)
+	if !ok {
+		return NotRetrievedRuntimeValue
+	}
+	return r
+}
+
+// CurrentGoroutines returns the count of goroutines.
+func CurrentGoroutines() float64 {
+	return loadRuntimeValue(&currentGoroutines)
+}
+
+// CurrentGcPauseP99 returns the p99 of GC stop-the-world pause latency in milliseconds within the latest collecting interval.
+func CurrentGcPauseP99() float64 {
+	return loadRuntimeValue(&currentGcPauseP99)
+}
+
+// CurrentSchedLatencyP99 returns the p99 of the time goroutines spent in runnable state before running
+// in milliseconds within the latest collecting interval.
+func CurrentSchedLatencyP99() float64 {
+	return loadRuntimeValue(&currentSchedLatency)
+}
+
+// CurrentHeapLiveBytes returns the heap memory occupied by live objects marked by the latest GC.
+func CurrentHeapLiveBytes() float64 {
+	return loadRuntimeValue(&currentHeapLiveBytes)
+}
+
+// CurrentGcCpuFraction returns the fraction of CPU time used by GC within the latest collecting interval, in [0.0, 1.0].
+func CurrentGcCpuFraction() float64 {
+	return loadRuntimeValue(&currentGcCpuFraction)
+}
+
+// Note: SetGoroutines is used for unit test, the user shouldn't call this function.
+func SetGoroutines(v float64) {
+	currentGoroutines.Store(v)
+}
+
+// Note: SetGcPauseP99 is used for unit test, the user shouldn't call this function.
+func SetGcPauseP99(v float64) {
+	currentGcPauseP99.Store(v)
+}
+
+// Note: SetSchedLatencyP99 is used for unit test, the user shouldn't call this function.
+func SetSchedLatencyP99(v float64) {
+	currentSchedLatency.Store(v)
+}
+
+// Note: SetHeapLiveBytes is used for unit test, the user shouldn't call this function.
+func SetHeapLiveBytes(v float64) {
+	currentHeapLiveBytes.Store(v)
+}
+
+// Note: SetGcCpuFraction is used for unit test, the user shouldn't call this function.
+func SetGcCpuFraction(v float64) {
+	currentGcCpuFraction.Store(v)
+}
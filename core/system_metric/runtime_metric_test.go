@@ -0,0 +1,73 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package system_metric
+
+import (
+	"math"
+	"runtime/metrics"
+	"testing"
+
+	"github.com/alibaba/sentinel-golang/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogramQuantile(t *testing.T) {
+	buckets := []float64{math.Inf(-1), 0.001, 0.01, 0.1, math.Inf(1)}
+
+	t.Run("WithoutPrev", func(t *testing.T) {
+		cur := &metrics.Float64Histogram{Counts: []uint64{0, 98, 1, 1}, Buckets: buckets}
+		assert.True(t, util.Float64Equals(0.1, histogramQuantile(cur, nil, 0.99)))
+		assert.True(t, util.Float64Equals(0.01, histogramQuantile(cur, nil, 0.5)))
+	})
+
+	t.Run("Delta", func(t *testing.T) {
+		prev := &metrics.Float64Histogram{Counts: []uint64{0, 98, 1, 0}, Buckets: buckets}
+		cur := &metrics.Float64Histogram{Counts: []uint64{0, 98, 2, 9}, Buckets: buckets}
+		// Only the latest 10 observations are considered, 9 of which are in the last bucket.
+		assert.True(t, util.Float64Equals(0.1, histogramQuantile(cur, prev, 0.99)))
+	})
+
+	t.Run("NoObservation", func(t *testing.T) {
+		cur := &metrics.Float64Histogram{Counts: []uint64{0, 1, 0, 0}, Buckets: buckets}
+		assert.True(t, util.Float64Equals(0, histogramQuantile(cur, cur, 0.99)))
+	})
+
+	t.Run("InvalidHistogram", func(t *testing.T) {
+		assert.True(t, util.Float64Equals(0, histogramQuantile(nil, nil, 0.99)))
+		cur := &metrics.Float64Histogram{Counts: []uint64{1}, Buckets: buckets}
+		assert.True(t, util.Float64Equals(0, histogramQuantile(cur, nil, 0.99)))
+	})
+}
+
+func TestRuntimeSampler(t *testing.T) {
+	defer func() {
+		SetGoroutines(NotRetrievedRuntimeValue)
+		SetHeapLiveBytes(NotRetrievedRuntimeValue)
+		SetGcPauseP99(NotRetrievedRuntimeValue)
+		SetSchedLatencyP99(NotRetrievedRuntimeValue)
+		SetGcCpuFraction(NotRetrievedRuntimeValue)
+	}()
+
+	s := newRuntimeSampler()
+	assert.NotEmpty(t, s.samples)
+	s.sample()
+	s.sample()
+
+	assert.True(t, CurrentGoroutines() >= 1)
+	assert.True(t, CurrentHeapLiveBytes() >= 0)
+	assert.True(t, CurrentGcPauseP99() >= 0)
+	assert.True(t, CurrentSchedLatencyP99() >= 0)
+	assert.True(t, CurrentGcCpuFraction() <= 1)
+}
@@ -15,7 +15,9 @@
 package system_metric
 
 import (
+	"math"
 	"os"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -46,6 +48,7 @@ var (
 	CurrentPID         = os.Getpid()
 	currentProcess     atomic.Value
 	currentProcessOnce sync.Once
+	currentCgroup      = newCgroupReader(defaultCgroupRoot)
 	TotalMemorySize    = getTotalMemorySize()
 
 	ssStopChan = make(chan struct{})
@@ -78,14 +81,19 @@ func init() {
 	metric_exporter.Register(processMemoryGauge)
 }
 
-// getTotalMemorySize returns the current machine's memory statistic
+// getTotalMemorySize returns the current machine's memory statistic,
+// or the memory limit of container if it's less than the machine's memory.
 func getTotalMemorySize() (total uint64) {
 	stat, err := mem.VirtualMemory()
 	if err != nil {
 		logging.Error(err, "Fail to read Virtual Memory")
-		return 0
+	} else {
+		total = stat.Total
 	}
-	return stat.Total
+	if limit, ok := currentCgroup.memoryLimitBytes(); ok && (total == 0 || limit < total) {
+		total = limit
+	}
+	return total
 }
 
 func InitMemoryCollector(intervalMs uint32) {
@@ -112,7 +120,7 @@ func InitMemoryCollector(intervalMs uint32) {
 }
 
 func retrieveAndUpdateMemoryStat() {
-	memoryUsedBytes, err := GetProcessMemoryStat()
+	memoryUsedBytes, err := getMemoryStat()
 	if err != nil {
 		logging.Error(err, "Fail to retrieve and update memory statistic")
 		return
@@ -123,6 +131,19 @@ func retrieveAndUpdateMemoryStat() {
 	currentMemoryUsage.Store(memoryUsedBytes)
 }
 
+// getMemoryStat gets the working set of container in Bytes if the memory of container is limited by cgroup,
+// otherwise gets current process's memory usage.
+func getMemoryStat() (int64, error) {
+	if _, ok := currentCgroup.memoryLimitBytes(); ok {
+		usage, err := currentCgroup.memoryUsageBytes()
+		if err == nil {
+			return int64(usage), nil
+		}
+		logging.Warn("[getMemoryStat] Fail to read the memory usage of cgroup, fallback to process memory", "err", err.Error())
+	}
+	return GetProcessMemoryStat()
+}
+
 // GetProcessMemoryStat gets current process's memory usage in Bytes
 func GetProcessMemoryStat() (int64, error) {
 	curProcess := currentProcess.Load()
@@ -170,15 +191,46 @@ func InitCpuCollector(intervalMs uint32) {
 }
 
 func retrieveAndUpdateCpuStat() {
-	cpuPercent, err := getProcessCpuStat()
+	cpuRatio, err := getCpuStat()
 	if err != nil {
 		logging.Error(err, "Fail to retrieve and update cpu statistic")
 		return
 	}
 
-	cpuRatioGauge.Set(cpuPercent)
+	cpuRatioGauge.Set(cpuRatio)
+
+	currentCpuUsage.Store(cpuRatio)
+}
+
+// getCpuStat gets the cpu usage ratio in [0.0, 1.0]. The ratio is relative to the cpu limit of container
+// if the cpu of container is limited by cgroup, otherwise it's current process's cpu usage relative to all the cpus.
+func getCpuStat() (float64, error) {
+	if _, ok := currentCgroup.cpuLimitCores(); ok {
+		ratio, err := currentCgroup.cpuUsageRatio(time.Now())
+		if err == nil {
+			return ratio, nil
+		}
+		logging.Warn("[getCpuStat] Fail to read the cpu usage of cgroup, fallback to process cpu usage", "err", err.Error())
+	}
+	percent, err := getProcessCpuStat()
+	if err != nil {
+		return 0, err
+	}
+	return processCpuRatio(percent, runtime.NumCPU()), nil
+}
+
+// processCpuRatio converts the cpu usage percentage of process, where 100 means one cpu fully used,
+// to the ratio relative to all the cpus.
+func processCpuRatio(percent float64, cpus int) float64 {
+	if cpus <= 0 {
+		cpus = 1
+	}
+	return math.Min(percent/100/float64(cpus), 1.0)
+}
 
-	currentCpuUsage.Store(cpuPercent)
+// CurrentCgroupVersion returns the version of cgroup detected for current container.
+func CurrentCgroupVersion() CgroupVersion {
+	return currentCgroup.version
 }
 
 // getProcessCpuStat gets current process's cpu usage in percentage
@@ -245,6 +297,8 @@ func SetSystemLoad(load float64) {
 	currentLoad.Store(load)
 }
 
+// CurrentCpuUsage returns the cpu usage ratio in [0.0, 1.0], which is relative to the cpu limit of container
+// in container with cgroup cpu limit, otherwise relative to all the cpus. NotRetrievedCpuUsageValue is returned if not retrieved.
 func CurrentCpuUsage() float64 {
 	r, ok := currentCpuUsage.Load().(float64)
 	if !ok {
@@ -253,6 +307,7 @@ func CurrentCpuUsage() float64 {
 	return r
 }
 
+// SetSystemCpuUsage sets the cpu usage ratio in [0.0, 1.0].
 // Note: SetSystemCpuUsage is used for unit test, the user shouldn't call this function.
 func SetSystemCpuUsage(cpuUsage float64) {
 	currentCpuUsage.Store(cpuUsage)
@@ -47,6 +47,14 @@ func TestCurrentCpuUsage(t *testing.T) {
 	assert.True(t, util.Float64Equals(v, cpuUsage))
 }
 
+func Test_processCpuRatio(t *testing.T) {
+	// 350% means 3.5 cpus fully used
+	assert.True(t, util.Float64Equals(0.4375, processCpuRatio(350, 8)))
+	assert.True(t, util.Float64Equals(0.6, processCpuRatio(60, 1)))
+	assert.True(t, util.Float64Equals(1.0, processCpuRatio(820, 8)))
+	assert.True(t, util.Float64Equals(0.5, processCpuRatio(50, 0)))
+}
+
 func Test_getProcessCpuStat(t *testing.T) {
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
@@ -83,3 +91,25 @@ func Test_getProcessCpuStat(t *testing.T) {
 	assert.True(t, int(got) > 0)
 	time.Sleep(time.Millisecond * 200)
 }
+
+func Test_retrieveAndUpdateCpuStat(t *testing.T) {
+	defer currentCpuUsage.Store(NotRetrievedCpuUsageValue)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}()
+
+	retrieveAndUpdateCpuStat()
+	time.Sleep(200 * time.Millisecond)
+	retrieveAndUpdateCpuStat()
+	// the real reading of the busy process is stored as the ratio rather than the percentage
+	usage := CurrentCpuUsage()
+	assert.True(t, usage > 0 && usage <= 1.0, "usage: %f", usage)
+}
@@ -37,6 +37,7 @@ func main() {
 	// use mock memory usage to replace actual memory usage, so close memory collector
 	conf.Sentinel.Stat.System.CollectIntervalMs = 0
 	conf.Sentinel.Stat.System.CollectMemoryIntervalMs = 0
+	conf.Sentinel.Stat.System.CollectRuntimeIntervalMs = 0
 
 	err := sentinel.InitWithConfig(conf)
 	if err != nil {
@@ -18,10 +18,13 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/alibaba/sentinel-golang/core/adaptive"
 	cb "github.com/alibaba/sentinel-golang/core/circuitbreaker"
 	"github.com/alibaba/sentinel-golang/core/flow"
 	"github.com/alibaba/sentinel-golang/core/hotspot"
 	"github.com/alibaba/sentinel-golang/core/isolation"
+	"github.com/alibaba/sentinel-golang/core/quota"
+	"github.com/alibaba/sentinel-golang/core/stat"
 	"github.com/alibaba/sentinel-golang/core/system"
 )
 
@@ -193,6 +196,7 @@ func HotSpotParamRuleJsonArrayParser(src []byte) (interface{}, error) {
 			DurationInSec:     hotspotRule.DurationInSec,
 			ParamsMaxCapacity: hotspotRule.ParamsMaxCapacity,
 			SpecificItems:     parseSpecificItems(hotspotRule.SpecificItems),
+			CacheType:         hotspotRule.CacheType,
 		}
 	}
 	return rules, nil
@@ -277,3 +281,144 @@ func IsolationRulesUpdater(data interface{}) error {
 func NewIsolationRulesHandler(converter PropertyConverter) *DefaultPropertyHandler {
 	return NewDefaultPropertyHandler(converter, IsolationRulesUpdater)
 }
+
+// AdaptiveConcurrencyRuleJsonArrayParser provide JSON  as the default serialization for list of adaptive.Rule
+func AdaptiveConcurrencyRuleJsonArrayParser(src []byte) (interface{}, error) {
+	if valid, err := checkSrcComplianceJson(src); !valid {
+		return nil, err
+	}
+
+	rules := make([]*adaptive.Rule, 0, 8)
+	if err := json.Unmarshal(src, &rules); err != nil {
+		desc := fmt.Sprintf("Fail to convert source bytes to []*adaptive.Rule, err: %s", err.Error())
+		return nil, NewError(ConvertSourceError, desc)
+	}
+	return rules, nil
+}
+
+// AdaptiveConcurrencyRulesUpdater load the newest []adaptive.Rule to downstream adaptive concurrency component.
+func AdaptiveConcurrencyRulesUpdater(data interface{}) error {
+	if data == nil {
+		return adaptive.ClearRules()
+	}
+
+	rules := make([]*adaptive.Rule, 0, 8)
+	if val, ok := data.([]adaptive.Rule); ok {
+		for i := range val {
+			rules = append(rules, &val[i])
+		}
+	} else if val, ok := data.([]*adaptive.Rule); ok {
+		rules = val
+	} else {
+		return NewError(
+			UpdatePropertyError,
+			fmt.Sprintf("Fail to type assert data to []adaptive.Rule or []*adaptive.Rule, in fact, data: %+v", data),
+		)
+	}
+	_, err := adaptive.LoadRules(rules)
+	if err == nil {
+		return nil
+	}
+	return NewError(
+		UpdatePropertyError,
+		fmt.Sprintf("%+v", err),
+	)
+}
+
+func NewAdaptiveConcurrencyRulesHandler(converter PropertyConverter) *DefaultPropertyHandler {
+	return NewDefaultPropertyHandler(converter, AdaptiveConcurrencyRulesUpdater)
+}
+
+// QuotaRuleJsonArrayParser provide JSON  as the default serialization for list of quota.Rule
+func QuotaRuleJsonArrayParser(src []byte) (interface{}, error) {
+	if valid, err := checkSrcComplianceJson(src); !valid {
+		return nil, err
+	}
+
+	rules := make([]*quota.Rule, 0, 8)
+	if err := json.Unmarshal(src, &rules); err != nil {
+		desc := fmt.Sprintf("Fail to convert source bytes to []*quota.Rule, err: %s", err.Error())
+		return nil, NewError(ConvertSourceError, desc)
+	}
+	return rules, nil
+}
+
+// QuotaRulesUpdater load the newest []quota.Rule to downstream quota component.
+func QuotaRulesUpdater(data interface{}) error {
+	if data == nil {
+		return quota.ClearRules()
+	}
+
+	rules := make([]*quota.Rule, 0, 8)
+	if val, ok := data.([]quota.Rule); ok {
+		for i := range val {
+			rules = append(rules, &val[i])
+		}
+	} else if val, ok := data.([]*quota.Rule); ok {
+		rules = val
+	} else {
+		return NewError(
+			UpdatePropertyError,
+			fmt.Sprintf("Fail to type assert data to []quota.Rule or []*quota.Rule, in fact, data: %+v", data),
+		)
+	}
+	_, err := quota.LoadRules(rules)
+	if err == nil {
+		return nil
+	}
+	return NewError(
+		UpdatePropertyError,
+		fmt.Sprintf("%+v", err),
+	)
+}
+
+func NewQuotaRulesHandler(converter PropertyConverter) *DefaultPropertyHandler {
+	return NewDefaultPropertyHandler(converter, QuotaRulesUpdater)
+}
+
+// ResourceStatConfigJsonArrayParser provide JSON  as the default serialization for list of stat.ResourceStatConfig
+func ResourceStatConfigJsonArrayParser(src []byte) (interface{}, error) {
+	if valid, err := checkSrcComplianceJson(src); !valid {
+		return nil, err
+	}
+
+	configs := make([]*stat.ResourceStatConfig, 0, 8)
+	if err := json.Unmarshal(src, &configs); err != nil {
+		desc := fmt.Sprintf("Fail to convert source bytes to []*stat.ResourceStatConfig, err: %s", err.Error())
+		return nil, NewError(ConvertSourceError, desc)
+	}
+	return configs, nil
+}
+
+// ResourceStatConfigsUpdater load the newest []stat.ResourceStatConfig to downstream stat component.
+func ResourceStatConfigsUpdater(data interface{}) error {
+	if data == nil {
+		return stat.ClearResourceStatConfigs()
+	}
+
+	configs := make([]*stat.ResourceStatConfig, 0, 8)
+	if val, ok := data.([]stat.ResourceStatConfig); ok {
+		for i := range val {
+			configs = append(configs, &val[i])
+		}
+	} else if val, ok := data.([]*stat.ResourceStatConfig); ok {
+		configs = val
+	} else {
+		return NewError(
+			UpdatePropertyError,
+			fmt.Sprintf("Fail to type assert data to []stat.ResourceStatConfig or []*stat.ResourceStatConfig, in fact, data: %+v", data),
+		)
+	}
+	_, err := stat.LoadResourceStatConfigs(configs)
+	if err == nil {
+		return nil
+	}
+	return NewError(
+		UpdatePropertyError,
+		fmt.Sprintf("%+v", err),
+	)
+}
+
+func NewResourceStatConfigsHandler(converter PropertyConverter) *DefaultPropertyHandler {
+	return NewDefaultPropertyHandler(converter, ResourceStatConfigsUpdater)
+}
@@ -22,10 +22,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/alibaba/sentinel-golang/core/adaptive"
 	cb "github.com/alibaba/sentinel-golang/core/circuitbreaker"
 	"github.com/alibaba/sentinel-golang/core/flow"
 	"github.com/alibaba/sentinel-golang/core/hotspot"
 	"github.com/alibaba/sentinel-golang/core/isolation"
+	"github.com/alibaba/sentinel-golang/core/quota"
+	"github.com/alibaba/sentinel-golang/core/stat"
 	"github.com/alibaba/sentinel-golang/core/system"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -602,3 +605,189 @@ func TestIsolationRuleListJsonUpdater(t *testing.T) {
 		assert.True(t, strings.Contains(err.(Error).desc, "Fail to type assert"))
 	})
 }
+
+func TestAdaptiveConcurrencyRuleJsonArrayParser(t *testing.T) {
+	t.Run("TestAdaptiveConcurrencyRuleJsonArrayParser_Invalid", func(t *testing.T) {
+		_, err := AdaptiveConcurrencyRuleJsonArrayParser([]byte{'s', 'r', 'c'})
+		assert.True(t, err != nil)
+	})
+
+	t.Run("TestAdaptiveConcurrencyRuleJsonArrayParser_Normal", func(t *testing.T) {
+		src, err := ioutil.ReadFile("../../tests/testdata/extension/helper/AdaptiveConcurrencyRule.json")
+		if err != nil {
+			t.Fatalf("Fail to read file, err: %+v.", err)
+		}
+
+		properties, err := AdaptiveConcurrencyRuleJsonArrayParser(src)
+		assert.True(t, err == nil)
+		rules := properties.([]*adaptive.Rule)
+		assert.True(t, len(rules) == 3)
+		assert.True(t, reflect.DeepEqual(rules[0], &adaptive.Rule{
+			Resource:     "abc",
+			Algorithm:    adaptive.Vegas,
+			InitialLimit: 20,
+			MaxLimit:     200,
+		}))
+		assert.True(t, reflect.DeepEqual(rules[1], &adaptive.Rule{
+			Resource:     "def",
+			Algorithm:    adaptive.Gradient2,
+			Smoothing:    0.2,
+			RttTolerance: 2,
+			LongWindow:   100,
+		}))
+		assert.True(t, reflect.DeepEqual(rules[2], &adaptive.Rule{
+			Resource:     "ghi",
+			Algorithm:    adaptive.AIMD,
+			BackoffRatio: 0.8,
+			TimeoutMs:    500,
+		}))
+	})
+
+	t.Run("TestAdaptiveConcurrencyRuleJsonArrayParser_Nil", func(t *testing.T) {
+		got, err := AdaptiveConcurrencyRuleJsonArrayParser(nil)
+		assert.True(t, got == nil && err == nil)
+	})
+}
+
+func TestAdaptiveConcurrencyRulesUpdater(t *testing.T) {
+	t.Run("TestAdaptiveConcurrencyRulesUpdater", func(t *testing.T) {
+		defer adaptive.ClearRules()
+
+		r1 := adaptive.Rule{Resource: "abc", Algorithm: adaptive.AIMD, InitialLimit: 10}
+		r2 := adaptive.Rule{Resource: "abc", Algorithm: adaptive.Vegas, InitialLimit: 20}
+		err := AdaptiveConcurrencyRulesUpdater([]adaptive.Rule{r1, r2})
+		assert.True(t, err == nil)
+
+		rules := adaptive.GetRulesOfResource("abc")
+		assert.True(t, reflect.DeepEqual(rules, []adaptive.Rule{r1, r2}))
+
+		assert.True(t, AdaptiveConcurrencyRulesUpdater(nil) == nil)
+		assert.True(t, len(adaptive.GetRules()) == 0)
+	})
+
+	t.Run("TestAdaptiveConcurrencyRulesUpdater_Type_Err", func(t *testing.T) {
+		err := AdaptiveConcurrencyRulesUpdater([]*flow.Rule{{Resource: "abc"}})
+		assert.True(t, err.(Error).Code() == UpdatePropertyError)
+		assert.True(t, strings.Contains(err.(Error).desc, "Fail to type assert"))
+	})
+}
+
+func TestQuotaRuleJsonArrayParser(t *testing.T) {
+	t.Run("TestQuotaRuleJsonArrayParser_Invalid", func(t *testing.T) {
+		_, err := QuotaRuleJsonArrayParser([]byte{'s', 'r', 'c'})
+		assert.True(t, err != nil)
+	})
+
+	t.Run("TestQuotaRuleJsonArrayParser_Normal", func(t *testing.T) {
+		src, err := ioutil.ReadFile("../../tests/testdata/extension/helper/QuotaRule.json")
+		if err != nil {
+			t.Fatalf("Fail to read file, err: %+v.", err)
+		}
+
+		properties, err := QuotaRuleJsonArrayParser(src)
+		assert.True(t, err == nil)
+		rules := properties.([]*quota.Rule)
+		assert.True(t, len(rules) == 2)
+		assert.True(t, reflect.DeepEqual(rules[0], &quota.Rule{
+			Resource:   "abc",
+			Threshold:  10000,
+			WindowUnit: quota.Day,
+			WindowMode: quota.Calendar,
+			TimeZone:   "Asia/Shanghai",
+			PerParam:   true,
+			ParamKey:   "tenant",
+		}))
+		assert.True(t, reflect.DeepEqual(rules[1], &quota.Rule{
+			ID:         "def-hourly",
+			Resource:   "def",
+			Threshold:  100,
+			WindowUnit: quota.Hour,
+			WindowMode: quota.Rolling,
+		}))
+	})
+
+	t.Run("TestQuotaRuleJsonArrayParser_Nil", func(t *testing.T) {
+		got, err := QuotaRuleJsonArrayParser(nil)
+		assert.True(t, got == nil && err == nil)
+	})
+}
+
+func TestQuotaRulesUpdater(t *testing.T) {
+	t.Run("TestQuotaRulesUpdater", func(t *testing.T) {
+		defer quota.ClearRules()
+
+		r1 := quota.Rule{Resource: "abc", Threshold: 100, WindowUnit: quota.Minute}
+		r2 := quota.Rule{Resource: "abc", Threshold: 1000, WindowUnit: quota.Hour}
+		err := QuotaRulesUpdater([]quota.Rule{r1, r2})
+		assert.True(t, err == nil)
+
+		rules := quota.GetRulesOfResource("abc")
+		assert.True(t, reflect.DeepEqual(rules, []quota.Rule{r1, r2}))
+
+		assert.True(t, QuotaRulesUpdater(nil) == nil)
+		assert.True(t, len(quota.GetRules()) == 0)
+	})
+
+	t.Run("TestQuotaRulesUpdater_Type_Err", func(t *testing.T) {
+		err := QuotaRulesUpdater([]*flow.Rule{{Resource: "abc"}})
+		assert.True(t, err.(Error).Code() == UpdatePropertyError)
+		assert.True(t, strings.Contains(err.(Error).desc, "Fail to type assert"))
+	})
+}
+
+func TestResourceStatConfigJsonArrayParser(t *testing.T) {
+	t.Run("TestResourceStatConfigJsonArrayParser_Invalid", func(t *testing.T) {
+		_, err := ResourceStatConfigJsonArrayParser([]byte{'s', 'r', 'c'})
+		assert.True(t, err != nil)
+	})
+
+	t.Run("TestResourceStatConfigJsonArrayParser_Normal", func(t *testing.T) {
+		src, err := ioutil.ReadFile("../../tests/testdata/extension/helper/ResourceStatConfig.json")
+		if err != nil {
+			t.Fatalf("Fail to read file, err: %+v.", err)
+		}
+
+		properties, err := ResourceStatConfigJsonArrayParser(src)
+		assert.True(t, err == nil)
+		configs := properties.([]*stat.ResourceStatConfig)
+		assert.True(t, len(configs) == 2)
+		assert.True(t, reflect.DeepEqual(configs[0], &stat.ResourceStatConfig{
+			Resource:         "abc",
+			SampleCountTotal: 60,
+			IntervalMsTotal:  60000,
+			SampleCount:      10,
+			IntervalMs:       10000,
+		}))
+		assert.True(t, reflect.DeepEqual(configs[1], &stat.ResourceStatConfig{
+			Resource:         "def",
+			SampleCountTotal: 40,
+			IntervalMsTotal:  20000,
+		}))
+	})
+
+	t.Run("TestResourceStatConfigJsonArrayParser_Nil", func(t *testing.T) {
+		got, err := ResourceStatConfigJsonArrayParser(nil)
+		assert.True(t, got == nil && err == nil)
+	})
+}
+
+func TestResourceStatConfigsUpdater(t *testing.T) {
+	t.Run("TestResourceStatConfigsUpdater", func(t *testing.T) {
+		defer stat.ClearResourceStatConfigs()
+
+		c1 := stat.ResourceStatConfig{Resource: "abc", SampleCountTotal: 60, IntervalMsTotal: 60000}
+		c2 := stat.ResourceStatConfig{Resource: "def", SampleCountTotal: 40, IntervalMsTotal: 20000}
+		err := ResourceStatConfigsUpdater([]stat.ResourceStatConfig{c1, c2})
+		assert.True(t, err == nil)
+		assert.True(t, len(stat.GetResourceStatConfigs()) == 2)
+
+		assert.True(t, ResourceStatConfigsUpdater(nil) == nil)
+		assert.True(t, len(stat.GetResourceStatConfigs()) == 0)
+	})
+
+	t.Run("TestResourceStatConfigsUpdater_Type_Err", func(t *testing.T) {
+		err := ResourceStatConfigsUpdater([]*flow.Rule{{Resource: "abc"}})
+		assert.True(t, err.(Error).Code() == UpdatePropertyError)
+		assert.True(t, strings.Contains(err.(Error).desc, "Fail to type assert"))
+	})
+}
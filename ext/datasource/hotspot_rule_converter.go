@@ -54,6 +54,8 @@ type HotspotRule struct {
 	// ParamsMaxCapacity is the max capacity of cache statistic
 	ParamsMaxCapacity int64           `json:"paramsMaxCapacity"`
 	SpecificItems     []SpecificValue `json:"specificItems"`
+	// CacheType is the cache strategy of the statistic of parameters
+	CacheType hotspot.CacheType `json:"cacheType"`
 }
 
 // ParamKind represents the Param kind.
@@ -21,12 +21,18 @@ func SentinelMiddleware(opts ...Option) echo.MiddlewareFunc {
 			if options.resourceExtract != nil {
 				resourceName = options.resourceExtract(c)
 			}
-			entry, blockErr := sentinel.Entry(
-				resourceName,
+			entryOpts := []sentinel.EntryOption{
 				sentinel.WithResourceType(base.ResTypeWeb),
 				sentinel.WithTrafficType(base.Inbound),
-			)
+			}
+			if options.rateLimitHeaders {
+				entryOpts = append(entryOpts, sentinel.WithRateLimitInfo())
+			}
+			entry, blockErr := sentinel.Entry(resourceName, entryOpts...)
 			if blockErr != nil {
+				if options.rateLimitHeaders {
+					base.WriteBlockHeaders(blockErr, c.Response().Header().Set)
+				}
 				if options.blockFallback != nil {
 					err = options.blockFallback(c)
 				} else {
@@ -36,6 +42,9 @@ func SentinelMiddleware(opts ...Option) echo.MiddlewareFunc {
 				return err
 			}
 			defer entry.Exit()
+			if options.rateLimitHeaders {
+				base.WriteRateLimitHeaders(entry.RateLimitInfo(), c.Response().Header().Set)
+			}
 
 			err = next(c)
 			return err
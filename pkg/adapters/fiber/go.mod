@@ -2,6 +2,8 @@ module github.com/alibaba/sentinel-golang/pkg/adapters/fiber
 
 go 1.13
 
+replace github.com/alibaba/sentinel-golang => ../../../
+
 require (
 	github.com/StackExchange/wmi v1.2.1 // indirect
 	github.com/alibaba/sentinel-golang v1.0.2
@@ -21,13 +21,19 @@ func SentinelMiddleware(opts ...Option) fiber.Handler {
 			resourceName = options.resourceExtract(ctx)
 		}
 
-		entry, entryErr := sentinel.Entry(
-			resourceName,
+		entryOpts := []sentinel.EntryOption{
 			sentinel.WithResourceType(base.ResTypeWeb),
 			sentinel.WithTrafficType(base.Inbound),
-		)
+		}
+		if options.rateLimitHeaders {
+			entryOpts = append(entryOpts, sentinel.WithRateLimitInfo())
+		}
+		entry, entryErr := sentinel.Entry(resourceName, entryOpts...)
 
 		if entryErr != nil {
+			if options.rateLimitHeaders {
+				base.WriteBlockHeaders(entryErr, ctx.Set)
+			}
 			if options.blockFallback != nil {
 				return options.blockFallback(ctx)
 			} else {
@@ -36,6 +42,9 @@ func SentinelMiddleware(opts ...Option) fiber.Handler {
 		}
 
 		defer entry.Exit()
+		if options.rateLimitHeaders {
+			base.WriteRateLimitHeaders(entry.RateLimitInfo(), ctx.Set)
+		}
 		return ctx.Next()
 	}
 }
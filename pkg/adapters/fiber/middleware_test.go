@@ -132,3 +132,47 @@ func TestSentinelMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestSentinelMiddleware_RateLimitHeaders(t *testing.T) {
+	initSentinel(t)
+	_, err := flow.LoadRules([]*flow.Rule{
+		{
+			Resource:               "GET:/headers",
+			Threshold:              2.0,
+			TokenCalculateStrategy: flow.Direct,
+			ControlBehavior:        flow.Reject,
+			StatIntervalInMs:       1000,
+		},
+	})
+	assert.NoError(t, err)
+	defer flow.ClearRules()
+
+	app := fiber.New()
+	app.Use(SentinelMiddleware(WithRateLimitHeaders()))
+	app.Get("/headers", func(ctx *fiber.Ctx) error {
+		return ctx.SendString("ok")
+	})
+	serve := func() *http.Response {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/headers", nil))
+		assert.NoError(t, err)
+		return resp
+	}
+
+	resp := serve()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=1", resp.Header.Get("RateLimit-Policy"))
+	assert.NotEmpty(t, resp.Header.Get("RateLimit-Reset"))
+	assert.Empty(t, resp.Header.Get("Retry-After"))
+
+	resp = serve()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+
+	resp = serve()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+}
@@ -7,6 +7,8 @@ type (
 	options struct {
 		resourceExtract func(*fiber.Ctx) string
 		blockFallback   func(*fiber.Ctx) error
+		// rateLimitHeaders indicates whether to write the RateLimit headers on both pass and block.
+		rateLimitHeaders bool
 	}
 )
 
@@ -32,3 +34,12 @@ func WithBlockFallback(fn func(ctx *fiber.Ctx) error) Option {
 		opts.blockFallback = fn
 	}
 }
+
+// WithRateLimitHeaders enables writing the IETF RateLimit headers
+// (RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy)
+// on both passed and blocked requests, and Retry-After on blocked requests.
+func WithRateLimitHeaders() Option {
+	return func(opts *options) {
+		opts.rateLimitHeaders = true
+	}
+}
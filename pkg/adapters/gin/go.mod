@@ -2,6 +2,8 @@ module github.com/alibaba/sentinel-golang/pkg/adapters/gin
 
 go 1.13
 
+replace github.com/alibaba/sentinel-golang => ../../../
+
 require (
 	github.com/StackExchange/wmi v0.0.0-20190523213315-cbe66965904d // indirect
 	github.com/alibaba/sentinel-golang v1.0.2
@@ -21,13 +21,19 @@ func SentinelMiddleware(opts ...Option) gin.HandlerFunc {
 			resourceName = options.resourceExtract(c)
 		}
 
-		entry, err := sentinel.Entry(
-			resourceName,
+		entryOpts := []sentinel.EntryOption{
 			sentinel.WithResourceType(base.ResTypeWeb),
 			sentinel.WithTrafficType(base.Inbound),
-		)
+		}
+		if options.rateLimitHeaders {
+			entryOpts = append(entryOpts, sentinel.WithRateLimitInfo())
+		}
+		entry, err := sentinel.Entry(resourceName, entryOpts...)
 
 		if err != nil {
+			if options.rateLimitHeaders {
+				base.WriteBlockHeaders(err, c.Header)
+			}
 			if options.blockFallback != nil {
 				options.blockFallback(c)
 			} else {
@@ -37,6 +43,9 @@ func SentinelMiddleware(opts ...Option) gin.HandlerFunc {
 		}
 
 		defer entry.Exit()
+		if options.rateLimitHeaders {
+			base.WriteRateLimitHeaders(entry.RateLimitInfo(), c.Header)
+		}
 		c.Next()
 	}
 }
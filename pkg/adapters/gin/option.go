@@ -9,6 +9,8 @@ type (
 	options struct {
 		resourceExtract func(*gin.Context) string
 		blockFallback   func(*gin.Context)
+		// rateLimitHeaders indicates whether to write the RateLimit headers on both pass and block.
+		rateLimitHeaders bool
 	}
 )
 
@@ -34,3 +36,12 @@ func WithBlockFallback(fn func(ctx *gin.Context)) Option {
 		opts.blockFallback = fn
 	}
 }
+
+// WithRateLimitHeaders enables writing the IETF RateLimit headers
+// (RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy)
+// on both passed and blocked requests, and Retry-After on blocked requests.
+func WithRateLimitHeaders() Option {
+	return func(opts *options) {
+		opts.rateLimitHeaders = true
+	}
+}
@@ -2,6 +2,8 @@ module github.com/alibaba/sentinel-golang/pkg/adapters/hertz
 
 go 1.16
 
+replace github.com/alibaba/sentinel-golang => ../../../
+
 require (
 	github.com/alibaba/sentinel-golang v1.0.4
 	github.com/cloudwego/hertz v0.2.0
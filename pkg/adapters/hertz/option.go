@@ -16,6 +16,8 @@ type (
 	serverOptions struct {
 		resourceExtract func(context.Context, *app.RequestContext) string
 		blockFallback   func(context.Context, *app.RequestContext)
+		// rateLimitHeaders indicates whether to write the RateLimit headers on both pass and block.
+		rateLimitHeaders bool
 	}
 )
 
@@ -86,6 +88,17 @@ func WithServerBlockFallback(fn func(context.Context, *app.RequestContext)) Serv
 	}
 }
 
+// WithServerRateLimitHeaders enables writing the IETF RateLimit headers
+// (RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy)
+// on both passed and blocked requests, and Retry-After on blocked requests.
+func WithServerRateLimitHeaders() ServerOption {
+	return ServerOption{
+		Fn: func(o *serverOptions) {
+			o.rateLimitHeaders = true
+		},
+	}
+}
+
 // WithClientResourceExtractor sets the resource extractor of the web requests for client side.
 func WithClientResourceExtractor(fn func(context.Context, *protocol.Request,
 	*protocol.Response) string,
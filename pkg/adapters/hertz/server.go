@@ -17,16 +17,25 @@ func SentinelServerMiddleware(opts ...ServerOption) app.HandlerFunc {
 	return func(c context.Context, ctx *app.RequestContext) {
 		resourceName := options.resourceExtract(c, ctx)
 
-		entry, err := sentinel.Entry(
-			resourceName,
+		entryOpts := []sentinel.EntryOption{
 			sentinel.WithResourceType(base.ResTypeWeb),
 			sentinel.WithTrafficType(base.Inbound),
-		)
+		}
+		if options.rateLimitHeaders {
+			entryOpts = append(entryOpts, sentinel.WithRateLimitInfo())
+		}
+		entry, err := sentinel.Entry(resourceName, entryOpts...)
 		if err != nil {
+			if options.rateLimitHeaders {
+				base.WriteBlockHeaders(err, ctx.Response.Header.Set)
+			}
 			options.blockFallback(c, ctx)
 			return
 		}
 		defer entry.Exit()
+		if options.rateLimitHeaders {
+			base.WriteRateLimitHeaders(entry.RateLimitInfo(), ctx.Response.Header.Set)
+		}
 		ctx.Next(c)
 	}
 }
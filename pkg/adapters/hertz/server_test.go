@@ -10,6 +10,7 @@ import (
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/common/config"
 	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/protocol"
 	"github.com/cloudwego/hertz/pkg/route"
 	"github.com/stretchr/testify/assert"
 )
@@ -125,3 +126,45 @@ func TestServerSentinelMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestServerSentinelMiddleware_RateLimitHeaders(t *testing.T) {
+	initServerSentinel(t)
+	_, err := flow.LoadRules([]*flow.Rule{
+		{
+			Resource:               "GET:/server_headers",
+			Threshold:              2.0,
+			TokenCalculateStrategy: flow.Direct,
+			ControlBehavior:        flow.Reject,
+			StatIntervalInMs:       1000,
+		},
+	})
+	assert.NoError(t, err)
+	defer flow.ClearRules()
+
+	router := route.NewEngine(config.NewOptions([]config.Option{}))
+	router.Use(SentinelServerMiddleware(WithServerRateLimitHeaders()))
+	router.GET("/server_headers", func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	serve := func() *protocol.Response {
+		return ut.PerformRequest(router, http.MethodGet, "/server_headers", nil).Result()
+	}
+
+	resp := serve()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=1", resp.Header.Get("RateLimit-Policy"))
+	assert.NotEmpty(t, resp.Header.Get("RateLimit-Reset"))
+	assert.Empty(t, resp.Header.Get("Retry-After"))
+
+	resp = serve()
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+
+	resp = serve()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+	assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+}
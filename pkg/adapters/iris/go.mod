@@ -2,6 +2,8 @@ module github.com/alibaba/sentinel-golang/pkg/adapters/iris/v12
 
 go 1.20
 
+replace github.com/alibaba/sentinel-golang => ../../../
+
 require (
 	github.com/alibaba/sentinel-golang v1.0.4
 	github.com/kataras/iris/v12 v12.2.0
@@ -17,13 +17,19 @@ func SentinelMiddleware(opts ...Option) iris.Handler {
 			resourceName = options.resourceExtract(c)
 		}
 
-		entry, err := sentinel.Entry(
-			resourceName,
+		entryOpts := []sentinel.EntryOption{
 			sentinel.WithResourceType(base.ResTypeWeb),
 			sentinel.WithTrafficType(base.Inbound),
-		)
+		}
+		if options.rateLimitHeaders {
+			entryOpts = append(entryOpts, sentinel.WithRateLimitInfo())
+		}
+		entry, err := sentinel.Entry(resourceName, entryOpts...)
 
 		if err != nil {
+			if options.rateLimitHeaders {
+				base.WriteBlockHeaders(err, c.Header)
+			}
 			if options.blockFallback != nil {
 				options.blockFallback(c)
 			} else {
@@ -34,6 +40,9 @@ func SentinelMiddleware(opts ...Option) iris.Handler {
 		}
 
 		defer entry.Exit()
+		if options.rateLimitHeaders {
+			base.WriteRateLimitHeaders(entry.RateLimitInfo(), c.Header)
+		}
 		c.Next()
 	}
 }
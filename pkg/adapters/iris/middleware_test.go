@@ -141,3 +141,42 @@ func TestSentinelMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestSentinelMiddleware_RateLimitHeaders(t *testing.T) {
+	initSentinel(t)
+	_, err := flow.LoadRules([]*flow.Rule{
+		{
+			Resource:               "GET:/headers",
+			Threshold:              2.0,
+			TokenCalculateStrategy: flow.Direct,
+			ControlBehavior:        flow.Reject,
+			StatIntervalInMs:       1000,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+	defer flow.ClearRules()
+
+	router := iris.New()
+	router.Use(SentinelMiddleware(WithRateLimitHeaders()))
+	router.Get("/headers", func(ctx iris.Context) {
+		_, _ = ctx.WriteString("ok")
+	})
+	e := httptest.New(t, router)
+
+	resp := e.GET("/headers").Expect().Status(http.StatusOK)
+	resp.Header("RateLimit-Limit").Equal("2")
+	resp.Header("RateLimit-Remaining").Equal("1")
+	resp.Header("RateLimit-Policy").Equal("2;w=1")
+	resp.Header("RateLimit-Reset").NotEmpty()
+	resp.Header("Retry-After").Empty()
+
+	resp = e.GET("/headers").Expect().Status(http.StatusOK)
+	resp.Header("RateLimit-Remaining").Equal("0")
+
+	resp = e.GET("/headers").Expect().Status(http.StatusTooManyRequests)
+	resp.Header("RateLimit-Limit").Equal("2")
+	resp.Header("RateLimit-Remaining").Equal("0")
+	resp.Header("Retry-After").NotEmpty()
+}
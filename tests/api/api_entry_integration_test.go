@@ -24,6 +24,7 @@ func initSentinel() {
 	conf.Sentinel.Log.Metric.FlushIntervalSec = 0
 	conf.Sentinel.Stat.System.CollectIntervalMs = 0
 	conf.Sentinel.Stat.System.CollectMemoryIntervalMs = 0
+	conf.Sentinel.Stat.System.CollectRuntimeIntervalMs = 0
 	conf.Sentinel.Stat.System.CollectCpuIntervalMs = 0
 	conf.Sentinel.Stat.System.CollectLoadIntervalMs = 0
 	err := api.InitWithConfig(conf)
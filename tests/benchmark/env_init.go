@@ -30,6 +30,7 @@ func InitSentinel() {
 	conf.Sentinel.Log.Metric.FlushIntervalSec = 0
 	conf.Sentinel.Stat.System.CollectIntervalMs = 0
 	conf.Sentinel.Stat.System.CollectMemoryIntervalMs = 0
+	conf.Sentinel.Stat.System.CollectRuntimeIntervalMs = 0
 	conf.Sentinel.Stat.System.CollectCpuIntervalMs = 0
 	conf.Sentinel.Stat.System.CollectLoadIntervalMs = 0
 	err := sentinel.InitWithConfig(conf)
@@ -0,0 +1,89 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import (
+	"testing"
+
+	sentinel "github.com/alibaba/sentinel-golang/api"
+	"github.com/alibaba/sentinel-golang/core/flow"
+)
+
+const (
+	resSlidingLog = "abc-sliding-log"
+)
+
+func init() {
+	_, err := flow.LoadRulesOfResource(resSlidingLog, []*flow.Rule{
+		{
+			Resource:               resSlidingLog,
+			TokenCalculateStrategy: flow.Direct,
+			ControlBehavior:        flow.SlidingLog,
+			Threshold:              5,
+			StatIntervalInMs:       60000,
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func benchmarkSlidingLogChecker(b *testing.B, threshold float64, statIntervalMs uint32) {
+	tc := flow.NewSlidingLogChecker(nil, uint32(threshold), statIntervalMs)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tc.DoCheck(nil, 1, threshold)
+	}
+}
+
+func Benchmark_SlidingLogChecker_DoCheck_5PerMinute(b *testing.B) {
+	benchmarkSlidingLogChecker(b, 5, 60000)
+}
+
+func Benchmark_SlidingLogChecker_DoCheck_1000PerSecond(b *testing.B) {
+	benchmarkSlidingLogChecker(b, 1000, 1000)
+}
+
+func Benchmark_SlidingLogChecker_DoCheck_10000PerMillisecond(b *testing.B) {
+	// Most of the requests pass, which is the worst case of log replacing.
+	benchmarkSlidingLogChecker(b, 10000, 1)
+}
+
+func Benchmark_SlidingLogChecker_DoCheck_Parallel_8(b *testing.B) {
+	tc := flow.NewSlidingLogChecker(nil, 1000, 1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.SetParallelism(8)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			tc.DoCheck(nil, 1, 1000)
+		}
+	})
+}
+
+func Benchmark_SlidingLog_SlotCheck_4(b *testing.B) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.SetParallelism(4)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			// Most of the requests are blocked by the low threshold.
+			if se, err := sentinel.Entry(resSlidingLog); err == nil {
+				se.Exit()
+			}
+		}
+	})
+}
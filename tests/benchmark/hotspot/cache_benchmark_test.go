@@ -0,0 +1,106 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hotspot
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/alibaba/sentinel-golang/core/hotspot/cache"
+)
+
+const (
+	cacheSize     = 4000
+	workloadSize  = 1 << 20
+	zipfKeySpace  = 1000000
+	attackPercent = 50
+)
+
+var cacheFactories = []struct {
+	name    string
+	factory func(size int) cache.ConcurrentCounterCache
+}{
+	{name: "LRU", factory: cache.NewLRUCacheMap},
+	{name: "TinyLFU", factory: cache.NewTinyLfuCacheMap},
+}
+
+// zipfWorkload generates the keys following zipf distribution, which simulates the real hot parameters.
+// attackPercent of keys are replaced with one-off random keys, which simulates a high-cardinality attack.
+func zipfWorkload(attackPercent int) []interface{} {
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.1, 1, zipfKeySpace)
+	keys := make([]interface{}, workloadSize)
+	for i := range keys {
+		if r.Intn(100) < attackPercent {
+			// Random user ids never seen again.
+			keys[i] = zipfKeySpace + r.Int63()
+		} else {
+			keys[i] = int64(zipf.Uint64())
+		}
+	}
+	return keys
+}
+
+func benchmarkHitRatio(b *testing.B, factory func(size int) cache.ConcurrentCounterCache, keys []interface{}) {
+	hits, total := 0, 0
+	for i := 0; i < b.N; i++ {
+		c := factory(cacheSize)
+		for _, key := range keys {
+			val := int64(0)
+			if c.AddIfAbsent(key, &val) != nil {
+				hits++
+			}
+			total++
+		}
+	}
+	b.ReportMetric(float64(hits)*100/float64(total), "hit%")
+}
+
+func Benchmark_Cache_HitRatio_Zipf(b *testing.B) {
+	keys := zipfWorkload(0)
+	for _, f := range cacheFactories {
+		b.Run(f.name, func(b *testing.B) {
+			benchmarkHitRatio(b, f.factory, keys)
+		})
+	}
+}
+
+func Benchmark_Cache_HitRatio_Zipf_Attack(b *testing.B) {
+	keys := zipfWorkload(attackPercent)
+	for _, f := range cacheFactories {
+		b.Run(f.name, func(b *testing.B) {
+			benchmarkHitRatio(b, f.factory, keys)
+		})
+	}
+}
+
+func Benchmark_Cache_Throughput_Parallel(b *testing.B) {
+	keys := zipfWorkload(attackPercent)
+	for _, f := range cacheFactories {
+		b.Run(f.name, func(b *testing.B) {
+			c := f.factory(cacheSize)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := rand.Intn(workloadSize)
+				for pb.Next() {
+					val := int64(0)
+					c.AddIfAbsent(keys[i&(workloadSize-1)], &val)
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 1999-2020 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stat benchmarks the single and the sharded counters of the metric buckets (see config.ShardedCounter)
+// on the hot resource, the difference shows with the high core count:
+//
+//	go test -run=^$ -bench=. -cpu 1,8,32,64 ./tests/benchmark/stat/
+package stat
+
+import (
+	"testing"
+
+	sentinel "github.com/alibaba/sentinel-golang/api"
+	"github.com/alibaba/sentinel-golang/core/base"
+	"github.com/alibaba/sentinel-golang/core/config"
+	"github.com/alibaba/sentinel-golang/core/stat"
+	sbase "github.com/alibaba/sentinel-golang/core/stat/base"
+	"github.com/alibaba/sentinel-golang/tests/benchmark"
+)
+
+func init() {
+	benchmark.InitSentinel()
+}
+
+// withShardedCounter runs f with given setting of config.ShardedCounter, the buckets created in f honor the setting.
+func withShardedCounter(b *testing.B, sharded bool, f func()) {
+	conf := config.NewDefaultConfig()
+	conf.Sentinel.Stat.ShardedCounter = sharded
+	config.ResetGlobalConfig(conf)
+	defer config.ResetGlobalConfig(config.NewDefaultConfig())
+
+	b.ReportAllocs()
+	f()
+}
+
+func benchmarkMetricBucketAdd(b *testing.B, sharded bool) {
+	withShardedCounter(b, sharded, func() {
+		mb := sbase.NewMetricBucket()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				mb.Add(base.MetricEventPass, 1)
+			}
+		})
+	})
+}
+
+func Benchmark_MetricBucket_Add_Single(b *testing.B) {
+	benchmarkMetricBucketAdd(b, false)
+}
+
+func Benchmark_MetricBucket_Add_Sharded(b *testing.B) {
+	benchmarkMetricBucketAdd(b, true)
+}
+
+func benchmarkMetricBucketGet(b *testing.B, sharded bool) {
+	withShardedCounter(b, sharded, func() {
+		mb := sbase.NewMetricBucket()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = mb.Get(base.MetricEventPass)
+			}
+		})
+	})
+}
+
+func Benchmark_MetricBucket_Get_Single(b *testing.B) {
+	benchmarkMetricBucketGet(b, false)
+}
+
+func Benchmark_MetricBucket_Get_Sharded(b *testing.B) {
+	benchmarkMetricBucketGet(b, true)
+}
+
+func benchmarkBucketLeapArrayAddCount(b *testing.B, sharded bool) {
+	withShardedCounter(b, sharded, func() {
+		bla := sbase.NewBucketLeapArray(config.GlobalStatisticSampleCountTotal(), config.GlobalStatisticIntervalMsTotal())
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				bla.AddCount(base.MetricEventPass, 1)
+			}
+		})
+	})
+}
+
+func Benchmark_BucketLeapArray_AddCount_Single(b *testing.B) {
+	benchmarkBucketLeapArrayAddCount(b, false)
+}
+
+func Benchmark_BucketLeapArray_AddCount_Sharded(b *testing.B) {
+	benchmarkBucketLeapArrayAddCount(b, true)
+}
+
+func benchmarkEntry(b *testing.B, resource string, sharded bool) {
+	withShardedCounter(b, sharded, func() {
+		sc := base.NewSlotChain()
+		sc.AddStatPrepareSlot(stat.DefaultResourceNodePrepareSlot)
+		sc.AddStatSlot(stat.DefaultSlot)
+		// create the resource node with the setting
+		stat.GetOrCreateResourceNode(resource, base.ResTypeCommon)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				e, blockErr := sentinel.Entry(resource, sentinel.WithSlotChain(sc))
+				if blockErr == nil {
+					e.Exit()
+				}
+			}
+		})
+	})
+}
+
+func Benchmark_Entry_Single(b *testing.B) {
+	benchmarkEntry(b, "metric_bucket_benchmark_single", false)
+}
+
+func Benchmark_Entry_Sharded(b *testing.B) {
+	benchmarkEntry(b, "metric_bucket_benchmark_sharded", true)
+}